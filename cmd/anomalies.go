@@ -0,0 +1,187 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/analytics"
+	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/notify"
+	"github.com/peter941221/CICost/internal/store"
+)
+
+// exitAnomalies is the exit code of `anomalies --fail` when something was
+// flagged.
+const exitAnomalies = 4
+
+func runAnomalies(args []string) error {
+	rt, err := newRuntimeContext()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("anomalies", flag.ContinueOnError)
+	repoFlag := fs.String("repo", "", "Target repository in owner/repo format")
+	orgFlag := fs.String("org", "", "Check every repo of this org or user recorded by scan instead of --repo")
+	groupByFlag := fs.String("group-by", "workflow", "Series to check: repo|workflow|job|runner")
+	daysFlag := fs.Int("days", 28, "Baseline days before the checked days")
+	checkDaysFlag := fs.Int("check-days", 1, "Complete days to check, ending yesterday")
+	thresholdFlag := fs.Float64("threshold", analytics.DefaultAnomalyThreshold, "Robust z-score (median/MAD) a day must reach")
+	minUSDFlag := fs.Float64("min-usd", 1, "Ignore days cheaper than this (USD)")
+	topRunsFlag := fs.Int("top-runs", 3, "Runs to attribute each anomaly to")
+	formatFlag := fs.String("format", "table", "Output format: table|json")
+	failFlag := fs.Bool("fail", false, "Exit with code 4 when anomalies are found")
+	notifyFlag := fs.String("notify", rt.cfg.Budget.Notify, "Notification mode: stdout|webhook|file")
+	webhookFlag := fs.String("webhook-url", rt.cfg.Budget.WebhookURL, "Webhook URL")
+	notifierFlag := fs.String("notifier", rt.cfg.Budget.Notifier, "Named notifier from notifiers: to use when notify=webhook")
+	outputFlag := fs.String("output", "", "Output file path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *daysFlag < 7 {
+		return fmt.Errorf("--days must be at least 7")
+	}
+	if *checkDaysFlag < 1 {
+		return fmt.Errorf("--check-days must be positive")
+	}
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		return err
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	var repos []string
+	if owner := strings.TrimSpace(*orgFlag); owner != "" {
+		outcomes, err := st.ListScanOutcomes(owner)
+		if err != nil {
+			return err
+		}
+		for _, o := range outcomes {
+			if o.Status != store.ScanFailed {
+				repos = append(repos, o.Repo)
+			}
+		}
+		if len(repos) == 0 {
+			return fmt.Errorf("no scanned repositories for %s, run `cicost scan --org %s` first", owner, owner)
+		}
+	} else {
+		repo, err := pickRepo(*repoFlag, rt.cfg)
+		if err != nil {
+			return err
+		}
+		repos = []string{repo}
+	}
+
+	pcfg, err := loadPricingConfig(rt)
+	if err != nil {
+		return err
+	}
+	now := time.Now().UTC()
+	end := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	checkFrom := end.AddDate(0, 0, -*checkDaysFlag)
+	start := checkFrom.AddDate(0, 0, -*daysFlag)
+	data := make([]analytics.RollupRepo, 0, len(repos))
+	for _, repo := range repos {
+		runs, err := st.ListRuns(repo, start, end)
+		if err != nil {
+			return err
+		}
+		jobs, err := st.ListJobs(repo, start, end)
+		if err != nil {
+			return err
+		}
+		data = append(data, analytics.RollupRepo{Repo: repo, Runs: runs, Jobs: jobs})
+	}
+	anomalies, err := analytics.DetectAnomalies(data, pcfg, start, checkFrom, end, analytics.AnomalyOptions{
+		GroupBy:   *groupByFlag,
+		Threshold: *thresholdFlag,
+		MinUSD:    *minUSDFlag,
+		TopRuns:   *topRunsFlag,
+	})
+	if err != nil {
+		return err
+	}
+
+	payload := map[string]any{
+		"type":        "anomalies",
+		"repos":       repos,
+		"group_by":    strings.ToLower(strings.TrimSpace(*groupByFlag)),
+		"check_from":  checkFrom,
+		"check_until": end,
+		"threshold":   *thresholdFlag,
+		"anomalies":   anomalies,
+	}
+	var msg string
+	switch strings.ToLower(strings.TrimSpace(*formatFlag)) {
+	case "json":
+		b, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			return err
+		}
+		msg = string(b) + "\n"
+	default:
+		msg = renderAnomalies(repos, checkFrom, end, anomalies)
+	}
+	opts := notifyOptions{Mode: *notifyFlag, WebhookURL: *webhookFlag, Notifier: *notifierFlag, Output: *outputFlag, DefaultFile: "anomalies.txt"}
+	if len(anomalies) == 0 && opts.mode() != "file" {
+		// Nothing to tell a webhook about.
+		opts.Mode = "stdout"
+	}
+	facts := make([]notify.Fact, 0, len(anomalies))
+	for _, a := range anomalies {
+		facts = append(facts, notify.Fact{
+			Name:  fmt.Sprintf("%s %s (%s)", a.GroupType, a.Name, a.Repo),
+			Value: fmt.Sprintf("$%.2f vs median $%.2f on %s", a.CostUSD, a.BaselineUSD, a.Date.Format("2006-01-02")),
+		})
+	}
+	if err := sendNotification(rt.cfg, opts, notify.Message{
+		Kind:     "anomalies",
+		Title:    fmt.Sprintf("Cost anomalies for %s: %d", strings.Join(repos, ", "), len(anomalies)),
+		Severity: notify.SeverityWarning,
+		Text:     msg,
+		Facts:    facts,
+		Data:     payload,
+	}); err != nil {
+		return err
+	}
+
+	if *failFlag && len(anomalies) > 0 {
+		return withExit(exitAnomalies, fmt.Errorf("cost anomalies found: %d", len(anomalies)))
+	}
+	return nil
+}
+
+func renderAnomalies(repos []string, checkFrom, end time.Time, anomalies []analytics.Anomaly) string {
+	var b strings.Builder
+	period := checkFrom.Format("2006-01-02")
+	if last := end.AddDate(0, 0, -1); last.After(checkFrom) {
+		period += " ~ " + last.Format("2006-01-02")
+	}
+	if len(anomalies) == 0 {
+		fmt.Fprintf(&b, "No cost anomalies for %s on %s\n", strings.Join(repos, ", "), period)
+		return b.String()
+	}
+	fmt.Fprintf(&b, "Cost anomalies for %s on %s: %d\n", strings.Join(repos, ", "), period, len(anomalies))
+	for _, a := range anomalies {
+		ratio := "new"
+		if a.Ratio > 0 {
+			ratio = fmt.Sprintf("%.1fx", a.Ratio)
+		}
+		fmt.Fprintf(&b, "\n%s  %s %s (%s)\n", a.Date.Format("2006-01-02"), a.GroupType, a.Name, a.Repo)
+		fmt.Fprintf(&b, "  Cost     : $%.2f vs median $%.2f (%s, score %.1f)\n", a.CostUSD, a.BaselineUSD, ratio, a.Score)
+		if len(a.TopRuns) > 0 {
+			b.WriteString("  Top runs :\n")
+			for i, r := range a.TopRuns {
+				fmt.Fprintf(&b, "    %d. $%.2f %s on %s (%s) %s\n", i+1, r.CostUSD, r.Workflow, r.Branch, r.Event, r.URL)
+			}
+		}
+	}
+	return b.String()
+}
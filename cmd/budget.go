@@ -1,20 +1,22 @@
 package cmd
 
 import (
-	"bytes"
-	"encoding/json"
 	"flag"
 	"fmt"
-	"net/http"
+	"math"
 	"strings"
 	"time"
 
 	"github.com/peter941221/CICost/internal/analytics"
 	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/notify"
 	"github.com/peter941221/CICost/internal/store"
 )
 
 func runBudget(args []string) error {
+	if len(args) > 0 && args[0] == "check" {
+		return runBudgetCheck(args[1:])
+	}
 	rt, err := newRuntimeContext()
 	if err != nil {
 		return err
@@ -25,7 +27,10 @@ func runBudget(args []string) error {
 	weeklyFlag := fs.Float64("weekly", 0, "Weekly budget threshold (USD)")
 	notifyFlag := fs.String("notify", rt.cfg.Budget.Notify, "Notification mode: stdout|webhook|file")
 	webhookFlag := fs.String("webhook-url", rt.cfg.Budget.WebhookURL, "Webhook URL")
+	notifierFlag := fs.String("notifier", rt.cfg.Budget.Notifier, "Named notifier from notifiers: to use when notify=webhook")
 	outputFlag := fs.String("output", "", "Output file path")
+	renotifyFlag := fs.String("renotify", "", "Repeat an alert still in force after this long, e.g. 24h (default: budget.renotify_interval)")
+	dryRunFlag := fs.Bool("dry-run", false, "Show what would be sent without sending or recording it")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -33,6 +38,10 @@ func runBudget(args []string) error {
 	if err != nil {
 		return err
 	}
+	renotify, err := renotifyInterval(rt.cfg, *renotifyFlag)
+	if err != nil {
+		return err
+	}
 
 	checkType := "monthly"
 	threshold := rt.cfg.Budget.Monthly
@@ -57,8 +66,20 @@ func runBudget(args []string) error {
 	}
 	defer st.Close()
 
+	pcfg, err := loadPricingConfig(rt)
+	if err != nil {
+		return err
+	}
+	owner, _, err := splitRepo(repo)
+	if err != nil {
+		return err
+	}
+	if pcfg, err = withCycleUsage(st, owner, pcfg); err != nil {
+		return err
+	}
+
 	now := time.Now().UTC()
-	start, _ := analytics.PeriodBounds(now, checkType)
+	start, end := analytics.PeriodBounds(now, checkType, pcfg.BillingCycleDay)
 	runs, err := st.ListRuns(repo, start, now)
 	if err != nil {
 		return err
@@ -67,23 +88,40 @@ func runBudget(args []string) error {
 	if err != nil {
 		return err
 	}
-	pcfg, err := loadPricingConfig(rt)
+	cost, _, _, err := accountCost(st, repo, jobs, start, now, pcfg)
 	if err != nil {
 		return err
 	}
-	cost, _ := analytics.CalculateCost(jobs, pcfg, 1.0)
-	result := analytics.EvaluateBudget(now, cost.TotalCostUSD, threshold, checkType)
+	forecast, err := spendForecast(st, repo, now, start, end, cost.TotalCostUSD, defaultForecastHistoryDays, pcfg)
+	if err != nil {
+		return err
+	}
+	check := analytics.EvaluateScopedBudget(now, analytics.Budget{
+		Name:       repo,
+		Period:     checkType,
+		AmountUSD:  threshold,
+		Thresholds: []float64{100},
+	}, cost.TotalCostUSD, &forecast, pcfg.BillingCycleDay)
+	result := check.BudgetResult
 	top := analytics.CalculateHotspots(runs, jobs, pcfg, analytics.HotspotOptions{
 		GroupBy: "workflow",
 		TopN:    3,
 		SortBy:  "cost",
 	})
 
-	_ = st.InsertBudgetCheck(repo, checkType, start, now, threshold, cost.TotalCostUSD, result.Status != analytics.BudgetOK)
+	if !*dryRunFlag {
+		_ = st.InsertBudgetCheck(repo, checkType, start, now, threshold, cost.TotalCostUSD, result.Status != analytics.BudgetOK)
+	}
+	alert, err := decideBudgetAlert(st, repo+"#"+checkType, check, now, renotify)
+	if err != nil {
+		return err
+	}
 
-	msg := fmt.Sprintf("Budget %s for %s\n  Period   : %s ~ %s\n  Budget   : $%.2f\n  Actual   : $%.2f\n  Projected: $%.2f\n",
+	msg := fmt.Sprintf("Budget %s for %s\n  Period   : %s ~ %s\n  Budget   : $%.2f\n  Actual   : $%.2f (gross $%.2f, %.0f free min)\n  Projected: $%.2f (%.0f%% interval $%.2f ~ $%.2f, %s)\n  Free tier: %.0f of %.0f min used this cycle (%s plan)\n",
 		strings.ToUpper(string(result.Status)), repo, start.Format("2006-01-02"), now.Format("2006-01-02"),
-		result.ThresholdUSD, result.ActualUSD, result.ProjectedUSD)
+		result.ThresholdUSD, result.ActualUSD, cost.GrossCostUSD, cost.FreeTierUsed, result.ProjectedUSD,
+		forecast.Confidence*100, result.ProjectedLowerUSD, result.ProjectedUpperUSD, result.ForecastMethod,
+		math.Min(pcfg.AlreadyUsedThisMon, pcfg.FreeTierPerMonth), pcfg.FreeTierPerMonth, rt.cfg.FreeTier.Plan)
 	if len(top) > 0 {
 		msg += "  Top Contributors:\n"
 		for i, e := range top {
@@ -91,40 +129,44 @@ func runBudget(args []string) error {
 		}
 	}
 
-	switch strings.ToLower(*notifyFlag) {
-	case "file":
-		target := *outputFlag
-		if target == "" {
-			target = "budget.txt"
-		}
-		if err := writeOutput(target, msg); err != nil {
-			return err
-		}
-	case "webhook":
-		if strings.TrimSpace(*webhookFlag) == "" {
-			return fmt.Errorf("webhook-url is required when notify=webhook")
-		}
-		payload := map[string]any{
-			"status":           string(result.Status),
-			"repo":             repo,
-			"budget_usd":       result.ThresholdUSD,
-			"actual_usd":       result.ActualUSD,
-			"projected_usd":    result.ProjectedUSD,
-			"top_contributors": top,
-		}
-		b, _ := json.Marshal(payload)
-		resp, err := http.Post(*webhookFlag, "application/json", bytes.NewReader(b))
-		if err != nil {
-			fmt.Printf("WARN: webhook send failed: %v\n", err)
-		} else {
-			_ = resp.Body.Close()
-			if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-				fmt.Printf("WARN: webhook returned status %d\n", resp.StatusCode)
-			}
+	if alert.Action != analytics.AlertNone {
+		msg += "  Notify   : " + alert.Message + "\n"
+	}
+
+	message := func([]budgetAlert) notify.Message {
+		return notify.Message{
+			Kind:     "budget",
+			Title:    alert.Message,
+			Severity: alertSeverity([]budgetAlert{alert}),
+			Text:     msg,
+			Facts: []notify.Fact{
+				{Name: "Period", Value: fmt.Sprintf("%s %s ~ %s", checkType, start.Format("2006-01-02"), now.Format("2006-01-02"))},
+				{Name: "Budget", Value: fmt.Sprintf("$%.2f", result.ThresholdUSD)},
+				{Name: "Actual", Value: fmt.Sprintf("$%.2f (%.1f%%)", result.ActualUSD, result.PercentageUsed)},
+				{Name: "Projected", Value: fmt.Sprintf("$%.2f", result.ProjectedUSD)},
+			},
+			Data: map[string]any{
+				"status":           string(result.Status),
+				"action":           alert.Action,
+				"level":            alert.Level,
+				"message":          alert.Message,
+				"repo":             repo,
+				"budget_usd":       result.ThresholdUSD,
+				"actual_usd":       result.ActualUSD,
+				"gross_usd":        cost.GrossCostUSD,
+				"projected_usd":    result.ProjectedUSD,
+				"projected_lower":  result.ProjectedLowerUSD,
+				"projected_upper":  result.ProjectedUpperUSD,
+				"forecast_method":  result.ForecastMethod,
+				"free_tier_used":   pcfg.AlreadyUsedThisMon,
+				"free_tier_min":    pcfg.FreeTierPerMonth,
+				"top_contributors": top,
+			},
 		}
-		fmt.Print(msg)
-	default:
-		fmt.Print(msg)
+	}
+	opts := notifyOptions{Mode: *notifyFlag, WebhookURL: *webhookFlag, Notifier: *notifierFlag, Output: *outputFlag, DefaultFile: "budget.txt", DryRun: *dryRunFlag}
+	if err := notifyBudgetAlerts(st, rt.cfg, opts, msg, []budgetAlert{alert}, message, now); err != nil {
+		return err
 	}
 
 	if result.Status == analytics.BudgetExceeded || result.Status == analytics.BudgetWarning {
@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/analytics"
+	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/notify"
+	"github.com/peter941221/CICost/internal/store"
+)
+
+// budgetAlert is the notification decided for one budget check.
+type budgetAlert struct {
+	Key      string                `json:"key"`
+	Action   string                `json:"action"`
+	Level    float64               `json:"level"`
+	Message  string                `json:"message"`
+	Check    analytics.BudgetCheck `json:"check"`
+	decision analytics.AlertDecision
+}
+
+func renotifyInterval(cfg config.Config, flagValue string) (time.Duration, error) {
+	v := strings.TrimSpace(flagValue)
+	if v == "" {
+		v = strings.TrimSpace(cfg.Budget.RenotifyInterval)
+	}
+	if v == "" {
+		return 0, nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid renotify interval %q (use a duration such as 24h)", v)
+	}
+	return d, nil
+}
+
+// decideBudgetAlert compares check with the notifications already sent for
+// key in its period.
+func decideBudgetAlert(st *store.Store, key string, check analytics.BudgetCheck, now time.Time, renotify time.Duration) (budgetAlert, error) {
+	stored, err := st.ListNotificationStates(key, check.PeriodStart)
+	if err != nil {
+		return budgetAlert{}, err
+	}
+	states := make([]analytics.AlertState, 0, len(stored))
+	for _, s := range stored {
+		states = append(states, analytics.AlertState{Level: s.Level, Status: s.Status, NotifiedAt: s.NotifiedAt})
+	}
+	d := analytics.DecideAlert(check, states, now, renotify)
+	a := budgetAlert{Key: key, Action: d.Action, Level: d.Level, Check: check, decision: d}
+	a.Message = budgetAlertMessage(a)
+	return a, nil
+}
+
+// activeAlerts drops the alerts with nothing to send.
+func activeAlerts(alerts []budgetAlert) []budgetAlert {
+	var out []budgetAlert
+	for _, a := range alerts {
+		if a.Action != analytics.AlertNone {
+			out = append(out, a)
+		}
+	}
+	return out
+}
+
+func budgetAlertMessage(a budgetAlert) string {
+	c := a.Check
+	level := fmt.Sprintf("%.0f%%", a.Level)
+	if a.Level == 0 {
+		level = "projected overrun"
+	}
+	switch a.Action {
+	case analytics.AlertFire, analytics.AlertRenotify:
+		prefix := "ALERT"
+		if a.Action == analytics.AlertRenotify {
+			prefix = "REMINDER"
+		}
+		return fmt.Sprintf("%s %s reached %s: $%.2f of $%.2f, projected $%.2f (%s %s)", prefix, c.Name, level,
+			c.ActualUSD, c.ThresholdUSD, c.ProjectedUSD, c.CheckType, c.PeriodStart.Format("2006-01-02"))
+	case analytics.AlertRecovered:
+		return fmt.Sprintf("RECOVERED %s back under %s: $%.2f of $%.2f, projected $%.2f (%s %s)", c.Name, level,
+			c.ActualUSD, c.ThresholdUSD, c.ProjectedUSD, c.CheckType, c.PeriodStart.Format("2006-01-02"))
+	}
+	return ""
+}
+
+// notifyBudgetAlerts prints report and, for the webhook and file modes,
+// delivers it only when alerts has something not already sent this period,
+// then records what was sent so the next run stays quiet. With DryRun
+// nothing is sent or recorded.
+func notifyBudgetAlerts(st *store.Store, cfg config.Config, opts notifyOptions, report string, alerts []budgetAlert, message func([]budgetAlert) notify.Message, now time.Time) error {
+	mode := opts.mode()
+	if mode != "webhook" && mode != "file" {
+		fmt.Print(report)
+		return nil
+	}
+	via := mode
+	var n notify.Notifier
+	if mode == "webhook" {
+		var err error
+		if n, err = notifierFor(cfg, opts); err != nil {
+			return err
+		}
+		via = "webhook " + n.Name()
+	}
+	active := activeAlerts(alerts)
+
+	if opts.DryRun {
+		fmt.Print(report)
+		if len(active) == 0 {
+			fmt.Printf("\nDry run: nothing new to send via %s\n", via)
+			return nil
+		}
+		fmt.Printf("\nDry run: would send via %s:\n", via)
+		for _, a := range active {
+			fmt.Printf("  %s\n", a.Message)
+		}
+		return nil
+	}
+
+	switch mode {
+	case "webhook":
+		fmt.Print(report)
+		if len(active) == 0 {
+			return nil
+		}
+		if err := n.Send(context.Background(), message(active)); err != nil {
+			// Unrecorded, so the next run tries again.
+			fmt.Printf("WARN: %v\n", err)
+			return nil
+		}
+	case "file":
+		if len(active) == 0 {
+			return nil
+		}
+		target := opts.Output
+		if target == "" {
+			target = opts.DefaultFile
+		}
+		if err := writeOutput(target, report); err != nil {
+			return err
+		}
+	}
+	return recordBudgetAlerts(st, active, now)
+}
+
+// alertSeverity is the message severity of alerts: error when one is for
+// an exceeded budget, warning for a warning and info when all recovered.
+func alertSeverity(alerts []budgetAlert) string {
+	severity := notify.SeverityInfo
+	for _, a := range alerts {
+		if a.Action == analytics.AlertRecovered {
+			continue
+		}
+		switch a.Check.Status {
+		case analytics.BudgetExceeded:
+			return notify.SeverityError
+		case analytics.BudgetWarning:
+			severity = notify.SeverityWarning
+		}
+	}
+	return severity
+}
+
+// alertFacts lists each alert's budget and spend for card layouts.
+func alertFacts(alerts []budgetAlert) []notify.Fact {
+	facts := make([]notify.Fact, 0, len(alerts))
+	for _, a := range alerts {
+		c := a.Check
+		facts = append(facts, notify.Fact{
+			Name:  c.Name,
+			Value: fmt.Sprintf("%s: $%.2f of $%.2f (%.1f%%), projected $%.2f", a.Action, c.ActualUSD, c.ThresholdUSD, c.PercentageUsed, c.ProjectedUSD),
+		})
+	}
+	return facts
+}
+
+func recordBudgetAlerts(st *store.Store, alerts []budgetAlert, now time.Time) error {
+	var states []store.NotificationState
+	for _, a := range alerts {
+		for _, level := range a.decision.Alerted {
+			states = append(states, store.NotificationState{BudgetKey: a.Key, PeriodStart: a.Check.PeriodStart, Level: level, Status: analytics.AlertStateAlerted, NotifiedAt: now})
+		}
+		for _, level := range a.decision.Recovered {
+			states = append(states, store.NotificationState{BudgetKey: a.Key, PeriodStart: a.Check.PeriodStart, Level: level, Status: analytics.AlertStateRecovered, NotifiedAt: now})
+		}
+	}
+	return st.UpsertNotificationStates(states)
+}
@@ -0,0 +1,374 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/analytics"
+	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/glob"
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/notify"
+	"github.com/peter941221/CICost/internal/store"
+)
+
+type budgetCheckPayload struct {
+	GeneratedAt time.Time                       `json:"generated_at"`
+	Budgets     []analytics.BudgetCheck         `json:"budgets"`
+	Summary     map[string]int                  `json:"summary"`
+	Contributor map[string][]model.HotspotEntry `json:"top_contributors,omitempty"`
+	Alerts      []budgetAlert                   `json:"alerts,omitempty"`
+}
+
+// runBudgetCheck evaluates every budget of the budgets: config section in
+// one pass and records each in budget_checks.
+func runBudgetCheck(args []string) error {
+	rt, err := newRuntimeContext()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("budget check", flag.ContinueOnError)
+	nameFlag := fs.String("name", "", "Only check these budgets (comma-separated names)")
+	formatFlag := fs.String("format", "table", "Output format: table|json")
+	notifyFlag := fs.String("notify", rt.cfg.Budget.Notify, "Notification mode: stdout|webhook|file")
+	webhookFlag := fs.String("webhook-url", rt.cfg.Budget.WebhookURL, "Webhook URL")
+	notifierFlag := fs.String("notifier", rt.cfg.Budget.Notifier, "Named notifier from notifiers: to use when notify=webhook")
+	outputFlag := fs.String("output", "", "Output file path")
+	renotifyFlag := fs.String("renotify", "", "Repeat an alert still in force after this long, e.g. 24h (default: budget.renotify_interval)")
+	dryRunFlag := fs.Bool("dry-run", false, "Show what would be sent without sending or recording it")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	budgets, err := configuredBudgets(rt.cfg, splitList(*nameFlag))
+	if err != nil {
+		return err
+	}
+	renotify, err := renotifyInterval(rt.cfg, *renotifyFlag)
+	if err != nil {
+		return err
+	}
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		return err
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+	pcfg, err := loadPricingConfig(rt)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	histStart, _ := forecastHistoryWindow(now, defaultForecastHistoryDays)
+	loaded := map[string]analytics.RollupRepo{}
+	payload := budgetCheckPayload{GeneratedAt: now, Summary: map[string]int{}, Contributor: map[string][]model.HotspotEntry{}}
+	var alerts []budgetAlert
+	for _, b := range budgets {
+		start, end := analytics.PeriodBounds(now, b.Period, pcfg.BillingCycleDay)
+		repos, err := budgetRepos(st, rt.cfg, b.Scope)
+		if err != nil {
+			return fmt.Errorf("budget %q: %w", b.Name, err)
+		}
+		loadStart := histStart
+		if start.Before(loadStart) {
+			loadStart = start
+		}
+		data := make([]analytics.RollupRepo, 0, len(repos))
+		for _, repo := range repos {
+			r, ok := loaded[repo]
+			if !ok {
+				if r.Runs, err = st.ListRuns(repo, loadStart, now); err != nil {
+					return err
+				}
+				if r.Jobs, err = st.ListJobs(repo, loadStart, now); err != nil {
+					return err
+				}
+				r.Repo = repo
+				loaded[repo] = r
+			}
+			data = append(data, r)
+		}
+
+		scoped := analytics.ScopedJobs(data, b.Scope)
+		byRepo := map[string][]model.Job{}
+		for _, j := range scoped {
+			if !j.StartedAt.Before(start) && !j.StartedAt.After(now) {
+				byRepo[j.Repo] = append(byRepo[j.Repo], j)
+			}
+		}
+		actual := 0.0
+		for repo, jobs := range byRepo {
+			cost, _, _, err := accountCost(st, repo, jobs, start, now, pcfg)
+			if err != nil {
+				return err
+			}
+			actual += cost.TotalCostUSD
+		}
+		freeLeft := 0.0
+		if b.Scope.WholeRepos() {
+			// Free minutes still left belong to the whole account, so they
+			// only soften forecasts of budgets that cover whole repos, each
+			// by its share of the account's usage.
+			for _, repo := range repos {
+				owner, _, _ := strings.Cut(repo, "/")
+				ocfg, err := withCycleUsage(st, owner, pcfg)
+				if err != nil {
+					return err
+				}
+				left, err := repoFreeMinutesLeft(st, repo, now, histStart, ocfg)
+				if err != nil {
+					return err
+				}
+				freeLeft += left
+			}
+		}
+		forecast, err := forecastJobs(scoped, now, start, end, actual, defaultForecastHistoryDays, freeLeft, pcfg)
+		if err != nil {
+			return err
+		}
+		check := analytics.EvaluateScopedBudget(now, b, actual, &forecast, pcfg.BillingCycleDay)
+		check.Repos = repos
+
+		prev, err := st.ListBudgetChecks(b.Name, 1)
+		if err != nil {
+			return err
+		}
+		if len(prev) > 0 && prev[0].PeriodStart.Equal(check.PeriodStart) {
+			check.PreviousLevel = prev[0].Level
+		}
+		alert, err := decideBudgetAlert(st, b.Name, check, now, renotify)
+		if err != nil {
+			return err
+		}
+		alerts = append(alerts, alert)
+		// A dry run leaves history alone too, so it never shifts the previous
+		// level the next real run compares with.
+		if !*dryRunFlag {
+			if err := st.InsertBudgetCheckRecord(store.BudgetCheckRecord{
+				Name:         b.Name,
+				Scope:        b.Scope.String(),
+				Repos:        strings.Join(repos, ","),
+				CheckType:    b.Period,
+				PeriodStart:  check.PeriodStart,
+				PeriodEnd:    check.PeriodEnd,
+				ThresholdUSD: b.AmountUSD,
+				ActualUSD:    check.ActualUSD,
+				ProjectedUSD: check.ProjectedUSD,
+				Level:        check.Level,
+				Status:       string(check.Status),
+				Exceeded:     check.Status == analytics.BudgetExceeded,
+				CheckedAt:    now,
+			}); err != nil {
+				return err
+			}
+		}
+		if check.Status != analytics.BudgetOK {
+			var runs []model.WorkflowRun
+			for _, r := range data {
+				runs = append(runs, r.Runs...)
+			}
+			var periodJobs []model.Job
+			for _, jobs := range byRepo {
+				periodJobs = append(periodJobs, jobs...)
+			}
+			payload.Contributor[b.Name] = analytics.CalculateHotspots(runs, periodJobs, pcfg, analytics.HotspotOptions{GroupBy: "workflow", TopN: 3, SortBy: "cost"})
+		}
+		payload.Budgets = append(payload.Budgets, check)
+		payload.Summary[string(check.Status)]++
+	}
+
+	payload.Alerts = activeAlerts(alerts)
+
+	var msg string
+	switch strings.ToLower(strings.TrimSpace(*formatFlag)) {
+	case "json":
+		b, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			return err
+		}
+		msg = string(b) + "\n"
+	default:
+		msg = renderBudgetChecks(payload)
+	}
+	message := func(active []budgetAlert) notify.Message {
+		return notify.Message{
+			Kind:     "budget",
+			Title:    fmt.Sprintf("Budget check: %d of %d budgets changed level", len(active), len(payload.Budgets)),
+			Severity: alertSeverity(active),
+			Text:     msg,
+			Facts:    alertFacts(active),
+			Data:     payload,
+		}
+	}
+	opts := notifyOptions{Mode: *notifyFlag, WebhookURL: *webhookFlag, Notifier: *notifierFlag, Output: *outputFlag, DefaultFile: "budget.txt", DryRun: *dryRunFlag}
+	if err := notifyBudgetAlerts(st, rt.cfg, opts, msg, alerts, message, now); err != nil {
+		return err
+	}
+	if alerts := payload.Summary[string(analytics.BudgetWarning)] + payload.Summary[string(analytics.BudgetExceeded)]; alerts > 0 {
+		return withExit(2, fmt.Errorf("budgets over threshold: %d", alerts))
+	}
+	return nil
+}
+
+// configuredBudgets validates the budgets: config section, keeping only
+// names when given.
+func configuredBudgets(cfg config.Config, names []string) ([]analytics.Budget, error) {
+	if len(cfg.Budgets) == 0 {
+		return nil, fmt.Errorf("no budgets configured: add a budgets: section to .cicost.yml")
+	}
+	want := map[string]bool{}
+	for _, n := range names {
+		want[n] = false
+	}
+	seen := map[string]struct{}{}
+	var out []analytics.Budget
+	for i, c := range cfg.Budgets {
+		name := strings.TrimSpace(c.Name)
+		if name == "" {
+			return nil, fmt.Errorf("budgets[%d]: name is required", i)
+		}
+		if _, dup := seen[name]; dup {
+			return nil, fmt.Errorf("budgets[%d]: duplicate name %q", i, name)
+		}
+		seen[name] = struct{}{}
+		period := strings.ToLower(strings.TrimSpace(c.Period))
+		switch period {
+		case "":
+			period = "monthly"
+		case "monthly", "weekly":
+		default:
+			return nil, fmt.Errorf("budget %q: invalid period %q (use monthly|weekly)", name, c.Period)
+		}
+		if c.AmountUSD <= 0 {
+			return nil, fmt.Errorf("budget %q: amount_usd must be positive", name)
+		}
+		thresholds := append([]float64(nil), c.Thresholds...)
+		if len(thresholds) == 0 {
+			thresholds = append(thresholds, analytics.DefaultBudgetThresholds...)
+		}
+		for _, t := range thresholds {
+			if t <= 0 {
+				return nil, fmt.Errorf("budget %q: thresholds must be positive percentages", name)
+			}
+		}
+		sort.Float64s(thresholds)
+		for _, f := range [][2]string{{"repo", c.Scope.Repo}, {"workflow", c.Scope.Workflow}, {"branch", c.Scope.Branch}} {
+			if err := glob.Validate(strings.TrimSpace(f[1])); err != nil {
+				return nil, fmt.Errorf("budget %q: invalid %s pattern %q: %w", name, f[0], f[1], err)
+			}
+		}
+		if len(want) > 0 {
+			if _, ok := want[name]; !ok {
+				continue
+			}
+			want[name] = true
+		}
+		out = append(out, analytics.Budget{
+			Name:       name,
+			Period:     period,
+			AmountUSD:  c.AmountUSD,
+			Thresholds: thresholds,
+			Scope: analytics.BudgetScope{
+				Repo:     strings.TrimSpace(c.Scope.Repo),
+				Org:      strings.TrimSpace(c.Scope.Org),
+				Workflow: strings.TrimSpace(c.Scope.Workflow),
+				RunnerOS: strings.TrimSpace(c.Scope.RunnerOS),
+				Branch:   strings.TrimSpace(c.Scope.Branch),
+			},
+		})
+	}
+	for n, found := range want {
+		if !found {
+			return nil, fmt.Errorf("no budget named %q", n)
+		}
+	}
+	return out, nil
+}
+
+// budgetRepos resolves the repositories a scope covers: its literal repo,
+// the scanned repositories of its org (or of the owner of a repo glob), or
+// the configured repos.
+func budgetRepos(st *store.Store, cfg config.Config, scope analytics.BudgetScope) ([]string, error) {
+	if scope.Repo != "" && !strings.ContainsAny(scope.Repo, "*?[") {
+		if _, _, err := splitRepo(scope.Repo); err != nil {
+			return nil, err
+		}
+		return []string{scope.Repo}, nil
+	}
+	owner := scope.Org
+	if owner == "" && scope.Repo != "" {
+		owner, _, _ = strings.Cut(scope.Repo, "/")
+		if strings.ContainsAny(owner, "*?[") {
+			return nil, fmt.Errorf("repo glob %q needs a literal owner or an org scope", scope.Repo)
+		}
+	}
+	var candidates []string
+	if owner != "" {
+		outcomes, err := st.ListScanOutcomes(owner)
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range outcomes {
+			if o.Status != store.ScanFailed {
+				candidates = append(candidates, o.Repo)
+			}
+		}
+	} else {
+		candidates = cfg.Repos
+	}
+	var repos []string
+	for _, r := range candidates {
+		if scope.MatchesRepo(r) {
+			repos = append(repos, r)
+		}
+	}
+	if len(repos) == 0 {
+		return nil, fmt.Errorf("no repositories in scope %s (scan them first or list them under repos:)", scope)
+	}
+	return repos, nil
+}
+
+func renderBudgetChecks(p budgetCheckPayload) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Budget check: %d budgets (%d exceeded, %d warning, %d ok)\n\n", len(p.Budgets),
+		p.Summary[string(analytics.BudgetExceeded)], p.Summary[string(analytics.BudgetWarning)], p.Summary[string(analytics.BudgetOK)])
+	fmt.Fprintf(&b, "%-20s %-8s %10s %10s %7s %10s %-14s %-8s  %s\n", "BUDGET", "PERIOD", "AMOUNT", "ACTUAL", "USED", "PROJECTED", "LEVEL", "STATUS", "SCOPE")
+	for _, c := range p.Budgets {
+		level := "-"
+		if c.Level > 0 {
+			level = fmt.Sprintf("%.0f%%", c.Level)
+		}
+		if c.Level > c.PreviousLevel && c.PreviousLevel > 0 {
+			level += fmt.Sprintf(" (was %.0f%%)", c.PreviousLevel)
+		} else if c.Level > 0 && c.PreviousLevel == 0 {
+			level += " (new)"
+		}
+		fmt.Fprintf(&b, "%-20s %-8s %10.2f %10.2f %6.1f%% %10.2f %-14s %-8s  %s\n",
+			c.Name, c.CheckType, c.ThresholdUSD, c.ActualUSD, c.PercentageUsed, c.ProjectedUSD, level, strings.ToUpper(string(c.Status)), c.Scope)
+	}
+	if len(p.Alerts) > 0 {
+		b.WriteString("\nNotifications:\n")
+		for _, a := range p.Alerts {
+			fmt.Fprintf(&b, "  %s\n", a.Message)
+		}
+	}
+	for _, c := range p.Budgets {
+		top := p.Contributor[c.Name]
+		if len(top) == 0 {
+			continue
+		}
+		fmt.Fprintf(&b, "\nTop contributors to %s:\n", c.Name)
+		for i, e := range top {
+			fmt.Fprintf(&b, "  %d. %s $%.2f\n", i+1, e.Name, e.CostUSD)
+		}
+	}
+	return b.String()
+}
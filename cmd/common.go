@@ -1,15 +1,21 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"strings"
+	"syscall"
 	"time"
 
+	"github.com/peter941221/CICost/internal/analytics"
 	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/model"
 	"github.com/peter941221/CICost/internal/pricing"
+	"github.com/peter941221/CICost/internal/store"
 )
 
 type runtimeContext struct {
@@ -34,6 +40,12 @@ func pickRepo(input string, cfg config.Config) (string, error) {
 	return "", errors.New("repo is required (use --repo owner/repo)")
 }
 
+// signalContext returns a context cancelled on Ctrl-C or SIGTERM, so long
+// running commands can stop cleanly and keep the progress already stored.
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
 func splitRepo(full string) (owner, repo string, err error) {
 	parts := strings.Split(strings.TrimSpace(full), "/")
 	if len(parts) != 2 || parts[0] == "" || parts[1] == "" {
@@ -42,6 +54,18 @@ func splitRepo(full string) (owner, repo string, err error) {
 	return parts[0], parts[1], nil
 }
 
+// splitList splits a comma separated flag value, dropping empty items.
+func splitList(in string) []string {
+	parts := strings.Split(in, ",")
+	out := make([]string, 0, len(parts))
+	for _, p := range parts {
+		if s := strings.TrimSpace(p); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
 func calcPeriod(days int) (time.Time, time.Time) {
 	now := time.Now().UTC()
 	start := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).AddDate(0, 0, -days+1)
@@ -83,7 +107,7 @@ func loadPricingConfig(rt runtimeContext) (pricing.Config, error) {
 		break
 	}
 	if !loaded {
-		cfg = pricing.Config{}
+		cfg = pricing.Config{Storage: pricing.DefaultStorageRates}
 	}
 	if cfg.PerMinuteUSD == 0 {
 		cfg.PerMinuteUSD = rt.cfg.Pricing.LinuxPerMin
@@ -94,9 +118,101 @@ func loadPricingConfig(rt runtimeContext) (pricing.Config, error) {
 	if rt.cfg.Pricing.MacOSMultiplier > 0 {
 		cfg.MacOSMultiplier = rt.cfg.Pricing.MacOSMultiplier
 	}
-	cfg.FreeTierPerMonth = rt.cfg.FreeTier.MinutesPerMonth
+	plan, err := cfg.Plan(rt.cfg.FreeTier.Plan)
+	if err != nil {
+		return pricing.Config{}, err
+	}
+	cfg.FreeTierPerMonth = plan.Minutes
+	if rt.cfg.FreeTier.MinutesPerMonth > 0 {
+		cfg.FreeTierPerMonth = rt.cfg.FreeTier.MinutesPerMonth
+	}
+	cfg.FreeStorageGB = plan.StorageGB
+	if rt.cfg.FreeTier.StorageGB > 0 {
+		cfg.FreeStorageGB = rt.cfg.FreeTier.StorageGB
+	}
+	if d := rt.cfg.FreeTier.BillingCycleDay; d < 0 || d > 31 {
+		return pricing.Config{}, fmt.Errorf("invalid free_tier.billing_cycle_day %d (use 1-31)", d)
+	}
+	cfg.BillingCycleDay = rt.cfg.FreeTier.BillingCycleDay
+	switch alloc := strings.ToLower(strings.TrimSpace(rt.cfg.FreeTier.Allocation)); alloc {
+	case "", analytics.AllocateChronological:
+		cfg.FreeTierAllocation = analytics.AllocateChronological
+	case analytics.AllocateProRata:
+		cfg.FreeTierAllocation = alloc
+	default:
+		return pricing.Config{}, fmt.Errorf("invalid free_tier.allocation %q (use chronological|pro-rata)", rt.cfg.FreeTier.Allocation)
+	}
 	if len(cfg.Snapshots) == 0 {
 		fmt.Fprintln(os.Stderr, "WARN: pricing_snapshots not found; fallback to legacy OS multiplier pricing.")
 	}
 	return cfg, nil
 }
+
+// accountCost prices the jobs of repo with the free tier allocated across
+// every scanned repository of the same owner, starting from the billing
+// cycle containing start.
+func accountCost(st *store.Store, repo string, jobs []model.Job, start, end time.Time, cfg pricing.Config) (model.CostResult, map[int64]float64, analytics.CostPricingMeta, error) {
+	account, cfg, err := accountJobs(st, repo, start, end, cfg)
+	if err != nil {
+		return model.CostResult{}, nil, analytics.CostPricingMeta{}, err
+	}
+	return analytics.CalculateAccountCost(jobs, account, cfg, 1.0)
+}
+
+// accountJobs loads the jobs of repo's owner that share the free tier with
+// [start, end] and returns cfg with the owner's usage in the current cycle.
+func accountJobs(st *store.Store, repo string, start, end time.Time, cfg pricing.Config) ([]model.Job, pricing.Config, error) {
+	owner, _, err := splitRepo(repo)
+	if err != nil {
+		return nil, cfg, err
+	}
+	if cfg, err = withCycleUsage(st, owner, cfg); err != nil {
+		return nil, cfg, err
+	}
+	account, err := st.ListAccountJobs(owner, analytics.AccountStart(start, cfg.BillingCycleDay), end)
+	if err != nil {
+		return nil, cfg, err
+	}
+	return account, cfg, nil
+}
+
+// withCycleUsage sets cfg.AlreadyUsedThisMon to the stored usage of owner in
+// the current billing cycle, unless a caller has already set it.
+func withCycleUsage(st *store.Store, owner string, cfg pricing.Config) (pricing.Config, error) {
+	if cfg.AlreadyUsedThisMon > 0 {
+		return cfg, nil
+	}
+	now := time.Now().UTC()
+	jobs, err := st.ListAccountJobs(owner, pricing.CycleStart(now, cfg.BillingCycleDay), now)
+	if err != nil {
+		return cfg, err
+	}
+	used, err := analytics.HostedMinutes(jobs, cfg)
+	if err != nil {
+		return cfg, err
+	}
+	cfg.AlreadyUsedThisMon = used
+	return cfg, nil
+}
+
+// storageCost prices the artifact and cache storage of repo over
+// [start, end], sharing included storage across the owner's repositories.
+func storageCost(st *store.Store, repo string, start, end time.Time, cfg pricing.Config) (model.StorageCost, []model.Artifact, []model.ActionsCache, error) {
+	owner, _, err := splitRepo(repo)
+	if err != nil {
+		return model.StorageCost{}, nil, nil, err
+	}
+	artifacts, err := st.ListArtifacts(repo, start, end)
+	if err != nil {
+		return model.StorageCost{}, nil, nil, err
+	}
+	caches, err := st.ListCaches(repo, start, end)
+	if err != nil {
+		return model.StorageCost{}, nil, nil, err
+	}
+	account, err := st.ListAccountArtifacts(owner, start, end)
+	if err != nil {
+		return model.StorageCost{}, nil, nil, err
+	}
+	return analytics.CalculateStorage(artifacts, caches, account, start, end, cfg), artifacts, caches, nil
+}
@@ -0,0 +1,219 @@
+package cmd
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/analytics"
+	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+	"github.com/peter941221/CICost/internal/store"
+)
+
+// defaultForecastHistoryDays is eight weeks, enough for Holt-Winters to
+// settle on a weekday pattern.
+const defaultForecastHistoryDays = 56
+
+func runForecast(args []string) error {
+	rt, err := newRuntimeContext()
+	if err != nil {
+		return err
+	}
+	fs := flag.NewFlagSet("forecast", flag.ContinueOnError)
+	repoFlag := fs.String("repo", "", "Target repository in owner/repo format")
+	periodFlag := fs.String("period", "monthly", "Period to forecast: monthly|weekly")
+	historyFlag := fs.Int("history-days", defaultForecastHistoryDays, "Days of stored history to fit")
+	formatFlag := fs.String("format", "table", "Output format: table|json")
+	outputFlag := fs.String("output", "", "Output file path")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	repo, err := pickRepo(*repoFlag, rt.cfg)
+	if err != nil {
+		return err
+	}
+	checkType := strings.ToLower(strings.TrimSpace(*periodFlag))
+	if checkType != "monthly" && checkType != "weekly" {
+		return fmt.Errorf("invalid --period %q (use monthly|weekly)", *periodFlag)
+	}
+	if *historyFlag < 1 {
+		return fmt.Errorf("--history-days must be positive")
+	}
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		return err
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		return err
+	}
+	defer st.Close()
+
+	pcfg, err := loadPricingConfig(rt)
+	if err != nil {
+		return err
+	}
+	owner, _, err := splitRepo(repo)
+	if err != nil {
+		return err
+	}
+	if pcfg, err = withCycleUsage(st, owner, pcfg); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	start, end := analytics.PeriodBounds(now, checkType, pcfg.BillingCycleDay)
+	jobs, err := st.ListJobs(repo, start, now)
+	if err != nil {
+		return err
+	}
+	cost, _, _, err := accountCost(st, repo, jobs, start, now, pcfg)
+	if err != nil {
+		return err
+	}
+	f, err := spendForecast(st, repo, now, start, end, cost.TotalCostUSD, *historyFlag, pcfg)
+	if err != nil {
+		return err
+	}
+	if f.HistoryDays == 0 && len(jobs) == 0 {
+		return fmt.Errorf("no data in local store for %s, run `cicost scan --repo %s` first", repo, repo)
+	}
+
+	var rendered string
+	switch strings.ToLower(strings.TrimSpace(*formatFlag)) {
+	case "json":
+		b, err := json.MarshalIndent(map[string]any{"repo": repo, "forecast": f}, "", "  ")
+		if err != nil {
+			return err
+		}
+		rendered = string(b) + "\n"
+	default:
+		rendered = renderForecast(repo, f)
+	}
+	return writeOutput(*outputFlag, rendered)
+}
+
+// spendForecast forecasts net spend of repo for [start, end) from
+// historyDays of stored daily history before today. actual is the net spend
+// from start to now.
+func spendForecast(st *store.Store, repo string, now, start, end time.Time, actual float64, historyDays int, cfg pricing.Config) (analytics.Forecast, error) {
+	histStart, today := forecastHistoryWindow(now, historyDays)
+	jobs, err := st.ListJobs(repo, histStart, today)
+	if err != nil {
+		return analytics.Forecast{}, err
+	}
+	freeLeft, err := repoFreeMinutesLeft(st, repo, now, histStart, cfg)
+	if err != nil {
+		return analytics.Forecast{}, err
+	}
+	return forecastJobs(jobs, now, start, end, actual, historyDays, freeLeft, cfg)
+}
+
+// repoFreeMinutesLeft is repo's part of the free minutes its account has
+// left this cycle, pro-rated by the repo's share of the account's hosted
+// minutes so far in the cycle, as AllocateFreeTier shares them. Before the
+// account has used any, the share is taken over the history from histStart.
+func repoFreeMinutesLeft(st *store.Store, repo string, now, histStart time.Time, cfg pricing.Config) (float64, error) {
+	left := math.Max(cfg.FreeTierPerMonth-cfg.AlreadyUsedThisMon, 0)
+	if left == 0 {
+		return 0, nil
+	}
+	owner, _, err := splitRepo(repo)
+	if err != nil {
+		return 0, err
+	}
+	for _, from := range []time.Time{pricing.CycleStart(now, cfg.BillingCycleDay), histStart} {
+		account, err := st.ListAccountJobs(owner, from, now)
+		if err != nil {
+			return 0, err
+		}
+		var own []model.Job
+		for _, j := range account {
+			if j.Repo == repo {
+				own = append(own, j)
+			}
+		}
+		total, err := analytics.HostedMinutes(account, cfg)
+		if err != nil {
+			return 0, err
+		}
+		if total == 0 {
+			continue
+		}
+		used, err := analytics.HostedMinutes(own, cfg)
+		if err != nil {
+			return 0, err
+		}
+		return left * used / total, nil
+	}
+	return 0, nil
+}
+
+// forecastHistoryWindow is the historyDays of complete days before now.
+func forecastHistoryWindow(now time.Time, historyDays int) (time.Time, time.Time) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	return today.AddDate(0, 0, -historyDays), today
+}
+
+// forecastJobs forecasts [start, end) from the daily history of jobs, which
+// may extend past the history window.
+func forecastJobs(jobs []model.Job, now, start, end time.Time, actual float64, historyDays int, freeMinutesLeft float64, cfg pricing.Config) (analytics.Forecast, error) {
+	histStart, today := forecastHistoryWindow(now, historyDays)
+	history, err := analytics.DailyHistory(jobs, cfg, histStart, today)
+	if err != nil {
+		return analytics.Forecast{}, err
+	}
+	// Leading days before the first stored job are not zero spend, just
+	// days the store knows nothing about.
+	history = trimLeadingEmpty(history, jobs)
+	return analytics.ForecastSpend(analytics.ForecastInput{
+		History:         history,
+		Now:             now,
+		PeriodStart:     start,
+		PeriodEnd:       end,
+		ActualUSD:       actual,
+		FreeMinutesLeft: freeMinutesLeft,
+	}), nil
+}
+
+func trimLeadingEmpty(history []analytics.DailySpend, jobs []model.Job) []analytics.DailySpend {
+	var first time.Time
+	for _, j := range jobs {
+		if !j.StartedAt.IsZero() && (first.IsZero() || j.StartedAt.Before(first)) {
+			first = j.StartedAt
+		}
+	}
+	if first.IsZero() {
+		return nil
+	}
+	first = first.UTC()
+	firstDay := time.Date(first.Year(), first.Month(), first.Day(), 0, 0, 0, 0, time.UTC)
+	for i, d := range history {
+		if !d.Date.Before(firstDay) {
+			return history[i:]
+		}
+	}
+	return nil
+}
+
+func renderForecast(repo string, f analytics.Forecast) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Spend forecast for %s\n", repo)
+	fmt.Fprintf(&b, "  Period   : %s ~ %s\n", f.PeriodStart.Format("2006-01-02"), f.PeriodEnd.AddDate(0, 0, -1).Format("2006-01-02"))
+	fmt.Fprintf(&b, "  Method   : %s (%d days of history)\n", f.Method, f.HistoryDays)
+	fmt.Fprintf(&b, "  Actual   : $%.2f\n", f.ActualUSD)
+	fmt.Fprintf(&b, "  Forecast : $%.2f (%.0f%% interval $%.2f ~ $%.2f)\n", f.ForecastUSD, f.Confidence*100, f.LowerUSD, f.UpperUSD)
+	if len(f.Daily) > 0 {
+		b.WriteString("\nDate        Weekday  Gross(USD)  Interval\n")
+		for _, d := range f.Daily {
+			fmt.Fprintf(&b, "%s  %-7s  %10.2f  %.2f ~ %.2f\n", d.Date.Format("2006-01-02"), d.Date.Weekday().String()[:3], d.GrossUSD, d.LowerUSD, d.UpperUSD)
+		}
+	}
+	return b.String()
+}
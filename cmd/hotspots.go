@@ -18,7 +18,7 @@ func runHotspots(args []string) error {
 	fs := flag.NewFlagSet("hotspots", flag.ContinueOnError)
 	repoFlag := fs.String("repo", "", "Target repository in owner/repo format")
 	daysFlag := fs.Int("days", rt.cfg.Scan.Days, "Time window in days")
-	groupByFlag := fs.String("group-by", "workflow", "Group by: workflow|job|runner|branch")
+	groupByFlag := fs.String("group-by", "workflow", "Group by: workflow|path|job|step|runner|branch|event")
 	topFlag := fs.Int("top", 10, "Show top N entries")
 	sortFlag := fs.String("sort", "cost", "Sort by: cost|minutes|fail_rate")
 	formatFlag := fs.String("format", "table", "Output format: table|md|json")
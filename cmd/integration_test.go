@@ -2,15 +2,23 @@ package cmd
 
 import (
 	"encoding/json"
+	"encoding/xml"
 	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/peter941221/CICost/internal/config"
 	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/notify"
+	"github.com/peter941221/CICost/internal/pricing"
 	"github.com/peter941221/CICost/internal/store"
 )
 
@@ -436,3 +444,1485 @@ func TestOrgReportPartialResult(t *testing.T) {
 		t.Fatalf("expected success_repos=1")
 	}
 }
+
+func TestReconcileGitHubSourceIntegration(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	now := time.Now().UTC()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/organizations/owner/settings/billing/usage" {
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer test-token" {
+			t.Errorf("expected bearer token, got %q", r.Header.Get("Authorization"))
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		_, _ = fmt.Fprintf(w, `{"usageItems":[{"date":"%s","product":"Actions","sku":"Actions Linux","netAmount":7.5,"repositoryName":"repo"},{"date":"%s","product":"Actions","sku":"Actions Linux","netAmount":4,"repositoryName":"other"}]}`,
+			now.Format(time.RFC3339), now.Format(time.RFC3339))
+	}))
+	defer srv.Close()
+	t.Setenv("CICOST_GITHUB_API_BASE_URL", srv.URL)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	if _, _, err := st.UpsertRuns([]model.WorkflowRun{
+		{ID: 601, Repo: "owner/repo", WorkflowID: 1, WorkflowName: "ci", Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: now, UpdatedAt: now, RunStartedAt: now},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs([]model.Job{
+		{ID: 602, RunID: 601, RunAttempt: 1, Repo: "owner/repo", Name: "build", Status: "completed", RunnerOS: "Linux", DurationSec: 400000, StartedAt: now, CompletedAt: now.Add(400000 * time.Second)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	month := now.Format("2006-01")
+	if err := runReconcile([]string{"--repo", "owner/repo", "--month", month, "--source", "github", "--token", "test-token"}); err != nil {
+		t.Fatal(err)
+	}
+	snap, ok, err := st.GetBillingSnapshot("owner/repo", month)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || snap.Source != "github" || snap.ActualCostUSD != 7.5 {
+		t.Fatalf("unexpected github billing snapshot: %+v", snap)
+	}
+	if other, ok, _ := st.GetBillingSnapshot("owner/other", month); !ok || other.ActualCostUSD != 4 {
+		t.Fatalf("expected sibling repo snapshot stored, got %+v", other)
+	}
+	rec, ok, err := st.GetLatestReconcile("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || rec.ActualCostUSD != 7.5 {
+		t.Fatalf("expected reconcile against github actual, got %+v", rec)
+	}
+}
+
+func TestReconcileUsageReportIntegration(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC()
+	if _, _, err := st.UpsertRuns([]model.WorkflowRun{
+		{ID: 701, Repo: "owner/repo", WorkflowID: 1, WorkflowName: "ci", Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: now, UpdatedAt: now, RunStartedAt: now},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs([]model.Job{
+		{ID: 702, RunID: 701, RunAttempt: 1, Repo: "owner/repo", Name: "build", Status: "completed", RunnerOS: "Linux", DurationSec: 400000, StartedAt: now, CompletedAt: now.Add(400000 * time.Second)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	day := now.Format("2006-01-02")
+	report := filepath.Join(tmp, "usage.csv")
+	content := "Date,Product,SKU,Quantity,Unit Type,Price Per Unit ($),Multiplier,Owner,Repository Slug,Username,Actions Workflow,Notes\n" +
+		day + ",Actions,Compute - UBUNTU,500,minute,0.008,1.0,owner,repo,dev,.github/workflows/ci.yml,\n" +
+		day + ",Actions,Compute - UBUNTU,250,minute,0.008,1.0,owner,other,dev,.github/workflows/ci.yml,\n"
+	if err := os.WriteFile(report, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	month := now.Format("2006-01")
+	if err := runReconcile([]string{"--repo", "owner/repo", "--month", month, "--source", "csv", "--input", report}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := st.ListBillingSnapshots("owner/repo", month, "usage_report")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].SKU != "linux" || rows[0].Workflow != ".github/workflows/ci.yml" || rows[0].ActualCostUSD != 4 {
+		t.Fatalf("unexpected usage report rows: %+v", rows)
+	}
+	if other, ok, _ := st.GetBillingSnapshot("owner/other", month); !ok || other.ActualCostUSD != 2 {
+		t.Fatalf("expected sibling repo imported from report, got %+v", other)
+	}
+	rec, ok, err := st.GetLatestReconcile("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !ok || rec.ActualCostUSD != 4 {
+		t.Fatalf("expected reconcile against usage report actual, got %+v", rec)
+	}
+}
+
+func TestScanResumesPendingJobFetches(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	created := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	var mu sync.Mutex
+	jobHits := map[string]int{}
+	failRun2 := true
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/runs":
+			_, _ = fmt.Fprintf(w, `{"total_count":2,"workflow_runs":[{"id":1,"workflow_id":5,"name":"ci","status":"completed","conclusion":"success","run_attempt":1,"created_at":"%[1]s","updated_at":"%[1]s","run_started_at":"%[1]s"},{"id":2,"workflow_id":5,"name":"ci","status":"completed","conclusion":"success","run_attempt":1,"created_at":"%[1]s","updated_at":"%[1]s","run_started_at":"%[1]s"}]}`, created)
+		case "/repos/owner/repo/actions/runs/1/attempts/1/jobs", "/repos/owner/repo/actions/runs/2/attempts/1/jobs":
+			mu.Lock()
+			jobHits[r.URL.Path]++
+			fail := failRun2 && strings.Contains(r.URL.Path, "/runs/2/")
+			mu.Unlock()
+			if fail {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"boom"}`))
+				return
+			}
+			id := 10
+			if strings.Contains(r.URL.Path, "/runs/2/") {
+				id = 20
+			}
+			_, _ = fmt.Fprintf(w, `{"total_count":1,"jobs":[{"id":%d,"name":"build","status":"completed","conclusion":"success","started_at":"%[2]s","completed_at":"%[2]s","labels":["ubuntu-latest"]}]}`, id, created)
+		default:
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("CICOST_GITHUB_API_BASE_URL", srv.URL)
+
+	if err := runScan([]string{"--repo", "owner/repo", "--token", "test-token", "--workers", "1", "--storage=false"}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	failRun2 = false
+	mu.Unlock()
+	if err := runScan([]string{"--repo", "owner/repo", "--token", "test-token", "--workers", "1", "--storage=false"}); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if jobHits["/repos/owner/repo/actions/runs/1/attempts/1/jobs"] != 1 {
+		t.Fatalf("expected finished run not to be refetched, hits=%v", jobHits)
+	}
+	if jobHits["/repos/owner/repo/actions/runs/2/attempts/1/jobs"] != 2 {
+		t.Fatalf("expected failed run to be retried once, hits=%v", jobHits)
+	}
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	pending, err := st.ListPendingJobFetches("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("expected no pending job fetches, got %+v", pending)
+	}
+	if cur, ok, _ := st.GetCursor("owner/repo"); !ok || cur.TotalJobs != 1 {
+		t.Fatalf("expected cursor written after resumed scan, got %+v", cur)
+	}
+}
+
+func TestScanOrgRecordsOutcomesForOrgReport(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	created := time.Now().UTC().Add(-time.Hour).Format(time.RFC3339)
+	var mu sync.Mutex
+	listed := map[string]bool{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/orgs/acme/repos":
+			_, _ = w.Write([]byte(`[
+{"full_name":"acme/api","name":"api","topics":["ci"]},
+{"full_name":"acme/web","name":"web","topics":["ci","frontend"]},
+{"full_name":"acme/legacy-app","name":"legacy-app","topics":["ci"]},
+{"full_name":"acme/old","name":"old","archived":true,"topics":["ci"]},
+{"full_name":"acme/fork","name":"fork","fork":true,"topics":["ci"]},
+{"full_name":"acme/docs","name":"docs"}]`))
+		case strings.HasSuffix(r.URL.Path, "/actions/runs"):
+			repo := strings.TrimSuffix(strings.TrimPrefix(r.URL.Path, "/repos/"), "/actions/runs")
+			mu.Lock()
+			listed[repo] = true
+			mu.Unlock()
+			if repo == "acme/web" {
+				w.WriteHeader(http.StatusNotFound)
+				_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+				return
+			}
+			_, _ = fmt.Fprintf(w, `{"total_count":1,"workflow_runs":[{"id":7,"workflow_id":5,"name":"ci","status":"completed","conclusion":"success","run_attempt":1,"created_at":"%[1]s","updated_at":"%[1]s","run_started_at":"%[1]s"}]}`, created)
+		case r.URL.Path == "/repos/acme/api/actions/runs/7/attempts/1/jobs":
+			_, _ = fmt.Fprintf(w, `{"total_count":1,"jobs":[{"id":70,"name":"build","status":"completed","conclusion":"success","started_at":"%[1]s","completed_at":"%[2]s","labels":["ubuntu-latest"]}]}`, created, time.Now().UTC().Add(-30*time.Minute).Format(time.RFC3339))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("CICOST_GITHUB_API_BASE_URL", srv.URL)
+
+	if err := runScan([]string{"--org", "acme", "--token", "test-token", "--exclude", "legacy-*", "--topic", "ci", "--repo-workers", "2", "--storage=false"}); err != nil {
+		t.Fatal(err)
+	}
+	mu.Lock()
+	if len(listed) != 2 || !listed["acme/api"] || !listed["acme/web"] {
+		t.Fatalf("expected only api and web to be scanned, got %v", listed)
+	}
+	mu.Unlock()
+
+	out := filepath.Join(tmp, "org.json")
+	if err := runOrgReport([]string{"--org", "acme", "--days", "30", "--format", "json", "--output", out}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload orgReportPayload
+	if err := json.Unmarshal(b, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.TotalRepos != 2 || payload.SuccessRepos != 1 || payload.FailedRepos != 1 {
+		t.Fatalf("unexpected org report totals: %+v", payload)
+	}
+	if len(payload.RepoRankings) != 1 || payload.RepoRankings[0].Repo != "acme/api" {
+		t.Fatalf("expected acme/api in rankings, got %+v", payload.RepoRankings)
+	}
+	if !strings.Contains(payload.Failures["acme/web"], "last scan failed") {
+		t.Fatalf("expected failed scan to be reported, got %+v", payload.Failures)
+	}
+}
+
+func TestOrgReportRollupFormats(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC()
+	for i, repo := range []string{"acme/api", "acme/web", "acme-labs/ml"} {
+		runID := int64(900 + i)
+		if _, _, err := st.UpsertRuns([]model.WorkflowRun{{
+			ID: runID, Repo: repo, WorkflowID: runID, WorkflowName: "ci", Event: "push",
+			Status: "completed", Conclusion: "success", RunAttempt: 1,
+			CreatedAt: now, UpdatedAt: now, RunStartedAt: now,
+		}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := st.UpsertJobs([]model.Job{{
+			ID: runID * 10, RunID: runID, RunAttempt: 1, Repo: repo, Name: "build",
+			Status: "completed", RunnerOS: "Linux", DurationSec: 1500 * 60,
+			StartedAt: now, CompletedAt: now.Add(1500 * time.Minute),
+		}}); err != nil {
+			t.Fatal(err)
+		}
+		if err := st.RecordScanOutcome(store.ScanOutcome{Repo: repo, Status: store.ScanOK, Runs: 1, Jobs: 1}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out := filepath.Join(tmp, "rollup.json")
+	if err := runOrgReport([]string{"--rollup", "--enterprise", "acme-corp", "--days", "30", "--format", "json", "--output", out}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload orgRollupPayload
+	if err := json.Unmarshal(b, &payload); err != nil {
+		t.Fatal(err)
+	}
+	root := payload.Rollup
+	if root.Name != "acme-corp" || len(root.Children) != 2 || payload.FreeTierLevel != "org" {
+		t.Fatalf("unexpected rollup root: %+v", payload)
+	}
+	if acme := root.Children[0]; acme.Name != "acme" || len(acme.Children) != 2 || acme.FreeTierMinutes != 2000 {
+		t.Fatalf("expected acme to use one free tier across both repos, got %+v", acme)
+	}
+
+	flat := filepath.Join(tmp, "org.json")
+	if err := runOrgReport([]string{"--days", "30", "--format", "json", "--output", flat}); err != nil {
+		t.Fatal(err)
+	}
+	b, err = os.ReadFile(flat)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report orgReportPayload
+	if err := json.Unmarshal(b, &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.GrossCostUSD != 33.75 || report.TotalCostUSD != 7.5 {
+		t.Fatalf("expected one free tier per owner (gross 33.75, net 7.50), got gross=%.2f net=%.2f", report.GrossCostUSD, report.TotalCostUSD)
+	}
+
+	csvOut := filepath.Join(tmp, "rollup.csv")
+	if err := runOrgReport([]string{"--rollup", "--days", "30", "--format", "csv", "--output", csvOut}); err != nil {
+		t.Fatal(err)
+	}
+	b, err = os.ReadFile(csvOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(b)), "\n")
+	// header + enterprise + 2 orgs + 3 repos + 3 workflows
+	if len(lines) != 10 || !strings.HasPrefix(lines[0], "level,enterprise,org,repo,workflow") {
+		t.Fatalf("unexpected csv rollup:\n%s", b)
+	}
+	if !strings.Contains(string(b), "workflow,all,acme,acme/api,ci,1,") {
+		t.Fatalf("expected workflow rows with full path, got:\n%s", b)
+	}
+}
+
+func TestScanStorageFeedsReport(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	now := time.Now().UTC()
+	created := now.Add(-time.Hour).Format(time.RFC3339)
+	old := now.AddDate(0, 0, -20).Format(time.RFC3339)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/runs":
+			_, _ = fmt.Fprintf(w, `{"total_count":1,"workflow_runs":[{"id":1,"workflow_id":5,"name":"ci","status":"completed","conclusion":"success","run_attempt":1,"created_at":"%[1]s","updated_at":"%[1]s","run_started_at":"%[1]s"}]}`, created)
+		case "/repos/owner/repo/actions/runs/1/attempts/1/jobs":
+			_, _ = fmt.Fprintf(w, `{"total_count":1,"jobs":[{"id":10,"name":"build","status":"completed","conclusion":"success","started_at":"%[1]s","completed_at":"%[1]s","labels":["ubuntu-latest"]}]}`, created)
+		case "/repos/owner/repo/actions/artifacts":
+			_, _ = fmt.Fprintf(w, `{"total_count":1,"artifacts":[{"id":5,"name":"bundle","size_in_bytes":21474836480,"created_at":"%s","expires_at":"%s","workflow_run":{"id":1}}]}`, old, now.AddDate(0, 0, 70).Format(time.RFC3339))
+		case "/repos/owner/repo/actions/caches":
+			_, _ = fmt.Fprintf(w, `{"total_count":1,"actions_caches":[{"id":3,"key":"npm-x","ref":"refs/pull/4/merge","size_in_bytes":1073741824,"created_at":"%[1]s","last_accessed_at":"%[1]s"}]}`, old)
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	t.Setenv("CICOST_GITHUB_API_BASE_URL", srv.URL)
+
+	if err := runScan([]string{"--repo", "owner/repo", "--token", "test-token", "--workers", "1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(tmp, "report.json")
+	if err := runReport([]string{"--repo", "owner/repo", "--days", "30", "--format", "json", "--output", out}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var envelope struct {
+		Report struct {
+			Cost model.CostResult `json:"cost"`
+		} `json:"report"`
+	}
+	if err := json.Unmarshal(b, &envelope); err != nil {
+		t.Fatal(err)
+	}
+	st := envelope.Report.Cost.Storage
+	if st.Artifacts != 1 || st.Caches != 1 || st.ArtifactGBMonths <= 0 || st.CostUSD <= 0 {
+		t.Fatalf("expected stored artifacts and caches in the report, got %+v", st)
+	}
+
+	sugOut := filepath.Join(tmp, "suggest.yml")
+	if err := runSuggest([]string{"--repo", "owner/repo", "--days", "30", "--format", "yaml", "--output", sugOut}); err != nil {
+		t.Fatal(err)
+	}
+	sb, err := os.ReadFile(sugOut)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(sb), "artifact_retention") || !strings.Contains(string(sb), "retention-days: 7") {
+		t.Fatalf("expected artifact retention suggestion, got:\n%s", sb)
+	}
+}
+
+func TestForecastCommandUsesStoredHistory(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	var runs []model.WorkflowRun
+	var jobs []model.Job
+	for i := 1; i <= 21; i++ {
+		at := today.AddDate(0, 0, -i).Add(9 * time.Hour)
+		runs = append(runs, model.WorkflowRun{ID: int64(i), Repo: "owner/repo", WorkflowID: 10, WorkflowName: "ci", Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: at, UpdatedAt: at, RunStartedAt: at})
+		jobs = append(jobs, model.Job{ID: int64(100 + i), RunID: int64(i), RunAttempt: 1, Repo: "owner/repo", Name: "build", Status: "completed", Conclusion: "success", RunnerOS: "Linux", DurationSec: 600, StartedAt: at, CompletedAt: at.Add(10 * time.Minute)})
+	}
+	if _, _, err := st.UpsertRuns(runs); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(tmp, "forecast.json")
+	if err := runForecast([]string{"--repo", "owner/repo", "--period", "weekly", "--format", "json", "--output", out}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Forecast struct {
+			Method      string            `json:"method"`
+			HistoryDays int               `json:"history_days"`
+			Daily       []json.RawMessage `json:"daily"`
+		} `json:"forecast"`
+	}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Forecast.Method != "holt-winters" || payload.Forecast.HistoryDays != 21 || len(payload.Forecast.Daily) == 0 {
+		t.Fatalf("unexpected forecast: %s", b)
+	}
+
+	if err := runForecast([]string{"--repo", "owner/repo", "--period", "yearly"}); err == nil {
+		t.Fatal("expected invalid period error")
+	}
+}
+
+func TestForecastProRatesFreeMinutesAcrossOwnerRepos(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC()
+	at := now.Add(-time.Minute)
+	var runs []model.WorkflowRun
+	var jobs []model.Job
+	for i, u := range []struct {
+		repo    string
+		minutes int
+	}{{"owner/repo", 300}, {"owner/other", 900}, {"someone/else", 5000}} {
+		id := int64(i + 1)
+		runs = append(runs, model.WorkflowRun{ID: id, Repo: u.repo, WorkflowID: 10, WorkflowName: "ci", Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: at, UpdatedAt: at, RunStartedAt: at})
+		jobs = append(jobs, model.Job{ID: 100 + id, RunID: id, RunAttempt: 1, Repo: u.repo, Name: "build", Status: "completed", Conclusion: "success", RunnerOS: "Linux", DurationSec: u.minutes * 60, StartedAt: at, CompletedAt: at})
+	}
+	if _, _, err := st.UpsertRuns(runs); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg, err := withCycleUsage(st, "owner", pricing.Config{PerMinuteUSD: 0.008, FreeTierPerMonth: 2000})
+	if err != nil {
+		t.Fatal(err)
+	}
+	histStart, _ := forecastHistoryWindow(now, defaultForecastHistoryDays)
+	// 800 minutes are left, a quarter of the owner's usage is owner/repo's.
+	left, err := repoFreeMinutesLeft(st, "owner/repo", now, histStart, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if left != 200 {
+		t.Fatalf("expected 200 free minutes for owner/repo, got %v", left)
+	}
+}
+
+func TestAnomaliesNotifyAndExitCode(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	today := time.Now().UTC().Truncate(24 * time.Hour)
+	var runs []model.WorkflowRun
+	var jobs []model.Job
+	add := func(id int64, day int, minutes int) {
+		at := today.AddDate(0, 0, -day).Add(8 * time.Hour)
+		runs = append(runs, model.WorkflowRun{ID: id, Repo: "owner/repo", WorkflowID: 7, WorkflowName: "release", HeadBranch: "main", Event: "push", Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: at, UpdatedAt: at, RunStartedAt: at})
+		jobs = append(jobs, model.Job{ID: id * 10, RunID: id, RunAttempt: 1, Repo: "owner/repo", Name: "publish", Status: "completed", Conclusion: "success", RunnerOS: "Linux", DurationSec: minutes * 60, StartedAt: at, CompletedAt: at.Add(time.Duration(minutes) * time.Minute)})
+	}
+	for day := 2; day <= 29; day++ {
+		add(int64(day), day, 150)
+	}
+	add(100, 1, 600)
+	add(101, 1, 300)
+	if _, _, err := st.UpsertRuns(runs); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var received map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		_ = json.NewDecoder(r.Body).Decode(&received)
+	}))
+	defer srv.Close()
+
+	err = runAnomalies([]string{"--repo", "owner/repo", "--fail", "--notify", "webhook", "--webhook-url", srv.URL})
+	var ex ExitError
+	if !errors.As(err, &ex) || ex.Code != exitAnomalies {
+		t.Fatalf("expected exit code %d, got %v", exitAnomalies, err)
+	}
+	mu.Lock()
+	list, _ := received["anomalies"].([]any)
+	mu.Unlock()
+	if len(list) != 1 {
+		t.Fatalf("expected one anomaly in webhook payload, got %v", received)
+	}
+	a := list[0].(map[string]any)
+	top, _ := a["top_runs"].([]any)
+	if a["name"] != "release" || len(top) != 2 || top[0].(map[string]any)["run_id"] != float64(100) {
+		t.Fatalf("unexpected anomaly payload: %v", a)
+	}
+
+	out := filepath.Join(tmp, "anomalies.txt")
+	if err := runAnomalies([]string{"--repo", "owner/repo", "--min-usd", "100", "--fail", "--notify", "file", "--output", out}); err != nil {
+		t.Fatalf("expected no anomalies above min-usd, got %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "No cost anomalies") {
+		t.Fatalf("unexpected output: %s", b)
+	}
+}
+
+func TestBudgetCheckEvaluatesScopedBudgets(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	originalWD, _ := os.Getwd()
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+		_ = os.Chdir(originalWD)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+	_ = os.Chdir(tmp)
+
+	cfg := `repos: [owner/repo]
+budgets:
+  - name: all
+    amount_usd: 1
+  - name: release-linux
+    amount_usd: 10000
+    scope:
+      workflow: release.yml
+      runner_os: linux
+  - name: feature
+    period: weekly
+    amount_usd: 5
+    thresholds: [50, 10]
+    scope:
+      repo: owner/*
+      branch: feature/*
+`
+	if err := os.WriteFile(filepath.Join(tmp, ".cicost.yml"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	if err := st.RecordScanOutcome(store.ScanOutcome{Repo: "owner/repo", Status: "success"}); err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now().UTC()
+	// release runs first and takes the 2000 free minutes; the feature branch
+	// run is billed in full.
+	release, feature := now.Add(-2*time.Minute), now.Add(-time.Minute)
+	runs := []model.WorkflowRun{
+		{ID: 1, Repo: "owner/repo", WorkflowID: 1, WorkflowName: "release", WorkflowPath: ".github/workflows/release.yml", HeadBranch: "main", Status: "completed", RunAttempt: 1, CreatedAt: release, UpdatedAt: release, RunStartedAt: release},
+		{ID: 2, Repo: "owner/repo", WorkflowID: 2, WorkflowName: "ci", WorkflowPath: ".github/workflows/ci.yml", HeadBranch: "feature/x", Status: "completed", RunAttempt: 1, CreatedAt: feature, UpdatedAt: feature, RunStartedAt: feature},
+	}
+	if _, _, err := st.UpsertRuns(runs); err != nil {
+		t.Fatal(err)
+	}
+	jobs := []model.Job{
+		{ID: 10, RunID: 1, RunAttempt: 1, Repo: "owner/repo", Name: "publish", Status: "completed", RunnerOS: "Linux", DurationSec: 3000 * 60, StartedAt: release, CompletedAt: release},
+		{ID: 20, RunID: 2, RunAttempt: 1, Repo: "owner/repo", Name: "test", Status: "completed", RunnerOS: "Linux", DurationSec: 600 * 60, StartedAt: feature, CompletedAt: feature},
+	}
+	if _, _, err := st.UpsertJobs(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	out := filepath.Join(tmp, "budgets.json")
+	err = runBudget([]string{"check", "--format", "json", "--notify", "file", "--output", out})
+	var ex ExitError
+	if !errors.As(err, &ex) || ex.Code != 2 {
+		t.Fatalf("expected exit code 2, got %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		Summary map[string]int `json:"summary"`
+		Budgets []struct {
+			Name       string    `json:"name"`
+			Status     string    `json:"status"`
+			Level      float64   `json:"level"`
+			Thresholds []float64 `json:"thresholds"`
+			ActualUSD  float64   `json:"actual_usd"`
+			CheckType  string    `json:"check_type"`
+		} `json:"budgets"`
+	}
+	if err := json.Unmarshal(b, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if payload.Summary["exceeded"] != 1 || payload.Summary["warning"] != 1 || payload.Summary["ok"] != 1 {
+		t.Fatalf("unexpected summary: %s", b)
+	}
+	byName := map[string]int{}
+	for i, c := range payload.Budgets {
+		byName[c.Name] = i
+	}
+	if c := payload.Budgets[byName["all"]]; c.Status != "exceeded" || c.Level != 120 {
+		t.Fatalf("unexpected all budget: %+v", c)
+	}
+	if c := payload.Budgets[byName["release-linux"]]; c.Status != "ok" || c.ActualUSD != 8 {
+		t.Fatalf("expected 1000 billed release minutes: %+v", c)
+	}
+	if c := payload.Budgets[byName["feature"]]; c.Status != "warning" || c.Level != 50 || c.CheckType != "weekly" || c.Thresholds[0] != 10 {
+		t.Fatalf("unexpected feature budget: %+v", c)
+	}
+
+	history, err := st.ListBudgetChecks("feature", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 1 || history[0].Level != 50 || history[0].Status != "warning" || history[0].Scope != "repo=owner/* branch=feature/*" {
+		t.Fatalf("unexpected budget history: %+v", history)
+	}
+
+	if err := runBudget([]string{"check", "--name", "nope"}); err == nil || !strings.Contains(err.Error(), `no budget named "nope"`) {
+		t.Fatalf("expected unknown budget error, got %v", err)
+	}
+}
+
+func TestBudgetWebhookSentOncePerLevel(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC().Add(-time.Minute)
+	if _, _, err := st.UpsertRuns([]model.WorkflowRun{{ID: 1, Repo: "owner/repo", WorkflowID: 1, WorkflowName: "ci", Status: "completed", RunAttempt: 1, CreatedAt: now, UpdatedAt: now, RunStartedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs([]model.Job{{ID: 11, RunID: 1, RunAttempt: 1, Repo: "owner/repo", Name: "build", Status: "completed", RunnerOS: "Linux", DurationSec: 3000 * 60, StartedAt: now, CompletedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+
+	var mu sync.Mutex
+	var actions []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		mu.Lock()
+		actions = append(actions, fmt.Sprint(p["action"]))
+		mu.Unlock()
+	}))
+	defer srv.Close()
+	run := func(extra ...string) {
+		t.Helper()
+		args := append([]string{"--repo", "owner/repo", "--notify", "webhook", "--webhook-url", srv.URL}, extra...)
+		err := runBudget(args)
+		var ex ExitError
+		if err != nil && (!errors.As(err, &ex) || ex.Code != 2) {
+			t.Fatal(err)
+		}
+	}
+	sent := func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), actions...)
+	}
+
+	run("--monthly", "1", "--dry-run")
+	if got := sent(); len(got) != 0 {
+		t.Fatalf("dry run must not send, got %v", got)
+	}
+	run("--monthly", "1")
+	run("--monthly", "1")
+	if got := sent(); len(got) != 1 || got[0] != "alert" {
+		t.Fatalf("expected a single alert, got %v", got)
+	}
+	run("--monthly", "1", "--renotify", "1ns")
+	if got := sent(); len(got) != 2 || got[1] != "renotify" {
+		t.Fatalf("expected a reminder once the interval passed, got %v", got)
+	}
+	run("--monthly", "100000")
+	if got := sent(); len(got) != 3 || got[2] != "recovered" {
+		t.Fatalf("expected a recovered message, got %v", got)
+	}
+	run("--monthly", "100000")
+	if got := sent(); len(got) != 3 {
+		t.Fatalf("expected no further messages, got %v", got)
+	}
+}
+
+func TestPolicyCheckNotifiesNamedSlackNotifier(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	originalWD, _ := os.Getwd()
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+		_ = os.Chdir(originalWD)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+	t.Setenv("CICOST_TEST_NOTIFY_SECRET", "s3cret")
+	_ = os.Chdir(tmp)
+
+	var mu sync.Mutex
+	var bodies [][]byte
+	var signatures []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, b)
+		signatures = append(signatures, r.Header.Get(notify.DefaultSignatureHeader))
+		if len(bodies) == 1 {
+			// The first delivery fails and must be retried.
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := fmt.Sprintf(`repos: [owner/repo]
+notifiers:
+  - name: ci-slack
+    type: slack
+    url: %s
+    secret: ${CICOST_TEST_NOTIFY_SECRET}
+`, srv.URL)
+	if err := os.WriteFile(filepath.Join(tmp, ".cicost.yml"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	now := time.Now().UTC().Add(-time.Hour)
+	if _, _, err := st.UpsertRuns([]model.WorkflowRun{{ID: 1, Repo: "owner/repo", WorkflowID: 1, WorkflowName: "ci", Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: now, UpdatedAt: now, RunStartedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs([]model.Job{{ID: 11, RunID: 1, RunAttempt: 1, Repo: "owner/repo", Name: "build", Status: "completed", Conclusion: "success", RunnerOS: "Linux", DurationSec: 3000 * 60, StartedAt: now, CompletedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+
+	policyPath := filepath.Join(tmp, ".cicost.policy.yml")
+	if err := os.WriteFile(policyPath, []byte("rules:\n  - id: cost_cap\n    when: total_cost_usd > 1\n    severity: error\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = runPolicy([]string{"check", "--repo", "owner/repo", "--policy", policyPath, "--notify", "webhook", "--notifier", "ci-slack"})
+	var ex ExitError
+	if !errors.As(err, &ex) || ex.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(bodies) != 2 {
+		t.Fatalf("expected one retry, got %d deliveries", len(bodies))
+	}
+	if signatures[1] != notify.Sign("s3cret", bodies[1]) {
+		t.Fatalf("unexpected signature %q", signatures[1])
+	}
+	var payload struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type   string `json:"type"`
+			Fields []struct {
+				Text string `json:"text"`
+			} `json:"fields"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(bodies[1], &payload); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(payload.Text, "1 findings") || len(payload.Blocks) < 2 || !strings.HasPrefix(payload.Blocks[1].Fields[0].Text, "*cost_cap*") {
+		t.Fatalf("unexpected slack payload: %s", bodies[1])
+	}
+}
+
+func TestPolicyCheckScopedRuleFindsEachWorkflow(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC().Add(-time.Hour)
+	var runs []model.WorkflowRun
+	var jobs []model.Job
+	for i, w := range []struct {
+		name    string
+		minutes int
+	}{{"build", 4000}, {"docs", 10}, {"release", 3000}} {
+		id := int64(i + 1)
+		runs = append(runs, model.WorkflowRun{ID: id, Repo: "owner/repo", WorkflowID: id, WorkflowName: w.name, Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: now, UpdatedAt: now, RunStartedAt: now})
+		jobs = append(jobs, model.Job{ID: id * 10, RunID: id, RunAttempt: 1, Repo: "owner/repo", Name: "main", Status: "completed", Conclusion: "success", RunnerOS: "Linux", DurationSec: w.minutes * 60, StartedAt: now, CompletedAt: now})
+	}
+	if _, _, err := st.UpsertRuns(runs); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	policyPath := filepath.Join(tmp, ".cicost.policy.yml")
+	content := `rules:
+  - id: workflow_cap
+    scope: workflow
+    match: ["build", "docs"]
+    when: monthly_cost_usd > 5
+    severity: error
+  - id: any_workflow_runs
+    scope: workflow
+    when: total_runs >= 1
+    severity: info
+  - id: free_tier_spent
+    when: free_tier_remaining_min == 0 and p95_job_duration_sec > 3600
+    severity: warn
+`
+	if err := os.WriteFile(policyPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(tmp, "policy.txt")
+	err = runPolicy([]string{"check", "--repo", "owner/repo", "--policy", policyPath, "--notify", "file", "--output", out})
+	var ex ExitError
+	if !errors.As(err, &ex) || ex.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(b)
+	// 7010 minutes with 2000 free: build's share of the net cost is over $5,
+	// release is not matched and docs is under the cap.
+	if !strings.Contains(text, `[ERROR] workflow_cap workflow="build"`) || strings.Contains(text, `workflow_cap workflow="docs"`) || strings.Contains(text, `workflow_cap workflow="release"`) {
+		t.Fatalf("unexpected findings:\n%s", text)
+	}
+	if strings.Count(text, "[INFO] any_workflow_runs") != 3 {
+		t.Fatalf("expected one info finding per workflow:\n%s", text)
+	}
+	if !strings.Contains(text, "[WARN] free_tier_spent (evidence: free_tier_remaining_min=0.0000, p95_job_duration_sec=240000.0000") {
+		t.Fatalf("expected the registered free tier and duration metrics:\n%s", text)
+	}
+}
+
+func TestPolicyCheckAppliesRuleActions(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	originalWD, _ := os.Getwd()
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+		_ = os.Chdir(originalWD)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+	stepSummary := filepath.Join(tmp, "step-summary.md")
+	t.Setenv("GITHUB_STEP_SUMMARY", stepSummary)
+	_ = os.Chdir(tmp)
+
+	var mu sync.Mutex
+	var bodies []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		defer mu.Unlock()
+		bodies = append(bodies, body)
+	}))
+	defer srv.Close()
+	cfg := fmt.Sprintf("repos: [owner/repo]\nnotifiers:\n  - name: finops\n    url: %s\n", srv.URL)
+	if err := os.WriteFile(filepath.Join(tmp, ".cicost.yml"), []byte(cfg), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	now := time.Now().UTC().Add(-time.Hour)
+	if _, _, err := st.UpsertRuns([]model.WorkflowRun{{ID: 1, Repo: "owner/repo", WorkflowID: 1, WorkflowName: "ci", Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: now, UpdatedAt: now, RunStartedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs([]model.Job{{ID: 11, RunID: 1, RunAttempt: 1, Repo: "owner/repo", Name: "build", Status: "completed", Conclusion: "success", RunnerOS: "Linux", DurationSec: 3000 * 60, StartedAt: now, CompletedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+
+	policyPath := filepath.Join(tmp, ".cicost.policy.yml")
+	content := `rules:
+  - id: cost_cap
+    when: total_cost_usd > 1
+    severity: error
+    actions: [warn, notify:finops]
+  - id: pr_cost
+    when: total_cost_usd > 2
+    severity: warn
+    actions: comment
+  - id: noisy
+    when: total_runs >= 1
+    severity: error
+    actions: ignore
+`
+	if err := os.WriteFile(policyPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(tmp, "policy.txt")
+	commentPath := filepath.Join(tmp, "comment.md")
+	summaryPath := filepath.Join(tmp, "summary.json")
+	// noisy is an error but ignored and cost_cap is overridden to warn, so
+	// nothing fails the check.
+	if err := runPolicy([]string{"check", "--repo", "owner/repo", "--policy", policyPath, "--notify", "file", "--output", out,
+		"--comment-file", commentPath, "--summary-file", summaryPath}); err != nil {
+		t.Fatalf("expected no exit error, got %v", err)
+	}
+
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(b)
+	if !strings.Contains(text, "actions: warn, notify:finops") || !strings.Contains(text, "pr_cost") || strings.Contains(text, "noisy") {
+		t.Fatalf("unexpected report:\n%s", text)
+	}
+
+	mu.Lock()
+	if len(bodies) != 1 {
+		t.Fatalf("expected one notify:finops delivery, got %d", len(bodies))
+	}
+	findings, _ := bodies[0]["findings"].([]any)
+	if len(findings) != 1 || findings[0].(map[string]any)["rule_id"] != "cost_cap" {
+		t.Fatalf("unexpected notify:finops payload %v", bodies[0])
+	}
+	mu.Unlock()
+
+	comment, err := os.ReadFile(commentPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(comment), "| WARN | `pr_cost` |") || strings.Contains(string(comment), "cost_cap") {
+		t.Fatalf("unexpected comment:\n%s", comment)
+	}
+	summaryMD, err := os.ReadFile(stepSummary)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(summaryMD), "pr_cost") {
+		t.Fatalf("step summary missing comment:\n%s", summaryMD)
+	}
+
+	var summary struct {
+		Actions  map[string]int `json:"actions"`
+		Failed   bool           `json:"failed"`
+		Ignored  int            `json:"ignored"`
+		ExitCode int            `json:"exit_code"`
+		Findings []struct {
+			RuleID  string   `json:"rule_id"`
+			Actions []string `json:"actions"`
+		} `json:"findings"`
+	}
+	b, err = os.ReadFile(summaryPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &summary); err != nil {
+		t.Fatal(err)
+	}
+	if summary.Failed || summary.ExitCode != 0 || summary.Ignored != 1 || len(summary.Findings) != 3 ||
+		summary.Actions["warn"] != 1 || summary.Actions["notify:finops"] != 1 || summary.Actions["comment"] != 1 || summary.Actions["ignore"] != 1 {
+		t.Fatalf("unexpected summary: %s", b)
+	}
+
+	// Without an override the error severity falls back to fail.
+	if err := os.WriteFile(policyPath, []byte("rules:\n  - id: cost_cap\n    when: total_cost_usd > 1\n    severity: error\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	err = runPolicy([]string{"check", "--repo", "owner/repo", "--policy", policyPath, "--notify", "file", "--output", out, "--summary-file", summaryPath})
+	var ex ExitError
+	if !errors.As(err, &ex) || ex.Code != 3 {
+		t.Fatalf("expected exit code 3, got %v", err)
+	}
+	b, _ = os.ReadFile(summaryPath)
+	if !strings.Contains(string(b), `"exit_code": 3`) {
+		t.Fatalf("unexpected summary: %s", b)
+	}
+}
+
+func TestPolicyCheckFormats(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	originalWD, _ := os.Getwd()
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+		_ = os.Chdir(originalWD)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+	_ = os.Chdir(tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	now := time.Now().UTC().Add(-time.Hour)
+	var runs []model.WorkflowRun
+	var jobs []model.Job
+	for i, w := range []struct {
+		name    string
+		minutes int
+	}{{"build", 4000}, {"docs", 10}} {
+		id := int64(i + 1)
+		runs = append(runs, model.WorkflowRun{ID: id, Repo: "owner/repo", WorkflowID: id, WorkflowName: w.name, Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: now, UpdatedAt: now, RunStartedAt: now})
+		jobs = append(jobs, model.Job{ID: id * 10, RunID: id, RunAttempt: 1, Repo: "owner/repo", Name: "main", Status: "completed", Conclusion: "success", RunnerOS: "Linux", DurationSec: w.minutes * 60, StartedAt: now, CompletedAt: now})
+	}
+	if _, _, err := st.UpsertRuns(runs); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs(jobs); err != nil {
+		t.Fatal(err)
+	}
+
+	content := `rules:
+  - id: workflow_cap
+    scope: workflow
+    when: monthly_cost_usd > 5
+    severity: error
+  - id: busy_repo
+    when: total_runs >= 2
+    severity: warn
+  - id: failing
+    when: fail_rate > 50
+    severity: error
+`
+	if err := os.WriteFile(filepath.Join(tmp, ".cicost.policy.yml"), []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	check := func(format string) string {
+		t.Helper()
+		out := filepath.Join(tmp, "policy."+format)
+		// --output alone takes the document; --notify is for delivery.
+		err := runPolicy([]string{"check", "--repo", "owner/repo", "--format", format, "--output", out})
+		var ex ExitError
+		if !errors.As(err, &ex) || ex.Code != 3 {
+			t.Fatalf("%s: expected exit code 3, got %v", format, err)
+		}
+		b, err := os.ReadFile(out)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+
+	var sarif struct {
+		Version string `json:"version"`
+		Runs    []struct {
+			Tool struct {
+				Driver struct {
+					Rules []struct {
+						ID                   string `json:"id"`
+						DefaultConfiguration struct {
+							Level string `json:"level"`
+						} `json:"defaultConfiguration"`
+					} `json:"rules"`
+				} `json:"driver"`
+			} `json:"tool"`
+			Results []struct {
+				RuleID    string `json:"ruleId"`
+				RuleIndex int    `json:"ruleIndex"`
+				Level     string `json:"level"`
+				Message   struct {
+					Text string `json:"text"`
+				} `json:"message"`
+				Locations []struct {
+					PhysicalLocation struct {
+						ArtifactLocation struct {
+							URI string `json:"uri"`
+						} `json:"artifactLocation"`
+						Region struct {
+							StartLine int `json:"startLine"`
+						} `json:"region"`
+					} `json:"physicalLocation"`
+				} `json:"locations"`
+			} `json:"results"`
+		} `json:"runs"`
+	}
+	if err := json.Unmarshal([]byte(check("sarif")), &sarif); err != nil {
+		t.Fatal(err)
+	}
+	if sarif.Version != "2.1.0" || len(sarif.Runs) != 1 || len(sarif.Runs[0].Tool.Driver.Rules) != 3 {
+		t.Fatalf("unexpected sarif log %+v", sarif)
+	}
+	results := sarif.Runs[0].Results
+	if len(results) != 2 {
+		t.Fatalf("expected 2 results, got %+v", results)
+	}
+	// Findings come in rule order: workflow_cap for build, then busy_repo.
+	first, second := results[0], results[1]
+	if first.RuleID != "workflow_cap" || first.RuleIndex != 0 || first.Level != "error" || !strings.Contains(first.Message.Text, `workflow="build"`) {
+		t.Fatalf("unexpected first result %+v", first)
+	}
+	if loc := first.Locations[0].PhysicalLocation; loc.ArtifactLocation.URI != ".cicost.policy.yml" || loc.Region.StartLine != 2 {
+		t.Fatalf("unexpected location %+v", loc)
+	}
+	if second.RuleID != "busy_repo" || second.RuleIndex != 1 || second.Level != "warning" || second.Locations[0].PhysicalLocation.Region.StartLine != 6 {
+		t.Fatalf("unexpected second result %+v", second)
+	}
+
+	var junit struct {
+		Tests    int `xml:"tests,attr"`
+		Failures int `xml:"failures,attr"`
+		Cases    []struct {
+			Name    string `xml:"name,attr"`
+			Failure *struct {
+				Type string `xml:"type,attr"`
+			} `xml:"failure"`
+		} `xml:"testcase"`
+	}
+	if err := xml.Unmarshal([]byte(check("junit")), &junit); err != nil {
+		t.Fatal(err)
+	}
+	if junit.Tests != 3 || junit.Failures != 2 || junit.Cases[0].Failure == nil || junit.Cases[0].Failure.Type != "error" ||
+		junit.Cases[2].Name != "failing" || junit.Cases[2].Failure != nil {
+		t.Fatalf("unexpected junit suite %+v", junit)
+	}
+
+	annotations := strings.Split(strings.TrimSpace(check("github")), "\n")
+	if len(annotations) != 2 ||
+		!strings.HasPrefix(annotations[0], "::error file=.cicost.policy.yml,line=2,title=CICost policy workflow_cap workflow=\"build\"::workflow_cap") ||
+		!strings.HasPrefix(annotations[1], "::warning file=.cicost.policy.yml,line=6,") {
+		t.Fatalf("unexpected annotations:\n%s", strings.Join(annotations, "\n"))
+	}
+
+	var doc struct {
+		Findings []json.RawMessage `json:"findings"`
+		ExitCode int               `json:"exit_code"`
+	}
+	if err := json.Unmarshal([]byte(check("json")), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if len(doc.Findings) != 2 || doc.ExitCode != 3 {
+		t.Fatalf("unexpected json document %+v", doc)
+	}
+
+	if err := runPolicy([]string{"check", "--repo", "owner/repo", "--format", "xml"}); err == nil {
+		t.Fatal("expected an error for an unknown format")
+	}
+}
+
+func TestPolicyCheckSharesFreeTierAcrossOwnerRepos(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	now := time.Now().UTC().Add(-time.Hour)
+	// owner/other ran first and used 1800 of the 2000 free minutes.
+	for i, r := range []struct {
+		repo    string
+		minutes int
+		at      time.Time
+	}{{"owner/other", 1800, now.Add(-time.Hour)}, {"owner/repo", 1500, now}} {
+		id := int64(i + 1)
+		if _, _, err := st.UpsertRuns([]model.WorkflowRun{{ID: id, Repo: r.repo, WorkflowID: id, WorkflowName: "ci", Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: r.at, UpdatedAt: r.at, RunStartedAt: r.at}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := st.UpsertJobs([]model.Job{{ID: id * 10, RunID: id, RunAttempt: 1, Repo: r.repo, Name: "build", Status: "completed", Conclusion: "success", RunnerOS: "Linux", DurationSec: r.minutes * 60, StartedAt: r.at, CompletedAt: r.at}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policyPath := filepath.Join(tmp, ".cicost.policy.yml")
+	content := `rules:
+  - id: repo_cost
+    when: total_cost_usd > 1
+    severity: warn
+  - id: workflow_cost
+    scope: workflow
+    when: total_cost_usd > 1
+    severity: warn
+  - id: free_tier_gone
+    when: free_tier_remaining_min == 0
+    severity: info
+`
+	if err := os.WriteFile(policyPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(tmp, "policy.txt")
+	if err := runPolicy([]string{"check", "--repo", "owner/repo", "--policy", policyPath, "--notify", "file", "--output", out}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Alone, 1500 minutes would fit in the free tier; with owner/other
+	// counted, 1300 of them are billed.
+	text := string(b)
+	if !strings.Contains(text, "[WARN] repo_cost") || !strings.Contains(text, `[WARN] workflow_cost workflow="ci"`) {
+		t.Fatalf("expected the shared free tier to leave cost on owner/repo:\n%s", text)
+	}
+	if !strings.Contains(text, "[INFO] free_tier_gone") {
+		t.Fatalf("expected free_tier_remaining_min to count owner/other:\n%s", text)
+	}
+}
+
+func TestPolicyCheckWeekOverWeekLooksBehindShortWindows(t *testing.T) {
+	tmp := t.TempDir()
+	originalHome := os.Getenv("USERPROFILE")
+	originalHomeUnix := os.Getenv("HOME")
+	t.Cleanup(func() {
+		_ = os.Setenv("USERPROFILE", originalHome)
+		_ = os.Setenv("HOME", originalHomeUnix)
+	})
+	_ = os.Setenv("USERPROFILE", tmp)
+	_ = os.Setenv("HOME", tmp)
+
+	dbPath, err := config.DBPath()
+	if err != nil {
+		t.Fatal(err)
+	}
+	st, err := store.Open(dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	now := time.Now().UTC()
+	for i, r := range []struct {
+		minutes int
+		at      time.Time
+	}{{300, now.AddDate(0, 0, -10)}, {100, now.AddDate(0, 0, -2)}} {
+		id := int64(i + 1)
+		if _, _, err := st.UpsertRuns([]model.WorkflowRun{{ID: id, Repo: "owner/repo", WorkflowID: 1, WorkflowName: "ci", Status: "completed", Conclusion: "success", RunAttempt: 1, CreatedAt: r.at, UpdatedAt: r.at, RunStartedAt: r.at}}); err != nil {
+			t.Fatal(err)
+		}
+		if _, _, err := st.UpsertJobs([]model.Job{{ID: id * 10, RunID: id, RunAttempt: 1, Repo: "owner/repo", Name: "build", Status: "completed", Conclusion: "success", RunnerOS: "Linux", DurationSec: r.minutes * 60, StartedAt: r.at, CompletedAt: r.at}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	policyPath := filepath.Join(tmp, ".cicost.policy.yml")
+	content := `rules:
+  - id: spend_dropped
+    when: wow_cost_delta_pct < -50
+    severity: info
+  - id: workflow_spend_dropped
+    scope: workflow
+    when: wow_cost_delta_pct < -50
+    severity: info
+`
+	if err := os.WriteFile(policyPath, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	out := filepath.Join(tmp, "policy.txt")
+	if err := runPolicy([]string{"check", "--repo", "owner/repo", "--days", "7", "--policy", policyPath, "--notify", "file", "--output", out}); err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 100 minutes this week against 300 the week before, outside --days 7.
+	text := string(b)
+	if !strings.Contains(text, "spend_dropped (evidence: wow_cost_delta_pct=-66.67") || !strings.Contains(text, `workflow_spend_dropped workflow="ci"`) {
+		t.Fatalf("expected the earlier week to be loaded:\n%s", text)
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/notify"
+)
+
+// notifyOptions is where a command delivers its report: printed (stdout),
+// written to Output or DefaultFile (file), or sent to the notifier named
+// Notifier, else as JSON to WebhookURL (webhook).
+type notifyOptions struct {
+	Mode        string
+	WebhookURL  string
+	Notifier    string
+	Output      string
+	DefaultFile string
+	DryRun      bool
+}
+
+func (o notifyOptions) mode() string {
+	return strings.ToLower(strings.TrimSpace(o.Mode))
+}
+
+// notifierFor resolves the notifier of the webhook mode.
+func notifierFor(cfg config.Config, opts notifyOptions) (notify.Notifier, error) {
+	if name := strings.TrimSpace(opts.Notifier); name != "" {
+		for _, c := range cfg.Notifiers {
+			if strings.TrimSpace(c.Name) != name {
+				continue
+			}
+			headers := make(map[string]string, len(c.Headers))
+			for k, v := range c.Headers {
+				headers[k] = config.Expand(v)
+			}
+			return notify.New(notify.Config{
+				Name:            name,
+				Type:            c.Type,
+				URL:             config.Expand(c.URL),
+				Template:        c.Template,
+				ContentType:     c.ContentType,
+				Headers:         headers,
+				Secret:          config.Expand(c.Secret),
+				SignatureHeader: c.SignatureHeader,
+				Retries:         c.Retries,
+			})
+		}
+		return nil, fmt.Errorf("no notifier named %q in notifiers:", name)
+	}
+	if strings.TrimSpace(opts.WebhookURL) == "" {
+		return nil, fmt.Errorf("webhook-url or notifier is required when notify=webhook")
+	}
+	return notify.New(notify.Config{Type: notify.TypeWebhook, URL: opts.WebhookURL})
+}
+
+// sendNotification delivers m.Text by opts.Mode: written to a file, or
+// printed to stdout after sending m for webhook. Webhook failures only
+// warn, so a flaky receiver never hides the result.
+func sendNotification(cfg config.Config, opts notifyOptions, m notify.Message) error {
+	switch opts.mode() {
+	case "file":
+		target := opts.Output
+		if target == "" {
+			target = opts.DefaultFile
+		}
+		return writeOutput(target, m.Text)
+	case "webhook":
+		n, err := notifierFor(cfg, opts)
+		if err != nil {
+			return err
+		}
+		if err := n.Send(context.Background(), m); err != nil {
+			fmt.Printf("WARN: %v\n", err)
+		}
+		fmt.Print(m.Text)
+	default:
+		fmt.Print(m.Text)
+	}
+	return nil
+}
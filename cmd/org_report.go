@@ -12,12 +12,15 @@ import (
 
 	"github.com/peter941221/CICost/internal/analytics"
 	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/model"
 	"github.com/peter941221/CICost/internal/store"
 )
 
 type orgRepoSummary struct {
 	Repo          string  `json:"repo"`
 	TotalRuns     int     `json:"total_runs"`
+	GrossCostUSD  float64 `json:"gross_cost_usd"`
+	FreeTierUsed  float64 `json:"free_tier_used_min"`
 	TotalCostUSD  float64 `json:"total_cost_usd"`
 	TotalWasteUSD float64 `json:"total_waste_usd"`
 }
@@ -34,6 +37,7 @@ type orgReportPayload struct {
 	TotalRepos   int               `json:"total_repos"`
 	SuccessRepos int               `json:"success_repos"`
 	FailedRepos  int               `json:"failed_repos"`
+	GrossCostUSD float64           `json:"gross_cost_usd"`
 	TotalCostUSD float64           `json:"total_cost_usd"`
 	RepoRankings []orgRepoSummary  `json:"repo_rankings"`
 	TopHotspots  []orgHotspot      `json:"top_hotspots"`
@@ -46,25 +50,16 @@ func runOrgReport(args []string) error {
 		return err
 	}
 	fs := flag.NewFlagSet("org-report", flag.ContinueOnError)
-	reposFlag := fs.String("repos", "", "Repository list file (one owner/repo per line)")
+	reposFlag := fs.String("repos", "", "Repository list file (one owner/repo per line); defaults to repos recorded by scan")
+	orgFlag := fs.String("org", "", "Without --repos: only repos of this org or user recorded by scan")
 	daysFlag := fs.Int("days", rt.cfg.Scan.Days, "Time window in days")
-	formatFlag := fs.String("format", "md", "Output format: md|json")
+	formatFlag := fs.String("format", "md", "Output format: md|json (with --rollup also csv)")
+	rollupFlag := fs.Bool("rollup", false, "Report an enterprise → org → repo → workflow rollup")
+	enterpriseFlag := fs.String("enterprise", rt.cfg.Enterprise.Name, "Enterprise name for --rollup")
 	outputFlag := fs.String("output", "", "Output file path")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
-	if strings.TrimSpace(*reposFlag) == "" {
-		return fmt.Errorf("--repos is required")
-	}
-
-	repos, err := readRepoList(*reposFlag)
-	if err != nil {
-		return err
-	}
-	if len(repos) == 0 {
-		return fmt.Errorf("no repositories found in %s", *reposFlag)
-	}
-
 	dbPath, err := config.DBPath()
 	if err != nil {
 		return err
@@ -75,16 +70,69 @@ func runOrgReport(args []string) error {
 	}
 	defer st.Close()
 
+	var repos []string
+	scanFailures := map[string]string{}
+	if strings.TrimSpace(*reposFlag) != "" {
+		repos, err = readRepoList(*reposFlag)
+		if err != nil {
+			return err
+		}
+		if len(repos) == 0 {
+			return fmt.Errorf("no repositories found in %s", *reposFlag)
+		}
+	} else {
+		owners := []string{strings.TrimSpace(*orgFlag)}
+		if owners[0] == "" && len(rt.cfg.Enterprise.Orgs) > 0 {
+			owners = rt.cfg.Enterprise.Orgs
+		}
+		var outcomes []store.ScanOutcome
+		for _, owner := range owners {
+			list, err := st.ListScanOutcomes(owner)
+			if err != nil {
+				return err
+			}
+			outcomes = append(outcomes, list...)
+		}
+		if len(outcomes) == 0 {
+			return fmt.Errorf("--repos is required until `cicost scan` has recorded repositories")
+		}
+		for _, o := range outcomes {
+			if o.Status == store.ScanFailed {
+				scanFailures[o.Repo] = "last scan failed: " + o.Error
+				continue
+			}
+			repos = append(repos, o.Repo)
+		}
+	}
+
 	pcfg, err := loadPricingConfig(rt)
 	if err != nil {
 		return err
 	}
 	start, end := calcPeriod(*daysFlag)
+	// Repos of one owner share its free tier, so each is priced against the
+	// whole account's usage.
+	accountJobs := map[string][]model.Job{}
+	for _, repo := range repos {
+		owner, _, err := splitRepo(repo)
+		if err != nil {
+			continue
+		}
+		if _, ok := accountJobs[owner]; ok {
+			continue
+		}
+		jobs, err := st.ListAccountJobs(owner, analytics.AccountStart(start, pcfg.BillingCycleDay), end)
+		if err != nil {
+			return err
+		}
+		accountJobs[owner] = jobs
+	}
 
 	type repoResult struct {
 		repo     string
 		summary  orgRepoSummary
 		hotspots []orgHotspot
+		data     analytics.RollupRepo
 		err      error
 	}
 	in := make(chan string)
@@ -113,12 +161,17 @@ func runOrgReport(args []string) error {
 					out <- repoResult{repo: repo, err: fmt.Errorf("no local data")}
 					continue
 				}
-				cost, _, _, err := analytics.CalculateCostDetailed(jobs, pcfg, 1.0)
+				owner, _, err := splitRepo(repo)
+				if err != nil {
+					out <- repoResult{repo: repo, err: err}
+					continue
+				}
+				cost, _, _, err := analytics.CalculateAccountCost(jobs, accountJobs[owner], pcfg, 1.0)
 				if err != nil {
 					out <- repoResult{repo: repo, err: err}
 					continue
 				}
-				waste := analytics.CalculateWaste(runs, jobs, pcfg, cost.TotalCostUSD)
+				waste := analytics.CalculateWaste(runs, jobs, pcfg, analytics.TotalSpendUSD(cost))
 				entries := analytics.CalculateHotspots(runs, jobs, pcfg, analytics.HotspotOptions{
 					GroupBy: "workflow",
 					TopN:    3,
@@ -133,10 +186,13 @@ func runOrgReport(args []string) error {
 					summary: orgRepoSummary{
 						Repo:          repo,
 						TotalRuns:     len(runs),
+						GrossCostUSD:  cost.GrossCostUSD,
+						FreeTierUsed:  cost.FreeTierUsed,
 						TotalCostUSD:  cost.TotalCostUSD,
 						TotalWasteUSD: waste.TotalWasteUSD,
 					},
 					hotspots: hs,
+					data:     analytics.RollupRepo{Repo: repo, Runs: runs, Jobs: jobs},
 				}
 			}
 		}()
@@ -154,18 +210,23 @@ func runOrgReport(args []string) error {
 	report := orgReportPayload{
 		GeneratedAt: time.Now().UTC(),
 		Days:        *daysFlag,
-		TotalRepos:  len(repos),
-		Failures:    map[string]string{},
+		TotalRepos:  len(repos) + len(scanFailures),
+		Failures:    scanFailures,
 	}
 
+	var rollupRepos []analytics.RollupRepo
 	for r := range out {
 		if r.err != nil {
 			report.Failures[r.repo] = r.err.Error()
 			continue
 		}
+		if *rollupFlag {
+			rollupRepos = append(rollupRepos, r.data)
+		}
 		report.RepoRankings = append(report.RepoRankings, r.summary)
 		report.TopHotspots = append(report.TopHotspots, r.hotspots...)
 		report.TotalCostUSD += r.summary.TotalCostUSD
+		report.GrossCostUSD += r.summary.GrossCostUSD
 	}
 	report.SuccessRepos = len(report.RepoRankings)
 	report.FailedRepos = len(report.Failures)
@@ -179,6 +240,11 @@ func runOrgReport(args []string) error {
 		report.TopHotspots = report.TopHotspots[:10]
 	}
 	report.TotalCostUSD = round2(report.TotalCostUSD)
+	report.GrossCostUSD = round2(report.GrossCostUSD)
+
+	if *rollupFlag {
+		return writeOrgRollup(rt, report, rollupRepos, accountJobs, pcfg, *enterpriseFlag, *formatFlag, *outputFlag)
+	}
 
 	var rendered string
 	switch strings.ToLower(strings.TrimSpace(*formatFlag)) {
@@ -217,12 +283,12 @@ func renderOrgReportMarkdown(report orgReportPayload) string {
 	fmt.Fprintf(&b, "- GeneratedAt: `%s`\n", report.GeneratedAt.Format(time.RFC3339))
 	fmt.Fprintf(&b, "- Days: `%d`\n", report.Days)
 	fmt.Fprintf(&b, "- Total repos: `%d` (success=%d, failed=%d)\n", report.TotalRepos, report.SuccessRepos, report.FailedRepos)
-	fmt.Fprintf(&b, "- Total cost: `$%.2f`\n\n", report.TotalCostUSD)
+	fmt.Fprintf(&b, "- Total cost: `$%.2f` (gross `$%.2f` before free tier)\n\n", report.TotalCostUSD, report.GrossCostUSD)
 
 	fmt.Fprintf(&b, "## Repo Ranking\n\n")
-	fmt.Fprintf(&b, "| Repo | Runs | Cost(USD) | Waste(USD) |\n|---|---:|---:|---:|\n")
+	fmt.Fprintf(&b, "| Repo | Runs | Gross(USD) | Free min | Net(USD) | Waste(USD) |\n|---|---:|---:|---:|---:|---:|\n")
 	for _, r := range report.RepoRankings {
-		fmt.Fprintf(&b, "| %s | %d | %.2f | %.2f |\n", r.Repo, r.TotalRuns, r.TotalCostUSD, r.TotalWasteUSD)
+		fmt.Fprintf(&b, "| %s | %d | %.2f | %.0f | %.2f | %.2f |\n", r.Repo, r.TotalRuns, r.GrossCostUSD, r.FreeTierUsed, r.TotalCostUSD, r.TotalWasteUSD)
 	}
 	fmt.Fprintf(&b, "\n## Top Hotspots\n\n")
 	fmt.Fprintf(&b, "| Repo | Workflow | Cost(USD) |\n|---|---|---:|\n")
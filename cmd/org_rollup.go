@@ -0,0 +1,155 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/analytics"
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+type orgRollupPayload struct {
+	GeneratedAt   time.Time         `json:"generated_at"`
+	Days          int               `json:"days"`
+	FreeTierLevel string            `json:"free_tier_level"`
+	Rollup        model.RollupNode  `json:"rollup"`
+	Failures      map[string]string `json:"failures,omitempty"`
+}
+
+func writeOrgRollup(rt runtimeContext, report orgReportPayload, repos []analytics.RollupRepo, accountJobs map[string][]model.Job, pcfg pricing.Config, enterprise, format, output string) error {
+	level := strings.ToLower(strings.TrimSpace(rt.cfg.Enterprise.FreeTierLevel))
+	switch level {
+	case "", analytics.FreeTierPerOrg:
+		level = analytics.FreeTierPerOrg
+	case analytics.FreeTierPerEnterprise:
+	default:
+		return fmt.Errorf("invalid enterprise.free_tier_level %q (use org|enterprise)", rt.cfg.Enterprise.FreeTierLevel)
+	}
+	if strings.TrimSpace(enterprise) == "" {
+		enterprise = "all"
+	}
+	rollup, err := analytics.BuildRollup(enterprise, repos, accountJobs, pcfg, level)
+	if err != nil {
+		return err
+	}
+	payload := orgRollupPayload{
+		GeneratedAt:   report.GeneratedAt,
+		Days:          report.Days,
+		FreeTierLevel: level,
+		Rollup:        rollup,
+		Failures:      report.Failures,
+	}
+
+	var rendered string
+	switch strings.ToLower(strings.TrimSpace(format)) {
+	case "json":
+		b, err := json.MarshalIndent(payload, "", "  ")
+		if err != nil {
+			return err
+		}
+		rendered = string(b)
+	case "csv":
+		rendered, err = renderOrgRollupCSV(payload)
+		if err != nil {
+			return err
+		}
+	default:
+		rendered = renderOrgRollupMarkdown(payload)
+	}
+	return writeOutput(output, rendered)
+}
+
+func renderOrgRollupMarkdown(p orgRollupPayload) string {
+	root := p.Rollup
+	var b strings.Builder
+	fmt.Fprintf(&b, "# CICost Enterprise Rollup: %s\n\n", root.Name)
+	fmt.Fprintf(&b, "- GeneratedAt: `%s`\n", p.GeneratedAt.Format(time.RFC3339))
+	fmt.Fprintf(&b, "- Days: `%d`\n", p.Days)
+	fmt.Fprintf(&b, "- Free tier applied per: `%s`\n", p.FreeTierLevel)
+	fmt.Fprintf(&b, "- Orgs: `%d`, runs: `%d`, billable minutes: `%.0f`\n", len(root.Children), root.Runs, root.BillableMinutes)
+	fmt.Fprintf(&b, "- Gross cost: `$%.2f`, free tier: `%.0f min`, net cost: `$%.2f`\n", root.GrossCostUSD, root.FreeTierMinutes, root.NetCostUSD)
+	if root.SelfHostedUSD > 0 {
+		fmt.Fprintf(&b, "- Self-hosted cost: `$%.2f`\n", root.SelfHostedUSD)
+	}
+
+	fmt.Fprintf(&b, "\n## Orgs\n\n")
+	fmt.Fprintf(&b, "| Org | Repos | Runs | Billable min | Free min | Gross(USD) | Net(USD) |\n|---|---:|---:|---:|---:|---:|---:|\n")
+	for _, org := range root.Children {
+		fmt.Fprintf(&b, "| %s | %d | %d | %.0f | %.0f | %.2f | %.2f |\n", org.Name, len(org.Children), org.Runs, org.BillableMinutes, org.FreeTierMinutes, org.GrossCostUSD, org.NetCostUSD)
+	}
+	for _, org := range root.Children {
+		fmt.Fprintf(&b, "\n## %s\n\n", org.Name)
+		fmt.Fprintf(&b, "| Repo | Workflow | Runs | Billable min | Free min | Gross(USD) | Net(USD) |\n|---|---|---:|---:|---:|---:|---:|\n")
+		for _, repo := range org.Children {
+			fmt.Fprintf(&b, "| **%s** | **total** | %d | %.0f | %.0f | %.2f | %.2f |\n", repo.Name, repo.Runs, repo.BillableMinutes, repo.FreeTierMinutes, repo.GrossCostUSD, repo.NetCostUSD)
+			for _, wf := range repo.Children {
+				fmt.Fprintf(&b, "| %s | %s | %d | %.0f | %.0f | %.2f | %.2f |\n", repo.Name, wf.Name, wf.Runs, wf.BillableMinutes, wf.FreeTierMinutes, wf.GrossCostUSD, wf.NetCostUSD)
+			}
+		}
+	}
+	if len(p.Failures) > 0 {
+		fmt.Fprintf(&b, "\n## Partial Failures\n\n")
+		repos := make([]string, 0, len(p.Failures))
+		for repo := range p.Failures {
+			repos = append(repos, repo)
+		}
+		sort.Strings(repos)
+		for _, repo := range repos {
+			fmt.Fprintf(&b, "- %s: %s\n", repo, p.Failures[repo])
+		}
+	}
+	return b.String()
+}
+
+// renderOrgRollupCSV flattens the rollup to one row per node; the level
+// column tells subtotal rows from workflow rows.
+func renderOrgRollupCSV(p orgRollupPayload) (string, error) {
+	var b strings.Builder
+	w := csv.NewWriter(&b)
+	if err := w.Write([]string{"level", "enterprise", "org", "repo", "workflow", "runs", "minutes", "billable_minutes", "free_tier_minutes", "gross_cost_usd", "net_cost_usd", "self_hosted_usd"}); err != nil {
+		return "", err
+	}
+	var walk func(n model.RollupNode, path [4]string) error
+	walk = func(n model.RollupNode, path [4]string) error {
+		switch n.Level {
+		case model.RollupEnterprise:
+			path[0] = n.Name
+		case model.RollupOrg:
+			path[1] = n.Name
+		case model.RollupRepo:
+			path[2] = n.Name
+		case model.RollupWorkflow:
+			path[3] = n.Name
+		}
+		row := []string{
+			n.Level, path[0], path[1], path[2], path[3],
+			strconv.Itoa(n.Runs),
+			strconv.FormatFloat(n.Minutes, 'f', 2, 64),
+			strconv.FormatFloat(n.BillableMinutes, 'f', 2, 64),
+			strconv.FormatFloat(n.FreeTierMinutes, 'f', 2, 64),
+			strconv.FormatFloat(n.GrossCostUSD, 'f', 2, 64),
+			strconv.FormatFloat(n.NetCostUSD, 'f', 2, 64),
+			strconv.FormatFloat(n.SelfHostedUSD, 'f', 2, 64),
+		}
+		if err := w.Write(row); err != nil {
+			return err
+		}
+		for _, c := range n.Children {
+			if err := walk(c, path); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(p.Rollup, [4]string{}); err != nil {
+		return "", err
+	}
+	w.Flush()
+	return b.String(), w.Error()
+}
@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"context"
+	"encoding/json"
 	"flag"
 	"fmt"
 	"os"
@@ -10,12 +12,16 @@ import (
 
 	"github.com/peter941221/CICost/internal/analytics"
 	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/metrics"
 	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/notify"
+	"github.com/peter941221/CICost/internal/output"
 	"github.com/peter941221/CICost/internal/policy"
 	"github.com/peter941221/CICost/internal/store"
 )
 
 func runPolicy(args []string) error {
+	analytics.RegisterMetrics()
 	if len(args) == 0 {
 		return runPolicyHelp()
 	}
@@ -34,7 +40,9 @@ func runPolicy(args []string) error {
 
 func runPolicyHelp() error {
 	fmt.Println(`Usage:
-  cicost policy check --repo owner/repo --days 30 [--policy .cicost.policy.yml]
+  cicost policy check --repo owner/repo --days 30 [--policy .cicost.policy.yml] [--notify webhook --notifier name]
+                      [--comment-file cicost-policy-comment.md] [--summary-file policy-summary.json]
+                      [--format text|json|sarif|junit|github]
   cicost policy lint [--policy .cicost.policy.yml]
   cicost policy explain`)
 	return nil
@@ -67,9 +75,22 @@ func runPolicyCheck(args []string) error {
 	repoFlag := fs.String("repo", "", "Target repository in owner/repo format")
 	daysFlag := fs.Int("days", rt.cfg.Scan.Days, "Time window in days")
 	policyPath := fs.String("policy", ".cicost.policy.yml", "Policy file path")
+	notifyFlag := fs.String("notify", "stdout", "Notification mode for findings: stdout|webhook|file")
+	webhookFlag := fs.String("webhook-url", rt.cfg.Budget.WebhookURL, "Webhook URL")
+	notifierFlag := fs.String("notifier", rt.cfg.Budget.Notifier, "Named notifier from notifiers: to use when notify=webhook")
+	outputFlag := fs.String("output", "", "Output file path")
+	commentFileFlag := fs.String("comment-file", "cicost-policy-comment.md", "Pull request comment markdown written by the comment action")
+	summaryFlag := fs.String("summary-file", "", "Write a JSON summary of findings and the actions that fired")
+	formatFlag := fs.String("format", "text", "Output format: text|json|sarif|junit|github")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
+	format := strings.ToLower(strings.TrimSpace(*formatFlag))
+	switch format {
+	case "text", "json", "sarif", "junit", "github":
+	default:
+		return fmt.Errorf("invalid --format %q (use text|json|sarif|junit|github)", *formatFlag)
+	}
 
 	repo, err := pickRepo(*repoFlag, rt.cfg)
 	if err != nil {
@@ -107,75 +128,350 @@ func runPolicyCheck(args []string) error {
 	if err != nil {
 		return err
 	}
-	cost, _, _, err := analytics.CalculateCostDetailed(jobs, pcfg, 1.0)
+	// The owner's other repositories share the free tier, as in report.
+	account, pcfg, err := accountJobs(st, repo, start, end, pcfg)
 	if err != nil {
 		return err
 	}
-	waste := analytics.CalculateWaste(runs, jobs, pcfg, cost.TotalCostUSD)
-	metrics := map[string]float64{
-		"monthly_cost_usd": cost.TotalCostUSD,
-		"total_cost_usd":   cost.TotalCostUSD,
-		"waste_percentage": waste.WastePercentage,
-		"fail_rate":        waste.FailRate * 100,
-		"total_runs":       float64(len(runs)),
+	// Week-over-week metrics compare two full weeks even when --days is
+	// shorter.
+	historyRuns, history := runs, jobs
+	if trendStart := end.AddDate(0, 0, -analytics.TrendLookbackDays); trendStart.Before(start) {
+		if historyRuns, err = st.ListRuns(repo, trendStart, end); err != nil {
+			return err
+		}
+		if history, err = st.ListJobs(repo, trendStart, end); err != nil {
+			return err
+		}
+	}
+	values, err := metrics.Compute(metrics.Input{Runs: runs, Jobs: jobs, AccountJobs: account, History: history, Pricing: pcfg, Start: start, End: end})
+	if err != nil {
+		return err
+	}
+	in := policy.Input{Metrics: values, Entities: map[string][]policy.Entity{}}
+	for _, scope := range cfg.EntityScopes() {
+		groups, err := analytics.GroupEntities(runs, jobs, scope)
+		if err != nil {
+			return err
+		}
+		historyGroups, err := analytics.GroupEntities(historyRuns, history, scope)
+		if err != nil {
+			return err
+		}
+		historyByKey := make(map[string][]model.Job, len(historyGroups))
+		for _, g := range historyGroups {
+			historyByKey[g.Key] = g.Jobs
+		}
+		entities := make([]policy.Entity, 0, len(groups))
+		for _, g := range groups {
+			// A non-nil History keeps entities without older jobs at 0.
+			h := historyByKey[g.Key]
+			if h == nil {
+				h = []model.Job{}
+			}
+			m, err := metrics.Compute(metrics.Input{Runs: g.Runs, Jobs: g.Jobs, AccountJobs: account, History: h, Pricing: pcfg, Start: start, End: end})
+			if err != nil {
+				return err
+			}
+			entities = append(entities, policy.Entity{Name: g.Name, Metrics: m})
+		}
+		in.Entities[scope] = entities
 	}
 
-	findings, err := policy.Evaluate(cfg, metrics)
+	findings, err := policy.EvaluateInput(cfg, in)
 	if err != nil {
 		return err
 	}
-	if len(findings) == 0 {
-		fmt.Println("Policy check: no rules matched.")
-		return nil
+	summary, err := policy.Resolve(cfg, findings)
+	if err != nil {
+		return err
+	}
+	// Resolve every notify action's notifier before anything is sent, so a
+	// typo fails the check instead of dropping the notification.
+	names, byNotifier := summary.Notifiers()
+	notifiers := make([]notify.Notifier, 0, len(names))
+	for _, name := range names {
+		n, err := notifierFor(rt.cfg, notifyOptions{Notifier: name})
+		if err != nil {
+			return fmt.Errorf("policy action notify:%s: %w", name, err)
+		}
+		notifiers = append(notifiers, n)
 	}
 
-	fmt.Printf("Policy check findings for %s (%d days)\n", repo, *daysFlag)
-	hasError := false
-	for _, f := range findings {
-		fmt.Printf("- [%s] %s (evidence: %s=%.4f, when: %s)\n", strings.ToUpper(string(f.Severity)), f.RuleID, f.EvidenceKey, f.EvidenceValue, f.When)
+	now := time.Now().UTC()
+	for _, f := range summary.Findings {
 		if err := st.InsertPolicyRun(model.PolicyRun{
 			Repo:          repo,
 			PeriodStart:   start,
 			PeriodEnd:     end,
 			RuleID:        f.RuleID,
+			Scope:         f.Scope,
+			Entity:        f.Entity,
 			Severity:      string(f.Severity),
 			Matched:       true,
 			EvidenceKey:   f.EvidenceKey,
 			EvidenceValue: f.EvidenceValue,
 			Expression:    f.When,
-			CreatedAt:     time.Now().UTC(),
+			CreatedAt:     now,
 		}); err != nil {
 			return err
 		}
-		if f.Severity == policy.SeverityError {
-			hasError = true
+	}
+	exitCode := 0
+	if summary.Failed {
+		exitCode = 3
+	}
+	doc, err := json.MarshalIndent(policyCheckSummary{
+		Repo:        repo,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		ExitCode:    exitCode,
+		Summary:     summary,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if *summaryFlag != "" {
+		if err := writeOutput(*summaryFlag, string(doc)+"\n"); err != nil {
+			return err
+		}
+	}
+
+	reported := summary.Reported()
+	opts := notifyOptions{
+		Mode:        *notifyFlag,
+		WebhookURL:  *webhookFlag,
+		Notifier:    *notifierFlag,
+		Output:      *outputFlag,
+		DefaultFile: "policy.txt",
+	}
+	if len(reported) == 0 {
+		if format == "text" {
+			if summary.Ignored > 0 {
+				fmt.Printf("Policy check: no rules matched (%d findings ignored).\n", summary.Ignored)
+			} else {
+				fmt.Println("Policy check: no rules matched.")
+			}
+			return nil
+		}
+		// Report consumers still want an empty result; webhooks do not.
+		if opts.mode() != "file" {
+			opts.Mode = "stdout"
 		}
 	}
 
-	if hasError {
-		return withExit(3, fmt.Errorf("policy check failed: one or more error rules matched"))
+	msg := policyMessage(repo, *daysFlag, start, end, reported)
+	view := output.PolicyView{
+		Repo:        repo,
+		PolicyPath:  policyURI(p),
+		PeriodStart: start,
+		PeriodEnd:   end,
+		ToolVersion: version,
+		Rules:       cfg.Rules,
+		Findings:    reported,
+	}
+	switch format {
+	case "json":
+		msg.Text = string(doc) + "\n"
+	case "sarif":
+		if msg.Text, err = output.RenderPolicySARIF(view); err != nil {
+			return err
+		}
+	case "junit":
+		if msg.Text, err = output.RenderPolicyJUnit(view); err != nil {
+			return err
+		}
+	case "github":
+		msg.Text = output.RenderPolicyGitHub(view)
+	}
+	// Report formats go to --output whenever it is set; --notify only adds
+	// a webhook delivery.
+	delivered := false
+	if format != "text" && *outputFlag != "" {
+		if err := writeOutput(*outputFlag, msg.Text); err != nil {
+			return err
+		}
+		delivered = opts.mode() != "webhook"
+	}
+	if !delivered {
+		if err := sendNotification(rt.cfg, opts, msg); err != nil {
+			return err
+		}
+	}
+	for i, n := range notifiers {
+		if err := n.Send(context.Background(), policyMessage(repo, *daysFlag, start, end, byNotifier[names[i]])); err != nil {
+			fmt.Printf("WARN: %v\n", err)
+		}
+	}
+	if summary.Actions[policy.ActionComment] > 0 {
+		var commented []policy.Finding
+		for _, f := range reported {
+			if f.Has(policy.ActionComment) {
+				commented = append(commented, f)
+			}
+		}
+		if err := writePolicyComment(*commentFileFlag, renderPolicyComment(repo, start, end, commented, summary.Failed)); err != nil {
+			return err
+		}
+	}
+
+	if summary.Failed {
+		return withExit(3, fmt.Errorf("policy check failed: %d findings with action fail", summary.Actions[policy.ActionFail]))
 	}
 	return nil
 }
 
+// policyCheckSummary is the --summary-file document.
+type policyCheckSummary struct {
+	Repo        string    `json:"repo"`
+	PeriodStart time.Time `json:"period_start"`
+	PeriodEnd   time.Time `json:"period_end"`
+	ExitCode    int       `json:"exit_code"`
+	policy.Summary
+}
+
+func policyMessage(repo string, days int, start, end time.Time, findings []policy.Finding) notify.Message {
+	var b strings.Builder
+	fmt.Fprintf(&b, "Policy check findings for %s (%d days)\n", repo, days)
+	severity := notify.SeverityWarning
+	facts := make([]notify.Fact, 0, len(findings))
+	for _, f := range findings {
+		evidence := f.Evidence()
+		fmt.Fprintf(&b, "- [%s] %s (evidence: %s, when: %s, actions: %s)\n", strings.ToUpper(string(f.Severity)), f.Subject(), evidence, f.When, strings.Join(f.Actions, ", "))
+		facts = append(facts, notify.Fact{Name: f.Subject(), Value: fmt.Sprintf("%s: %s (%s)", f.Severity, evidence, f.When)})
+		if f.Has(policy.ActionFail) {
+			severity = notify.SeverityError
+		}
+	}
+	return notify.Message{
+		Kind:     "policy",
+		Title:    fmt.Sprintf("Policy check for %s: %d findings", repo, len(findings)),
+		Severity: severity,
+		Text:     b.String(),
+		Facts:    facts,
+		Data: map[string]any{
+			"type":         "policy",
+			"repo":         repo,
+			"period_start": start,
+			"period_end":   end,
+			"findings":     findings,
+		},
+	}
+}
+
+// renderPolicyComment renders findings as a pull request comment.
+func renderPolicyComment(repo string, start, end time.Time, findings []policy.Finding, failed bool) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "### CICost policy check for %s\n\n", repo)
+	result := "passed with findings"
+	if failed {
+		result = "failed"
+	}
+	fmt.Fprintf(&b, "Policy check %s for %s ~ %s.\n\n", result, start.Format("2006-01-02"), end.Format("2006-01-02"))
+	b.WriteString("| Severity | Rule | Entity | Evidence | Condition |\n|---|---|---|---|---|\n")
+	for _, f := range findings {
+		entity := "-"
+		if f.Entity != "" {
+			entity = f.Scope + ": " + f.Entity
+		}
+		fmt.Fprintf(&b, "| %s | `%s` | %s | %s | `%s` |\n", strings.ToUpper(string(f.Severity)), f.RuleID,
+			markdownCell(entity), markdownCell(f.Evidence()), markdownCell(f.When))
+	}
+	return b.String()
+}
+
+func markdownCell(s string) string {
+	return strings.ReplaceAll(strings.ReplaceAll(s, "|", "\\|"), "\n", " ")
+}
+
+// writePolicyComment writes comment to path and, inside GitHub Actions,
+// appends it to the job's step summary.
+func writePolicyComment(path, comment string) error {
+	if err := writeOutput(path, comment); err != nil {
+		return err
+	}
+	summaryPath := os.Getenv("GITHUB_STEP_SUMMARY")
+	if summaryPath == "" {
+		return nil
+	}
+	f, err := os.OpenFile(summaryPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.WriteString(comment + "\n"); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
 func runPolicyExplain(_ []string) error {
-	fmt.Println(`Policy explain
-- supported metrics:
-  - monthly_cost_usd
-  - total_cost_usd
-  - waste_percentage
-  - fail_rate
-  - total_runs
-- supported operators: >, >=, <, <=, ==, !=
+	fmt.Println("Policy explain")
+	fmt.Print(metricListing())
+	fmt.Println(`- comparisons: >, >=, <, <=, ==, != (between metrics, numbers or arithmetic)
+- arithmetic: + - * / % and parentheses
+- logic: and, or, not (also &&, ||, !)
+- functions: abs(x), min(a, b, ...), max(a, b, ...), round(x), pct_change(current, previous)
+- scope: repo (default), or workflow|job|branch|runner|event to evaluate the
+  rule once per entity, named as in hotspots --group-by; match: globs that
+  select entities by name, where * and ** also match / (e.g. "CI / *",
+  "release/**")
+- actions: fail (exit code 3), warn, notify:<notifier>, comment (written to
+  --comment-file and $GITHUB_STEP_SUMMARY) and ignore; a rule's actions
+  override actions.on_error/on_warn/on_info, which default to fail for
+  errors and warn otherwise; --summary-file records which actions fired
 
 example:
 rules:
   - id: budget_monthly
     when: monthly_cost_usd > 200
-    severity: error`)
+    severity: error
+  - id: costly_waste
+    when: waste_percentage > 10 and total_cost_usd > 50
+    severity: warn
+  - id: failures_per_run
+    when: total_runs > 0 and fail_rate / 100 * total_runs > 20
+    severity: warn
+  - id: workflow_cap
+    scope: workflow
+    match: ["*"]
+    when: monthly_cost_usd > 40
+    severity: error
+    actions: [warn, notify:ci-slack]
+actions:
+  on_warn: [warn, comment]`)
 	return nil
 }
 
+// metricListing lists the registered metrics with their units and
+// descriptions, aligned in columns.
+func metricListing() string {
+	all := metrics.All()
+	width := 0
+	for _, m := range all {
+		width = max(width, len(m.Name))
+	}
+	var b strings.Builder
+	b.WriteString("- supported metrics:\n")
+	for _, m := range all {
+		fmt.Fprintf(&b, "  - %-*s  %-8s %s\n", width, m.Name, m.Unit, m.Description)
+	}
+	return b.String()
+}
+
+// policyURI is the policy file path as reports point at it: relative to the
+// working directory, normally the repository root, with forward slashes.
+func policyURI(path string) string {
+	if filepath.IsAbs(path) {
+		if wd, err := os.Getwd(); err == nil {
+			if rel, err := filepath.Rel(wd, path); err == nil && !strings.HasPrefix(rel, "..") {
+				path = rel
+			}
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
 func resolvePolicyPath(path string) string {
 	if strings.TrimSpace(path) == "" {
 		path = ".cicost.policy.yml"
@@ -1,14 +1,17 @@
 package cmd
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"strings"
 	"time"
 
 	"github.com/peter941221/CICost/internal/analytics"
+	"github.com/peter941221/CICost/internal/auth"
 	"github.com/peter941221/CICost/internal/billing"
 	"github.com/peter941221/CICost/internal/config"
+	gh "github.com/peter941221/CICost/internal/github"
 	"github.com/peter941221/CICost/internal/model"
 	"github.com/peter941221/CICost/internal/reconcile"
 	"github.com/peter941221/CICost/internal/store"
@@ -23,9 +26,10 @@ func runReconcile(args []string) error {
 	repoFlag := fs.String("repo", "", "Target repository in owner/repo format")
 	monthFlag := fs.String("month", time.Now().UTC().Format("2006-01"), "Month in YYYY-MM format")
 	sourceFlag := fs.String("source", "csv", "Billing source: csv|github")
-	inputFlag := fs.String("input", "", "Billing CSV file (repo,period,actual_cost_usd)")
+	inputFlag := fs.String("input", "", "Billing CSV file (repo,period,actual_cost_usd[,sku] or a GitHub usage report export)")
 	actualFlag := fs.Float64("actual-usd", 0, "Actual billed amount (overrides --input)")
 	applyFlag := fs.Bool("apply-calibration", false, "Apply calibration factor to future --calibrated reports")
+	tokenFlag := fs.String("token", "", "GitHub token for --source=github (optional)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
@@ -71,45 +75,74 @@ func runReconcile(args []string) error {
 	}
 	estimated := cost.TotalCostUSD
 
-	actual := *actualFlag
 	src := strings.ToLower(strings.TrimSpace(*sourceFlag))
-	if actual <= 0 {
+	var rows []model.BillingSnapshot
+	if *actualFlag > 0 {
+		rows = []model.BillingSnapshot{{Repo: repo, Period: period, ActualCostUSD: *actualFlag}}
+	} else {
 		switch src {
-		case "csv":
+		case billing.SourceCSV:
 			if strings.TrimSpace(*inputFlag) == "" {
 				return fmt.Errorf("--input is required when --source=csv and --actual-usd is not provided")
 			}
-			actual, err = billing.LoadActualFromCSV(*inputFlag, repo, period)
+			isReport, err := billing.IsUsageReportFile(*inputFlag)
+			if err != nil {
+				return err
+			}
+			if isReport {
+				src = billing.SourceUsageReport
+				rows, err = importUsageReport(st, *inputFlag, repo, period)
+			} else {
+				rows, err = billing.LoadActualRowsFromCSV(*inputFlag, repo, period)
+			}
+			if err != nil {
+				return err
+			}
+		case billing.SourceGitHub:
+			rows, err = fetchGitHubActual(st, *tokenFlag, rt.cfg.Auth.Token, repo, monthStart)
 			if err != nil {
 				return err
 			}
-		case "github":
-			return fmt.Errorf("github billing source is not available yet; use --source csv or --actual-usd")
 		default:
 			return fmt.Errorf("unsupported source %q, expected csv|github", src)
 		}
 	}
+	actual := 0.0
+	actualBySKU := map[string]float64{}
+	for _, row := range rows {
+		actual += row.ActualCostUSD
+		actualBySKU[row.SKU] += row.ActualCostUSD
+	}
 	if actual <= 0 {
 		return fmt.Errorf("actual cost must be > 0")
 	}
 
-	if err := st.UpsertBillingSnapshot(model.BillingSnapshot{
-		Repo:          repo,
-		Period:        period,
-		ActualCostUSD: actual,
-		Source:        src,
-		FetchedAt:     time.Now().UTC(),
-	}); err != nil {
-		return err
+	if (src != billing.SourceGitHub && src != billing.SourceUsageReport) || *actualFlag > 0 {
+		fetchedAt := time.Now().UTC()
+		for i := range rows {
+			rows[i].FetchedAt = fetchedAt
+		}
+		if err := st.ReplaceBillingSnapshots(repo, period, src, rows); err != nil {
+			return err
+		}
 	}
 
 	res := reconcile.BuildResult(repo, period, estimated, actual)
+	skuResults := reconcile.BuildSKUResults(repo, period, reconcile.EstimateBySKU(cost), actualBySKU)
 	if !*applyFlag {
 		res.CalibrationFactor = 1
+		for i := range skuResults {
+			skuResults[i].CalibrationFactor = 1
+		}
 	}
 	if err := st.InsertReconcileResult(res); err != nil {
 		return err
 	}
+	for _, skuRes := range skuResults {
+		if err := st.InsertReconcileResult(skuRes); err != nil {
+			return err
+		}
+	}
 
 	fmt.Printf("Reconcile Result: %s %s\n", repo, period)
 	fmt.Printf("  Estimate  : $%.2f\n", res.EstimatedCostUSD)
@@ -117,6 +150,13 @@ func runReconcile(args []string) error {
 	fmt.Printf("  Delta     : %.2f%%\n", res.DeltaRatio*100)
 	fmt.Printf("  Factor    : %.4f\n", res.CalibrationFactor)
 	fmt.Printf("  Confidence: %s\n", res.Confidence)
+	if len(skuResults) > 0 {
+		fmt.Println("  By SKU:")
+		for _, skuRes := range skuResults {
+			fmt.Printf("    %-16s estimate=$%8.2f actual=$%8.2f delta=%7.2f%% factor=%.4f confidence=%s\n",
+				skuRes.SKU, skuRes.EstimatedCostUSD, skuRes.ActualCostUSD, skuRes.DeltaRatio*100, skuRes.CalibrationFactor, skuRes.Confidence)
+		}
+	}
 	if *applyFlag {
 		fmt.Println("  Calibration: enabled for future `report --calibrated`")
 	} else {
@@ -124,3 +164,69 @@ func runReconcile(args []string) error {
 	}
 	return nil
 }
+
+// fetchGitHubActual pulls the account's billing usage for the month, stores
+// github-sourced per-SKU snapshots for every repository in it and returns the
+// rows for repo.
+func fetchGitHubActual(st *store.Store, tokenFlag, cfgToken, repo string, month time.Time) ([]model.BillingSnapshot, error) {
+	owner, _, err := splitRepo(repo)
+	if err != nil {
+		return nil, err
+	}
+	token, err := auth.ResolveToken(tokenFlag, cfgToken)
+	if err != nil {
+		return nil, fmt.Errorf("token missing: %w", err)
+	}
+	client := gh.NewClient(token)
+	items, _, err := client.GetBillingUsage(context.Background(), owner, month.Year(), month.Month())
+	if err != nil {
+		return nil, fmt.Errorf("fetch github billing usage failed: %w", err)
+	}
+	rows, err := storeBillingSnapshots(st, billing.SourceGitHub, billing.SnapshotsFromGitHubUsage(items, owner, time.Now().UTC()), repo, month.Format("2006-01"))
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no github billing usage matched repo=%s period=%s", repo, month.Format("2006-01"))
+	}
+	return rows, nil
+}
+
+// importUsageReport loads every repository and month in a GitHub usage report
+// CSV into the store and returns the rows for repo and period.
+func importUsageReport(st *store.Store, path, repo, period string) ([]model.BillingSnapshot, error) {
+	snaps, err := billing.LoadUsageReport(path)
+	if err != nil {
+		return nil, err
+	}
+	fetchedAt := time.Now().UTC()
+	for i := range snaps {
+		snaps[i].FetchedAt = fetchedAt
+	}
+	rows, err := storeBillingSnapshots(st, billing.SourceUsageReport, snaps, repo, period)
+	if err != nil {
+		return nil, err
+	}
+	if len(rows) == 0 {
+		return nil, fmt.Errorf("no usage report row matched repo=%s period=%s in %s", repo, period, path)
+	}
+	fmt.Printf("Imported usage report: %d rows from %s\n", len(snaps), path)
+	return rows, nil
+}
+
+// storeBillingSnapshots replaces the stored rows of source for every repo and
+// period in snaps and returns the rows for repo and period.
+func storeBillingSnapshots(st *store.Store, source string, snaps []model.BillingSnapshot, repo, period string) ([]model.BillingSnapshot, error) {
+	type key struct{ repo, period string }
+	grouped := map[key][]model.BillingSnapshot{}
+	for _, snap := range snaps {
+		k := key{repo: snap.Repo, period: snap.Period}
+		grouped[k] = append(grouped[k], snap)
+	}
+	for k, group := range grouped {
+		if err := st.ReplaceBillingSnapshots(k.repo, k.period, source, group); err != nil {
+			return nil, err
+		}
+	}
+	return grouped[key{repo: repo, period: period}], nil
+}
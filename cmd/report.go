@@ -9,7 +9,9 @@ import (
 
 	"github.com/peter941221/CICost/internal/analytics"
 	"github.com/peter941221/CICost/internal/config"
+	"github.com/peter941221/CICost/internal/model"
 	"github.com/peter941221/CICost/internal/output"
+	"github.com/peter941221/CICost/internal/reconcile"
 	"github.com/peter941221/CICost/internal/store"
 )
 
@@ -61,26 +63,31 @@ func runReport(args []string) error {
 		return err
 	}
 
-	cost, _, pricingMeta, err := analytics.CalculateCostDetailed(jobs, pricingCfg, 1.0)
+	cost, _, pricingMeta, err := accountCost(st, repo, jobs, start, end, pricingCfg)
 	if err != nil {
 		return err
 	}
+	if cost.Storage, _, _, err = storageCost(st, repo, start, end, pricingCfg); err != nil {
+		return err
+	}
 	calibrationFactor := 1.0
 	calibrated := false
+	var skuCalibration []model.ReconcileResult
 	if *calibratedFlag {
 		if rec, ok, err := st.GetLatestReconcile(repo); err == nil && ok && rec.CalibrationFactor > 0 {
 			calibrationFactor = rec.CalibrationFactor
 			calibrated = true
+			skuCalibration, _ = st.ListLatestSKUReconcile(repo)
 		}
 	}
-	if calibrated && calibrationFactor != 1 {
-		cost.TotalCostUSD = round2(cost.TotalCostUSD * calibrationFactor)
-		for k, osCost := range cost.ByOS {
-			osCost.CostUSD = round2(osCost.CostUSD * calibrationFactor)
-			cost.ByOS[k] = osCost
+	if calibrated {
+		factors := make(map[string]float64, len(skuCalibration))
+		for _, r := range skuCalibration {
+			factors[r.SKU] = r.CalibrationFactor
 		}
+		calibrationFactor = reconcile.ApplySKUCalibration(&cost, factors, calibrationFactor)
 	}
-	waste := analytics.CalculateWaste(runs, jobs, pricingCfg, cost.TotalCostUSD)
+	waste := analytics.CalculateWaste(runs, jobs, pricingCfg, analytics.TotalSpendUSD(cost))
 	if calibrated && calibrationFactor != 1 {
 		waste.RerunWasteUSD = round2(waste.RerunWasteUSD * calibrationFactor)
 		waste.CancelWasteUSD = round2(waste.CancelWasteUSD * calibrationFactor)
@@ -99,6 +106,7 @@ func runReport(args []string) error {
 		PricingSource:          pricingMeta.PricingSource,
 		Calibrated:             calibrated,
 		CalibrationFactor:      calibrationFactor,
+		CalibrationBySKU:       skuCalibration,
 	}
 
 	if *compareFlag {
@@ -107,7 +115,7 @@ func runReport(args []string) error {
 		prevRuns, _ := st.ListRuns(repo, prevStart, prevEnd)
 		prevJobs, _ := st.ListJobs(repo, prevStart, prevEnd)
 		if len(prevRuns) > 0 && len(prevJobs) > 0 {
-			prevCost, _, _, _ := analytics.CalculateCostDetailed(prevJobs, pricingCfg, 1.0)
+			prevCost, _, _, _ := accountCost(st, repo, prevJobs, prevStart, prevEnd, pricingCfg)
 			trend := analytics.CompareCost(cost, prevCost)
 			fmt.Printf("Compare previous %d days: %s %.2f USD (%.2f%%)\n", *daysFlag, trend.Direction, trend.DeltaUSD, trend.DeltaPct)
 		} else {
@@ -16,6 +16,8 @@ var commands = map[string]commandHandler{
 	"policy":     runPolicy,
 	"hotspots":   runHotspots,
 	"budget":     runBudget,
+	"forecast":   runForecast,
+	"anomalies":  runAnomalies,
 	"suggest":    runSuggest,
 	"org-report": runOrgReport,
 	"explain":    runExplain,
@@ -58,6 +60,8 @@ Commands:
   org-report Multi-repo aggregate report (md/json, partial result supported)
   hotspots   Hotspot ranking (workflow/job/runner/branch)
   budget     Budget alerting (stdout/webhook/file)
+  forecast   Forecast period spend with weekday seasonality and interval
+  anomalies  Flag days of unusual spend per repo/workflow/job/runner
   explain    Generate optimization suggestions
   config     show/edit config
   version    Print version info
@@ -2,10 +2,11 @@ package cmd
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
-	"sort"
+	"strconv"
 	"sync"
 	"time"
 
@@ -16,6 +17,40 @@ import (
 	"github.com/peter941221/CICost/internal/store"
 )
 
+type scanOptions struct {
+	Days        int
+	Incremental bool
+	Full        bool
+	Workers     int
+	// Storage also lists the repository's artifacts and caches.
+	Storage bool
+}
+
+// scanStats describes one repository scan. Listed is false when the scan
+// stopped before the runs listing completed.
+type scanStats struct {
+	Repo        string
+	Start       time.Time
+	Listed      bool
+	Runs        int
+	NewRuns     int
+	UpdatedRuns int
+	Jobs        int
+	NewJobs     int
+	UpdatedJobs int
+	Resumed     int
+	RunCalls    int
+	JobCalls    int
+	Failures    int
+	Pending     int
+	Artifacts   int
+	Caches      int
+	// StorageCalls counts artifact and cache listing calls; StorageErr is
+	// set when listing failed, which does not fail the scan.
+	StorageCalls int
+	StorageErr   error
+}
+
 func runScan(args []string) error {
 	ctx, err := newRuntimeContext()
 	if err != nil {
@@ -23,28 +58,42 @@ func runScan(args []string) error {
 	}
 	fs := flag.NewFlagSet("scan", flag.ContinueOnError)
 	repoFlag := fs.String("repo", "", "Target repository in owner/repo format")
+	orgFlag := fs.String("org", "", "Scan every repository of this org or user")
+	includeFlag := fs.String("include", "", "With --org: comma separated repo name globs to scan")
+	excludeFlag := fs.String("exclude", "", "With --org: comma separated repo name globs to skip")
+	topicFlag := fs.String("topic", "", "With --org: only repos with one of these comma separated topics")
+	archivedFlag := fs.Bool("include-archived", false, "With --org: also scan archived repos")
+	forksFlag := fs.Bool("include-forks", false, "With --org: also scan forks")
+	repoWorkersFlag := fs.Int("repo-workers", 2, "With --org: repositories scanned concurrently")
 	daysFlag := fs.Int("days", ctx.cfg.Scan.Days, "Time window in days")
 	incrementalFlag := fs.Bool("incremental", ctx.cfg.Scan.Incremental, "Enable incremental sync")
 	fullFlag := fs.Bool("full", false, "Force full sync")
 	workersFlag := fs.Int("workers", ctx.cfg.Scan.Workers, "Concurrent worker count")
+	storageFlag := fs.Bool("storage", true, "Also fetch artifact and cache storage")
 	tokenFlag := fs.String("token", "", "GitHub token (optional)")
 	if err := fs.Parse(args); err != nil {
 		return err
 	}
-
-	repo, err := pickRepo(*repoFlag, ctx.cfg)
-	if err != nil {
-		return err
+	if *orgFlag != "" && *repoFlag != "" {
+		return fmt.Errorf("--org and --repo cannot be combined")
 	}
-	owner, repoName, err := splitRepo(repo)
-	if err != nil {
-		return err
-	}
-	if *workersFlag <= 0 {
-		*workersFlag = 4
+
+	opts := scanOptions{
+		Days:        *daysFlag,
+		Incremental: *incrementalFlag,
+		Full:        *fullFlag,
+		Workers:     clampWorkers(*workersFlag, 4),
+		Storage:     *storageFlag,
 	}
-	if *workersFlag > 8 {
-		*workersFlag = 8
+	var repo string
+	if *orgFlag == "" {
+		repo, err = pickRepo(*repoFlag, ctx.cfg)
+		if err != nil {
+			return err
+		}
+		if _, _, err := splitRepo(repo); err != nil {
+			return err
+		}
 	}
 
 	token, err := auth.ResolveToken(*tokenFlag, ctx.cfg.Auth.Token)
@@ -61,30 +110,144 @@ func runScan(args []string) error {
 	}
 	defer st.Close()
 
-	start, _ := calcPeriod(*daysFlag)
-	if *incrementalFlag && !*fullFlag {
-		if cur, ok, err := st.GetCursor(repo); err == nil && ok && !cur.LastCreatedAt.IsZero() {
-			start = cur.LastCreatedAt
+	sigCtx, stop := signalContext()
+	defer stop()
+
+	client := gh.NewClient(token)
+	client.Cache = storeCache{st: st}
+	if _, err := st.PruneHTTPCache(time.Now().AddDate(0, 0, -httpCacheMaxAgeDays)); err != nil {
+		return err
+	}
+
+	if *orgFlag != "" {
+		filter := repoFilter{
+			Include:         splitList(*includeFlag),
+			Exclude:         splitList(*excludeFlag),
+			Topics:          splitList(*topicFlag),
+			IncludeArchived: *archivedFlag,
+			IncludeForks:    *forksFlag,
 		}
+		return runOrgScan(sigCtx, client, st, *orgFlag, filter, clampWorkers(*repoWorkersFlag, 2), opts, dbPath)
 	}
 
-	client := gh.NewClient(token)
-	runList, runCalls, err := client.ListWorkflowRuns(context.Background(), owner, repoName, start)
-	if err != nil {
+	stats, err := scanRepo(sigCtx, client, st, repo, opts)
+	if rerr := recordScanOutcome(st, stats, err); rerr != nil && err == nil {
+		err = rerr
+	}
+	if sigCtx.Err() != nil && !stats.Listed {
+		fmt.Printf("Interrupted while listing runs for %s: %d runs stored; rerun `cicost scan` to resume\n", repo, stats.Runs)
 		return err
 	}
-	for i := range runList {
-		runList[i].Repo = repo
+	if err != nil && sigCtx.Err() == nil {
+		return err
 	}
-	if len(runList) == 0 {
-		fmt.Printf("No workflow runs found for %s since %s\n", repo, start.Format(time.RFC3339))
+	if stats.Runs == 0 && stats.Resumed == 0 {
+		fmt.Printf("No workflow runs found for %s since %s\n", repo, stats.Start.Format(time.RFC3339))
 		return nil
 	}
-	sort.Slice(runList, func(i, j int) bool { return runList[i].CreatedAt.After(runList[j].CreatedAt) })
 
-	newRuns, updatedRuns, err := st.UpsertRuns(runList)
+	fmt.Printf("CICost Scan: %s\n", repo)
+	fmt.Printf("  Period     : %s ~ %s\n", stats.Start.Format("2006-01-02"), time.Now().UTC().Format("2006-01-02"))
+	fmt.Printf("  Runs found : %d (%d new, %d updated)\n", stats.Runs, stats.NewRuns, stats.UpdatedRuns)
+	fmt.Printf("  Jobs found : %d (%d new, %d updated)\n", stats.Jobs, stats.NewJobs, stats.UpdatedJobs)
+	if stats.Resumed > 0 {
+		fmt.Printf("  Resumed    : %d runs left pending by an earlier scan\n", stats.Resumed)
+	}
+	if opts.Storage {
+		if stats.StorageErr != nil {
+			fmt.Printf("  Storage    : not fetched (%v)\n", stats.StorageErr)
+		} else {
+			fmt.Printf("  Storage    : %d artifacts, %d caches\n", stats.Artifacts, stats.Caches)
+		}
+	}
+	fmt.Printf("  API calls  : %d (runs=%d jobs=%d storage=%d)\n", stats.RunCalls+stats.JobCalls+stats.StorageCalls, stats.RunCalls, stats.JobCalls, stats.StorageCalls)
+	printClientStats(client)
+	if stats.Failures > 0 {
+		fmt.Printf("  Partial    : %d runs failed to fetch jobs (retried on next scan)\n", stats.Failures)
+	}
+	fmt.Printf("  DB path    : %s\n", dbPath)
+	if sigCtx.Err() != nil {
+		fmt.Printf("  Interrupted: %d runs still need jobs; rerun `cicost scan` to resume\n", stats.Pending)
+		return fmt.Errorf("scan interrupted: %w", sigCtx.Err())
+	}
+	return nil
+}
+
+// scanRepo lists the runs of one repository and fetches jobs for every run
+// still pending. Progress is stored as it goes, so an error or cancellation
+// of sigCtx leaves the rest for the next scan.
+func scanRepo(sigCtx context.Context, client *gh.Client, st *store.Store, repo string, opts scanOptions) (scanStats, error) {
+	stats := scanStats{Repo: repo}
+	owner, repoName, err := splitRepo(repo)
 	if err != nil {
-		return err
+		return stats, err
+	}
+	start, _ := calcPeriod(opts.Days)
+	if opts.Incremental && !opts.Full {
+		if cur, ok, err := st.GetCursor(repo); err == nil && ok && !cur.LastCreatedAt.IsZero() {
+			start = cur.LastCreatedAt
+		}
+	}
+	stats.Start = start
+
+	scanCtx, cancel := context.WithCancel(sigCtx)
+	defer cancel()
+
+	var latest model.WorkflowRun
+	listed := map[string]struct{}{}
+	runCalls, err := client.ListWorkflowRunPages(scanCtx, owner, repoName, start, func(page []model.WorkflowRun) error {
+		for i := range page {
+			page[i].Repo = repo
+			listed[runKey(page[i].ID, page[i].RunAttempt)] = struct{}{}
+			if latest.ID == 0 || page[i].CreatedAt.After(latest.CreatedAt) {
+				latest = page[i]
+			}
+		}
+		n, u, err := st.UpsertRuns(page)
+		if err != nil {
+			return err
+		}
+		stats.NewRuns += n
+		stats.UpdatedRuns += u
+		return st.QueueJobFetches(page)
+	})
+	stats.RunCalls = runCalls
+	stats.Runs = len(listed)
+	if err != nil {
+		if sigCtx.Err() != nil {
+			return stats, fmt.Errorf("scan interrupted: %w", sigCtx.Err())
+		}
+		return stats, err
+	}
+	stats.Listed = true
+	// Runs are listed newest first, so the cursor only moves once the whole
+	// listing is stored; an interrupted listing is simply repeated.
+	if len(listed) > 0 {
+		if err := st.UpsertCursor(store.SyncCursor{
+			Repo:          repo,
+			LastRunID:     latest.ID,
+			LastCreatedAt: latest.CreatedAt,
+			LastSyncAt:    time.Now().UTC(),
+			TotalRuns:     len(listed),
+		}); err != nil {
+			return stats, err
+		}
+	}
+
+	pending, err := st.ListPendingJobFetches(repo)
+	if err != nil {
+		return stats, err
+	}
+	if len(listed) == 0 && len(pending) == 0 {
+		if opts.Storage {
+			return stats, scanStorage(sigCtx, client, st, repo, &stats)
+		}
+		return stats, nil
+	}
+	for _, r := range pending {
+		if _, ok := listed[runKey(r.ID, r.RunAttempt)]; !ok {
+			stats.Resumed++
+		}
 	}
 
 	type result struct {
@@ -95,74 +258,229 @@ func runScan(args []string) error {
 		attempt  int
 		workflow string
 	}
-	sem := make(chan struct{}, *workersFlag)
 	runCh := make(chan model.WorkflowRun)
 	resCh := make(chan result)
 	var wg sync.WaitGroup
 
-	for i := 0; i < *workersFlag; i++ {
+	for i := 0; i < opts.Workers; i++ {
 		wg.Add(1)
 		go func() {
 			defer wg.Done()
 			for r := range runCh {
-				sem <- struct{}{}
-				jobs, calls, err := client.ListJobsForRun(context.Background(), owner, repoName, r.ID, r.RunAttempt)
-				<-sem
+				jobs, calls, err := client.ListJobsForRun(scanCtx, owner, repoName, r.ID, r.RunAttempt)
 				resCh <- result{jobs: jobs, calls: calls, err: err, runID: r.ID, attempt: r.RunAttempt, workflow: r.WorkflowName}
 			}
 		}()
 	}
 	go func() {
-		for _, r := range runList {
-			runCh <- r
+		defer close(runCh)
+		for _, r := range pending {
+			select {
+			case runCh <- r:
+			case <-scanCtx.Done():
+				return
+			}
 		}
-		close(runCh)
+	}()
+	go func() {
 		wg.Wait()
 		close(resCh)
 	}()
 
-	allJobs := make([]model.Job, 0, len(runList)*4)
-	jobCalls := 0
-	failures := 0
+	// Jobs are committed every scanBatchRuns runs and each run is marked done
+	// only after its jobs are stored, so a rerun refetches just the rest.
+	batch := make([]model.Job, 0, scanBatchRuns*4)
+	batchRuns := make([]result, 0, scanBatchRuns)
+	flush := func() error {
+		if len(batchRuns) == 0 {
+			return nil
+		}
+		n, u, err := st.UpsertJobs(batch)
+		if err != nil {
+			return err
+		}
+		for _, r := range batchRuns {
+			if err := st.SetJobFetchStatus(r.runID, r.attempt, store.JobFetchDone, ""); err != nil {
+				return err
+			}
+		}
+		stats.NewJobs += n
+		stats.UpdatedJobs += u
+		stats.Jobs += len(batch)
+		batch = batch[:0]
+		batchRuns = batchRuns[:0]
+		return nil
+	}
+	abort := func(err error) (scanStats, error) {
+		cancel()
+		for range resCh {
+		}
+		return stats, err
+	}
+
 	for r := range resCh {
-		jobCalls += r.calls
+		stats.JobCalls += r.calls
 		if r.err != nil {
-			failures++
-			fmt.Fprintf(os.Stderr, "WARN: jobs fetch failed for run %d attempt %d (%s): %v\n", r.runID, r.attempt, r.workflow, r.err)
+			if scanCtx.Err() != nil {
+				// Left pending for the next scan.
+				continue
+			}
+			stats.Failures++
+			fmt.Fprintf(os.Stderr, "WARN: %s: jobs fetch failed for run %d attempt %d (%s): %v\n", repo, r.runID, r.attempt, r.workflow, r.err)
+			if err := st.SetJobFetchStatus(r.runID, r.attempt, store.JobFetchFailed, r.err.Error()); err != nil {
+				return abort(err)
+			}
 			continue
 		}
 		for i := range r.jobs {
 			r.jobs[i].Repo = repo
 		}
-		allJobs = append(allJobs, r.jobs...)
+		batch = append(batch, r.jobs...)
+		batchRuns = append(batchRuns, r)
+		if len(batchRuns) >= scanBatchRuns {
+			if err := flush(); err != nil {
+				return abort(err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return stats, err
+	}
+
+	if sigCtx.Err() != nil {
+		left, err := st.ListPendingJobFetches(repo)
+		if err != nil {
+			return stats, err
+		}
+		stats.Pending = len(left)
+		return stats, fmt.Errorf("scan interrupted: %w", sigCtx.Err())
+	}
+	if len(listed) > 0 {
+		if err := st.UpsertCursor(store.SyncCursor{
+			Repo:          repo,
+			LastRunID:     latest.ID,
+			LastCreatedAt: latest.CreatedAt,
+			LastSyncAt:    time.Now().UTC(),
+			TotalRuns:     len(listed),
+			TotalJobs:     stats.Jobs,
+		}); err != nil {
+			return stats, err
+		}
+	}
+	if opts.Storage {
+		if err := scanStorage(sigCtx, client, st, repo, &stats); err != nil {
+			return stats, err
+		}
 	}
+	return stats, nil
+}
 
-	newJobs, updatedJobs, err := st.UpsertJobs(allJobs)
+// scanStorage stores the artifacts and caches of repo. API failures, such
+// as a token without access to caches, are kept in stats.StorageErr and
+// reported without failing the scan; store errors are returned.
+func scanStorage(ctx context.Context, client *gh.Client, st *store.Store, repo string, stats *scanStats) error {
+	owner, repoName, err := splitRepo(repo)
 	if err != nil {
 		return err
 	}
-
-	latest := runList[0]
-	cursor := store.SyncCursor{
-		Repo:          repo,
-		LastRunID:     latest.ID,
-		LastCreatedAt: latest.CreatedAt,
-		LastSyncAt:    time.Now().UTC(),
-		TotalRuns:     len(runList),
-		TotalJobs:     len(allJobs),
+	listedAt := time.Now()
+	artifacts, calls, err := client.ListArtifacts(ctx, owner, repoName)
+	stats.StorageCalls += calls
+	if err != nil {
+		stats.StorageErr = fmt.Errorf("artifacts: %w", err)
+		return nil
+	}
+	for i := range artifacts {
+		artifacts[i].Repo = repo
+	}
+	if err := st.UpsertArtifacts(artifacts); err != nil {
+		return err
 	}
-	if err := st.UpsertCursor(cursor); err != nil {
+	if err := st.MarkRemovedArtifacts(repo, listedAt); err != nil {
 		return err
 	}
+	stats.Artifacts = len(artifacts)
 
-	fmt.Printf("CICost Scan: %s\n", repo)
-	fmt.Printf("  Period     : %s ~ %s\n", start.Format("2006-01-02"), time.Now().UTC().Format("2006-01-02"))
-	fmt.Printf("  Runs found : %d (%d new, %d updated)\n", len(runList), newRuns, updatedRuns)
-	fmt.Printf("  Jobs found : %d (%d new, %d updated)\n", len(allJobs), newJobs, updatedJobs)
-	fmt.Printf("  API calls  : %d (runs=%d jobs=%d)\n", runCalls+jobCalls, runCalls, jobCalls)
-	if failures > 0 {
-		fmt.Printf("  Partial    : %d runs failed to fetch jobs\n", failures)
+	caches, calls, err := client.ListCaches(ctx, owner, repoName)
+	stats.StorageCalls += calls
+	if err != nil {
+		stats.StorageErr = fmt.Errorf("caches: %w", err)
+		return nil
 	}
-	fmt.Printf("  DB path    : %s\n", dbPath)
+	for i := range caches {
+		caches[i].Repo = repo
+	}
+	if err := st.UpsertCaches(caches); err != nil {
+		return err
+	}
+	stats.Caches = len(caches)
 	return nil
 }
+
+// recordScanOutcome stores how the latest scan of a repository ended.
+func recordScanOutcome(st *store.Store, stats scanStats, scanErr error) error {
+	o := store.ScanOutcome{
+		Repo:   stats.Repo,
+		Status: store.ScanOK,
+		Runs:   stats.Runs,
+		Jobs:   stats.Jobs,
+	}
+	switch {
+	case errors.Is(scanErr, context.Canceled):
+		o.Status = store.ScanInterrupted
+	case scanErr != nil:
+		o.Status = store.ScanFailed
+		o.Error = scanErr.Error()
+	case stats.Failures > 0:
+		o.Error = fmt.Sprintf("%d runs failed to fetch jobs", stats.Failures)
+	}
+	return st.RecordScanOutcome(o)
+}
+
+func printClientStats(client *gh.Client) {
+	if cs := client.CacheStats.Summary(); cs.Hits+cs.Misses > 0 {
+		fmt.Printf("  Cache hits : %d/%d (%.0f%% not modified)\n", cs.Hits, cs.Hits+cs.Misses, cs.HitRatio()*100)
+	}
+	if th := client.Throttle.Summary(); th.Waited > 0 || th.Retries > 0 {
+		fmt.Printf("  Throttled  : %s waiting (%d retries, %d rate-limited)\n", th.Waited.Round(100*time.Millisecond), th.Retries, th.RateLimited)
+	}
+}
+
+func clampWorkers(n, def int) int {
+	if n <= 0 {
+		return def
+	}
+	if n > 8 {
+		return 8
+	}
+	return n
+}
+
+// scanBatchRuns is how many runs' jobs are fetched between store commits.
+const scanBatchRuns = 25
+
+func runKey(id int64, attempt int) string {
+	return strconv.FormatInt(id, 10) + "#" + strconv.Itoa(attempt)
+}
+
+// httpCacheMaxAgeDays is how long a cached response is kept without being
+// stored again; older ones are dropped when a scan starts.
+const httpCacheMaxAgeDays = 30
+
+// storeCache keeps GitHub responses in the local store so repeat scans can
+// send conditional requests.
+type storeCache struct {
+	st *store.Store
+}
+
+func (c storeCache) GetResponse(url string) (gh.CacheEntry, bool, error) {
+	e, ok, err := c.st.GetHTTPCache(url)
+	if err != nil || !ok {
+		return gh.CacheEntry{}, ok, err
+	}
+	return gh.CacheEntry{ETag: e.ETag, LastModified: e.LastModified, Link: e.Link, Body: e.Body}, true, nil
+}
+
+func (c storeCache) PutResponse(url string, e gh.CacheEntry) error {
+	return c.st.PutHTTPCache(store.HTTPCacheEntry{URL: url, ETag: e.ETag, LastModified: e.LastModified, Link: e.Link, Body: e.Body})
+}
@@ -0,0 +1,176 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"sync"
+
+	gh "github.com/peter941221/CICost/internal/github"
+	"github.com/peter941221/CICost/internal/store"
+)
+
+// repoFilter selects which discovered repositories an org scan covers.
+// Include and Exclude are globs matched against both the repo name and
+// owner/name; Topics keeps repos tagged with any of the listed topics.
+type repoFilter struct {
+	Include         []string
+	Exclude         []string
+	Topics          []string
+	IncludeArchived bool
+	IncludeForks    bool
+}
+
+func (f repoFilter) match(r gh.Repository) bool {
+	if r.Archived && !f.IncludeArchived {
+		return false
+	}
+	if r.Fork && !f.IncludeForks {
+		return false
+	}
+	if len(f.Include) > 0 && !matchRepoGlob(f.Include, r) {
+		return false
+	}
+	if matchRepoGlob(f.Exclude, r) {
+		return false
+	}
+	if len(f.Topics) == 0 {
+		return true
+	}
+	for _, want := range f.Topics {
+		for _, have := range r.Topics {
+			if strings.EqualFold(want, have) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func matchRepoGlob(patterns []string, r gh.Repository) bool {
+	name := strings.ToLower(r.Name)
+	full := strings.ToLower(r.FullName)
+	for _, p := range patterns {
+		p = strings.ToLower(p)
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+		if ok, _ := path.Match(p, full); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// runOrgScan discovers the repositories of org, scans the ones matching
+// filter with repoWorkers repositories in flight, and records each outcome
+// so org-report can run without a repo list. Failed repositories do not
+// stop the others.
+func runOrgScan(sigCtx context.Context, client *gh.Client, st *store.Store, org string, filter repoFilter, repoWorkers int, opts scanOptions, dbPath string) error {
+	all, publicOnly, listCalls, err := client.ListRepositories(sigCtx, org)
+	if err != nil {
+		return fmt.Errorf("list repositories of %s: %w", org, err)
+	}
+	if publicOnly {
+		fmt.Printf("WARN: %s is a user other than the token's owner; only its public repositories were found\n", org)
+	}
+	repos := make([]string, 0, len(all))
+	for _, r := range all {
+		if filter.match(r) {
+			repos = append(repos, r.FullName)
+		}
+	}
+	sort.Strings(repos)
+	if len(repos) == 0 {
+		fmt.Printf("No repositories of %s match the scan filters (%d found)\n", org, len(all))
+		return nil
+	}
+	fmt.Printf("CICost Org Scan: %s (%d of %d repositories)\n", org, len(repos), len(all))
+
+	type repoResult struct {
+		stats scanStats
+		err   error
+	}
+	in := make(chan string)
+	out := make(chan repoResult)
+	if repoWorkers > len(repos) {
+		repoWorkers = len(repos)
+	}
+	var wg sync.WaitGroup
+	for i := 0; i < repoWorkers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for repo := range in {
+				stats, err := scanRepo(sigCtx, client, st, repo, opts)
+				stats.Repo = repo
+				out <- repoResult{stats: stats, err: err}
+			}
+		}()
+	}
+	go func() {
+		defer close(in)
+		for _, r := range repos {
+			select {
+			case in <- r:
+			case <-sigCtx.Done():
+				return
+			}
+		}
+	}()
+	go func() {
+		wg.Wait()
+		close(out)
+	}()
+
+	results := make([]repoResult, 0, len(repos))
+	var recordErr error
+	for r := range out {
+		if err := recordScanOutcome(st, r.stats, r.err); err != nil && recordErr == nil {
+			recordErr = err
+		}
+		results = append(results, r)
+	}
+	if recordErr != nil {
+		return recordErr
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].stats.Repo < results[j].stats.Repo })
+
+	runs, jobs, calls, failed, interrupted := 0, 0, listCalls, 0, 0
+	for _, r := range results {
+		s := r.stats
+		runs += s.Runs
+		jobs += s.Jobs
+		calls += s.RunCalls + s.JobCalls + s.StorageCalls
+		switch {
+		case r.err != nil && sigCtx.Err() != nil:
+			interrupted++
+			fmt.Printf("  %-40s interrupted\n", s.Repo)
+		case r.err != nil:
+			failed++
+			fmt.Printf("  %-40s failed: %v\n", s.Repo, r.err)
+		case s.StorageErr != nil:
+			fmt.Printf("  %-40s runs=%d jobs=%d (storage not fetched: %v)\n", s.Repo, s.Runs, s.Jobs, s.StorageErr)
+		case s.Failures > 0:
+			fmt.Printf("  %-40s runs=%d jobs=%d (%d runs failed to fetch jobs)\n", s.Repo, s.Runs, s.Jobs, s.Failures)
+		default:
+			fmt.Printf("  %-40s runs=%d jobs=%d\n", s.Repo, s.Runs, s.Jobs)
+		}
+	}
+	fmt.Printf("  Repos      : %d scanned, %d failed\n", len(results)-failed-interrupted, failed)
+	fmt.Printf("  Runs found : %d\n", runs)
+	fmt.Printf("  Jobs found : %d\n", jobs)
+	fmt.Printf("  API calls  : %d (repos=%d)\n", calls, listCalls)
+	printClientStats(client)
+	fmt.Printf("  DB path    : %s\n", dbPath)
+	if sigCtx.Err() != nil {
+		fmt.Printf("  Interrupted: %d of %d repositories finished; rerun `cicost scan --org %s` to resume\n", len(results)-interrupted, len(repos), org)
+		return fmt.Errorf("scan interrupted: %w", sigCtx.Err())
+	}
+	if failed == len(results) {
+		return fmt.Errorf("scan failed for all %d repositories of %s", failed, org)
+	}
+	return nil
+}
@@ -66,7 +66,12 @@ func runSuggest(args []string) error {
 	if err != nil {
 		return err
 	}
-	waste := analytics.CalculateWaste(runs, jobs, pcfg, cost.TotalCostUSD)
+	storage, artifacts, caches, err := storageCost(st, repo, start, end, pcfg)
+	if err != nil {
+		return err
+	}
+	cost.Storage = storage
+	waste := analytics.CalculateWaste(runs, jobs, pcfg, analytics.TotalSpendUSD(cost))
 	hotspots := analytics.CalculateHotspots(runs, jobs, pcfg, analytics.HotspotOptions{
 		GroupBy: "workflow",
 		TopN:    5,
@@ -74,12 +79,17 @@ func runSuggest(args []string) error {
 	})
 
 	suggestions := suggest.Generate(suggest.Inputs{
-		Repo:     repo,
-		Runs:     runs,
-		Jobs:     jobs,
-		Cost:     cost,
-		Waste:    waste,
-		Hotspots: hotspots,
+		Repo:         repo,
+		Start:        start,
+		End:          end,
+		Runs:         runs,
+		Jobs:         jobs,
+		Cost:         cost,
+		Waste:        waste,
+		Hotspots:     hotspots,
+		Artifacts:    artifacts,
+		Caches:       caches,
+		StorageRates: pcfg.Storage,
 	})
 	if len(suggestions) == 0 {
 		fmt.Println("No data-backed suggestions found.")
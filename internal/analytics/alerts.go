@@ -0,0 +1,102 @@
+package analytics
+
+import (
+	"sort"
+	"time"
+)
+
+// Alert actions decided by DecideAlert.
+const (
+	AlertNone      = ""
+	AlertFire      = "alert"
+	AlertRenotify  = "renotify"
+	AlertRecovered = "recovered"
+)
+
+// Alert state statuses.
+const (
+	AlertStateAlerted   = "alerted"
+	AlertStateRecovered = "recovered"
+)
+
+// AlertState is what was last sent for one threshold level of a budget in
+// one period. Level 0 stands for a warning from the projection alone.
+type AlertState struct {
+	Level      float64
+	Status     string
+	NotifiedAt time.Time
+}
+
+// AlertDecision is what to send for a budget check and the state changes
+// to record once it is sent.
+type AlertDecision struct {
+	Action string
+	// Level is the level alerted, or for AlertRecovered the level spend is
+	// back under.
+	Level float64
+	// Alerted and Recovered are the levels whose state becomes alerted or
+	// recovered.
+	Alerted   []float64
+	Recovered []float64
+}
+
+// AlertLevel is the level c alerts at: the highest threshold reached, 0 for
+// a warning from the projection alone, or -1 when c is ok.
+func AlertLevel(c BudgetCheck) float64 {
+	switch {
+	case c.Level > 0:
+		return c.Level
+	case c.Status != BudgetOK:
+		return 0
+	default:
+		return -1
+	}
+}
+
+// DecideAlert sends each level of a budget once per period: a level is
+// alerted when first reached, again after renotify has passed (never when
+// renotify is 0), and recovered once spend is back under it. states are the
+// budget's states in c's period.
+func DecideAlert(c BudgetCheck, states []AlertState, now time.Time, renotify time.Duration) AlertDecision {
+	level := AlertLevel(c)
+	byLevel := make(map[float64]AlertState, len(states))
+	for _, s := range states {
+		byLevel[s.Level] = s
+	}
+
+	var d AlertDecision
+	for _, s := range states {
+		if s.Status == AlertStateAlerted && s.Level > level {
+			d.Recovered = append(d.Recovered, s.Level)
+		}
+	}
+	sort.Float64s(d.Recovered)
+
+	if level >= 0 {
+		s, ok := byLevel[level]
+		switch {
+		case !ok || s.Status != AlertStateAlerted:
+			d.Action = AlertFire
+			d.Level = level
+			// Levels passed on the way up count as alerted too, so falling
+			// back to one of them reads as a recovery, not a new alert.
+			for _, t := range c.Thresholds {
+				if t < level && byLevel[t].Status != AlertStateAlerted {
+					d.Alerted = append(d.Alerted, t)
+				}
+			}
+			d.Alerted = append(d.Alerted, level)
+			return d
+		case renotify > 0 && now.Sub(s.NotifiedAt) >= renotify:
+			d.Action = AlertRenotify
+			d.Level = level
+			d.Alerted = []float64{level}
+			return d
+		}
+	}
+	if len(d.Recovered) > 0 {
+		d.Action = AlertRecovered
+		d.Level = d.Recovered[0]
+	}
+	return d
+}
@@ -0,0 +1,54 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDecideAlertSequence(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	thresholds := DefaultBudgetThresholds
+	check := func(level float64, status BudgetStatus) BudgetCheck {
+		return BudgetCheck{Level: level, Thresholds: thresholds, BudgetResult: BudgetResult{Status: status}}
+	}
+	var states []AlertState
+	apply := func(d AlertDecision, at time.Time) {
+		set := func(level float64, status string) {
+			for i := range states {
+				if states[i].Level == level {
+					states[i] = AlertState{Level: level, Status: status, NotifiedAt: at}
+					return
+				}
+			}
+			states = append(states, AlertState{Level: level, Status: status, NotifiedAt: at})
+		}
+		for _, l := range d.Alerted {
+			set(l, AlertStateAlerted)
+		}
+		for _, l := range d.Recovered {
+			set(l, AlertStateRecovered)
+		}
+	}
+	step := func(c BudgetCheck, at time.Time, renotify time.Duration, action string, level float64) {
+		t.Helper()
+		d := DecideAlert(c, states, at, renotify)
+		if d.Action != action || (action != AlertNone && d.Level != level) {
+			t.Fatalf("expected %q at %.0f, got %+v (states %+v)", action, level, d, states)
+		}
+		apply(d, at)
+	}
+
+	step(check(0, BudgetWarning), now, 0, AlertFire, 0)
+	step(check(50, BudgetWarning), now, 0, AlertFire, 50)
+	step(check(50, BudgetWarning), now.Add(time.Hour), 0, AlertNone, 0)
+	step(check(50, BudgetWarning), now.Add(2*time.Hour), 6*time.Hour, AlertNone, 0)
+	step(check(50, BudgetWarning), now.Add(7*time.Hour), 6*time.Hour, AlertRenotify, 50)
+	// Jumping to 120% alerts once and counts 80% and 100% as passed.
+	step(check(120, BudgetExceeded), now.Add(8*time.Hour), 0, AlertFire, 120)
+	step(check(80, BudgetWarning), now.Add(9*time.Hour), 0, AlertRecovered, 100)
+	step(check(80, BudgetWarning), now.Add(10*time.Hour), 0, AlertNone, 0)
+	step(check(0, BudgetOK), now.Add(11*time.Hour), 0, AlertRecovered, 0)
+	step(check(0, BudgetOK), now.Add(12*time.Hour), 0, AlertNone, 0)
+	// Crossing again after recovering alerts again.
+	step(check(50, BudgetWarning), now.Add(13*time.Hour), 0, AlertFire, 50)
+}
@@ -0,0 +1,258 @@
+package analytics
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+const (
+	// DefaultAnomalyThreshold is the robust z-score above which a day is
+	// flagged, the usual cut-off for median/MAD outliers.
+	DefaultAnomalyThreshold = 3.5
+	// minAnomalyBaselineDays is the shortest baseline worth a median.
+	minAnomalyBaselineDays = 7
+	// minAnomalyActiveDays is how many baseline days a group must have spent
+	// on before its days are scored, so groups first seen in the check
+	// window or run only now and then are not flagged for running at all.
+	minAnomalyActiveDays = 3
+	// minAnomalyScaleUSD is the least scale of any baseline.
+	minAnomalyScaleUSD = 0.01
+)
+
+// AnomalyOptions configures DetectAnomalies.
+type AnomalyOptions struct {
+	// GroupBy is repo, workflow, job or runner.
+	GroupBy string
+	// Threshold is the robust z-score a day must reach; 0 means
+	// DefaultAnomalyThreshold.
+	Threshold float64
+	// MinUSD ignores days cheaper than this however unusual they are.
+	MinUSD float64
+	// TopRuns is how many runs to attribute each anomaly to; 0 means 3.
+	TopRuns int
+}
+
+// Anomaly is one day on which a group cost far more than its baseline.
+type Anomaly struct {
+	Repo        string       `json:"repo"`
+	GroupType   string       `json:"group_type"`
+	Name        string       `json:"name"`
+	Date        time.Time    `json:"date"`
+	CostUSD     float64      `json:"cost_usd"`
+	BaselineUSD float64      `json:"baseline_usd"`
+	Ratio       float64      `json:"ratio,omitempty"`
+	Score       float64      `json:"score"`
+	TopRuns     []AnomalyRun `json:"top_runs"`
+}
+
+// AnomalyRun is a run's share of an anomaly's cost.
+type AnomalyRun struct {
+	RunID    int64   `json:"run_id"`
+	Workflow string  `json:"workflow"`
+	Branch   string  `json:"branch"`
+	Event    string  `json:"event"`
+	CostUSD  float64 `json:"cost_usd"`
+	URL      string  `json:"url"`
+}
+
+// DetectAnomalies builds a daily cost series per repo and group from the
+// jobs of repos (Org is ignored) and flags each day in [checkFrom, end)
+// whose cost is an outlier against the days in [start, checkFrom): its
+// distance above their median, in units of 1.4826 × MAD, must reach the
+// threshold. Days are UTC and jobs count on the day they started.
+func DetectAnomalies(repos []RollupRepo, cfg pricing.Config, start, checkFrom, end time.Time, opts AnomalyOptions) ([]Anomaly, error) {
+	groupBy := strings.ToLower(strings.TrimSpace(opts.GroupBy))
+	switch groupBy {
+	case "":
+		groupBy = "workflow"
+	case "repo", "workflow", "job", "runner":
+	default:
+		return nil, fmt.Errorf("invalid group-by %q (use repo|workflow|job|runner)", opts.GroupBy)
+	}
+	if opts.Threshold <= 0 {
+		opts.Threshold = DefaultAnomalyThreshold
+	}
+	if opts.TopRuns <= 0 {
+		opts.TopRuns = 3
+	}
+	first := utcDay(start)
+	check := int(utcDay(checkFrom).Sub(first).Hours() / 24)
+	days := int(utcDay(end).Sub(first).Hours() / 24)
+	if check < minAnomalyBaselineDays || days <= check {
+		return nil, nil
+	}
+
+	type series struct {
+		name  string
+		daily []float64
+		// runs is cost per run on each check day.
+		runs []map[int64]float64
+	}
+	var out []Anomaly
+	for _, r := range repos {
+		names := latestWorkflowNames(r.Runs)
+		runByIDAttempt := make(map[string]model.WorkflowRun, len(r.Runs))
+		runByID := make(map[int64]model.WorkflowRun, len(r.Runs))
+		for _, run := range r.Runs {
+			runByIDAttempt[runAttemptKey(run.ID, run.RunAttempt)] = run
+			runByID[run.ID] = run
+		}
+		groups := map[string]*series{}
+		var keys []string
+		for _, job := range r.Jobs {
+			if strings.TrimSpace(job.Status) != "completed" || job.StartedAt.IsZero() {
+				continue
+			}
+			i := int(utcDay(job.StartedAt).Sub(first).Hours() / 24)
+			if i < 0 || i >= days {
+				continue
+			}
+			quote, err := quoteJob(job, cfg)
+			if err != nil {
+				return nil, err
+			}
+			run := runByIDAttempt[runAttemptKey(job.RunID, job.RunAttempt)]
+			key, name := r.Repo, r.Repo
+			if groupBy != "repo" {
+				key, name = hotspotGroup(groupBy, run, job, names)
+			}
+			s := groups[key]
+			if s == nil {
+				s = &series{name: name, daily: make([]float64, days), runs: make([]map[int64]float64, days-check)}
+				groups[key] = s
+				keys = append(keys, key)
+			}
+			s.daily[i] += quote.CostUSD
+			if i >= check {
+				if s.runs[i-check] == nil {
+					s.runs[i-check] = map[int64]float64{}
+				}
+				s.runs[i-check][job.RunID] += quote.CostUSD
+			}
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := groups[key]
+			if s.name == "" {
+				s.name = "unknown"
+			}
+			if activeDays(s.daily[:check]) < minAnomalyActiveDays {
+				continue
+			}
+			median, scale := robustScale(s.daily[:check])
+			for i := check; i < days; i++ {
+				cost := s.daily[i]
+				if cost < opts.MinUSD || cost <= median {
+					continue
+				}
+				score := (cost - median) / scale
+				if score < opts.Threshold {
+					continue
+				}
+				a := Anomaly{
+					Repo:        r.Repo,
+					GroupType:   groupBy,
+					Name:        s.name,
+					Date:        first.AddDate(0, 0, i),
+					CostUSD:     round2(cost),
+					BaselineUSD: round2(median),
+					Score:       round2(score),
+				}
+				if median > 0 {
+					a.Ratio = round2(cost / median)
+				}
+				a.TopRuns = topAnomalyRuns(r.Repo, s.runs[i-check], runByID, names, opts.TopRuns)
+				out = append(out, a)
+			}
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if !out[i].Date.Equal(out[j].Date) {
+			return out[i].Date.After(out[j].Date)
+		}
+		return out[i].Score > out[j].Score
+	})
+	return out, nil
+}
+
+// robustScale returns the median of v and 1.4826 × its median absolute
+// deviation, which estimates the standard deviation without letting earlier
+// spikes inflate it.
+//
+// When most days are alike the MAD is 0, as for a weekly job whose median
+// day costs nothing. The scale then falls back to half the largest
+// deviation in v, so only a day well beyond the baseline's own peaks
+// scores, or to a tenth of the mean for a series with no deviation at all.
+func robustScale(v []float64) (median, scale float64) {
+	median = medianOf(v)
+	dev := make([]float64, len(v))
+	maxDev, sum := 0.0, 0.0
+	for i, x := range v {
+		dev[i] = math.Abs(x - median)
+		maxDev = math.Max(maxDev, dev[i])
+		sum += x
+	}
+	scale = 1.4826 * medianOf(dev)
+	if scale == 0 {
+		scale = maxDev / 2
+		if len(v) > 0 {
+			scale = math.Max(scale, sum/float64(len(v))/10)
+		}
+	}
+	return median, math.Max(scale, minAnomalyScaleUSD)
+}
+
+// activeDays counts the days of v with any cost.
+func activeDays(v []float64) int {
+	n := 0
+	for _, x := range v {
+		if x > 0 {
+			n++
+		}
+	}
+	return n
+}
+
+func medianOf(v []float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	s := append([]float64(nil), v...)
+	sort.Float64s(s)
+	mid := len(s) / 2
+	if len(s)%2 == 0 {
+		return (s[mid-1] + s[mid]) / 2
+	}
+	return s[mid]
+}
+
+func topAnomalyRuns(repo string, costs map[int64]float64, runByID map[int64]model.WorkflowRun, names workflowNames, n int) []AnomalyRun {
+	out := make([]AnomalyRun, 0, len(costs))
+	for id, cost := range costs {
+		run := runByID[id]
+		out = append(out, AnomalyRun{
+			RunID:    id,
+			Workflow: names.name(run),
+			Branch:   run.HeadBranch,
+			Event:    run.Event,
+			CostUSD:  round2(cost),
+			URL:      fmt.Sprintf("https://github.com/%s/actions/runs/%d", repo, id),
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CostUSD != out[j].CostUSD {
+			return out[i].CostUSD > out[j].CostUSD
+		}
+		return out[i].RunID < out[j].RunID
+	})
+	if len(out) > n {
+		out = out[:n]
+	}
+	return out
+}
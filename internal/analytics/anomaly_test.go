@@ -0,0 +1,97 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+func TestDetectAnomalies(t *testing.T) {
+	cfg := pricing.Config{PerMinuteUSD: 0.01}
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	checkFrom := start.AddDate(0, 0, 14)
+	end := checkFrom.AddDate(0, 0, 1)
+
+	repo := RollupRepo{Repo: "owner/repo"}
+	add := func(id int64, wf int64, name string, day int, minutes int) {
+		at := start.AddDate(0, 0, day).Add(10 * time.Hour)
+		repo.Runs = append(repo.Runs, model.WorkflowRun{ID: id, WorkflowID: wf, WorkflowName: name, HeadBranch: "main", Event: "push", RunAttempt: 1, CreatedAt: at})
+		repo.Jobs = append(repo.Jobs, model.Job{ID: id * 10, RunID: id, RunAttempt: 1, Name: "build", Status: "completed", RunnerOS: "Linux", DurationSec: minutes * 60, StartedAt: at})
+	}
+	id := int64(1)
+	for day := 0; day < 14; day++ {
+		// ci costs $2-$2.20 a day, release $1.
+		add(id, 1, "ci", day, 200+day%3*10)
+		add(id+1, 2, "release", day, 100)
+		id += 2
+	}
+	// Check day: release runs five times, ci is normal.
+	add(id, 1, "ci", 14, 210)
+	for i := int64(1); i <= 5; i++ {
+		add(id+i, 2, "release", 14, 100*int(i))
+	}
+
+	got, err := DetectAnomalies([]RollupRepo{repo}, cfg, start, checkFrom, end, AnomalyOptions{GroupBy: "workflow", MinUSD: 1, TopRuns: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 {
+		t.Fatalf("expected one anomaly, got %+v", got)
+	}
+	a := got[0]
+	if a.Name != "release" || a.CostUSD != 15 || a.BaselineUSD != 1 || a.Ratio != 15 || !a.Date.Equal(checkFrom) {
+		t.Fatalf("unexpected anomaly %+v", a)
+	}
+	if len(a.TopRuns) != 2 || a.TopRuns[0].CostUSD != 5 || a.TopRuns[1].CostUSD != 4 || a.TopRuns[0].Workflow != "release" {
+		t.Fatalf("unexpected top runs %+v", a.TopRuns)
+	}
+
+	if got, err = DetectAnomalies([]RollupRepo{repo}, cfg, start, checkFrom, end, AnomalyOptions{GroupBy: "workflow", MinUSD: 20}); err != nil || len(got) != 0 {
+		t.Fatalf("expected min-usd to suppress the anomaly, got %+v %v", got, err)
+	}
+	if _, err := DetectAnomalies(nil, cfg, start, checkFrom, end, AnomalyOptions{GroupBy: "branch"}); err == nil {
+		t.Fatal("expected invalid group-by error")
+	}
+}
+
+func TestDetectAnomaliesSparseSeries(t *testing.T) {
+	cfg := pricing.Config{PerMinuteUSD: 0.01}
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	checkFrom := start.AddDate(0, 0, 28)
+	end := checkFrom.AddDate(0, 0, 1)
+
+	build := func(releaseMinutes int) RollupRepo {
+		repo := RollupRepo{Repo: "owner/repo"}
+		add := func(id int64, wf int64, name string, day int, minutes int) {
+			at := start.AddDate(0, 0, day).Add(10 * time.Hour)
+			repo.Runs = append(repo.Runs, model.WorkflowRun{ID: id, WorkflowID: wf, WorkflowName: name, RunAttempt: 1, CreatedAt: at})
+			repo.Jobs = append(repo.Jobs, model.Job{ID: id * 10, RunID: id, RunAttempt: 1, Name: "build", Status: "completed", RunnerOS: "Linux", DurationSec: minutes * 60, StartedAt: at})
+		}
+		// A $5 release every week, due again on the check day.
+		for day := 0; day < 28; day += 7 {
+			add(int64(day+1), 1, "release", day, 500)
+		}
+		add(100, 1, "release", 28, releaseMinutes)
+		// A workflow first seen on the check day.
+		add(101, 2, "nightly", 28, 3000)
+		return repo
+	}
+
+	got, err := DetectAnomalies([]RollupRepo{build(500)}, cfg, start, checkFrom, end, AnomalyOptions{GroupBy: "workflow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("expected a usual weekly release and a new workflow not to be flagged, got %+v", got)
+	}
+
+	got, err = DetectAnomalies([]RollupRepo{build(2000)}, cfg, start, checkFrom, end, AnomalyOptions{GroupBy: "workflow"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "release" || got[0].CostUSD != 20 || got[0].BaselineUSD != 0 || got[0].Score != 8 {
+		t.Fatalf("expected a $20 release against $5 weeks to be flagged, got %+v", got)
+	}
+}
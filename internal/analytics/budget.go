@@ -1,6 +1,10 @@
 package analytics
 
-import "time"
+import (
+	"time"
+
+	"github.com/peter941221/CICost/internal/pricing"
+)
 
 type BudgetStatus string
 
@@ -19,10 +23,18 @@ type BudgetResult struct {
 	PeriodStart    time.Time    `json:"period_start"`
 	PeriodEnd      time.Time    `json:"period_end"`
 	CheckType      string       `json:"check_type"`
+	// ForecastMethod, ProjectedLowerUSD and ProjectedUpperUSD are set when
+	// ProjectedUSD comes from a Forecast rather than a linear projection.
+	ForecastMethod    string  `json:"forecast_method,omitempty"`
+	ProjectedLowerUSD float64 `json:"projected_lower_usd,omitempty"`
+	ProjectedUpperUSD float64 `json:"projected_upper_usd,omitempty"`
 }
 
-func EvaluateBudget(now time.Time, actual, threshold float64, checkType string) BudgetResult {
-	start, end := periodBounds(now, checkType)
+// EvaluateBudget compares actual spend with threshold for the weekly or
+// monthly period containing now; monthly periods follow the billing cycle
+// that resets on cycleDay.
+func EvaluateBudget(now time.Time, actual, threshold float64, checkType string, cycleDay int) BudgetResult {
+	start, end := periodBounds(now, checkType, cycleDay)
 	elapsedDays := now.Sub(start).Hours() / 24
 	totalDays := end.Sub(start).Hours() / 24
 	if elapsedDays < 1 {
@@ -53,7 +65,29 @@ func EvaluateBudget(now time.Time, actual, threshold float64, checkType string)
 	return res
 }
 
-func periodBounds(now time.Time, checkType string) (time.Time, time.Time) {
+// WithForecast projects r from f instead of extrapolating the spend so far,
+// and warns when the point forecast crosses the threshold. Exceeded still
+// depends only on actual spend. A forecast from less than a week of history
+// leaves r as it is.
+func (r BudgetResult) WithForecast(f Forecast) BudgetResult {
+	if f.HistoryDays < minForecastHistoryDays {
+		return r
+	}
+	r.ProjectedUSD = round2(f.ForecastUSD)
+	r.ProjectedLowerUSD = round2(f.LowerUSD)
+	r.ProjectedUpperUSD = round2(f.UpperUSD)
+	r.ForecastMethod = f.Method
+	if r.Status == BudgetExceeded || r.ThresholdUSD <= 0 {
+		return r
+	}
+	r.Status = BudgetOK
+	if f.ForecastUSD > r.ThresholdUSD {
+		r.Status = BudgetWarning
+	}
+	return r
+}
+
+func periodBounds(now time.Time, checkType string, cycleDay int) (time.Time, time.Time) {
 	n := now.UTC()
 	if checkType == "weekly" {
 		wd := int(n.Weekday())
@@ -63,11 +97,9 @@ func periodBounds(now time.Time, checkType string) (time.Time, time.Time) {
 		start := time.Date(n.Year(), n.Month(), n.Day()-(wd-1), 0, 0, 0, 0, time.UTC)
 		return start, start.AddDate(0, 0, 7)
 	}
-	start := time.Date(n.Year(), n.Month(), 1, 0, 0, 0, 0, time.UTC)
-	end := start.AddDate(0, 1, 0)
-	return start, end
+	return pricing.CycleStart(n, cycleDay), pricing.CycleEnd(n, cycleDay)
 }
 
-func PeriodBounds(now time.Time, checkType string) (time.Time, time.Time) {
-	return periodBounds(now, checkType)
+func PeriodBounds(now time.Time, checkType string, cycleDay int) (time.Time, time.Time) {
+	return periodBounds(now, checkType, cycleDay)
 }
@@ -0,0 +1,165 @@
+package analytics
+
+import (
+	"path"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/glob"
+	"github.com/peter941221/CICost/internal/model"
+)
+
+// DefaultBudgetThresholds are the alert levels, in percent of the budget,
+// of a budget that does not list its own.
+var DefaultBudgetThresholds = []float64{50, 80, 100, 120}
+
+// BudgetScope narrows a budget to part of the account. Empty fields match
+// everything; Repo, Workflow and Branch are globs in which * and ** also
+// match /, so Branch "*" covers "feature/x" and "release/**" every release
+// branch, and RunnerOS compares case-insensitively.
+type BudgetScope struct {
+	Repo     string `json:"repo,omitempty"`
+	Org      string `json:"org,omitempty"`
+	Workflow string `json:"workflow,omitempty"`
+	RunnerOS string `json:"runner_os,omitempty"`
+	Branch   string `json:"branch,omitempty"`
+}
+
+// Budget is one named budget with escalating alert thresholds.
+type Budget struct {
+	Name       string
+	Period     string
+	AmountUSD  float64
+	Thresholds []float64
+	Scope      BudgetScope
+}
+
+// BudgetCheck is the evaluation of one Budget. Level is the highest
+// threshold actual spend has reached and ProjectedLevel the highest the
+// projection reaches, both 0 when none is.
+type BudgetCheck struct {
+	Name           string      `json:"name"`
+	Scope          BudgetScope `json:"scope"`
+	Repos          []string    `json:"repos"`
+	Thresholds     []float64   `json:"thresholds"`
+	Level          float64     `json:"level"`
+	ProjectedLevel float64     `json:"projected_level"`
+	PreviousLevel  float64     `json:"previous_level"`
+	BudgetResult
+}
+
+// MatchesRepo reports whether repo (owner/name) is in scope.
+func (s BudgetScope) MatchesRepo(repo string) bool {
+	owner, _, _ := strings.Cut(repo, "/")
+	if s.Org != "" && !strings.EqualFold(s.Org, owner) {
+		return false
+	}
+	return globMatch(s.Repo, repo)
+}
+
+// Matches reports whether job, of run, is in scope. The workflow glob is
+// tried against the workflow name, its path and the path's file name.
+func (s BudgetScope) Matches(run model.WorkflowRun, job model.Job) bool {
+	repo := job.Repo
+	if repo == "" {
+		repo = run.Repo
+	}
+	if !s.MatchesRepo(repo) {
+		return false
+	}
+	if s.RunnerOS != "" && !strings.EqualFold(s.RunnerOS, job.RunnerOS) {
+		return false
+	}
+	if !globMatch(s.Branch, run.HeadBranch) {
+		return false
+	}
+	if s.Workflow != "" && !globMatch(s.Workflow, run.WorkflowName) &&
+		!globMatch(s.Workflow, run.WorkflowPath) && !globMatch(s.Workflow, path.Base(run.WorkflowPath)) {
+		return false
+	}
+	return true
+}
+
+// WholeRepos reports whether the scope selects whole repositories, so the
+// account's free tier applies to it as it does to a repo.
+func (s BudgetScope) WholeRepos() bool {
+	return s.Workflow == "" && s.RunnerOS == "" && s.Branch == ""
+}
+
+func (s BudgetScope) String() string {
+	var parts []string
+	for _, f := range []struct{ k, v string }{
+		{"org", s.Org}, {"repo", s.Repo}, {"workflow", s.Workflow}, {"runner_os", s.RunnerOS}, {"branch", s.Branch},
+	} {
+		if f.v != "" {
+			parts = append(parts, f.k+"="+f.v)
+		}
+	}
+	if len(parts) == 0 {
+		return "all"
+	}
+	return strings.Join(parts, " ")
+}
+
+// ScopedJobs returns the jobs of repos that scope matches.
+func ScopedJobs(repos []RollupRepo, scope BudgetScope) []model.Job {
+	var out []model.Job
+	for _, r := range repos {
+		if !scope.MatchesRepo(r.Repo) {
+			continue
+		}
+		runByIDAttempt := make(map[string]model.WorkflowRun, len(r.Runs))
+		for _, run := range r.Runs {
+			runByIDAttempt[runAttemptKey(run.ID, run.RunAttempt)] = run
+		}
+		for _, job := range r.Jobs {
+			if job.Repo == "" {
+				job.Repo = r.Repo
+			}
+			if scope.Matches(runByIDAttempt[runAttemptKey(job.RunID, job.RunAttempt)], job) {
+				out = append(out, job)
+			}
+		}
+	}
+	return out
+}
+
+// EvaluateScopedBudget evaluates b for the period containing now from its
+// actual net spend and, when f is not nil, a forecast of the period.
+// Reaching any threshold, or a projection above the budget, is a warning;
+// spend above the budget is exceeded.
+func EvaluateScopedBudget(now time.Time, b Budget, actual float64, f *Forecast, cycleDay int) BudgetCheck {
+	res := EvaluateBudget(now, actual, b.AmountUSD, b.Period, cycleDay)
+	if f != nil {
+		res = res.WithForecast(*f)
+	}
+	c := BudgetCheck{
+		Name:         b.Name,
+		Scope:        b.Scope,
+		Thresholds:   b.Thresholds,
+		BudgetResult: res,
+	}
+	if b.AmountUSD <= 0 {
+		return c
+	}
+	for _, t := range b.Thresholds {
+		if actual >= b.AmountUSD*t/100 {
+			c.Level = t
+		}
+		if res.ProjectedUSD >= b.AmountUSD*t/100 {
+			c.ProjectedLevel = t
+		}
+	}
+	if c.Status == BudgetOK && c.Level > 0 {
+		c.Status = BudgetWarning
+	}
+	return c
+}
+
+func globMatch(pattern, value string) bool {
+	if pattern == "" {
+		return true
+	}
+	ok, err := glob.Match(pattern, value)
+	return err == nil && ok
+}
@@ -0,0 +1,60 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+)
+
+func TestBudgetScopeMatches(t *testing.T) {
+	run := model.WorkflowRun{Repo: "acme/api", WorkflowName: "Release", WorkflowPath: ".github/workflows/release.yml", HeadBranch: "release/1.2"}
+	job := model.Job{Repo: "acme/api", RunnerOS: "macOS"}
+	cases := []struct {
+		scope BudgetScope
+		want  bool
+	}{
+		{BudgetScope{}, true},
+		{BudgetScope{Org: "ACME"}, true},
+		{BudgetScope{Org: "other"}, false},
+		{BudgetScope{Repo: "acme/*"}, true},
+		{BudgetScope{Repo: "acme/web"}, false},
+		{BudgetScope{Workflow: "release.yml"}, true},
+		{BudgetScope{Workflow: "Release"}, true},
+		{BudgetScope{Workflow: "ci*"}, false},
+		{BudgetScope{RunnerOS: "macos"}, true},
+		{BudgetScope{RunnerOS: "linux"}, false},
+		{BudgetScope{Branch: "release/*"}, true},
+		{BudgetScope{Branch: "main"}, false},
+		{BudgetScope{Branch: "*"}, true},
+		{BudgetScope{Branch: "release/**"}, true},
+		{BudgetScope{Branch: "feature/**"}, false},
+	}
+	for _, c := range cases {
+		if got := c.scope.Matches(run, job); got != c.want {
+			t.Errorf("%s: got %v, want %v", c.scope, got, c.want)
+		}
+	}
+
+	nested := model.WorkflowRun{Repo: "acme/api", WorkflowName: "CI", HeadBranch: "release/1.2/hotfix"}
+	for _, branch := range []string{"*", "release/**", "release/*"} {
+		if !(BudgetScope{Branch: branch}).Matches(nested, job) {
+			t.Errorf("branch %q should match %q", branch, nested.HeadBranch)
+		}
+	}
+}
+
+func TestEvaluateScopedBudgetLevels(t *testing.T) {
+	now := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	b := Budget{Name: "ci", Period: "monthly", AmountUSD: 100, Thresholds: DefaultBudgetThresholds}
+
+	if c := EvaluateScopedBudget(now, b, 40, &Forecast{HistoryDays: 28, ForecastUSD: 60}, 1); c.Status != BudgetOK || c.Level != 0 || c.ProjectedLevel != 50 {
+		t.Fatalf("expected ok below the first threshold, got %+v", c)
+	}
+	if c := EvaluateScopedBudget(now, b, 85, &Forecast{HistoryDays: 28, ForecastUSD: 95}, 1); c.Status != BudgetWarning || c.Level != 80 {
+		t.Fatalf("expected warning at 80%%, got %+v", c)
+	}
+	if c := EvaluateScopedBudget(now, b, 130, nil, 1); c.Status != BudgetExceeded || c.Level != 120 {
+		t.Fatalf("expected exceeded at 120%%, got %+v", c)
+	}
+}
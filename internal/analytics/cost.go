@@ -15,11 +15,28 @@ type CostPricingMeta struct {
 	PricingEffectiveFrom   time.Time
 }
 
+// CalculateCostDetailed prices jobs as if they were the whole billing
+// account's usage. Use CalculateAccountCost when other repositories share
+// the free tier.
 func CalculateCostDetailed(jobs []model.Job, cfg pricing.Config, completeness float64) (model.CostResult, map[int64]float64, CostPricingMeta, error) {
+	return CalculateAccountCost(jobs, nil, cfg, completeness)
+}
+
+// CalculateAccountCost prices jobs with the free tier allocated over
+// accountJobs, every job of the billing account since the start of the first
+// month covered (see AccountStart). TotalCostUSD is net of the jobs' share
+// of the free tier and GrossCostUSD is before it. A nil accountJobs treats
+// jobs as the whole account.
+func CalculateAccountCost(jobs, accountJobs []model.Job, cfg pricing.Config, completeness float64) (model.CostResult, map[int64]float64, CostPricingMeta, error) {
 	result := model.CostResult{
 		ByOS:             map[string]model.OSCost{},
+		BySKU:            map[string]model.SKUCost{},
 		DataCompleteness: completeness,
-		Disclaimer:       "Estimate only. Free tier is shared across repositories in an account/org.",
+		Disclaimer:       "Estimate only. Free tier is allocated across the account's scanned repositories.",
+	}
+	shares, err := AllocateFreeTier(withJobs(accountJobs, jobs), cfg)
+	if err != nil {
+		return model.CostResult{}, nil, CostPricingMeta{}, err
 	}
 	jobCost := make(map[int64]float64, len(jobs))
 	preFreeTotalCost := 0.0
@@ -31,13 +48,14 @@ func CalculateCostDetailed(jobs []model.Job, cfg pricing.Config, completeness fl
 			continue
 		}
 		if job.IsSelfHosted {
+			addSelfHosted(&result.SelfHosted, job, cfg, jobCost)
 			continue
 		}
 		rawMin := round2(float64(job.DurationSec) / 60)
 		if rawMin < 0 {
 			rawMin = 0
 		}
-		quote, err := pricing.PriceJob(job.DurationSec, job.RunnerOS, job.RunnerName, job.StartedAt, cfg)
+		quote, err := pricing.PriceJob(job.DurationSec, job.RunnerOS, job.RunnerName, job.Labels, job.StartedAt, cfg)
 		if err != nil {
 			return model.CostResult{}, nil, CostPricingMeta{}, err
 		}
@@ -48,6 +66,9 @@ func CalculateCostDetailed(jobs []model.Job, cfg pricing.Config, completeness fl
 		result.BillableMinutes += billable
 		jobCost[job.ID] = cost
 		preFreeTotalCost += cost
+		share := shares[job.ID]
+		result.FreeTierUsed += share.FreeMinutes
+		result.TotalCostUSD += share.NetCostUSD
 
 		if firstMeta {
 			meta.PricingSource = quote.Source
@@ -76,18 +97,20 @@ func CalculateCostDetailed(jobs []model.Job, cfg pricing.Config, completeness fl
 			}
 		}
 		result.ByOS[job.RunnerOS] = osCost
-	}
 
-	charged := pricing.ChargedMinutes(result.BillableMinutes, cfg.FreeTierPerMonth, cfg.AlreadyUsedThisMon)
-	result.FreeTierUsed = math.Min(cfg.FreeTierPerMonth, result.BillableMinutes)
-	if result.BillableMinutes > 0 && preFreeTotalCost > 0 {
-		effectiveRate := preFreeTotalCost / result.BillableMinutes
-		result.TotalCostUSD = round2(charged * effectiveRate)
-	}
-	if result.BillableMinutes <= 0 {
-		result.TotalCostUSD = 0
+		skuKey := pricing.CanonicalSKU(quote.SKU)
+		skuCost := result.BySKU[skuKey]
+		skuCost.SKU = skuKey
+		skuCost.OS = job.RunnerOS
+		skuCost.Minutes += billable
+		skuCost.CostUSD += cost
+		result.BySKU[skuKey] = skuCost
 	}
 
+	result.GrossCostUSD = round2(preFreeTotalCost)
+	result.TotalCostUSD = round2(result.TotalCostUSD)
+	result.FreeTierUsed = round2(result.FreeTierUsed)
+
 	for k, osCost := range result.ByOS {
 		if preFreeTotalCost > 0 {
 			osCost.Percentage = round2((osCost.CostUSD / preFreeTotalCost) * 100)
@@ -96,11 +119,64 @@ func CalculateCostDetailed(jobs []model.Job, cfg pricing.Config, completeness fl
 		osCost.Minutes = round2(osCost.Minutes)
 		result.ByOS[k] = osCost
 	}
+	for k, skuCost := range result.BySKU {
+		skuCost.CostUSD = round2(skuCost.CostUSD)
+		skuCost.Minutes = round2(skuCost.Minutes)
+		result.BySKU[k] = skuCost
+	}
 	result.TotalMinutes = round2(result.TotalMinutes)
 	result.BillableMinutes = round2(result.BillableMinutes)
+	result.SelfHosted.Minutes = round2(result.SelfHosted.Minutes)
+	result.SelfHosted.CostUSD = round2(result.SelfHosted.CostUSD)
+	for k, g := range result.SelfHosted.ByGroup {
+		g.Minutes = round2(g.Minutes)
+		g.CostUSD = round2(g.CostUSD)
+		result.SelfHosted.ByGroup[k] = g
+	}
 	return result, jobCost, meta, nil
 }
 
+func addSelfHosted(sh *model.SelfHostedCost, job model.Job, cfg pricing.Config, jobCost map[int64]float64) {
+	quote := pricing.PriceSelfHostedJob(job.DurationSec, job.RunnerGroup, job.RunnerName, cfg)
+	group := selfHostedGroupName(job)
+	if sh.ByGroup == nil {
+		sh.ByGroup = map[string]model.SelfHostedGroupCost{}
+	}
+	g := sh.ByGroup[group]
+	g.Group = group
+	g.Minutes += quote.BillableMinutes
+	g.CostUSD += quote.CostUSD
+	g.Jobs++
+	sh.ByGroup[group] = g
+	sh.Minutes += quote.BillableMinutes
+	sh.CostUSD += quote.CostUSD
+	sh.Jobs++
+	jobCost[job.ID] = quote.CostUSD
+}
+
+// quoteJob prices one job, routing self-hosted jobs to the self-hosted model.
+func quoteJob(job model.Job, cfg pricing.Config) (pricing.JobPrice, error) {
+	if job.IsSelfHosted {
+		return pricing.PriceSelfHostedJob(job.DurationSec, job.RunnerGroup, job.RunnerName, cfg), nil
+	}
+	return pricing.PriceJob(job.DurationSec, job.RunnerOS, job.RunnerName, job.Labels, job.StartedAt, cfg)
+}
+
+func selfHostedGroupName(job model.Job) string {
+	if g := strings.TrimSpace(job.RunnerGroup); g != "" {
+		return g
+	}
+	if n := strings.TrimSpace(job.RunnerName); n != "" {
+		return n
+	}
+	return "unknown"
+}
+
+// TotalSpendUSD is GitHub-billed cost plus self-hosted runner cost.
+func TotalSpendUSD(c model.CostResult) float64 {
+	return round2(c.TotalCostUSD + c.SelfHosted.CostUSD)
+}
+
 func CalculateCost(jobs []model.Job, cfg pricing.Config, completeness float64) (model.CostResult, map[int64]float64) {
 	res, costs, _, err := CalculateCostDetailed(jobs, cfg, completeness)
 	if err != nil {
@@ -0,0 +1,43 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+func TestCalculateCostSeparatesSelfHosted(t *testing.T) {
+	cfg := pricing.Config{
+		PerMinuteUSD: 0.008,
+		SelfHosted: pricing.SelfHostedModel{
+			Groups: map[string]pricing.SelfHostedRate{"gpu-pool": {HourlyUSD: 6}},
+		},
+	}
+	runs := []model.WorkflowRun{{ID: 1, RunAttempt: 1, WorkflowName: "ci"}}
+	jobs := []model.Job{
+		{ID: 1, RunID: 1, RunAttempt: 1, Status: "completed", DurationSec: 600, RunnerOS: "Linux"},
+		{ID: 2, RunID: 1, RunAttempt: 1, Status: "completed", DurationSec: 600, RunnerOS: "Linux", IsSelfHosted: true, RunnerGroup: "gpu-pool"},
+	}
+	cost, _, _, err := CalculateCostDetailed(jobs, cfg, 1.0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cost.TotalCostUSD != 0.08 {
+		t.Fatalf("expected hosted cost 0.08, got %.2f", cost.TotalCostUSD)
+	}
+	if cost.SelfHosted.CostUSD != 1 || cost.SelfHosted.Jobs != 1 {
+		t.Fatalf("expected self-hosted cost 1.00, got %+v", cost.SelfHosted)
+	}
+	if g := cost.SelfHosted.ByGroup["gpu-pool"]; g.Minutes != 10 {
+		t.Fatalf("expected gpu-pool group minutes 10, got %+v", g)
+	}
+	if got := TotalSpendUSD(cost); got != 1.08 {
+		t.Fatalf("expected total spend 1.08, got %.2f", got)
+	}
+
+	entries := CalculateHotspots(runs, jobs, cfg, HotspotOptions{GroupBy: "runner"})
+	if len(entries) != 2 || entries[0].Name != "self-hosted/gpu-pool" || entries[0].CostUSD != 1 {
+		t.Fatalf("expected self-hosted runner group to lead hotspots, got %+v", entries)
+	}
+}
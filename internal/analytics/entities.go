@@ -0,0 +1,86 @@
+package analytics
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/peter941221/CICost/internal/model"
+)
+
+// EntityScopes are the groupings GroupEntities accepts.
+var EntityScopes = []string{"workflow", "job", "branch", "runner", "event"}
+
+// EntityGroup is one workflow, job, branch, runner or event and the runs
+// and jobs that belong to it.
+type EntityGroup struct {
+	Scope string
+	Key   string
+	Name  string
+	Runs  []model.WorkflowRun
+	Jobs  []model.Job
+}
+
+// GroupEntities partitions runs and jobs by scope with the grouping of
+// CalculateHotspots, so an entity is named as in `hotspots --group-by`. A
+// run belongs to every group one of its jobs falls in; for workflow, branch
+// and event, runs without recorded jobs are included too.
+func GroupEntities(runs []model.WorkflowRun, jobs []model.Job, scope string) ([]EntityGroup, error) {
+	scope = strings.ToLower(strings.TrimSpace(scope))
+	valid := false
+	for _, s := range EntityScopes {
+		valid = valid || s == scope
+	}
+	if !valid {
+		return nil, fmt.Errorf("invalid scope %q (use %s)", scope, strings.Join(EntityScopes, "|"))
+	}
+
+	names := latestWorkflowNames(runs)
+	runByIDAttempt := make(map[string]model.WorkflowRun, len(runs))
+	for _, r := range runs {
+		runByIDAttempt[runAttemptKey(r.ID, r.RunAttempt)] = r
+	}
+	groups := map[string]*EntityGroup{}
+	seenRun := map[string]map[string]struct{}{}
+	add := func(key, name string, run model.WorkflowRun, hasRun bool) *EntityGroup {
+		g := groups[key]
+		if g == nil {
+			g = &EntityGroup{Scope: scope, Key: key, Name: name}
+			groups[key] = g
+			seenRun[key] = map[string]struct{}{}
+		}
+		if !hasRun {
+			return g
+		}
+		rk := runAttemptKey(run.ID, run.RunAttempt)
+		if _, seen := seenRun[key][rk]; !seen {
+			seenRun[key][rk] = struct{}{}
+			g.Runs = append(g.Runs, run)
+		}
+		return g
+	}
+	for _, j := range jobs {
+		run, ok := runByIDAttempt[runAttemptKey(j.RunID, j.RunAttempt)]
+		key, name := hotspotGroup(scope, run, j, names)
+		g := add(key, name, run, ok)
+		g.Jobs = append(g.Jobs, j)
+	}
+	if scope != "job" && scope != "runner" {
+		for _, r := range runs {
+			key, name := hotspotGroup(scope, r, model.Job{}, names)
+			add(key, name, r, true)
+		}
+	}
+
+	out := make([]EntityGroup, 0, len(groups))
+	for _, g := range groups {
+		out = append(out, *g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Name != out[j].Name {
+			return out[i].Name < out[j].Name
+		}
+		return out[i].Key < out[j].Key
+	})
+	return out, nil
+}
@@ -0,0 +1,53 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+)
+
+func TestGroupEntities(t *testing.T) {
+	t0 := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	runs := []model.WorkflowRun{
+		{ID: 1, RunAttempt: 1, WorkflowID: 7, WorkflowName: "CI", HeadBranch: "main", Event: "push", CreatedAt: t0},
+		{ID: 2, RunAttempt: 1, WorkflowID: 7, WorkflowName: "Build", HeadBranch: "feature/x", Event: "pull_request", CreatedAt: t0.Add(time.Hour)},
+		{ID: 3, RunAttempt: 1, WorkflowID: 8, WorkflowName: "Nightly", Event: "schedule", CreatedAt: t0},
+	}
+	jobs := []model.Job{
+		{ID: 11, RunID: 1, RunAttempt: 1, Name: "test", Status: "completed", RunnerOS: "Linux"},
+		{ID: 12, RunID: 1, RunAttempt: 1, Name: "lint", Status: "completed", RunnerOS: "Linux"},
+		{ID: 21, RunID: 2, RunAttempt: 1, Name: "test", Status: "completed", RunnerOS: "macOS"},
+	}
+
+	byWorkflow, err := GroupEntities(runs, jobs, "workflow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byWorkflow) != 2 || byWorkflow[0].Name != "Build" || len(byWorkflow[0].Runs) != 2 || len(byWorkflow[0].Jobs) != 3 {
+		t.Fatalf("expected the renamed workflow as one entity, got %+v", byWorkflow)
+	}
+	if byWorkflow[1].Name != "Nightly" || len(byWorkflow[1].Runs) != 1 || len(byWorkflow[1].Jobs) != 0 {
+		t.Fatalf("expected the jobless run kept, got %+v", byWorkflow[1])
+	}
+
+	byJob, err := GroupEntities(runs, jobs, "job")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byJob) != 2 || byJob[1].Name != "Build / test" || len(byJob[1].Jobs) != 2 || len(byJob[1].Runs) != 2 {
+		t.Fatalf("unexpected job entities: %+v", byJob)
+	}
+
+	byEvent, err := GroupEntities(runs, jobs, "event")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(byEvent) != 3 || byEvent[0].Name != "pull_request" || byEvent[2].Name != "schedule" {
+		t.Fatalf("unexpected event entities: %+v", byEvent)
+	}
+
+	if _, err := GroupEntities(runs, jobs, "step"); err == nil {
+		t.Fatal("expected an invalid scope error")
+	}
+}
@@ -0,0 +1,264 @@
+package analytics
+
+import (
+	"math"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+// Forecast methods.
+const (
+	// ForecastHoltWinters is additive Holt-Winters with a damped trend and a
+	// weekly season, used once two full weeks of history are available.
+	ForecastHoltWinters = "holt-winters"
+	// ForecastLinear projects the mean daily spend of a short history.
+	ForecastLinear = "linear"
+)
+
+const (
+	forecastSeason  = 7
+	forecastDamping = 0.95
+	// minForecastHistoryDays is the least history a forecast needs before
+	// it replaces the linear projection of a budget.
+	minForecastHistoryDays = 7
+	// ForecastConfidence is the coverage of Forecast's interval.
+	ForecastConfidence = 0.8
+	forecastZ          = 1.2816
+)
+
+// DailySpend is the GitHub-hosted usage of one UTC day before free tier.
+type DailySpend struct {
+	Date            time.Time `json:"date"`
+	GrossUSD        float64   `json:"gross_usd"`
+	BillableMinutes float64   `json:"billable_minutes"`
+}
+
+// DailyForecast is the forecast gross spend of one UTC day with its
+// interval.
+type DailyForecast struct {
+	Date     time.Time `json:"date"`
+	GrossUSD float64   `json:"gross_usd"`
+	LowerUSD float64   `json:"lower_usd"`
+	UpperUSD float64   `json:"upper_usd"`
+}
+
+// Forecast is the expected net spend of a budget period. ActualUSD is spend
+// so far; ForecastUSD, LowerUSD and UpperUSD are period totals with the
+// remaining free tier applied.
+type Forecast struct {
+	Method      string          `json:"method"`
+	HistoryDays int             `json:"history_days"`
+	PeriodStart time.Time       `json:"period_start"`
+	PeriodEnd   time.Time       `json:"period_end"`
+	ActualUSD   float64         `json:"actual_usd"`
+	ForecastUSD float64         `json:"forecast_usd"`
+	LowerUSD    float64         `json:"lower_usd"`
+	UpperUSD    float64         `json:"upper_usd"`
+	Confidence  float64         `json:"confidence"`
+	Daily       []DailyForecast `json:"daily,omitempty"`
+}
+
+// ForecastInput is what ForecastSpend needs about a period.
+type ForecastInput struct {
+	// History is complete days, oldest first, ending the day before Now.
+	History     []DailySpend
+	Now         time.Time
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	// ActualUSD is net GitHub-billed spend in [PeriodStart, Now].
+	ActualUSD float64
+	// FreeMinutesLeft is the free tier still available this cycle.
+	FreeMinutesLeft float64
+}
+
+// DailyHistory totals completed GitHub-hosted jobs per UTC day for every day
+// from start up to, but excluding, the day of end. Days without jobs are 0.
+func DailyHistory(jobs []model.Job, cfg pricing.Config, start, end time.Time) ([]DailySpend, error) {
+	first := utcDay(start)
+	last := utcDay(end)
+	n := int(last.Sub(first).Hours() / 24)
+	if n <= 0 {
+		return nil, nil
+	}
+	out := make([]DailySpend, n)
+	for i := range out {
+		out[i].Date = first.AddDate(0, 0, i)
+	}
+	for _, job := range jobs {
+		if strings.TrimSpace(job.Status) != "completed" || job.IsSelfHosted {
+			continue
+		}
+		at := job.StartedAt
+		if at.IsZero() {
+			at = job.CompletedAt
+		}
+		i := int(utcDay(at).Sub(first).Hours() / 24)
+		if at.IsZero() || i < 0 || i >= n {
+			continue
+		}
+		quote, err := pricing.PriceJob(job.DurationSec, job.RunnerOS, job.RunnerName, job.Labels, job.StartedAt, cfg)
+		if err != nil {
+			return nil, err
+		}
+		out[i].GrossUSD += quote.CostUSD
+		out[i].BillableMinutes += quote.BillableMinutes
+	}
+	return out, nil
+}
+
+// ForecastSpend forecasts the rest of [in.Now, in.PeriodEnd) from daily
+// history, so quiet weekends early in a period do not read as a trend.
+func ForecastSpend(in ForecastInput) Forecast {
+	y := make([]float64, len(in.History))
+	gross, billable := 0.0, 0.0
+	for i, d := range in.History {
+		y[i] = d.GrossUSD
+		gross += d.GrossUSD
+		billable += d.BillableMinutes
+	}
+
+	today := utcDay(in.Now)
+	horizon := int(math.Ceil(in.PeriodEnd.Sub(today).Hours() / 24))
+	if horizon < 0 {
+		horizon = 0
+	}
+	var predict func(h int) (point, variance float64)
+	method := ForecastLinear
+	if len(y) >= 2*forecastSeason {
+		method = ForecastHoltWinters
+		predict = fitHoltWinters(y)
+	} else {
+		mean, sd := meanStd(y)
+		predict = func(int) (float64, float64) { return mean, sd * sd }
+	}
+
+	f := Forecast{
+		Method:      method,
+		HistoryDays: len(y),
+		PeriodStart: in.PeriodStart,
+		PeriodEnd:   in.PeriodEnd,
+		ActualUSD:   round2(in.ActualUSD),
+		Confidence:  ForecastConfidence,
+	}
+	remaining, variance := 0.0, 0.0
+	for h := 1; h <= horizon; h++ {
+		day := today.AddDate(0, 0, h-1)
+		// Only the part of today still ahead is forecast; the rest is in
+		// ActualUSD already.
+		weight := 1.0
+		if h == 1 {
+			weight = day.AddDate(0, 0, 1).Sub(in.Now).Hours() / 24
+		}
+		if end := day.AddDate(0, 0, 1); end.After(in.PeriodEnd) {
+			weight *= in.PeriodEnd.Sub(day).Hours() / 24
+		}
+		point, v := predict(h)
+		point = math.Max(point, 0)
+		sd := math.Sqrt(v)
+		f.Daily = append(f.Daily, DailyForecast{
+			Date:     day,
+			GrossUSD: round2(point),
+			LowerUSD: round2(math.Max(point-forecastZ*sd, 0)),
+			UpperUSD: round2(point + forecastZ*sd),
+		})
+		remaining += weight * point
+		variance += weight * weight * v
+	}
+
+	freeUSD := 0.0
+	if billable > 0 {
+		freeUSD = in.FreeMinutesLeft * gross / billable
+	}
+	net := func(v float64) float64 { return in.ActualUSD + math.Max(v-freeUSD, 0) }
+	spread := forecastZ * math.Sqrt(variance)
+	f.ForecastUSD = round2(net(remaining))
+	f.LowerUSD = round2(net(math.Max(remaining-spread, 0)))
+	f.UpperUSD = round2(net(remaining + spread))
+	return f
+}
+
+// fitHoltWinters grid-searches smoothing parameters by one-step squared
+// error over y and returns the h-step forecast of the best fit, with the
+// usual variance approximation sigma^2 * (1 + (h-1) * alpha^2).
+func fitHoltWinters(y []float64) func(h int) (float64, float64) {
+	type fit struct {
+		alpha, beta, gamma float64
+		level, trend       float64
+		season             []float64
+		sse                float64
+		n                  int
+	}
+	best := fit{sse: math.Inf(1)}
+	for _, alpha := range []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9} {
+		for _, beta := range []float64{0, 0.05, 0.1, 0.2} {
+			for _, gamma := range []float64{0.1, 0.2, 0.3, 0.4, 0.5, 0.6, 0.7, 0.8, 0.9} {
+				level, trend, season := initHoltWinters(y)
+				sse, n := 0.0, 0
+				for t := forecastSeason; t < len(y); t++ {
+					s := season[t%forecastSeason]
+					e := y[t] - (level + forecastDamping*trend + s)
+					sse += e * e
+					n++
+					prev := level
+					level = alpha*(y[t]-s) + (1-alpha)*(prev+forecastDamping*trend)
+					trend = beta*(level-prev) + (1-beta)*forecastDamping*trend
+					season[t%forecastSeason] = gamma*(y[t]-level) + (1-gamma)*s
+				}
+				if sse < best.sse {
+					best = fit{alpha, beta, gamma, level, trend, season, sse, n}
+				}
+			}
+		}
+	}
+	sigma2 := 0.0
+	if best.n > 0 {
+		sigma2 = best.sse / float64(best.n)
+	}
+	return func(h int) (float64, float64) {
+		damp := 0.0
+		for i, p := 1, forecastDamping; i <= h; i, p = i+1, p*forecastDamping {
+			damp += p
+		}
+		point := best.level + damp*best.trend + best.season[(len(y)+h-1)%forecastSeason]
+		return point, sigma2 * (1 + float64(h-1)*best.alpha*best.alpha)
+	}
+}
+
+// initHoltWinters starts the level at the first week's mean, the trend at
+// the weekly change between the first two weeks, and the season at the
+// first week's deviations from its mean.
+func initHoltWinters(y []float64) (level, trend float64, season []float64) {
+	m := forecastSeason
+	first, _ := meanStd(y[:m])
+	second, _ := meanStd(y[m : 2*m])
+	season = make([]float64, m)
+	for i := 0; i < m; i++ {
+		season[i] = y[i] - first
+	}
+	return first, (second - first) / float64(m), season
+}
+
+func meanStd(v []float64) (mean, sd float64) {
+	if len(v) == 0 {
+		return 0, 0
+	}
+	for _, x := range v {
+		mean += x
+	}
+	mean /= float64(len(v))
+	if len(v) < 2 {
+		return mean, 0
+	}
+	for _, x := range v {
+		sd += (x - mean) * (x - mean)
+	}
+	return mean, math.Sqrt(sd / float64(len(v)-1))
+}
+
+func utcDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
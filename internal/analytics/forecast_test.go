@@ -0,0 +1,88 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+)
+
+func weekdayHistory(end time.Time, weeks int) []DailySpend {
+	var out []DailySpend
+	for d := end.AddDate(0, 0, -7*weeks); d.Before(end); d = d.AddDate(0, 0, 1) {
+		usd := 10.0
+		if d.Weekday() == time.Saturday || d.Weekday() == time.Sunday {
+			usd = 1
+		}
+		out = append(out, DailySpend{Date: d, GrossUSD: usd, BillableMinutes: usd / 0.008})
+	}
+	return out
+}
+
+func TestForecastSpendWeekdaySeasonality(t *testing.T) {
+	// Saturday morning: the weekend ahead is cheap, a linear projection of
+	// the working week would not be.
+	now := time.Date(2026, 3, 7, 6, 0, 0, 0, time.UTC)
+	start, end := PeriodBounds(now, "weekly", 1)
+	f := ForecastSpend(ForecastInput{
+		History:     weekdayHistory(time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC), 8),
+		Now:         now,
+		PeriodStart: start,
+		PeriodEnd:   end,
+		ActualUSD:   50,
+	})
+	if f.Method != ForecastHoltWinters {
+		t.Fatalf("expected %s, got %s", ForecastHoltWinters, f.Method)
+	}
+	if len(f.Daily) != 2 || f.Daily[0].Date.Weekday() != time.Saturday {
+		t.Fatalf("unexpected daily forecast: %+v", f.Daily)
+	}
+	for _, d := range f.Daily {
+		if d.GrossUSD > 2 {
+			t.Fatalf("weekend day forecast too high: %+v", d)
+		}
+	}
+	if f.ForecastUSD < 50 || f.ForecastUSD > 53 {
+		t.Fatalf("expected forecast near 51.75, got %.2f", f.ForecastUSD)
+	}
+	if f.LowerUSD > f.ForecastUSD || f.UpperUSD < f.ForecastUSD || f.LowerUSD < f.ActualUSD {
+		t.Fatalf("interval does not bracket forecast: %+v", f)
+	}
+
+	res := EvaluateBudget(now, 50, 55, "weekly", 1)
+	if res.Status != BudgetWarning {
+		t.Fatalf("linear projection should warn, got %s", res.Status)
+	}
+	if res = res.WithForecast(f); res.Status != BudgetOK || res.ForecastMethod != ForecastHoltWinters {
+		t.Fatalf("forecast should clear the warning, got %+v", res)
+	}
+	recent := weekdayHistory(time.Date(2026, 3, 7, 0, 0, 0, 0, time.UTC), 1)
+	short := ForecastSpend(ForecastInput{History: recent[len(recent)-3:], Now: now, PeriodStart: start, PeriodEnd: end, ActualUSD: 50})
+	if kept := EvaluateBudget(now, 50, 55, "weekly", 1).WithForecast(short); kept.Status != BudgetWarning || kept.ForecastMethod != "" {
+		t.Fatalf("a forecast from %d days should keep the linear projection, got %+v", short.HistoryDays, kept)
+	}
+	if kept := EvaluateBudget(now, 50, 55, "weekly", 1).WithForecast(ForecastSpend(ForecastInput{Now: now, PeriodStart: start, PeriodEnd: end, ActualUSD: 50})); kept.Status != BudgetWarning {
+		t.Fatalf("a forecast without history should keep the warning, got %+v", kept)
+	}
+}
+
+func TestForecastSpendShortHistoryAndFreeTier(t *testing.T) {
+	now := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+	start, end := PeriodBounds(now, "monthly", 1)
+	history := []DailySpend{
+		{Date: now.AddDate(0, 0, -2), GrossUSD: 8, BillableMinutes: 1000},
+		{Date: now.AddDate(0, 0, -1), GrossUSD: 8, BillableMinutes: 1000},
+	}
+	f := ForecastSpend(ForecastInput{History: history, Now: now, PeriodStart: start, PeriodEnd: end})
+	if f.Method != ForecastLinear {
+		t.Fatalf("expected %s, got %s", ForecastLinear, f.Method)
+	}
+	// 12 days left in March at $8/day.
+	if f.ForecastUSD != 96 || f.LowerUSD != 96 || f.UpperUSD != 96 {
+		t.Fatalf("unexpected forecast %+v", f)
+	}
+
+	// 6000 free minutes are worth $48 at the history's rate.
+	f = ForecastSpend(ForecastInput{History: history, Now: now, PeriodStart: start, PeriodEnd: end, FreeMinutesLeft: 6000})
+	if f.ForecastUSD != 48 {
+		t.Fatalf("expected free tier to absorb $48, got %.2f", f.ForecastUSD)
+	}
+}
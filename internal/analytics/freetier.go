@@ -0,0 +1,191 @@
+package analytics
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+// Free tier allocation methods.
+const (
+	// AllocateChronological spends each month's free minutes on jobs in the
+	// order they started, as GitHub does, so early jobs run free.
+	AllocateChronological = "chronological"
+	// AllocateProRata spreads each month's free minutes over all jobs in
+	// proportion to their billable minutes.
+	AllocateProRata = "pro-rata"
+)
+
+// FreeTierShare is the part of an account's monthly free tier assigned to
+// one GitHub-hosted job.
+type FreeTierShare struct {
+	BillableMinutes float64
+	FreeMinutes     float64
+	GrossCostUSD    float64
+	NetCostUSD      float64
+}
+
+// AllocateFreeTier spends cfg.FreeTierPerMonth once per billing cycle (see
+// pricing.CycleStart) across jobs, which should be every job of one billing
+// account in the cycles of interest. AlreadyUsedThisMon is the account's
+// usage so far in the current cycle; only the part jobs do not account for,
+// such as usage in repositories that were never scanned, reduces the pool.
+// Self-hosted and unfinished jobs get no share; larger-runner jobs keep
+// their gross cost.
+func AllocateFreeTier(jobs []model.Job, cfg pricing.Config) (map[int64]FreeTierShare, error) {
+	type entry struct {
+		job   model.Job
+		share FreeTierShare
+	}
+	out := make(map[int64]FreeTierShare, len(jobs))
+	byCycle := map[time.Time][]*entry{}
+	for _, job := range jobs {
+		if strings.TrimSpace(job.Status) != "completed" || job.IsSelfHosted {
+			continue
+		}
+		quote, err := pricing.PriceJob(job.DurationSec, job.RunnerOS, job.RunnerName, job.Labels, job.StartedAt, cfg)
+		if err != nil {
+			return nil, err
+		}
+		if largerRunner(job, quote) {
+			// Plan minutes never apply to larger runners.
+			out[job.ID] = FreeTierShare{BillableMinutes: quote.BillableMinutes, GrossCostUSD: quote.CostUSD, NetCostUSD: quote.CostUSD}
+			continue
+		}
+		cycle := jobCycle(job, cfg.BillingCycleDay)
+		byCycle[cycle] = append(byCycle[cycle], &entry{
+			job: job,
+			share: FreeTierShare{
+				BillableMinutes: quote.BillableMinutes,
+				GrossCostUSD:    quote.CostUSD,
+				NetCostUSD:      quote.CostUSD,
+			},
+		})
+	}
+
+	currentCycle := pricing.CycleStart(time.Now(), cfg.BillingCycleDay)
+	for cycle, entries := range byCycle {
+		free := cfg.FreeTierPerMonth
+		if cycle.Equal(currentCycle) {
+			seen := 0.0
+			for _, e := range entries {
+				seen += e.share.BillableMinutes
+			}
+			if cfg.AlreadyUsedThisMon > seen {
+				free -= cfg.AlreadyUsedThisMon - seen
+			}
+		}
+		if free < 0 {
+			free = 0
+		}
+		if strings.EqualFold(cfg.FreeTierAllocation, AllocateProRata) {
+			total := 0.0
+			for _, e := range entries {
+				total += e.share.BillableMinutes
+			}
+			if total > 0 {
+				ratio := free / total
+				if ratio > 1 {
+					ratio = 1
+				}
+				for _, e := range entries {
+					e.share.FreeMinutes = e.share.BillableMinutes * ratio
+				}
+			}
+		} else {
+			sort.SliceStable(entries, func(i, j int) bool {
+				a, b := entries[i].job, entries[j].job
+				if !a.StartedAt.Equal(b.StartedAt) {
+					return a.StartedAt.Before(b.StartedAt)
+				}
+				return a.ID < b.ID
+			})
+			for _, e := range entries {
+				if free <= 0 {
+					break
+				}
+				used := e.share.BillableMinutes
+				if used > free {
+					used = free
+				}
+				e.share.FreeMinutes = used
+				free -= used
+			}
+		}
+		for _, e := range entries {
+			if e.share.BillableMinutes > 0 {
+				e.share.NetCostUSD = e.share.GrossCostUSD * (1 - e.share.FreeMinutes/e.share.BillableMinutes)
+			}
+			out[e.job.ID] = e.share
+		}
+	}
+	return out, nil
+}
+
+// AccountStart is the start of the billing cycle containing start. Free
+// tier allocation for a window has to see the account's usage from there,
+// since earlier jobs in the cycle consume free minutes first.
+func AccountStart(start time.Time, cycleDay int) time.Time {
+	return pricing.CycleStart(start, cycleDay)
+}
+
+// HostedMinutes is the billable minutes of the completed standard
+// GitHub-hosted jobs, the usage that counts against the free tier.
+func HostedMinutes(jobs []model.Job, cfg pricing.Config) (float64, error) {
+	total := 0.0
+	for _, job := range jobs {
+		if strings.TrimSpace(job.Status) != "completed" || job.IsSelfHosted {
+			continue
+		}
+		quote, err := pricing.PriceJob(job.DurationSec, job.RunnerOS, job.RunnerName, job.Labels, job.StartedAt, cfg)
+		if err != nil {
+			return 0, err
+		}
+		if largerRunner(job, quote) {
+			continue
+		}
+		total += quote.BillableMinutes
+	}
+	return total, nil
+}
+
+func jobCycle(job model.Job, cycleDay int) time.Time {
+	t := job.StartedAt
+	if t.IsZero() {
+		t = job.CompletedAt
+	}
+	if t.IsZero() {
+		// Without timestamps the job can only be recent; a zero cycle would
+		// get a free tier of its own.
+		t = time.Now()
+	}
+	return pricing.CycleStart(t, cycleDay)
+}
+
+// largerRunner reports whether job ran on a larger runner, which is billed
+// from the first minute.
+func largerRunner(job model.Job, quote pricing.JobPrice) bool {
+	return quote.Source == pricing.PricingSourceLargerRunner || pricing.ParseRunnerLabels(job.RunnerOS, job.Labels).Larger()
+}
+
+// withJobs returns account plus any of jobs it lacks, so allocation always
+// covers the jobs being priced.
+func withJobs(account, jobs []model.Job) []model.Job {
+	if account == nil {
+		return jobs
+	}
+	seen := make(map[int64]struct{}, len(account))
+	for _, j := range account {
+		seen[j.ID] = struct{}{}
+	}
+	out := append([]model.Job(nil), account...)
+	for _, j := range jobs {
+		if _, ok := seen[j.ID]; !ok {
+			out = append(out, j)
+		}
+	}
+	return out
+}
@@ -0,0 +1,138 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+func TestAllocateFreeTierPerMonth(t *testing.T) {
+	jan := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	feb := time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC)
+	jobs := []model.Job{
+		{ID: 2, Repo: "acme/b", Status: "completed", RunnerOS: "Linux", DurationSec: 1500 * 60, StartedAt: jan.Add(time.Hour)},
+		{ID: 1, Repo: "acme/a", Status: "completed", RunnerOS: "Linux", DurationSec: 1000 * 60, StartedAt: jan},
+		{ID: 3, Repo: "acme/a", Status: "completed", RunnerOS: "Linux", DurationSec: 500 * 60, StartedAt: feb},
+		{ID: 4, Repo: "acme/a", Status: "completed", IsSelfHosted: true, DurationSec: 600, StartedAt: feb},
+	}
+	cfg := pricing.Config{PerMinuteUSD: 0.01, FreeTierPerMonth: 2000}
+
+	shares, err := AllocateFreeTier(jobs, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shares[1].FreeMinutes != 1000 || shares[1].NetCostUSD != 0 {
+		t.Fatalf("expected first January job fully free, got %+v", shares[1])
+	}
+	if shares[2].FreeMinutes != 1000 || round2(shares[2].NetCostUSD) != 5 {
+		t.Fatalf("expected second January job to get the remaining 1000 min, got %+v", shares[2])
+	}
+	if shares[3].FreeMinutes != 500 || shares[3].NetCostUSD != 0 {
+		t.Fatalf("expected February to start a fresh free tier, got %+v", shares[3])
+	}
+	if _, ok := shares[4]; ok {
+		t.Fatal("expected self-hosted job to get no free tier share")
+	}
+
+	cfg.FreeTierAllocation = AllocateProRata
+	shares, err = AllocateFreeTier(jobs, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shares[1].FreeMinutes != 800 || shares[2].FreeMinutes != 1200 {
+		t.Fatalf("expected January free tier split 40/60, got %+v %+v", shares[1], shares[2])
+	}
+}
+
+func TestCalculateAccountCostSharesFreeTier(t *testing.T) {
+	start := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC)
+	other := []model.Job{{ID: 9, Repo: "acme/other", Status: "completed", RunnerOS: "Linux", DurationSec: 1800 * 60, StartedAt: start}}
+	mine := []model.Job{{ID: 1, Repo: "acme/mine", Status: "completed", RunnerOS: "Linux", DurationSec: 500 * 60, StartedAt: start.Add(time.Hour)}}
+	cfg := pricing.Config{PerMinuteUSD: 0.01, FreeTierPerMonth: 2000}
+
+	alone, _, _, err := CalculateCostDetailed(mine, cfg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if alone.TotalCostUSD != 0 || alone.GrossCostUSD != 5 || alone.FreeTierUsed != 500 {
+		t.Fatalf("expected a lone repo to use the whole free tier, got %+v", alone)
+	}
+	shared, _, _, err := CalculateAccountCost(mine, append(other, mine...), cfg, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shared.TotalCostUSD != 3 || shared.GrossCostUSD != 5 || shared.FreeTierUsed != 200 {
+		t.Fatalf("expected only 200 free minutes left after the other repo, got %+v", shared)
+	}
+}
+
+func TestAllocateFreeTierBillingCycle(t *testing.T) {
+	// With cycles resetting on the 15th, jobs on March 10 and March 20 fall
+	// in different cycles even though they share a calendar month.
+	jobs := []model.Job{
+		{ID: 1, Status: "completed", RunnerOS: "Linux", DurationSec: 2000 * 60, StartedAt: time.Date(2026, 3, 10, 0, 0, 0, 0, time.UTC)},
+		{ID: 2, Status: "completed", RunnerOS: "Linux", DurationSec: 500 * 60, StartedAt: time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)},
+	}
+	cfg := pricing.Config{PerMinuteUSD: 0.01, FreeTierPerMonth: 2000, BillingCycleDay: 15}
+	shares, err := AllocateFreeTier(jobs, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shares[1].FreeMinutes != 2000 || shares[2].FreeMinutes != 500 {
+		t.Fatalf("expected a fresh free tier after the 15th, got %+v %+v", shares[1], shares[2])
+	}
+}
+
+func TestAllocateFreeTierAlreadyUsed(t *testing.T) {
+	now := time.Now().UTC()
+	jobs := []model.Job{{ID: 1, Status: "completed", RunnerOS: "Linux", DurationSec: 500 * 60, StartedAt: now}}
+	cfg := pricing.Config{PerMinuteUSD: 0.01, FreeTierPerMonth: 2000, AlreadyUsedThisMon: 500}
+
+	shares, err := AllocateFreeTier(jobs, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shares[1].FreeMinutes != 500 {
+		t.Fatalf("expected usage covered by the jobs not to count twice, got %+v", shares[1])
+	}
+	cfg.AlreadyUsedThisMon = 2300
+	shares, err = AllocateFreeTier(jobs, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if shares[1].FreeMinutes != 200 {
+		t.Fatalf("expected 1800 min used elsewhere to leave 200 free, got %+v", shares[1])
+	}
+}
+
+func TestAllocateFreeTierSkipsLargerRunnersAndUndatedJobs(t *testing.T) {
+	now := time.Now().UTC()
+	jobs := []model.Job{
+		{ID: 1, Status: "completed", RunnerOS: "Linux", Labels: []string{"ubuntu-latest-16-cores"}, DurationSec: 100 * 60, StartedAt: now},
+		{ID: 2, Status: "completed", RunnerOS: "Linux", DurationSec: 1500 * 60, StartedAt: now},
+		{ID: 3, Status: "completed", RunnerOS: "Linux", DurationSec: 1500 * 60},
+	}
+	cfg := pricing.Config{PerMinuteUSD: 0.01, FreeTierPerMonth: 2000, LargerRunnersPerMin: map[string]float64{"linux-16core": 0.064}}
+
+	shares, err := AllocateFreeTier(jobs, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s := shares[1]; s.FreeMinutes != 0 || round2(s.NetCostUSD) != 6.4 || s.NetCostUSD != s.GrossCostUSD {
+		t.Fatalf("expected the larger runner job at full cost, got %+v", s)
+	}
+	// The undated job shares the current cycle instead of getting a free
+	// tier of its own.
+	if free := shares[2].FreeMinutes + shares[3].FreeMinutes; free != 2000 {
+		t.Fatalf("expected 2000 free minutes over both standard jobs, got %v", free)
+	}
+	minutes, err := HostedMinutes(jobs, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if minutes != 3000 {
+		t.Fatalf("expected larger runner minutes left out of free tier usage, got %v", minutes)
+	}
+}
@@ -2,7 +2,9 @@ package analytics
 
 import (
 	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/peter941221/CICost/internal/model"
 	"github.com/peter941221/CICost/internal/pricing"
@@ -29,6 +31,7 @@ func CalculateHotspots(runs []model.WorkflowRun, jobs []model.Job, cfg pricing.C
 	for _, r := range runs {
 		runByIDAttempt[runAttemptKey(r.ID, r.RunAttempt)] = r
 	}
+	names := latestWorkflowNames(runs)
 
 	type agg struct {
 		name      string
@@ -42,37 +45,67 @@ func CalculateHotspots(runs []model.WorkflowRun, jobs []model.Job, cfg pricing.C
 		jobCount  int
 	}
 	aggs := map[string]*agg{}
+	group := func(key, name string) *agg {
+		if name == "" {
+			name = "unknown"
+		}
+		a := aggs[key]
+		if a == nil {
+			a = &agg{name: name, groupType: opts.GroupBy, runIDs: map[int64]struct{}{}}
+			aggs[key] = a
+		}
+		return a
+	}
+	byStep := strings.EqualFold(opts.GroupBy, "step")
 	totalCost := 0.0
 	for _, j := range jobs {
-		if j.IsSelfHosted || j.Status != "completed" {
+		if j.Status != "completed" {
 			continue
 		}
 		key := runAttemptKey(j.RunID, j.RunAttempt)
 		run := runByIDAttempt[key]
-		groupName := hotspotGroupName(opts.GroupBy, run, j)
-		if groupName == "" {
-			groupName = "unknown"
-		}
-		a := aggs[groupName]
-		if a == nil {
-			a = &agg{name: groupName, groupType: opts.GroupBy, runIDs: map[int64]struct{}{}}
-			aggs[groupName] = a
-		}
-		quote, err := pricing.PriceJob(j.DurationSec, j.RunnerOS, j.RunnerName, j.StartedAt, cfg)
+		quote, err := quoteJob(j, cfg)
 		if err != nil {
 			continue
 		}
+		totalCost += quote.CostUSD
+		if byStep {
+			// Steps carry the job's cost in proportion to their share of its
+			// wall time, so a step row reads as "this much of the bill".
+			jobSec := stepsDurationSec(j)
+			for _, st := range j.Steps {
+				if jobSec <= 0 {
+					break
+				}
+				share := float64(st.DurationSec) / float64(jobSec)
+				a := group(workflowKey(run)+"/"+j.Name+"/"+st.Name, hotspotStepName(run, j, st, names))
+				a.minutes += quote.BillableMinutes * share
+				a.cost += quote.CostUSD * share
+				a.runIDs[j.RunID] = struct{}{}
+				a.durSum += float64(st.DurationSec)
+				a.jobCount++
+				if st.Conclusion == "failure" {
+					a.failRuns++
+				}
+				a.totalRuns++
+			}
+			continue
+		}
+		a := group(hotspotGroup(opts.GroupBy, run, j, names))
 		a.minutes += quote.BillableMinutes
 		a.cost += quote.CostUSD
 		a.runIDs[j.RunID] = struct{}{}
 		a.durSum += float64(j.DurationSec)
 		a.jobCount++
-		totalCost += quote.CostUSD
 	}
 
 	for _, r := range runs {
-		name := hotspotGroupName(opts.GroupBy, r, model.Job{})
-		if a, ok := aggs[name]; ok {
+		if byStep {
+			// Step fail rates come from step conclusions, counted above.
+			break
+		}
+		key, _ := hotspotGroup(opts.GroupBy, r, model.Job{}, names)
+		if a, ok := aggs[key]; ok {
 			if r.Conclusion == "failure" {
 				a.failRuns++
 			}
@@ -121,16 +154,35 @@ func CalculateHotspots(runs []model.WorkflowRun, jobs []model.Job, cfg pricing.C
 	return out
 }
 
-func hotspotGroupName(groupBy string, run model.WorkflowRun, job model.Job) string {
+// hotspotGroup returns the aggregation key and display label for a job.
+// Workflow groups key on the workflow itself so renaming `name:` keeps one
+// row, labelled with the name of the workflow's most recent run.
+func hotspotGroup(groupBy string, run model.WorkflowRun, job model.Job, names workflowNames) (string, string) {
+	name := hotspotGroupName(groupBy, run, job, names)
 	switch strings.ToLower(groupBy) {
 	case "job":
-		if run.WorkflowName != "" && job.Name != "" {
-			return run.WorkflowName + " / " + job.Name
+		if job.Name == "" {
+			return name, name
+		}
+		return workflowKey(run) + "/" + job.Name, name
+	case "runner", "branch", "path", "event":
+		return name, name
+	default:
+		return workflowKey(run), name
+	}
+}
+
+func hotspotGroupName(groupBy string, run model.WorkflowRun, job model.Job, names workflowNames) string {
+	workflow := names.name(run)
+	switch strings.ToLower(groupBy) {
+	case "job":
+		if workflow != "" && job.Name != "" {
+			return workflow + " / " + job.Name
 		}
 		return job.Name
 	case "runner":
 		if job.IsSelfHosted {
-			return "self-hosted"
+			return "self-hosted/" + selfHostedGroupName(job)
 		}
 		if job.RunnerOS != "" {
 			return job.RunnerOS
@@ -141,10 +193,99 @@ func hotspotGroupName(groupBy string, run model.WorkflowRun, job model.Job) stri
 			return "(no-branch)"
 		}
 		return run.HeadBranch
+	case "event":
+		if run.Event == "" {
+			return "(unknown-event)"
+		}
+		return run.Event
+	case "path":
+		if run.WorkflowPath != "" {
+			return run.WorkflowPath
+		}
+		if workflow == "" {
+			return "(unknown-workflow)"
+		}
+		return workflow
 	default:
-		if run.WorkflowName == "" {
+		if workflow == "" {
 			return "(unknown-workflow)"
 		}
-		return run.WorkflowName
+		return workflow
+	}
+}
+
+func hotspotStepName(run model.WorkflowRun, job model.Job, step model.Step, names workflowNames) string {
+	parts := []string{}
+	if workflow := names.name(run); workflow != "" {
+		parts = append(parts, workflow)
+	}
+	if job.Name != "" {
+		parts = append(parts, job.Name)
+	}
+	return strings.Join(append(parts, step.Name), " / ")
+}
+
+// stepsDurationSec is the job duration used to apportion cost to its steps,
+// falling back to the sum of step durations when the job has none recorded.
+func stepsDurationSec(job model.Job) int {
+	if job.DurationSec > 0 {
+		return job.DurationSec
+	}
+	total := 0
+	for _, st := range job.Steps {
+		total += st.DurationSec
+	}
+	return total
+}
+
+// workflowNames maps a workflow key to the name of its most recent run.
+type workflowNames map[string]string
+
+func latestWorkflowNames(runs []model.WorkflowRun) workflowNames {
+	names := workflowNames{}
+	latest := map[string]time.Time{}
+	for _, r := range runs {
+		if r.WorkflowName == "" {
+			continue
+		}
+		key := workflowKey(r)
+		if seen, ok := latest[key]; ok && !r.CreatedAt.After(seen) {
+			continue
+		}
+		latest[key] = r.CreatedAt
+		names[key] = r.WorkflowName
+	}
+	return names
+}
+
+func (n workflowNames) name(run model.WorkflowRun) string {
+	if name, ok := n[workflowKey(run)]; ok {
+		return name
+	}
+	return run.WorkflowName
+}
+
+// LatestWorkflowNames maps the workflow key of each of runs, as WorkflowKey
+// returns it, to the name of its most recent run.
+func LatestWorkflowNames(runs []model.WorkflowRun) map[string]string {
+	return latestWorkflowNames(runs)
+}
+
+// WorkflowKey identifies the workflow of run across renames, as hotspots
+// group workflows.
+func WorkflowKey(run model.WorkflowRun) string {
+	return workflowKey(run)
+}
+
+// workflowKey identifies a workflow across renames: its numeric ID, then its
+// file path, then its display name.
+func workflowKey(run model.WorkflowRun) string {
+	switch {
+	case run.WorkflowID != 0:
+		return "id:" + strconv.FormatInt(run.WorkflowID, 10)
+	case run.WorkflowPath != "":
+		return "path:" + run.WorkflowPath
+	default:
+		return "name:" + run.WorkflowName
 	}
 }
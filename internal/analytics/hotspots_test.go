@@ -0,0 +1,61 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+func TestCalculateHotspotsKeepsRenamedWorkflowTogether(t *testing.T) {
+	t0 := time.Date(2026, 2, 1, 10, 0, 0, 0, time.UTC)
+	runs := []model.WorkflowRun{
+		{ID: 1, RunAttempt: 1, WorkflowID: 7, WorkflowName: "CI", WorkflowPath: ".github/workflows/ci.yml", Conclusion: "success", CreatedAt: t0},
+		{ID: 2, RunAttempt: 1, WorkflowID: 7, WorkflowName: "Build & Test", WorkflowPath: ".github/workflows/ci.yml", Conclusion: "failure", CreatedAt: t0.Add(24 * time.Hour)},
+		{ID: 3, RunAttempt: 1, WorkflowID: 8, WorkflowName: "Release", WorkflowPath: ".github/workflows/release.yml", Conclusion: "success", CreatedAt: t0},
+	}
+	jobs := []model.Job{
+		{ID: 11, RunID: 1, RunAttempt: 1, Name: "test", Status: "completed", RunnerOS: "Linux", DurationSec: 600},
+		{ID: 21, RunID: 2, RunAttempt: 1, Name: "test", Status: "completed", RunnerOS: "Linux", DurationSec: 600},
+		{ID: 31, RunID: 3, RunAttempt: 1, Name: "publish", Status: "completed", RunnerOS: "Linux", DurationSec: 60},
+	}
+	cfg := pricing.Config{PerMinuteUSD: 0.008}
+
+	byWorkflow := CalculateHotspots(runs, jobs, cfg, HotspotOptions{GroupBy: "workflow"})
+	if len(byWorkflow) != 2 {
+		t.Fatalf("expected renamed workflow folded into one row, got %+v", byWorkflow)
+	}
+	if byWorkflow[0].Name != "Build & Test" || byWorkflow[0].RunCount != 2 || byWorkflow[0].FailRate != 50 {
+		t.Fatalf("expected latest name with both runs, got %+v", byWorkflow[0])
+	}
+
+	byPath := CalculateHotspots(runs, jobs, cfg, HotspotOptions{GroupBy: "path"})
+	if len(byPath) != 2 || byPath[0].Name != ".github/workflows/ci.yml" || byPath[0].GroupType != "path" {
+		t.Fatalf("unexpected path hotspots: %+v", byPath)
+	}
+}
+
+func TestCalculateHotspotsByStep(t *testing.T) {
+	runs := []model.WorkflowRun{{ID: 1, RunAttempt: 1, WorkflowID: 7, WorkflowName: "ci", Conclusion: "success"}}
+	jobs := []model.Job{
+		{
+			ID: 11, RunID: 1, RunAttempt: 1, Name: "build", Status: "completed", RunnerOS: "Linux", DurationSec: 600,
+			Steps: []model.Step{
+				{JobID: 11, RunAttempt: 1, Number: 1, Name: "Set up job", DurationSec: 60},
+				{JobID: 11, RunAttempt: 1, Number: 2, Name: "npm ci", DurationSec: 240},
+				{JobID: 11, RunAttempt: 1, Number: 3, Name: "npm test", DurationSec: 300, Conclusion: "failure"},
+			},
+		},
+	}
+	entries := CalculateHotspots(runs, jobs, pricing.Config{PerMinuteUSD: 0.01}, HotspotOptions{GroupBy: "step"})
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 step rows, got %+v", entries)
+	}
+	if entries[0].Name != "ci / build / npm test" || entries[0].CostUSD != 0.05 || entries[0].FailRate != 100 {
+		t.Fatalf("unexpected top step: %+v", entries[0])
+	}
+	if entries[1].Name != "ci / build / npm ci" || entries[1].CostPct != 40 || entries[1].AvgDuration != 240 {
+		t.Fatalf("unexpected npm ci step: %+v", entries[1])
+	}
+}
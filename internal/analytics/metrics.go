@@ -0,0 +1,226 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/peter941221/CICost/internal/metrics"
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+var registerOnce sync.Once
+
+// RegisterMetrics adds the metrics computed here to the metrics registry.
+// Commands that lint or evaluate policies call it first; repeated calls do
+// nothing.
+func RegisterMetrics() {
+	registerOnce.Do(registerMetrics)
+}
+
+func registerMetrics() {
+	metrics.Register(metrics.Source{
+		Name: "cost",
+		Metrics: []metrics.Metric{
+			{Name: "total_cost_usd", Unit: metrics.UnitUSD, Description: "GitHub-billed cost of the period, net of the free tier"},
+			{Name: "monthly_cost_usd", Unit: metrics.UnitUSD, Description: "same as total_cost_usd, kept for early policy files"},
+			{Name: "linux_cost_usd", Unit: metrics.UnitUSD, Description: "cost of Linux hosted runners before the free tier"},
+			{Name: "windows_cost_usd", Unit: metrics.UnitUSD, Description: "cost of Windows hosted runners before the free tier"},
+			{Name: "macos_cost_usd", Unit: metrics.UnitUSD, Description: "cost of macOS hosted runners before the free tier"},
+			{Name: "macos_share_pct", Unit: metrics.UnitPercent, Description: "macOS share of hosted runner cost before the free tier"},
+		},
+		Compute: costMetrics,
+	})
+	metrics.Register(metrics.Source{
+		Name: "waste",
+		Metrics: []metrics.Metric{
+			{Name: "waste_percentage", Unit: metrics.UnitPercent, Description: "rerun and cancel waste as a share of total spend"},
+			{Name: "rerun_waste_usd", Unit: metrics.UnitUSD, Description: "cost of the attempts that were rerun"},
+			{Name: "cancel_waste_usd", Unit: metrics.UnitUSD, Description: "cost of runs that ended cancelled"},
+			{Name: "fail_rate", Unit: metrics.UnitPercent, Description: "share of runs whose latest attempt failed"},
+			{Name: "total_runs", Unit: metrics.UnitCount, Description: "workflow runs in the period"},
+		},
+		Compute: wasteMetrics,
+	})
+	metrics.Register(metrics.Source{
+		Name: "timing",
+		Metrics: []metrics.Metric{
+			{Name: "p95_job_duration_sec", Unit: metrics.UnitSeconds, Description: "95th percentile duration of completed jobs"},
+			{Name: "avg_queue_time_sec", Unit: metrics.UnitSeconds, Description: "mean wait from a run starting to its first job starting"},
+		},
+		Compute: timingMetrics,
+	})
+	metrics.Register(metrics.Source{
+		Name: "trend",
+		Metrics: []metrics.Metric{
+			{Name: "wow_cost_delta_usd", Unit: metrics.UnitUSD, Description: "cost before the free tier of the last 7 days minus the 7 days before, 0 without that history"},
+			{Name: "wow_cost_delta_pct", Unit: metrics.UnitPercent, Description: "wow_cost_delta_usd in percent of the earlier week, as pct_change"},
+		},
+		Compute: trendMetrics,
+	})
+	metrics.Register(metrics.Source{
+		Name: "free_tier",
+		Metrics: []metrics.Metric{
+			{Name: "free_tier_remaining_min", Unit: metrics.UnitMinutes, Description: "free minutes the account has left in the current billing cycle"},
+		},
+		Compute: freeTierMetrics,
+	})
+}
+
+func costMetrics(in metrics.Input) (map[string]float64, error) {
+	cost, _, _, err := CalculateAccountCost(in.Jobs, in.AccountJobs, in.Pricing, 1.0)
+	if err != nil {
+		return nil, err
+	}
+	out := map[string]float64{
+		"total_cost_usd":   cost.TotalCostUSD,
+		"monthly_cost_usd": cost.TotalCostUSD,
+	}
+	for os, c := range cost.ByOS {
+		switch strings.ToLower(os) {
+		case "linux":
+			out["linux_cost_usd"] += c.CostUSD
+		case "windows":
+			out["windows_cost_usd"] += c.CostUSD
+		case "macos":
+			out["macos_cost_usd"] += c.CostUSD
+			out["macos_share_pct"] += c.Percentage
+		}
+	}
+	return out, nil
+}
+
+func wasteMetrics(in metrics.Input) (map[string]float64, error) {
+	cost, _, _, err := CalculateAccountCost(in.Jobs, in.AccountJobs, in.Pricing, 1.0)
+	if err != nil {
+		return nil, err
+	}
+	waste := CalculateWaste(in.Runs, in.Jobs, in.Pricing, TotalSpendUSD(cost))
+	return map[string]float64{
+		"waste_percentage": waste.WastePercentage,
+		"rerun_waste_usd":  waste.RerunWasteUSD,
+		"cancel_waste_usd": waste.CancelWasteUSD,
+		"fail_rate":        waste.FailRate * 100,
+		"total_runs":       float64(len(in.Runs)),
+	}, nil
+}
+
+func timingMetrics(in metrics.Input) (map[string]float64, error) {
+	var durations []float64
+	firstStart := map[string]time.Time{}
+	for _, j := range in.Jobs {
+		if j.Status != "completed" {
+			continue
+		}
+		durations = append(durations, float64(j.DurationSec))
+		if j.StartedAt.IsZero() {
+			continue
+		}
+		key := runAttemptKey(j.RunID, j.RunAttempt)
+		if t, ok := firstStart[key]; !ok || j.StartedAt.Before(t) {
+			firstStart[key] = j.StartedAt
+		}
+	}
+	queued, waits := 0.0, 0
+	for _, r := range in.Runs {
+		first, ok := firstStart[runAttemptKey(r.ID, r.RunAttempt)]
+		if !ok || r.RunStartedAt.IsZero() {
+			continue
+		}
+		queued += math.Max(0, first.Sub(r.RunStartedAt).Seconds())
+		waits++
+	}
+	out := map[string]float64{"p95_job_duration_sec": percentile(durations, 95)}
+	if waits > 0 {
+		out["avg_queue_time_sec"] = round2(queued / float64(waits))
+	}
+	return out, nil
+}
+
+// percentile is the nearest-rank p-th percentile of v, 0 for no values.
+func percentile(v []float64, p float64) float64 {
+	if len(v) == 0 {
+		return 0
+	}
+	s := append([]float64(nil), v...)
+	sort.Float64s(s)
+	rank := int(math.Ceil(p / 100 * float64(len(s))))
+	if rank < 1 {
+		rank = 1
+	}
+	return s[rank-1]
+}
+
+// TrendLookbackDays is the history, back from End, that the week-over-week
+// metrics compare.
+const TrendLookbackDays = 14
+
+func trendMetrics(in metrics.Input) (map[string]float64, error) {
+	end := in.End
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	weekStart, prevStart := end.AddDate(0, 0, -7), end.AddDate(0, 0, -TrendLookbackDays)
+	jobs := in.History
+	if jobs == nil {
+		jobs = in.Jobs
+		if in.Start.After(prevStart) {
+			// The earlier week is not covered; comparing against it would
+			// read as 100% growth on any spend.
+			return map[string]float64{}, nil
+		}
+	}
+	current, previous := 0.0, 0.0
+	for _, j := range jobs {
+		if j.Status != "completed" || j.StartedAt.Before(prevStart) || !j.StartedAt.Before(end) {
+			continue
+		}
+		quote, err := quoteJob(j, in.Pricing)
+		if err != nil {
+			return nil, err
+		}
+		if j.StartedAt.Before(weekStart) {
+			previous += quote.CostUSD
+		} else {
+			current += quote.CostUSD
+		}
+	}
+	// As pct_change: from nothing, no change is 0% and any spend 100%.
+	pct := 0.0
+	switch {
+	case previous > 0:
+		pct = (current - previous) / previous * 100
+	case current > 0:
+		pct = 100
+	}
+	return map[string]float64{
+		"wow_cost_delta_usd": round2(current - previous),
+		"wow_cost_delta_pct": round2(pct),
+	}, nil
+}
+
+func freeTierMetrics(in metrics.Input) (map[string]float64, error) {
+	end := in.End
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	cycle := pricing.CycleStart(end, in.Pricing.BillingCycleDay)
+	var current []model.Job
+	for _, j := range withJobs(in.AccountJobs, in.Jobs) {
+		if jobCycle(j, in.Pricing.BillingCycleDay).Equal(cycle) {
+			current = append(current, j)
+		}
+	}
+	used, err := HostedMinutes(current, in.Pricing)
+	if err != nil {
+		return nil, err
+	}
+	// AlreadyUsedThisMon also counts repositories that were never scanned.
+	used = math.Max(used, in.Pricing.AlreadyUsedThisMon)
+	return map[string]float64{
+		"free_tier_remaining_min": round2(math.Max(0, in.Pricing.FreeTierPerMonth-used)),
+	}, nil
+}
@@ -0,0 +1,104 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/metrics"
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+func TestRegisteredMetrics(t *testing.T) {
+	RegisterMetrics()
+	end := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+	thisWeek, lastWeek := end.AddDate(0, 0, -3), end.AddDate(0, 0, -10)
+	runs := []model.WorkflowRun{
+		{ID: 1, RunAttempt: 1, Conclusion: "success", RunStartedAt: thisWeek.Add(-30 * time.Second)},
+		{ID: 2, RunAttempt: 1, Conclusion: "success", RunStartedAt: lastWeek.Add(-90 * time.Second)},
+	}
+	jobs := []model.Job{
+		{ID: 11, RunID: 1, RunAttempt: 1, Status: "completed", DurationSec: 600, RunnerOS: "Linux", StartedAt: thisWeek},
+		{ID: 12, RunID: 1, RunAttempt: 1, Status: "completed", DurationSec: 120, RunnerOS: "Linux", StartedAt: thisWeek.Add(10 * time.Minute)},
+		{ID: 21, RunID: 2, RunAttempt: 1, Status: "completed", DurationSec: 60, RunnerOS: "macOS", StartedAt: lastWeek},
+	}
+	cfg := pricing.Config{PerMinuteUSD: 0.008, WindowsMultiplier: 2, MacOSMultiplier: 10, FreeTierPerMonth: 100}
+	got, err := metrics.Compute(metrics.Input{Runs: runs, Jobs: jobs, Pricing: cfg, Start: end.AddDate(0, 0, -30), End: end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]float64{
+		"total_cost_usd":          0, // 22 billable minutes fit in the free tier
+		"linux_cost_usd":          0.1,
+		"macos_cost_usd":          0.8,
+		"windows_cost_usd":        0,
+		"macos_share_pct":         89.29,
+		"p95_job_duration_sec":    600,
+		"avg_queue_time_sec":      60,
+		"wow_cost_delta_usd":      -0.7,
+		"wow_cost_delta_pct":      -88,
+		"free_tier_remaining_min": 78,
+		"total_runs":              2,
+	}
+	for name, v := range want {
+		if got[name] != v {
+			t.Errorf("%s = %v, want %v", name, got[name], v)
+		}
+	}
+	for _, m := range metrics.All() {
+		if _, ok := got[m.Name]; !ok {
+			t.Errorf("registered metric %s was not computed", m.Name)
+		}
+		if m.Description == "" || m.Unit == "" {
+			t.Errorf("metric %s lacks a description or unit", m.Name)
+		}
+	}
+}
+
+func TestFreeTierRemainingCountsTheWholeAccount(t *testing.T) {
+	RegisterMetrics()
+	end := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+	mine := []model.Job{{ID: 1, Repo: "acme/mine", Status: "completed", RunnerOS: "Linux", DurationSec: 300 * 60, StartedAt: end.AddDate(0, 0, -2)}}
+	account := append([]model.Job{
+		// Another repository, and this one before the --days window, in the
+		// same billing cycle.
+		{ID: 2, Repo: "acme/other", Status: "completed", RunnerOS: "Linux", DurationSec: 900 * 60, StartedAt: end.AddDate(0, 0, -5)},
+		{ID: 3, Repo: "acme/mine", Status: "completed", RunnerOS: "Linux", DurationSec: 200 * 60, StartedAt: end.AddDate(0, 0, -15)},
+		// The previous cycle does not count.
+		{ID: 4, Repo: "acme/other", Status: "completed", RunnerOS: "Linux", DurationSec: 500 * 60, StartedAt: end.AddDate(0, 0, -25)},
+	}, mine...)
+	cfg := pricing.Config{PerMinuteUSD: 0.008, FreeTierPerMonth: 2000}
+
+	got, err := metrics.Compute(metrics.Input{Jobs: mine, AccountJobs: account, Pricing: cfg, Start: end.AddDate(0, 0, -7), End: end})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["free_tier_remaining_min"] != 600 {
+		t.Fatalf("expected 2000-900-200-300 = 600 free minutes left, got %v", got["free_tier_remaining_min"])
+	}
+}
+
+func TestWeekOverWeekNeedsTwoWeeksOfHistory(t *testing.T) {
+	RegisterMetrics()
+	end := time.Date(2026, 3, 20, 0, 0, 0, 0, time.UTC)
+	recent := []model.Job{{ID: 1, Status: "completed", RunnerOS: "Linux", DurationSec: 600, StartedAt: end.AddDate(0, 0, -2)}}
+	older := []model.Job{{ID: 2, Status: "completed", RunnerOS: "Linux", DurationSec: 1200, StartedAt: end.AddDate(0, 0, -10)}}
+	cfg := pricing.Config{PerMinuteUSD: 0.01}
+	in := metrics.Input{Jobs: recent, Pricing: cfg, Start: end.AddDate(0, 0, -7), End: end}
+
+	got, err := metrics.Compute(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got["wow_cost_delta_pct"] != 0 || got["wow_cost_delta_usd"] != 0 {
+		t.Fatalf("expected no trend without the earlier week, got %v", got)
+	}
+
+	in.History = append(append([]model.Job{}, older...), recent...)
+	if got, err = metrics.Compute(in); err != nil {
+		t.Fatal(err)
+	}
+	if got["wow_cost_delta_pct"] != -50 || got["wow_cost_delta_usd"] != -0.1 {
+		t.Fatalf("expected the history to give -50%%, got %v", got)
+	}
+}
@@ -0,0 +1,172 @@
+package analytics
+
+import (
+	"math"
+	"sort"
+	"strings"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+// Levels at which BuildRollup applies the free tier.
+const (
+	FreeTierPerOrg        = "org"
+	FreeTierPerEnterprise = "enterprise"
+)
+
+// RollupRepo is the stored data of one repository for BuildRollup.
+type RollupRepo struct {
+	Org  string
+	Repo string
+	Runs []model.WorkflowRun
+	Jobs []model.Job
+}
+
+// BuildRollup groups repositories into an enterprise → org → repo → workflow
+// tree. The free tier is allocated once per org account, or once for the
+// whole enterprise when freeTierLevel is FreeTierPerEnterprise, over
+// accountJobs keyed by org (see CalculateAccountCost); orgs missing from
+// accountJobs use the jobs of their listed repos.
+func BuildRollup(enterprise string, repos []RollupRepo, accountJobs map[string][]model.Job, cfg pricing.Config, freeTierLevel string) (model.RollupNode, error) {
+	root := model.RollupNode{Level: model.RollupEnterprise, Name: enterprise}
+	byOrg := map[string][]RollupRepo{}
+	for _, r := range repos {
+		org := r.Org
+		if org == "" {
+			org, _, _ = strings.Cut(r.Repo, "/")
+		}
+		byOrg[org] = append(byOrg[org], r)
+	}
+	orgJobs := func(org string) []model.Job {
+		var jobs []model.Job
+		for _, r := range byOrg[org] {
+			jobs = append(jobs, r.Jobs...)
+		}
+		return withJobs(accountJobs[org], jobs)
+	}
+
+	perEnterprise := strings.EqualFold(freeTierLevel, FreeTierPerEnterprise)
+	shares := map[int64]FreeTierShare{}
+	if perEnterprise {
+		var all []model.Job
+		for org := range byOrg {
+			all = append(all, orgJobs(org)...)
+		}
+		var err error
+		if shares, err = AllocateFreeTier(all, cfg); err != nil {
+			return model.RollupNode{}, err
+		}
+		root.Account = true
+	}
+	for org, list := range byOrg {
+		if !perEnterprise {
+			orgShares, err := AllocateFreeTier(orgJobs(org), cfg)
+			if err != nil {
+				return model.RollupNode{}, err
+			}
+			for id, s := range orgShares {
+				shares[id] = s
+			}
+		}
+		orgNode := model.RollupNode{Level: model.RollupOrg, Name: org, Account: !perEnterprise}
+		for _, r := range list {
+			repoNode, err := rollupRepo(r, cfg, shares)
+			if err != nil {
+				return model.RollupNode{}, err
+			}
+			addRollupTotals(&orgNode, repoNode)
+			orgNode.Children = append(orgNode.Children, repoNode)
+		}
+		addRollupTotals(&root, orgNode)
+		root.Children = append(root.Children, orgNode)
+	}
+	finishRollup(&root)
+	return root, nil
+}
+
+func rollupRepo(r RollupRepo, cfg pricing.Config, shares map[int64]FreeTierShare) (model.RollupNode, error) {
+	node := model.RollupNode{Level: model.RollupRepo, Name: r.Repo}
+	names := latestWorkflowNames(r.Runs)
+	runByIDAttempt := make(map[string]model.WorkflowRun, len(r.Runs))
+	workflows := map[string]*model.RollupNode{}
+	workflow := func(run model.WorkflowRun) *model.RollupNode {
+		key := workflowKey(run)
+		w := workflows[key]
+		if w == nil {
+			name := names.name(run)
+			if name == "" {
+				name = "unknown"
+			}
+			w = &model.RollupNode{Level: model.RollupWorkflow, Name: name}
+			workflows[key] = w
+		}
+		return w
+	}
+	seenRuns := map[int64]struct{}{}
+	for _, run := range r.Runs {
+		runByIDAttempt[runAttemptKey(run.ID, run.RunAttempt)] = run
+		if _, ok := seenRuns[run.ID]; ok {
+			continue
+		}
+		seenRuns[run.ID] = struct{}{}
+		workflow(run).Runs++
+	}
+	for _, job := range r.Jobs {
+		if strings.TrimSpace(job.Status) != "completed" {
+			continue
+		}
+		w := workflow(runByIDAttempt[runAttemptKey(job.RunID, job.RunAttempt)])
+		quote, err := quoteJob(job, cfg)
+		if err != nil {
+			return model.RollupNode{}, err
+		}
+		w.Minutes += math.Max(float64(job.DurationSec)/60, 0)
+		if job.IsSelfHosted {
+			w.SelfHostedUSD += quote.CostUSD
+			continue
+		}
+		w.BillableMinutes += quote.BillableMinutes
+		w.GrossCostUSD += quote.CostUSD
+		share := shares[job.ID]
+		w.FreeTierMinutes += share.FreeMinutes
+		w.NetCostUSD += share.NetCostUSD
+	}
+	for _, w := range workflows {
+		if w.Runs == 0 && w.Minutes == 0 {
+			continue
+		}
+		addRollupTotals(&node, *w)
+		node.Children = append(node.Children, *w)
+	}
+	return node, nil
+}
+
+func addRollupTotals(dst *model.RollupNode, src model.RollupNode) {
+	dst.Runs += src.Runs
+	dst.Minutes += src.Minutes
+	dst.BillableMinutes += src.BillableMinutes
+	dst.GrossCostUSD += src.GrossCostUSD
+	dst.FreeTierMinutes += src.FreeTierMinutes
+	dst.NetCostUSD += src.NetCostUSD
+	dst.SelfHostedUSD += src.SelfHostedUSD
+}
+
+func finishRollup(n *model.RollupNode) {
+	n.Minutes = round2(n.Minutes)
+	n.BillableMinutes = round2(n.BillableMinutes)
+	n.GrossCostUSD = round2(n.GrossCostUSD)
+	n.FreeTierMinutes = round2(n.FreeTierMinutes)
+	n.NetCostUSD = round2(n.NetCostUSD)
+	n.SelfHostedUSD = round2(n.SelfHostedUSD)
+	for i := range n.Children {
+		finishRollup(&n.Children[i])
+	}
+	sort.SliceStable(n.Children, func(i, j int) bool {
+		a, b := n.Children[i], n.Children[j]
+		if a.GrossCostUSD != b.GrossCostUSD {
+			return a.GrossCostUSD > b.GrossCostUSD
+		}
+		return a.Name < b.Name
+	})
+}
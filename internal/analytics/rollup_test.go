@@ -0,0 +1,77 @@
+package analytics
+
+import (
+	"testing"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+func TestBuildRollupAppliesFreeTierPerAccount(t *testing.T) {
+	repos := []RollupRepo{
+		{
+			Repo: "acme/api",
+			Runs: []model.WorkflowRun{
+				{ID: 1, RunAttempt: 1, WorkflowID: 1, WorkflowName: "ci"},
+				{ID: 2, RunAttempt: 1, WorkflowID: 2, WorkflowName: "deploy"},
+			},
+			Jobs: []model.Job{
+				{ID: 11, RunID: 1, RunAttempt: 1, Status: "completed", RunnerOS: "Linux", DurationSec: 600 * 60},
+				{ID: 21, RunID: 2, RunAttempt: 1, Status: "completed", RunnerOS: "Linux", DurationSec: 400 * 60},
+				{ID: 22, RunID: 2, RunAttempt: 1, Status: "completed", IsSelfHosted: true, RunnerGroup: "gpu", DurationSec: 60},
+			},
+		},
+		{
+			Repo: "acme-labs/ml",
+			Runs: []model.WorkflowRun{{ID: 3, RunAttempt: 1, WorkflowID: 3, WorkflowName: "train"}},
+			Jobs: []model.Job{{ID: 31, RunID: 3, RunAttempt: 1, Status: "completed", RunnerOS: "Linux", DurationSec: 3000 * 60}},
+		},
+	}
+	cfg := pricing.Config{PerMinuteUSD: 0.008, FreeTierPerMonth: 2000}
+	cfg.SelfHosted.Groups = map[string]pricing.SelfHostedRate{"gpu": {HourlyUSD: 6}}
+
+	perOrg, err := BuildRollup("acme-corp", repos, nil, cfg, FreeTierPerOrg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if perOrg.Level != model.RollupEnterprise || len(perOrg.Children) != 2 || perOrg.Runs != 3 {
+		t.Fatalf("unexpected enterprise node: %+v", perOrg)
+	}
+	labs, acme := perOrg.Children[0], perOrg.Children[1]
+	if labs.Name != "acme-labs" || !labs.Account || labs.FreeTierMinutes != 2000 || labs.NetCostUSD != 8 || labs.GrossCostUSD != 24 {
+		t.Fatalf("unexpected acme-labs node: %+v", labs)
+	}
+	if acme.Name != "acme" || acme.FreeTierMinutes != 1000 || acme.NetCostUSD != 0 || acme.GrossCostUSD != 8 {
+		t.Fatalf("expected acme fully covered by its own free tier, got %+v", acme)
+	}
+	if perOrg.GrossCostUSD != 32 || perOrg.NetCostUSD != 8 || perOrg.FreeTierMinutes != 3000 || perOrg.Account {
+		t.Fatalf("expected enterprise subtotals over org accounts, got %+v", perOrg)
+	}
+	api := acme.Children[0]
+	if api.Level != model.RollupRepo || len(api.Children) != 2 || api.Children[0].Name != "ci" || api.Children[0].FreeTierMinutes != 600 {
+		t.Fatalf("unexpected repo node: %+v", api)
+	}
+	if api.SelfHostedUSD <= 0 || api.Children[1].SelfHostedUSD <= 0 {
+		t.Fatalf("expected self-hosted cost kept apart, got %+v", api)
+	}
+
+	perEnterprise, err := BuildRollup("acme-corp", repos, nil, cfg, FreeTierPerEnterprise)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !perEnterprise.Account || perEnterprise.FreeTierMinutes != 2000 || perEnterprise.NetCostUSD != 16 {
+		t.Fatalf("expected one shared free tier, got %+v", perEnterprise)
+	}
+	if perEnterprise.Children[0].NetCostUSD != 16 || perEnterprise.Children[1].NetCostUSD != 0 {
+		t.Fatalf("expected earlier jobs to use the shared free tier first, got %+v", perEnterprise.Children)
+	}
+
+	cfg.FreeTierAllocation = AllocateProRata
+	proRata, err := BuildRollup("acme-corp", repos, nil, cfg, FreeTierPerEnterprise)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if proRata.NetCostUSD != 16 || proRata.Children[0].NetCostUSD != 12 || proRata.Children[1].NetCostUSD != 4 {
+		t.Fatalf("expected net shared pro rata across orgs, got %+v", proRata.Children)
+	}
+}
@@ -0,0 +1,68 @@
+package analytics
+
+import (
+	"math"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+// CalculateStorage prices the artifact and cache storage of one repository
+// over [start, end]. The plan's included storage (cfg.FreeStorageGB) is
+// shared across accountArtifacts, every artifact of the billing account in
+// the window, in proportion to GB-months; a nil accountArtifacts treats
+// artifacts as the whole account. Caches are free up to
+// cfg.Storage.CacheIncludedGB per repository.
+func CalculateStorage(artifacts []model.Artifact, caches []model.ActionsCache, accountArtifacts []model.Artifact, start, end time.Time, cfg pricing.Config) model.StorageCost {
+	var out model.StorageCost
+	for _, a := range artifacts {
+		out.ArtifactGBMonths += artifactGBMonths(a, start, end)
+		out.Artifacts++
+	}
+	for _, c := range caches {
+		out.CacheGBMonths += pricing.GBMonths(c.SizeBytes, c.CreatedAt, c.LastSeenAt, start, end)
+		out.Caches++
+	}
+
+	months := pricing.Months(start, end)
+	free := cfg.FreeStorageGB * months
+	if accountArtifacts != nil {
+		account := 0.0
+		for _, a := range accountArtifacts {
+			account += artifactGBMonths(a, start, end)
+		}
+		if account > out.ArtifactGBMonths {
+			free *= out.ArtifactGBMonths / account
+		}
+	}
+	out.FreeGBMonths = math.Min(free, out.ArtifactGBMonths)
+	out.CacheIncludedGBMonths = math.Min(cfg.Storage.CacheIncludedGB*months, out.CacheGBMonths)
+	out.BillableGBMonths = out.ArtifactGBMonths - out.FreeGBMonths + out.CacheGBMonths - out.CacheIncludedGBMonths
+	out.CostUSD = round2(out.BillableGBMonths * cfg.Storage.GBMonthUSD)
+
+	out.ArtifactGBMonths = round4(out.ArtifactGBMonths)
+	out.CacheGBMonths = round4(out.CacheGBMonths)
+	out.FreeGBMonths = round4(out.FreeGBMonths)
+	out.CacheIncludedGBMonths = round4(out.CacheIncludedGBMonths)
+	out.BillableGBMonths = round4(out.BillableGBMonths)
+	return out
+}
+
+func artifactGBMonths(a model.Artifact, start, end time.Time) float64 {
+	return pricing.GBMonths(a.SizeBytes, a.CreatedAt, ArtifactStoredUntil(a), start, end)
+}
+
+// ArtifactStoredUntil is when a stopped taking storage: when it expires, or
+// when it was removed if that was earlier.
+func ArtifactStoredUntil(a model.Artifact) time.Time {
+	if !a.RemovedAt.IsZero() && a.RemovedAt.Before(a.ExpiresAt) {
+		return a.RemovedAt
+	}
+	return a.ExpiresAt
+}
+
+// round4 keeps GB-months readable; small artifacts are well under 0.01.
+func round4(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
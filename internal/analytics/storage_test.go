@@ -0,0 +1,42 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+func TestCalculateStorage(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(730 * time.Hour)
+	gb := int64(1 << 30)
+	artifacts := []model.Artifact{
+		// 4 GiB for the whole window, 2 GiB for half of it.
+		{ID: 1, Repo: "acme/a", SizeBytes: 4 * gb, CreatedAt: start.AddDate(0, 0, -10), ExpiresAt: end.AddDate(0, 1, 0)},
+		{ID: 2, Repo: "acme/a", SizeBytes: 2 * gb, CreatedAt: start, ExpiresAt: start.Add(365 * time.Hour)},
+	}
+	caches := []model.ActionsCache{{ID: 1, Repo: "acme/a", SizeBytes: 12 * gb, CreatedAt: start, LastSeenAt: end}}
+	cfg := pricing.Config{FreeStorageGB: 1, Storage: pricing.StorageRates{GBMonthUSD: 0.25, CacheIncludedGB: 10}}
+
+	got := CalculateStorage(artifacts, caches, nil, start, end, cfg)
+	if got.ArtifactGBMonths != 5 || got.CacheGBMonths != 12 || got.Artifacts != 2 || got.Caches != 1 {
+		t.Fatalf("unexpected usage: %+v", got)
+	}
+	if got.FreeGBMonths != 1 || got.CacheIncludedGBMonths != 10 || got.BillableGBMonths != 6 || got.CostUSD != 1.5 {
+		t.Fatalf("expected 4 artifact + 2 cache GB-months billed, got %+v", got)
+	}
+
+	other := model.Artifact{ID: 3, Repo: "acme/b", SizeBytes: 5 * gb, CreatedAt: start, ExpiresAt: end}
+	shared := CalculateStorage(artifacts, nil, append([]model.Artifact{other}, artifacts...), start, end, cfg)
+	if shared.FreeGBMonths != 0.5 || shared.CostUSD != 1.13 {
+		t.Fatalf("expected half the included storage for half the account's artifacts, got %+v", shared)
+	}
+
+	// Deleted halfway through, an artifact stops taking storage.
+	removed := model.Artifact{ID: 4, Repo: "acme/a", SizeBytes: 4 * gb, CreatedAt: start, ExpiresAt: end.AddDate(0, 1, 0), RemovedAt: start.Add(365 * time.Hour)}
+	if got := CalculateStorage([]model.Artifact{removed}, nil, nil, start, end, cfg); got.ArtifactGBMonths != 2 {
+		t.Fatalf("expected 2 GB-months until removal, got %+v", got)
+	}
+}
@@ -36,11 +36,11 @@ func CalculateWaste(runs []model.WorkflowRun, jobs []model.Job, cfg pricing.Conf
 	costByRunAttempt := map[string]float64{}
 	minByRunAttempt := map[string]float64{}
 	for _, j := range jobs {
-		if j.IsSelfHosted || j.Status != "completed" {
+		if j.Status != "completed" {
 			continue
 		}
 		key := runAttemptKey(j.RunID, j.RunAttempt)
-		quote, err := pricing.PriceJob(j.DurationSec, j.RunnerOS, j.RunnerName, j.StartedAt, cfg)
+		quote, err := quoteJob(j, cfg)
 		if err != nil {
 			continue
 		}
@@ -0,0 +1,67 @@
+package billing
+
+import (
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	gh "github.com/peter941221/CICost/internal/github"
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+const SourceGitHub = "github"
+
+// SnapshotsFromGitHubUsage sums Actions net amounts per repository, month and
+// canonical SKU. Repository names without an owner are qualified with account.
+func SnapshotsFromGitHubUsage(items []gh.BillingUsageItem, account string, fetchedAt time.Time) []model.BillingSnapshot {
+	type key struct{ repo, period, sku string }
+	totals := map[key]float64{}
+	for _, it := range items {
+		if !strings.EqualFold(strings.TrimSpace(it.Product), "actions") {
+			continue
+		}
+		repo := qualifyRepo(it.Repository, account)
+		if repo == "" || it.Date.IsZero() {
+			continue
+		}
+		totals[key{repo: repo, period: it.Date.Format("2006-01"), sku: pricing.CanonicalSKU(it.SKU)}] += it.NetAmount
+	}
+	out := make([]model.BillingSnapshot, 0, len(totals))
+	for k, v := range totals {
+		out = append(out, model.BillingSnapshot{
+			Repo:          k.repo,
+			Period:        k.period,
+			SKU:           k.sku,
+			ActualCostUSD: round2(v),
+			Source:        SourceGitHub,
+			FetchedAt:     fetchedAt,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Repo != out[j].Repo {
+			return out[i].Repo < out[j].Repo
+		}
+		if out[i].Period != out[j].Period {
+			return out[i].Period < out[j].Period
+		}
+		return out[i].SKU < out[j].SKU
+	})
+	return out
+}
+
+func qualifyRepo(repo, account string) string {
+	r := strings.TrimSpace(repo)
+	if r == "" || strings.Contains(r, "/") {
+		return r
+	}
+	if account == "" {
+		return r
+	}
+	return account + "/" + r
+}
+
+func round2(v float64) float64 {
+	return math.Round(v*100) / 100
+}
@@ -0,0 +1,31 @@
+package billing
+
+import (
+	"testing"
+	"time"
+
+	gh "github.com/peter941221/CICost/internal/github"
+)
+
+func TestSnapshotsFromGitHubUsage(t *testing.T) {
+	feb := time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC)
+	items := []gh.BillingUsageItem{
+		{Date: feb, Product: "Actions", SKU: "Actions Linux", NetAmount: 1.25, Repository: "octo/app"},
+		{Date: feb.AddDate(0, 0, 1), Product: "actions", SKU: "Actions macOS", NetAmount: 2.5, Repository: "app"},
+		{Date: feb, Product: "Packages", NetAmount: 9, Repository: "octo/app"},
+		{Date: feb, Product: "Actions", NetAmount: 3, Repository: "octo/lib"},
+	}
+	snaps := SnapshotsFromGitHubUsage(items, "octo", time.Now().UTC())
+	if len(snaps) != 3 {
+		t.Fatalf("expected 3 snapshots, got %d", len(snaps))
+	}
+	if snaps[0].Repo != "octo/app" || snaps[0].Period != "2026-02" || snaps[0].SKU != "linux" || snaps[0].ActualCostUSD != 1.25 || snaps[0].Source != SourceGitHub {
+		t.Fatalf("unexpected app linux snapshot: %+v", snaps[0])
+	}
+	if snaps[1].Repo != "octo/app" || snaps[1].SKU != "macos" || snaps[1].ActualCostUSD != 2.5 {
+		t.Fatalf("unexpected app macos snapshot: %+v", snaps[1])
+	}
+	if snaps[2].Repo != "octo/lib" || snaps[2].SKU != "" || snaps[2].ActualCostUSD != 3 {
+		t.Fatalf("unexpected lib snapshot: %+v", snaps[2])
+	}
+}
@@ -5,19 +5,45 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"sort"
 	"strconv"
 	"strings"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
 )
 
+const SourceCSV = "csv"
+
+// LoadActualFromCSV returns the billed total for repo and period, summing
+// per-SKU rows when the file has them.
 func LoadActualFromCSV(path, repo, period string) (float64, error) {
-	f, err := os.Open(path)
+	rows, err := LoadActualRowsFromCSV(path, repo, period)
 	if err != nil {
 		return 0, err
 	}
+	total := 0.0
+	for _, row := range rows {
+		total += row.ActualCostUSD
+	}
+	return round2(total), nil
+}
+
+// LoadActualRowsFromCSV reads repo,period,actual_cost_usd[,sku] rows matching
+// repo and period. Rows without a SKU are kept under the empty SKU; SKU labels
+// are normalised with pricing.CanonicalSKU and summed. GitHub usage report
+// exports are detected by their header and parsed with LoadUsageReport rules.
+func LoadActualRowsFromCSV(path, repo, period string) ([]model.BillingSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
 	defer f.Close()
 
 	r := csv.NewReader(f)
 	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	totals := map[string]float64{}
 	line := 0
 	for {
 		rec, err := r.Read()
@@ -25,9 +51,12 @@ func LoadActualFromCSV(path, repo, period string) (float64, error) {
 			break
 		}
 		if err != nil {
-			return 0, err
+			return nil, err
 		}
 		line++
+		if line == 1 && IsUsageReportHeader(rec) {
+			return usageReportRows(r, rec, path, repo, period)
+		}
 		if len(rec) < 3 {
 			continue
 		}
@@ -43,9 +72,44 @@ func LoadActualFromCSV(path, repo, period string) (float64, error) {
 		}
 		fv, err := strconv.ParseFloat(costVal, 64)
 		if err != nil {
-			return 0, fmt.Errorf("invalid cost value at line %d: %w", line, err)
+			return nil, fmt.Errorf("invalid cost value at line %d: %w", line, err)
 		}
-		return fv, nil
+		sku := ""
+		if len(rec) > 3 {
+			sku = pricing.CanonicalSKU(rec[3])
+		}
+		totals[sku] += fv
+	}
+	if len(totals) == 0 {
+		return nil, fmt.Errorf("no billing row matched repo=%s period=%s in %s", repo, period, path)
+	}
+	out := make([]model.BillingSnapshot, 0, len(totals))
+	for sku, v := range totals {
+		out = append(out, model.BillingSnapshot{
+			Repo:          repo,
+			Period:        period,
+			SKU:           sku,
+			ActualCostUSD: round2(v),
+			Source:        SourceCSV,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].SKU < out[j].SKU })
+	return out, nil
+}
+
+func usageReportRows(r *csv.Reader, header []string, path, repo, period string) ([]model.BillingSnapshot, error) {
+	all, err := parseUsageReport(r, header)
+	if err != nil {
+		return nil, err
+	}
+	out := []model.BillingSnapshot{}
+	for _, snap := range all {
+		if snap.Repo == repo && snap.Period == period {
+			out = append(out, snap)
+		}
+	}
+	if len(out) == 0 {
+		return nil, fmt.Errorf("no usage report row matched repo=%s period=%s in %s", repo, period, path)
 	}
-	return 0, fmt.Errorf("no billing row matched repo=%s period=%s in %s", repo, period, path)
+	return out, nil
 }
@@ -0,0 +1,34 @@
+package billing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadActualRowsFromCSVPerSKU(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "billing.csv")
+	content := "repo,period,actual_cost_usd,sku\n" +
+		"owner/repo,2026-02,10,Actions Linux\n" +
+		"owner/repo,2026-02,20,Actions macOS\n" +
+		"owner/repo,2026-02,6,actions_macos\n" +
+		"owner/repo,2026-03,99,Actions Linux\n" +
+		"owner/repo,2026-02,1.5\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := LoadActualRowsFromCSV(path, "owner/repo", "2026-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 3 || rows[0].SKU != "" || rows[1].SKU != "linux" || rows[2].SKU != "macos" || rows[2].ActualCostUSD != 26 {
+		t.Fatalf("unexpected rows: %+v", rows)
+	}
+	total, err := LoadActualFromCSV(path, "owner/repo", "2026-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 37.5 {
+		t.Fatalf("expected total 37.5, got %.2f", total)
+	}
+}
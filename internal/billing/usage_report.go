@@ -0,0 +1,235 @@
+package billing
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+const SourceUsageReport = "usage_report"
+
+// usageReportColumns maps the fields we read to the header names GitHub has
+// used for them across the classic and enhanced usage report exports.
+var usageReportColumns = map[string][]string{
+	"date":       {"date", "usage at"},
+	"product":    {"product"},
+	"sku":        {"sku"},
+	"quantity":   {"quantity"},
+	"price":      {"price per unit", "applied cost per quantity"},
+	"multiplier": {"multiplier"},
+	"owner":      {"owner", "organization"},
+	"repo":       {"repository", "repository slug"},
+	"workflow":   {"workflow path", "actions workflow", "workflow"},
+	"net":        {"net amount"},
+}
+
+var usageDateLayouts = []string{
+	time.RFC3339,
+	"2006-01-02",
+	"2006-01-02 15:04:05",
+	"01/02/2006",
+	"1/2/2006",
+}
+
+// IsUsageReportHeader reports whether header is the first row of GitHub's
+// downloadable Actions usage report rather than a repo,period,cost file.
+func IsUsageReportHeader(header []string) bool {
+	idx := usageReportIndex(header)
+	return hasIndex(idx, "date") && hasIndex(idx, "product") && hasIndex(idx, "sku") && hasIndex(idx, "repo")
+}
+
+// IsUsageReportFile reports whether the CSV at path starts with a usage report header.
+func IsUsageReportFile(path string) (bool, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return IsUsageReportHeader(header), nil
+}
+
+// LoadUsageReport parses GitHub's usage report CSV and returns Actions spend
+// aggregated per repository, month, canonical SKU and workflow path.
+func LoadUsageReport(path string) ([]model.BillingSnapshot, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.TrimLeadingSpace = true
+	r.FieldsPerRecord = -1
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("read usage report header: %w", err)
+	}
+	if !IsUsageReportHeader(header) {
+		return nil, fmt.Errorf("%s is not a GitHub usage report (missing date/product/sku/repository columns)", path)
+	}
+	return parseUsageReport(r, header)
+}
+
+func parseUsageReport(r *csv.Reader, header []string) ([]model.BillingSnapshot, error) {
+	idx := usageReportIndex(header)
+	if !hasIndex(idx, "net") && (!hasIndex(idx, "price") || !hasIndex(idx, "quantity")) {
+		return nil, fmt.Errorf("usage report needs a net amount column or quantity and price per unit columns")
+	}
+	field := func(rec []string, name string) string {
+		i, ok := idx[name]
+		if !ok || i >= len(rec) {
+			return ""
+		}
+		return strings.TrimSpace(rec[i])
+	}
+
+	type key struct{ repo, period, sku, workflow string }
+	totals := map[key]float64{}
+	line := 1
+	for {
+		rec, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		line++
+		if !strings.EqualFold(field(rec, "product"), "actions") {
+			continue
+		}
+		repo := qualifyRepo(field(rec, "repo"), field(rec, "owner"))
+		date := parseUsageDate(field(rec, "date"))
+		if repo == "" || date.IsZero() {
+			continue
+		}
+		amount, err := usageAmount(field(rec, "net"), field(rec, "quantity"), field(rec, "price"), field(rec, "multiplier"))
+		if err != nil {
+			return nil, fmt.Errorf("invalid usage report row at line %d: %w", line, err)
+		}
+		k := key{
+			repo:     repo,
+			period:   date.Format("2006-01"),
+			sku:      pricing.CanonicalSKU(field(rec, "sku")),
+			workflow: field(rec, "workflow"),
+		}
+		totals[k] += amount
+	}
+
+	out := make([]model.BillingSnapshot, 0, len(totals))
+	for k, v := range totals {
+		out = append(out, model.BillingSnapshot{
+			Repo:          k.repo,
+			Period:        k.period,
+			SKU:           k.sku,
+			Workflow:      k.workflow,
+			ActualCostUSD: round2(v),
+			Source:        SourceUsageReport,
+		})
+	}
+	sort.Slice(out, func(i, j int) bool {
+		a, b := out[i], out[j]
+		if a.Repo != b.Repo {
+			return a.Repo < b.Repo
+		}
+		if a.Period != b.Period {
+			return a.Period < b.Period
+		}
+		if a.SKU != b.SKU {
+			return a.SKU < b.SKU
+		}
+		return a.Workflow < b.Workflow
+	})
+	return out, nil
+}
+
+// usageAmount prefers the report's net amount; classic reports only carry
+// quantity, the base per-minute price and the OS multiplier.
+func usageAmount(net, quantity, price, multiplier string) (float64, error) {
+	if net != "" {
+		return parseMoney(net)
+	}
+	q, err := parseMoney(quantity)
+	if err != nil {
+		return 0, fmt.Errorf("quantity: %w", err)
+	}
+	p, err := parseMoney(price)
+	if err != nil {
+		return 0, fmt.Errorf("price per unit: %w", err)
+	}
+	m := 1.0
+	if multiplier != "" {
+		if m, err = parseMoney(multiplier); err != nil {
+			return 0, fmt.Errorf("multiplier: %w", err)
+		}
+	}
+	return q * p * m, nil
+}
+
+func parseMoney(v string) (float64, error) {
+	v = strings.NewReplacer("$", "", ",", "").Replace(strings.TrimSpace(v))
+	if v == "" {
+		return 0, nil
+	}
+	return strconv.ParseFloat(v, 64)
+}
+
+func parseUsageDate(v string) time.Time {
+	for _, layout := range usageDateLayouts {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t.UTC()
+		}
+	}
+	return time.Time{}
+}
+
+func usageReportIndex(header []string) map[string]int {
+	idx := map[string]int{}
+	for i, h := range header {
+		name := normalizeHeader(h)
+		for field, aliases := range usageReportColumns {
+			if _, seen := idx[field]; seen {
+				continue
+			}
+			for _, alias := range aliases {
+				if name == alias {
+					idx[field] = i
+				}
+			}
+		}
+	}
+	return idx
+}
+
+func hasIndex(idx map[string]int, name string) bool {
+	_, ok := idx[name]
+	return ok
+}
+
+// normalizeHeader lowercases a column name and drops unit suffixes, BOMs and
+// underscores so "Price Per Unit ($)" and "price_per_unit" compare equal.
+func normalizeHeader(h string) string {
+	h = strings.TrimPrefix(strings.TrimSpace(h), "\ufeff")
+	h = strings.ToLower(strings.TrimSpace(strings.TrimSuffix(h, "($)")))
+	h = strings.ReplaceAll(h, "_", " ")
+	return strings.Join(strings.Fields(h), " ")
+}
@@ -0,0 +1,65 @@
+package billing
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadUsageReportClassicExport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.csv")
+	content := "Date,Product,SKU,Quantity,Unit Type,Price Per Unit ($),Multiplier,Owner,Repository Slug,Username,Actions Workflow,Notes\n" +
+		"2026-02-01,Actions,Compute - UBUNTU,100,minute,0.008,1.0,octo,app,dev,.github/workflows/ci.yml,\n" +
+		"2026-02-02,Actions,Compute - UBUNTU,50,minute,0.008,1.0,octo,app,dev,.github/workflows/ci.yml,\n" +
+		"2026-02-02,Actions,Compute - MACOS,10,minute,0.008,10.0,octo,app,dev,.github/workflows/release.yml,\n" +
+		"2026-02-02,Shared Storage,Shared Storage,3,gb,0.008,1.0,octo,app,dev,,\n" +
+		"2026-03-01,Actions,Compute - UBUNTU,20,minute,0.008,1.0,octo,lib,dev,.github/workflows/ci.yml,\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	ok, err := IsUsageReportFile(path)
+	if err != nil || !ok {
+		t.Fatalf("expected usage report header detected, ok=%v err=%v", ok, err)
+	}
+	snaps, err := LoadUsageReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 3 {
+		t.Fatalf("expected 3 aggregated rows, got %+v", snaps)
+	}
+	if s := snaps[0]; s.Repo != "octo/app" || s.Period != "2026-02" || s.SKU != "linux" || s.Workflow != ".github/workflows/ci.yml" || s.ActualCostUSD != 1.2 || s.Source != SourceUsageReport {
+		t.Fatalf("unexpected linux row: %+v", s)
+	}
+	if s := snaps[1]; s.SKU != "macos" || s.Workflow != ".github/workflows/release.yml" || s.ActualCostUSD != 0.8 {
+		t.Fatalf("unexpected macos row: %+v", s)
+	}
+	if s := snaps[2]; s.Repo != "octo/lib" || s.Period != "2026-03" {
+		t.Fatalf("unexpected lib row: %+v", s)
+	}
+
+	total, err := LoadActualFromCSV(path, "octo/app", "2026-02")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != 2 {
+		t.Fatalf("expected usage report total 2, got %.2f", total)
+	}
+}
+
+func TestLoadUsageReportPrefersNetAmount(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "usage.csv")
+	content := "date,product,sku,quantity,unit_type,applied_cost_per_quantity,gross_amount,discount_amount,net_amount,organization,repository,workflow_path\n" +
+		"2026-02-01T00:00:00Z,actions,actions_linux,1000,minutes,0.008,8,8,0,octo,octo/app,.github/workflows/ci.yml\n" +
+		"2026-02-05T00:00:00Z,actions,actions_linux,500,minutes,0.008,4,0,4,octo,octo/app,.github/workflows/ci.yml\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	snaps, err := LoadUsageReport(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snaps) != 1 || snaps[0].ActualCostUSD != 4 || snaps[0].SKU != "linux" {
+		t.Fatalf("expected net amount aggregated, got %+v", snaps)
+	}
+}
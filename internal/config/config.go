@@ -31,20 +31,64 @@ type Config struct {
 	FreeTier struct {
 		Plan            string  `yaml:"plan"`
 		MinutesPerMonth float64 `yaml:"minutes_per_month"`
+		StorageGB       float64 `yaml:"storage_gb"`
+		BillingCycleDay int     `yaml:"billing_cycle_day"`
+		Allocation      string  `yaml:"allocation"`
 	} `yaml:"free_tier"`
 	Budget struct {
-		Monthly    float64 `yaml:"monthly"`
-		Weekly     float64 `yaml:"weekly"`
-		Notify     string  `yaml:"notify"`
-		WebhookURL string  `yaml:"webhook_url"`
+		Monthly          float64 `yaml:"monthly"`
+		Weekly           float64 `yaml:"weekly"`
+		Notify           string  `yaml:"notify"`
+		WebhookURL       string  `yaml:"webhook_url"`
+		RenotifyInterval string  `yaml:"renotify_interval"`
+		Notifier         string  `yaml:"notifier"`
 	} `yaml:"budget"`
-	Output struct {
+	Budgets   []BudgetConfig   `yaml:"budgets"`
+	Notifiers []NotifierConfig `yaml:"notifiers"`
+	Output    struct {
 		Format string `yaml:"format"`
 		Color  string `yaml:"color"`
 	} `yaml:"output"`
 	Ignore struct {
 		Workflows []string `yaml:"workflows"`
 	} `yaml:"ignore"`
+	Enterprise struct {
+		Name          string   `yaml:"name"`
+		Orgs          []string `yaml:"orgs"`
+		FreeTierLevel string   `yaml:"free_tier_level"`
+	} `yaml:"enterprise"`
+}
+
+// BudgetConfig is one entry of the budgets: list. Empty scope fields match
+// everything; repo, workflow and branch accept globs such as "org/*" or
+// "release/**", where * and ** also match /.
+type BudgetConfig struct {
+	Name       string    `yaml:"name"`
+	Period     string    `yaml:"period"`
+	AmountUSD  float64   `yaml:"amount_usd"`
+	Thresholds []float64 `yaml:"thresholds"`
+	Scope      struct {
+		Repo     string `yaml:"repo"`
+		Org      string `yaml:"org"`
+		Workflow string `yaml:"workflow"`
+		RunnerOS string `yaml:"runner_os"`
+		Branch   string `yaml:"branch"`
+	} `yaml:"scope"`
+}
+
+// NotifierConfig is one entry of the notifiers: list, selected by name with
+// --notifier. Type is webhook (the default), slack, teams or template; url,
+// secret and header values may reference environment variables.
+type NotifierConfig struct {
+	Name            string            `yaml:"name"`
+	Type            string            `yaml:"type"`
+	URL             string            `yaml:"url"`
+	Template        string            `yaml:"template"`
+	ContentType     string            `yaml:"content_type"`
+	Headers         map[string]string `yaml:"headers"`
+	Secret          string            `yaml:"secret"`
+	SignatureHeader string            `yaml:"signature_header"`
+	Retries         int               `yaml:"retries"`
 }
 
 var ErrNoHome = errors.New("unable to resolve user home dir")
@@ -60,12 +104,14 @@ func Default() Config {
 	c.Pricing.MacOSMultiplier = 10
 	c.Pricing.Currency = "USD"
 	c.FreeTier.Plan = "free"
-	c.FreeTier.MinutesPerMonth = 2000
+	c.FreeTier.BillingCycleDay = 1
+	c.FreeTier.Allocation = "chronological"
 	c.Budget.Monthly = 100
 	c.Budget.Weekly = 0
 	c.Budget.Notify = "stdout"
 	c.Output.Format = "table"
 	c.Output.Color = "auto"
+	c.Enterprise.FreeTierLevel = "org"
 	return c
 }
 
@@ -183,6 +229,15 @@ func merge(dst *Config, src Config) {
 	if src.FreeTier.MinutesPerMonth > 0 {
 		dst.FreeTier.MinutesPerMonth = src.FreeTier.MinutesPerMonth
 	}
+	if src.FreeTier.StorageGB > 0 {
+		dst.FreeTier.StorageGB = src.FreeTier.StorageGB
+	}
+	if src.FreeTier.BillingCycleDay > 0 {
+		dst.FreeTier.BillingCycleDay = src.FreeTier.BillingCycleDay
+	}
+	if src.FreeTier.Allocation != "" {
+		dst.FreeTier.Allocation = src.FreeTier.Allocation
+	}
 	if src.Budget.Monthly > 0 {
 		dst.Budget.Monthly = src.Budget.Monthly
 	}
@@ -195,6 +250,18 @@ func merge(dst *Config, src Config) {
 	if src.Budget.WebhookURL != "" {
 		dst.Budget.WebhookURL = src.Budget.WebhookURL
 	}
+	if src.Budget.RenotifyInterval != "" {
+		dst.Budget.RenotifyInterval = src.Budget.RenotifyInterval
+	}
+	if src.Budget.Notifier != "" {
+		dst.Budget.Notifier = src.Budget.Notifier
+	}
+	if len(src.Budgets) > 0 {
+		dst.Budgets = src.Budgets
+	}
+	if len(src.Notifiers) > 0 {
+		dst.Notifiers = src.Notifiers
+	}
 	if src.Output.Format != "" {
 		dst.Output.Format = src.Output.Format
 	}
@@ -204,6 +271,15 @@ func merge(dst *Config, src Config) {
 	if len(src.Ignore.Workflows) > 0 {
 		dst.Ignore.Workflows = src.Ignore.Workflows
 	}
+	if src.Enterprise.Name != "" {
+		dst.Enterprise.Name = src.Enterprise.Name
+	}
+	if len(src.Enterprise.Orgs) > 0 {
+		dst.Enterprise.Orgs = src.Enterprise.Orgs
+	}
+	if src.Enterprise.FreeTierLevel != "" {
+		dst.Enterprise.FreeTierLevel = src.Enterprise.FreeTierLevel
+	}
 }
 
 func mergeEnv(cfg *Config) {
@@ -0,0 +1,105 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+type billingUsageResponse struct {
+	UsageItems []billingUsagePayload `json:"usageItems"`
+}
+
+type billingUsagePayload struct {
+	Date             string  `json:"date"`
+	Product          string  `json:"product"`
+	SKU              string  `json:"sku"`
+	Quantity         float64 `json:"quantity"`
+	UnitType         string  `json:"unitType"`
+	PricePerUnit     float64 `json:"pricePerUnit"`
+	GrossAmount      float64 `json:"grossAmount"`
+	DiscountAmount   float64 `json:"discountAmount"`
+	NetAmount        float64 `json:"netAmount"`
+	OrganizationName string  `json:"organizationName"`
+	RepositoryName   string  `json:"repositoryName"`
+}
+
+// BillingUsageItem is one row of the enhanced billing usage report.
+type BillingUsageItem struct {
+	Date           time.Time
+	Product        string
+	SKU            string
+	Quantity       float64
+	UnitType       string
+	PricePerUnit   float64
+	GrossAmount    float64
+	DiscountAmount float64
+	NetAmount      float64
+	Organization   string
+	Repository     string
+}
+
+// GetBillingUsage fetches the enhanced billing usage report of an organization
+// for one month, falling back to the personal account endpoint when account
+// is not an organization.
+func (c *Client) GetBillingUsage(ctx context.Context, account string, year int, month time.Month) ([]BillingUsageItem, int, error) {
+	apiCalls := 0
+	var out []BillingUsageItem
+	for _, scope := range []string{"organizations", "users"} {
+		u, err := url.Parse(fmt.Sprintf("%s/%s/%s/settings/billing/usage", c.BaseURL, scope, account))
+		if err != nil {
+			return nil, apiCalls, err
+		}
+		q := u.Query()
+		q.Set("year", strconv.Itoa(year))
+		q.Set("month", strconv.Itoa(int(month)))
+		u.RawQuery = q.Encode()
+
+		req, err := c.newRequest(ctx, "GET", u.String())
+		if err != nil {
+			return nil, apiCalls, err
+		}
+		var payload billingUsageResponse
+		_, err = c.doJSON(req, &payload)
+		apiCalls++
+		if err != nil {
+			var apiErr APIError
+			if scope == "organizations" && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotFound {
+				continue
+			}
+			return nil, apiCalls, err
+		}
+		for _, p := range payload.UsageItems {
+			out = append(out, BillingUsageItem{
+				Date:           parseBillingDate(p.Date),
+				Product:        p.Product,
+				SKU:            p.SKU,
+				Quantity:       p.Quantity,
+				UnitType:       p.UnitType,
+				PricePerUnit:   p.PricePerUnit,
+				GrossAmount:    p.GrossAmount,
+				DiscountAmount: p.DiscountAmount,
+				NetAmount:      p.NetAmount,
+				Organization:   p.OrganizationName,
+				Repository:     p.RepositoryName,
+			})
+		}
+		return out, apiCalls, nil
+	}
+	return out, apiCalls, nil
+}
+
+func parseBillingDate(v string) time.Time {
+	if t := parseTime(v); !t.IsZero() {
+		return t
+	}
+	t, err := time.Parse("2006-01-02", v)
+	if err != nil {
+		return time.Time{}
+	}
+	return t.UTC()
+}
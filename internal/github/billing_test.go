@@ -0,0 +1,44 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestGetBillingUsageFallsBackToUserAccount(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/organizations/octo/settings/billing/usage":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case "/users/octo/settings/billing/usage":
+			if r.URL.Query().Get("year") != "2026" || r.URL.Query().Get("month") != "2" {
+				t.Errorf("unexpected query: %s", r.URL.RawQuery)
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			_, _ = w.Write([]byte(`{"usageItems":[{"date":"2026-02-03T00:00:00Z","product":"Actions","sku":"Actions Linux","quantity":120,"unitType":"minutes","pricePerUnit":0.008,"grossAmount":0.96,"discountAmount":0.16,"netAmount":0.8,"repositoryName":"octo/app"}]}`))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client()}
+	items, calls, err := c.GetBillingUsage(context.Background(), "octo", 2026, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 api calls, got %d", calls)
+	}
+	if len(items) != 1 {
+		t.Fatalf("expected 1 usage item, got %d", len(items))
+	}
+	if items[0].NetAmount != 0.8 || items[0].Repository != "octo/app" || items[0].Date.IsZero() {
+		t.Fatalf("unexpected usage item: %+v", items[0])
+	}
+}
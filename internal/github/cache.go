@@ -0,0 +1,152 @@
+package github
+
+import (
+	"bytes"
+	"io"
+	"net/http"
+	"sync"
+)
+
+// CacheEntry is a stored response body together with the validators GitHub
+// returned for it. Link is kept so paginated listings can be walked from
+// cached pages.
+type CacheEntry struct {
+	ETag         string
+	LastModified string
+	Link         string
+	Body         []byte
+}
+
+// Cache persists responses by request URL so later requests can be made
+// conditional. A 304 reply is served from the cached body.
+type Cache interface {
+	GetResponse(url string) (CacheEntry, bool, error)
+	PutResponse(url string, entry CacheEntry) error
+}
+
+// CacheStats counts conditional request outcomes. It is safe for concurrent use.
+type CacheStats struct {
+	mu     sync.Mutex
+	hits   int
+	misses int
+}
+
+// CacheSummary is a point-in-time copy of CacheStats.
+type CacheSummary struct {
+	Hits   int
+	Misses int
+}
+
+// HitRatio is the share of cacheable requests answered with a 304.
+func (s CacheSummary) HitRatio() float64 {
+	total := s.Hits + s.Misses
+	if total == 0 {
+		return 0
+	}
+	return float64(s.Hits) / float64(total)
+}
+
+func (s *CacheStats) add(hit bool) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if hit {
+		s.hits++
+	} else {
+		s.misses++
+	}
+}
+
+func (s *CacheStats) Summary() CacheSummary {
+	if s == nil {
+		return CacheSummary{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return CacheSummary{Hits: s.hits, Misses: s.misses}
+}
+
+// cacheKey is the URL a response to req is cached under. The created
+// filter of run listings moves with every scan window, so it is left out:
+// GitHub's ETag is of the body, so a 304 to any window still means the
+// cached body is the current one.
+func cacheKey(req *http.Request) string {
+	q := req.URL.Query()
+	if !q.Has("created") {
+		return req.URL.String()
+	}
+	q.Del("created")
+	u := *req.URL
+	u.RawQuery = q.Encode()
+	return u.String()
+}
+
+// setConditional adds validators from a cached response to req.
+func (c *Client) setConditional(req *http.Request) error {
+	if c.Cache == nil || req.Method != http.MethodGet {
+		return nil
+	}
+	entry, ok, err := c.Cache.GetResponse(cacheKey(req))
+	if err != nil || !ok {
+		return err
+	}
+	if entry.ETag != "" {
+		req.Header.Set("If-None-Match", entry.ETag)
+	}
+	if entry.LastModified != "" {
+		req.Header.Set("If-Modified-Since", entry.LastModified)
+	}
+	return nil
+}
+
+// fromCache turns a 304 into the cached response it refers to.
+func (c *Client) fromCache(req *http.Request, resp *http.Response) (*http.Response, error) {
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+	entry, ok, err := c.Cache.GetResponse(cacheKey(req))
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, APIError{StatusCode: resp.StatusCode, Message: "not modified but no cached response"}
+	}
+	c.CacheStats.add(true)
+	resp.StatusCode = http.StatusOK
+	resp.Body = io.NopCloser(bytes.NewReader(entry.Body))
+	if resp.Header == nil {
+		resp.Header = http.Header{}
+	}
+	if resp.Header.Get("Link") == "" && entry.Link != "" {
+		resp.Header.Set("Link", entry.Link)
+	}
+	return resp, nil
+}
+
+// storeResponse saves a 200 response that carries validators and returns it
+// with a re-readable body.
+func (c *Client) storeResponse(req *http.Request, resp *http.Response) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		return resp, nil
+	}
+	c.CacheStats.add(false)
+	etag := resp.Header.Get("ETag")
+	lastModified := resp.Header.Get("Last-Modified")
+	if etag == "" && lastModified == "" {
+		return resp, nil
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	err = c.Cache.PutResponse(cacheKey(req), CacheEntry{
+		ETag:         etag,
+		LastModified: lastModified,
+		Link:         resp.Header.Get("Link"),
+		Body:         body,
+	})
+	return resp, err
+}
@@ -0,0 +1,116 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type memCache map[string]CacheEntry
+
+func (m memCache) GetResponse(url string) (CacheEntry, bool, error) {
+	e, ok := m[url]
+	return e, ok, nil
+}
+
+func (m memCache) PutResponse(url string, e CacheEntry) error {
+	m[url] = e
+	return nil
+}
+
+func TestConditionalRequestsServeNotModifiedFromCache(t *testing.T) {
+	var full int32
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		etag := `"page-` + r.URL.Query().Get("page") + `"`
+		if r.Header.Get("If-None-Match") == etag {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		w.Header().Set("ETag", etag)
+		if r.URL.Query().Get("page") == "" {
+			w.Header().Set("Link", `<`+srv.URL+`/repos/o/r/actions/runs?page=2>; rel="next"`)
+			_, _ = w.Write([]byte(`{"workflow_runs":[{"id":1,"name":"ci","created_at":"2026-02-01T00:00:00Z"}]}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"workflow_runs":[{"id":2,"name":"ci","created_at":"2026-01-31T00:00:00Z"}]}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("CICOST_GITHUB_API_BASE_URL", srv.URL)
+	cache := memCache{}
+	for i := 0; i < 2; i++ {
+		c := NewClient("")
+		c.Cache = cache
+		runs, calls, err := c.ListWorkflowRuns(context.Background(), "o", "r", time.Time{})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(runs) != 2 || calls != 2 {
+			t.Fatalf("scan %d: expected 2 runs over 2 pages, got %d runs %d calls", i, len(runs), calls)
+		}
+		sum := c.CacheStats.Summary()
+		if i == 0 && (sum.Hits != 0 || sum.Misses != 2) {
+			t.Fatalf("first scan should miss, got %+v", sum)
+		}
+		if i == 1 && (sum.Hits != 2 || sum.Misses != 0 || sum.HitRatio() != 1) {
+			t.Fatalf("second scan should be served from cache, got %+v", sum)
+		}
+	}
+	if full != 2 {
+		t.Fatalf("expected full bodies only on first scan, got %d", full)
+	}
+}
+
+func TestRunListingCacheKeyIgnoresCreatedFilter(t *testing.T) {
+	var full int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"runs"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		atomic.AddInt32(&full, 1)
+		w.Header().Set("ETag", `"runs"`)
+		_, _ = w.Write([]byte(`{"workflow_runs":[{"id":1,"name":"ci","created_at":"2026-02-01T00:00:00Z"}]}`))
+	}))
+	defer srv.Close()
+
+	t.Setenv("CICOST_GITHUB_API_BASE_URL", srv.URL)
+	cache := memCache{}
+	// Each scan lists from a later day, as a scan of the last N days does.
+	for i, since := range []time.Time{time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)} {
+		c := NewClient("")
+		c.Cache = cache
+		if _, _, err := c.ListWorkflowRuns(context.Background(), "o", "r", since); err != nil {
+			t.Fatal(err)
+		}
+		if sum := c.CacheStats.Summary(); i == 1 && sum.Hits != 1 {
+			t.Fatalf("expected the later window to be served from cache, got %+v", sum)
+		}
+	}
+	if full != 1 || len(cache) != 1 {
+		t.Fatalf("expected one full response and one cache entry, got %d and %d", full, len(cache))
+	}
+}
+
+func TestNotModifiedWithoutCachedBodyIsAnError(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotModified)
+	}))
+	defer srv.Close()
+
+	c := NewClient("")
+	c.Cache = memCache{}
+	req, err := c.newRequest(context.Background(), http.MethodGet, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload map[string]any
+	if _, err := c.doJSON(req, &payload); err == nil {
+		t.Fatal("expected error for 304 without a cached response")
+	}
+}
@@ -7,14 +7,19 @@ import (
 	"io"
 	"net/http"
 	"os"
-	"strconv"
-	"time"
 )
 
 type Client struct {
 	BaseURL    string
 	HTTPClient *http.Client
 	Token      string
+	// Throttle collects retry and rate-limit waits when HTTPClient uses a
+	// RetryTransport; it is nil otherwise.
+	Throttle *ThrottleStats
+	// Cache, when set, makes GET requests conditional on the stored ETag or
+	// Last-Modified and serves 304 replies from it. CacheStats counts them.
+	Cache      Cache
+	CacheStats *CacheStats
 }
 
 func NewClient(token string) *Client {
@@ -22,12 +27,15 @@ func NewClient(token string) *Client {
 	if baseURL == "" {
 		baseURL = "https://api.github.com"
 	}
+	transport := NewRetryTransport(http.DefaultTransport)
 	return &Client{
 		BaseURL: baseURL,
-		HTTPClient: &http.Client{
-			Timeout: 30 * time.Second,
-		},
-		Token: token,
+		// No Client.Timeout: it would also cover rate-limit waits, which
+		// can last up to an hour. RetryTransport times out each attempt.
+		HTTPClient: &http.Client{Transport: transport},
+		Token:      token,
+		Throttle:   transport.Stats,
+		CacheStats: &CacheStats{},
 	}
 }
 
@@ -50,6 +58,9 @@ func (c *Client) newRequest(ctx context.Context, method, url string) (*http.Requ
 	if c.Token != "" {
 		req.Header.Set("Authorization", "Bearer "+c.Token)
 	}
+	if err := c.setConditional(req); err != nil {
+		return nil, err
+	}
 	return req, nil
 }
 
@@ -58,6 +69,17 @@ func (c *Client) doJSON(req *http.Request, v any) (*http.Response, error) {
 	if err != nil {
 		return nil, err
 	}
+	if c.Cache != nil {
+		switch resp.StatusCode {
+		case http.StatusNotModified:
+			resp, err = c.fromCache(req, resp)
+		case http.StatusOK:
+			resp, err = c.storeResponse(req, resp)
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
 	if resp.StatusCode >= 400 {
 		defer resp.Body.Close()
 		msg := http.StatusText(resp.StatusCode)
@@ -70,20 +92,6 @@ func (c *Client) doJSON(req *http.Request, v any) (*http.Response, error) {
 				msg = payload.Message
 			}
 		}
-		if resp.StatusCode == http.StatusForbidden {
-			if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "" {
-				if sec, err3 := strconv.Atoi(retryAfter); err3 == nil && sec > 0 {
-					time.Sleep(time.Duration(sec) * time.Second)
-				}
-			} else if reset := resp.Header.Get("X-RateLimit-Reset"); reset != "" {
-				if epoch, err3 := strconv.ParseInt(reset, 10, 64); err3 == nil {
-					sleep := time.Until(time.Unix(epoch, 0))
-					if sleep > 0 && sleep < 2*time.Minute {
-						time.Sleep(sleep)
-					}
-				}
-			}
-		}
 		return nil, APIError{StatusCode: resp.StatusCode, Message: msg}
 	}
 	defer resp.Body.Close()
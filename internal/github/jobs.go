@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/peter941221/CICost/internal/model"
 )
@@ -15,15 +16,25 @@ type jobsResponse struct {
 }
 
 type jobPayload struct {
-	ID              int64    `json:"id"`
-	Name            string   `json:"name"`
-	Status          string   `json:"status"`
-	Conclusion      string   `json:"conclusion"`
-	StartedAt       string   `json:"started_at"`
-	CompletedAt     string   `json:"completed_at"`
-	RunnerName      string   `json:"runner_name"`
-	RunnerGroupName string   `json:"runner_group_name"`
-	Labels          []string `json:"labels"`
+	ID              int64         `json:"id"`
+	Name            string        `json:"name"`
+	Status          string        `json:"status"`
+	Conclusion      string        `json:"conclusion"`
+	StartedAt       string        `json:"started_at"`
+	CompletedAt     string        `json:"completed_at"`
+	RunnerName      string        `json:"runner_name"`
+	RunnerGroupName string        `json:"runner_group_name"`
+	Labels          []string      `json:"labels"`
+	Steps           []stepPayload `json:"steps"`
+}
+
+type stepPayload struct {
+	Name        string `json:"name"`
+	Status      string `json:"status"`
+	Conclusion  string `json:"conclusion"`
+	Number      int    `json:"number"`
+	StartedAt   string `json:"started_at"`
+	CompletedAt string `json:"completed_at"`
 }
 
 func (c *Client) ListJobsForRun(ctx context.Context, owner, repo string, runID int64, attempt int) ([]model.Job, int, error) {
@@ -49,10 +60,6 @@ func (c *Client) ListJobsForRun(ctx context.Context, owner, repo string, runID i
 		for _, j := range payload.Jobs {
 			start := parseTime(j.StartedAt)
 			end := parseTime(j.CompletedAt)
-			dur := 0
-			if !start.IsZero() && !end.IsZero() && end.After(start) {
-				dur = int(end.Sub(start).Seconds())
-			}
 			out = append(out, model.Job{
 				ID:           j.ID,
 				RunID:        runID,
@@ -67,7 +74,9 @@ func (c *Client) ListJobsForRun(ctx context.Context, owner, repo string, runID i
 				RunnerName:   j.RunnerName,
 				RunnerGroup:  j.RunnerGroupName,
 				IsSelfHosted: hasSelfHosted(j.Labels),
-				DurationSec:  dur,
+				DurationSec:  durationSec(start, end),
+				Labels:       j.Labels,
+				Steps:        mapSteps(j.ID, attempt, j.Steps),
 			})
 		}
 		nextURL = NextPageURL(resp.Header)
@@ -76,6 +85,36 @@ func (c *Client) ListJobsForRun(ctx context.Context, owner, repo string, runID i
 	return out, apiCalls, nil
 }
 
+func mapSteps(jobID int64, attempt int, steps []stepPayload) []model.Step {
+	if len(steps) == 0 {
+		return nil
+	}
+	out := make([]model.Step, 0, len(steps))
+	for _, s := range steps {
+		start := parseTime(s.StartedAt)
+		end := parseTime(s.CompletedAt)
+		out = append(out, model.Step{
+			JobID:       jobID,
+			RunAttempt:  attempt,
+			Number:      s.Number,
+			Name:        s.Name,
+			Status:      s.Status,
+			Conclusion:  s.Conclusion,
+			StartedAt:   start,
+			CompletedAt: end,
+			DurationSec: durationSec(start, end),
+		})
+	}
+	return out
+}
+
+func durationSec(start, end time.Time) int {
+	if start.IsZero() || end.IsZero() || !end.After(start) {
+		return 0
+	}
+	return int(end.Sub(start).Seconds())
+}
+
 func hasSelfHosted(labels []string) bool {
 	for _, l := range labels {
 		if strings.EqualFold(l, "self-hosted") {
@@ -12,7 +12,7 @@ func TestListJobsForRunUsesAttemptFallback(t *testing.T) {
 		if r.URL.Path != "/repos/owner/repo/actions/runs/77/attempts/1/jobs" {
 			t.Fatalf("unexpected path: %s", r.URL.Path)
 		}
-		_, _ = w.Write([]byte(`{"total_count":1,"jobs":[{"id":7001,"name":"unit-test","status":"completed","conclusion":"success","started_at":"2026-02-26T10:00:00Z","completed_at":"2026-02-26T10:01:30Z","runner_name":"linux-host","runner_group_name":"default","labels":["self-hosted","linux"]}]}`))
+		_, _ = w.Write([]byte(`{"total_count":1,"jobs":[{"id":7001,"name":"unit-test","status":"completed","conclusion":"success","started_at":"2026-02-26T10:00:00Z","completed_at":"2026-02-26T10:01:30Z","runner_name":"linux-host","runner_group_name":"default","labels":["self-hosted","linux"],"steps":[{"name":"Set up job","status":"completed","conclusion":"success","number":1,"started_at":"2026-02-26T10:00:00Z","completed_at":"2026-02-26T10:00:05Z"},{"name":"npm ci","status":"completed","conclusion":"success","number":2,"started_at":"2026-02-26T10:00:05Z","completed_at":"2026-02-26T10:01:05Z"}]}]}`))
 	}))
 	defer srv.Close()
 
@@ -42,6 +42,12 @@ func TestListJobsForRunUsesAttemptFallback(t *testing.T) {
 	if jobs[0].DurationSec != 90 {
 		t.Fatalf("expected duration 90 sec, got %d", jobs[0].DurationSec)
 	}
+	if len(jobs[0].Labels) != 2 || jobs[0].Labels[1] != "linux" {
+		t.Fatalf("expected labels to be kept, got %v", jobs[0].Labels)
+	}
+	if len(jobs[0].Steps) != 2 || jobs[0].Steps[1].Name != "npm ci" || jobs[0].Steps[1].DurationSec != 60 || jobs[0].Steps[1].JobID != 7001 {
+		t.Fatalf("expected steps to be mapped, got %+v", jobs[0].Steps)
+	}
 }
 
 func TestGuessRunnerOSAndSelfHostedHelpers(t *testing.T) {
@@ -0,0 +1,101 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Repository is the subset of repository metadata used to pick scan targets.
+type Repository struct {
+	FullName string   `json:"full_name"`
+	Name     string   `json:"name"`
+	Archived bool     `json:"archived"`
+	Fork     bool     `json:"fork"`
+	Private  bool     `json:"private"`
+	Topics   []string `json:"topics"`
+}
+
+// ListRepositories lists every repository of an organization. When owner is
+// not an organization it falls back to the repositories owned by that user:
+// all of them when the user is the authenticated one, otherwise only the
+// public ones, which publicOnly reports.
+func (c *Client) ListRepositories(ctx context.Context, owner string) (repos []Repository, publicOnly bool, calls int, err error) {
+	repos, calls, err = c.listRepositoryPages(ctx, fmt.Sprintf("%s/orgs/%s/repos", c.BaseURL, url.PathEscape(owner)), url.Values{"type": {"all"}})
+	var apiErr APIError
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound {
+		if err != nil {
+			return nil, false, calls, err
+		}
+		return repos, false, calls, nil
+	}
+
+	login, err := c.authenticatedLogin(ctx)
+	calls++
+	if err != nil && (!errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized) {
+		return nil, false, calls, err
+	}
+	var userCalls int
+	if strings.EqualFold(login, owner) {
+		// Only /user/repos includes private repositories.
+		repos, userCalls, err = c.listRepositoryPages(ctx, c.BaseURL+"/user/repos", url.Values{"affiliation": {"owner"}})
+	} else {
+		repos, userCalls, err = c.listRepositoryPages(ctx, fmt.Sprintf("%s/users/%s/repos", c.BaseURL, url.PathEscape(owner)), url.Values{"type": {"owner"}})
+		publicOnly = true
+	}
+	calls += userCalls
+	if err != nil {
+		return nil, false, calls, err
+	}
+	return repos, publicOnly, calls, nil
+}
+
+// authenticatedLogin is the login of the user the token belongs to.
+func (c *Client) authenticatedLogin(ctx context.Context) (string, error) {
+	req, err := c.newRequest(ctx, "GET", c.BaseURL+"/user")
+	if err != nil {
+		return "", err
+	}
+	var user struct {
+		Login string `json:"login"`
+	}
+	if _, err := c.doJSON(req, &user); err != nil {
+		return "", err
+	}
+	return user.Login, nil
+}
+
+func (c *Client) listRepositoryPages(ctx context.Context, base string, params url.Values) ([]Repository, int, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return nil, 0, err
+	}
+	q := u.Query()
+	q.Set("per_page", "100")
+	for k, v := range params {
+		q[k] = v
+	}
+	u.RawQuery = q.Encode()
+	nextURL := u.String()
+
+	var out []Repository
+	apiCalls := 0
+	for nextURL != "" {
+		req, err := c.newRequest(ctx, "GET", nextURL)
+		if err != nil {
+			return nil, apiCalls, err
+		}
+		var payload []Repository
+		resp, err := c.doJSON(req, &payload)
+		if err != nil {
+			return nil, apiCalls, err
+		}
+		apiCalls++
+		out = append(out, payload...)
+		nextURL = NextPageURL(resp.Header)
+	}
+	return out, apiCalls, nil
+}
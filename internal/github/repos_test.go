@@ -0,0 +1,80 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListRepositoriesFallsBackToUser(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/octocat/repos":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case "/user":
+			_, _ = w.Write([]byte(`{"login":"hubot"}`))
+		case "/users/octocat/repos":
+			if r.URL.Query().Get("type") != "owner" {
+				t.Errorf("expected type=owner, got %s", r.URL.RawQuery)
+			}
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `<`+srv.URL+`/users/octocat/repos?type=owner&page=2>; rel="next"`)
+				_, _ = w.Write([]byte(`[{"full_name":"octocat/a","name":"a","topics":["ci"]}]`))
+				return
+			}
+			_, _ = w.Write([]byte(`[{"full_name":"octocat/b","name":"b","archived":true,"fork":true}]`))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client()}
+	repos, publicOnly, calls, err := c.ListRepositories(context.Background(), "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 3 || len(repos) != 2 {
+		t.Fatalf("expected 2 repos over 2 user pages after looking up the token's user, got %d repos %d calls", len(repos), calls)
+	}
+	if !publicOnly {
+		t.Fatal("expected another user's listing to be public only")
+	}
+	if repos[0].FullName != "octocat/a" || len(repos[0].Topics) != 1 || !repos[1].Archived || !repos[1].Fork {
+		t.Fatalf("unexpected repositories: %+v", repos)
+	}
+}
+
+func TestListRepositoriesOfAuthenticatedUserIncludesPrivate(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/orgs/octocat/repos":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"message":"Not Found"}`))
+		case "/user":
+			_, _ = w.Write([]byte(`{"login":"OctoCat"}`))
+		case "/user/repos":
+			if r.URL.Query().Get("affiliation") != "owner" || r.URL.Query().Has("type") {
+				t.Errorf("expected affiliation=owner without type, got %s", r.URL.RawQuery)
+			}
+			_, _ = w.Write([]byte(`[{"full_name":"octocat/a","name":"a"},{"full_name":"octocat/secret","name":"secret","private":true}]`))
+		default:
+			t.Errorf("unexpected path: %s", r.URL.Path)
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client()}
+	repos, publicOnly, _, err := c.ListRepositories(context.Background(), "octocat")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if publicOnly || len(repos) != 2 || !repos[1].Private {
+		t.Fatalf("expected the user's private repositories too, got %+v (public only %v)", repos, publicOnly)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/url"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/peter941221/CICost/internal/model"
@@ -31,9 +32,24 @@ type runPayload struct {
 }
 
 func (c *Client) ListWorkflowRuns(ctx context.Context, owner, repo string, since time.Time) ([]model.WorkflowRun, int, error) {
+	var out []model.WorkflowRun
+	apiCalls, err := c.ListWorkflowRunPages(ctx, owner, repo, since, func(page []model.WorkflowRun) error {
+		out = append(out, page...)
+		return nil
+	})
+	if err != nil {
+		return nil, apiCalls, err
+	}
+	return out, apiCalls, nil
+}
+
+// ListWorkflowRunPages walks the runs listing and hands each page to fn as it
+// arrives, so callers can persist progress before the listing completes.
+// It stops at the first error from the API, ctx or fn.
+func (c *Client) ListWorkflowRunPages(ctx context.Context, owner, repo string, since time.Time, fn func([]model.WorkflowRun) error) (int, error) {
 	u, err := url.Parse(fmt.Sprintf("%s/repos/%s/%s/actions/runs", c.BaseURL, owner, repo))
 	if err != nil {
-		return nil, 0, err
+		return 0, err
 	}
 	q := u.Query()
 	q.Set("per_page", "100")
@@ -43,26 +59,31 @@ func (c *Client) ListWorkflowRuns(ctx context.Context, owner, repo string, since
 	u.RawQuery = q.Encode()
 	nextURL := u.String()
 
-	var out []model.WorkflowRun
 	apiCalls := 0
-
 	for nextURL != "" {
+		if err := ctx.Err(); err != nil {
+			return apiCalls, err
+		}
 		req, err := c.newRequest(ctx, "GET", nextURL)
 		if err != nil {
-			return nil, apiCalls, err
+			return apiCalls, err
 		}
 		var payload runsResponse
 		resp, err := c.doJSON(req, &payload)
 		if err != nil {
-			return nil, apiCalls, err
+			return apiCalls, err
 		}
 		apiCalls++
+		page := make([]model.WorkflowRun, 0, len(payload.WorkflowRuns))
 		for _, r := range payload.WorkflowRuns {
-			out = append(out, mapRun(repo, r))
+			page = append(page, mapRun(repo, r))
+		}
+		if err := fn(page); err != nil {
+			return apiCalls, err
 		}
 		nextURL = NextPageURL(resp.Header)
 	}
-	return out, apiCalls, nil
+	return apiCalls, nil
 }
 
 func mapRun(repo string, p runPayload) model.WorkflowRun {
@@ -71,6 +92,7 @@ func mapRun(repo string, p runPayload) model.WorkflowRun {
 		Repo:         repo,
 		WorkflowID:   p.WorkflowID,
 		WorkflowName: p.Name,
+		WorkflowPath: workflowPath(p.WorkflowRef),
 		HeadBranch:   p.HeadBranch,
 		Event:        p.Event,
 		Status:       p.Status,
@@ -82,6 +104,15 @@ func mapRun(repo string, p runPayload) model.WorkflowRun {
 	}
 }
 
+// workflowPath drops the "@ref" suffix GitHub appends to reusable and
+// dynamic workflow paths so runs of one file share a single key.
+func workflowPath(ref string) string {
+	if i := strings.Index(ref, "@"); i >= 0 {
+		ref = ref[:i]
+	}
+	return strings.TrimSpace(ref)
+}
+
 func parseTime(v string) time.Time {
 	if v == "" {
 		return time.Time{}
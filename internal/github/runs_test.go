@@ -19,10 +19,10 @@ func TestListWorkflowRunsPaginationAndFallbackAttempt(t *testing.T) {
 		page := r.URL.Query().Get("page")
 		if page == "" || page == "1" {
 			w.Header().Set("Link", fmt.Sprintf(`<%s/repos/owner/repo/actions/runs?page=2>; rel="next"`, srv.URL))
-			_, _ = w.Write([]byte(`{"total_count":2,"workflow_runs":[{"id":1,"workflow_id":11,"name":"ci","head_branch":"main","event":"push","status":"completed","conclusion":"success","run_attempt":0,"run_started_at":"2026-02-26T10:00:00Z","updated_at":"2026-02-26T10:10:00Z","created_at":"2026-02-26T10:00:00Z"}]}`))
+			_, _ = w.Write([]byte(`{"total_count":2,"workflow_runs":[{"id":1,"workflow_id":11,"name":"ci","head_branch":"main","event":"push","status":"completed","conclusion":"success","run_attempt":0,"run_started_at":"2026-02-26T10:00:00Z","updated_at":"2026-02-26T10:10:00Z","created_at":"2026-02-26T10:00:00Z","path":".github/workflows/ci.yml"}]}`))
 			return
 		}
-		_, _ = w.Write([]byte(`{"total_count":2,"workflow_runs":[{"id":2,"workflow_id":22,"name":"deploy","head_branch":"main","event":"workflow_dispatch","status":"completed","conclusion":"failure","run_attempt":2,"run_started_at":"2026-02-26T11:00:00Z","updated_at":"2026-02-26T11:10:00Z","created_at":"2026-02-26T11:00:00Z"}]}`))
+		_, _ = w.Write([]byte(`{"total_count":2,"workflow_runs":[{"id":2,"workflow_id":22,"name":"deploy","head_branch":"main","event":"workflow_dispatch","status":"completed","conclusion":"failure","run_attempt":2,"run_started_at":"2026-02-26T11:00:00Z","updated_at":"2026-02-26T11:10:00Z","created_at":"2026-02-26T11:00:00Z","path":".github/workflows/deploy.yml@refs/heads/main"}]}`))
 	}))
 	defer srv.Close()
 
@@ -49,6 +49,9 @@ func TestListWorkflowRunsPaginationAndFallbackAttempt(t *testing.T) {
 	if runs[1].RunAttempt != 2 {
 		t.Fatalf("expected run attempt 2, got %d", runs[1].RunAttempt)
 	}
+	if runs[0].WorkflowPath != ".github/workflows/ci.yml" || runs[1].WorkflowPath != ".github/workflows/deploy.yml" {
+		t.Fatalf("unexpected workflow paths: %q, %q", runs[0].WorkflowPath, runs[1].WorkflowPath)
+	}
 }
 
 func TestParseRunID(t *testing.T) {
@@ -0,0 +1,109 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+)
+
+type artifactsResponse struct {
+	TotalCount int               `json:"total_count"`
+	Artifacts  []artifactPayload `json:"artifacts"`
+}
+
+type artifactPayload struct {
+	ID          int64  `json:"id"`
+	Name        string `json:"name"`
+	SizeInBytes int64  `json:"size_in_bytes"`
+	Expired     bool   `json:"expired"`
+	CreatedAt   string `json:"created_at"`
+	ExpiresAt   string `json:"expires_at"`
+	WorkflowRun struct {
+		ID int64 `json:"id"`
+	} `json:"workflow_run"`
+}
+
+type cachesResponse struct {
+	TotalCount   int            `json:"total_count"`
+	ActionsCache []cachePayload `json:"actions_caches"`
+}
+
+type cachePayload struct {
+	ID             int64  `json:"id"`
+	Key            string `json:"key"`
+	Ref            string `json:"ref"`
+	SizeInBytes    int64  `json:"size_in_bytes"`
+	CreatedAt      string `json:"created_at"`
+	LastAccessedAt string `json:"last_accessed_at"`
+}
+
+// ListArtifacts lists every artifact of a repository, expired ones included.
+func (c *Client) ListArtifacts(ctx context.Context, owner, repo string) ([]model.Artifact, int, error) {
+	nextURL := fmt.Sprintf("%s/repos/%s/%s/actions/artifacts?per_page=100", c.BaseURL, url.PathEscape(owner), url.PathEscape(repo))
+	var out []model.Artifact
+	apiCalls := 0
+	for nextURL != "" {
+		req, err := c.newRequest(ctx, "GET", nextURL)
+		if err != nil {
+			return nil, apiCalls, err
+		}
+		var payload artifactsResponse
+		resp, err := c.doJSON(req, &payload)
+		if err != nil {
+			return nil, apiCalls, err
+		}
+		apiCalls++
+		for _, a := range payload.Artifacts {
+			out = append(out, model.Artifact{
+				ID:        a.ID,
+				Repo:      repo,
+				Name:      a.Name,
+				RunID:     a.WorkflowRun.ID,
+				SizeBytes: a.SizeInBytes,
+				Expired:   a.Expired,
+				CreatedAt: parseTime(a.CreatedAt),
+				ExpiresAt: parseTime(a.ExpiresAt),
+			})
+		}
+		nextURL = NextPageURL(resp.Header)
+	}
+	return out, apiCalls, nil
+}
+
+// ListCaches lists the dependency caches a repository currently holds and
+// stamps them with LastSeenAt.
+func (c *Client) ListCaches(ctx context.Context, owner, repo string) ([]model.ActionsCache, int, error) {
+	nextURL := fmt.Sprintf("%s/repos/%s/%s/actions/caches?per_page=100", c.BaseURL, url.PathEscape(owner), url.PathEscape(repo))
+	seen := time.Now().UTC()
+	var out []model.ActionsCache
+	apiCalls := 0
+	for nextURL != "" {
+		req, err := c.newRequest(ctx, "GET", nextURL)
+		if err != nil {
+			return nil, apiCalls, err
+		}
+		var payload cachesResponse
+		resp, err := c.doJSON(req, &payload)
+		if err != nil {
+			return nil, apiCalls, err
+		}
+		apiCalls++
+		for _, a := range payload.ActionsCache {
+			out = append(out, model.ActionsCache{
+				ID:             a.ID,
+				Repo:           repo,
+				Key:            a.Key,
+				Ref:            a.Ref,
+				SizeBytes:      a.SizeInBytes,
+				CreatedAt:      parseTime(a.CreatedAt),
+				LastAccessedAt: parseTime(a.LastAccessedAt),
+				LastSeenAt:     seen,
+			})
+		}
+		nextURL = NextPageURL(resp.Header)
+	}
+	return out, apiCalls, nil
+}
@@ -0,0 +1,54 @@
+package github
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestListArtifactsAndCaches(t *testing.T) {
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/repos/owner/repo/actions/artifacts":
+			if r.URL.Query().Get("page") == "" {
+				w.Header().Set("Link", `<`+srv.URL+`/repos/owner/repo/actions/artifacts?per_page=100&page=2>; rel="next"`)
+				_, _ = w.Write([]byte(`{"total_count":2,"artifacts":[{"id":1,"name":"coverage","size_in_bytes":1048576,"expired":false,"created_at":"2026-02-01T00:00:00Z","expires_at":"2026-05-02T00:00:00Z","workflow_run":{"id":77}}]}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{"total_count":2,"artifacts":[{"id":2,"name":"dist","size_in_bytes":10,"expired":true,"created_at":"2026-01-01T00:00:00Z","expires_at":"2026-01-08T00:00:00Z","workflow_run":{"id":70}}]}`))
+		case "/repos/owner/repo/actions/caches":
+			_, _ = w.Write([]byte(`{"total_count":1,"actions_caches":[{"id":9,"ref":"refs/heads/main","key":"npm-abc","size_in_bytes":2048,"created_at":"2026-02-01T00:00:00Z","last_accessed_at":"2026-02-03T00:00:00Z"}]}`))
+		default:
+			t.Fatalf("unexpected path: %s", r.URL.Path)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Client{BaseURL: srv.URL, HTTPClient: srv.Client()}
+	artifacts, calls, err := c.ListArtifacts(context.Background(), "owner", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 2 || len(artifacts) != 2 {
+		t.Fatalf("expected 2 artifacts over 2 pages, got %d artifacts %d calls", len(artifacts), calls)
+	}
+	if a := artifacts[0]; a.Name != "coverage" || a.RunID != 77 || a.SizeBytes != 1048576 || a.ExpiresAt.Sub(a.CreatedAt).Hours() != 90*24 {
+		t.Fatalf("unexpected artifact: %+v", a)
+	}
+	if !artifacts[1].Expired {
+		t.Fatalf("expected expired flag to be kept: %+v", artifacts[1])
+	}
+
+	caches, calls, err := c.ListCaches(context.Background(), "owner", "repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if calls != 1 || len(caches) != 1 {
+		t.Fatalf("expected 1 cache, got %d (%d calls)", len(caches), calls)
+	}
+	if cc := caches[0]; cc.Key != "npm-abc" || cc.Ref != "refs/heads/main" || cc.LastAccessedAt.IsZero() || cc.LastSeenAt.IsZero() {
+		t.Fatalf("unexpected cache: %+v", cc)
+	}
+}
@@ -0,0 +1,378 @@
+package github
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultMaxRetries       = 5
+	defaultBaseDelay        = time.Second
+	defaultMaxDelay         = time.Minute
+	defaultMaxRateLimitWait = time.Hour
+	// defaultAttemptTimeout bounds one request attempt. It does not cover
+	// the waits between attempts, which rate limits can stretch to an hour.
+	defaultAttemptTimeout = 30 * time.Second
+	// GitHub asks clients hitting a secondary limit without Retry-After to
+	// wait at least a minute before retrying.
+	secondaryLimitWait = time.Minute
+	// Requests per second shared by every worker using one client. GitHub's
+	// secondary limits allow roughly 900 REST points a minute.
+	defaultRequestsPerSecond = 10
+	defaultBurst             = 10
+)
+
+// ThrottleStats accumulates how much a client was slowed down, for display
+// in command summaries. It is safe for concurrent use.
+type ThrottleStats struct {
+	mu          sync.Mutex
+	retries     int
+	rateLimited int
+	waited      time.Duration
+}
+
+// ThrottleSummary is a point-in-time copy of ThrottleStats.
+type ThrottleSummary struct {
+	Retries     int
+	RateLimited int
+	Waited      time.Duration
+}
+
+func (s *ThrottleStats) add(retries, rateLimited int, waited time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.retries += retries
+	s.rateLimited += rateLimited
+	s.waited += waited
+}
+
+func (s *ThrottleStats) Summary() ThrottleSummary {
+	if s == nil {
+		return ThrottleSummary{}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return ThrottleSummary{Retries: s.retries, RateLimited: s.rateLimited, Waited: s.waited}
+}
+
+// TokenBucket is a small rate limiter shared by concurrent callers. It can
+// also be paused until a point in time, which RetryTransport uses when the
+// primary rate limit is exhausted so every worker waits for the reset.
+type TokenBucket struct {
+	mu       sync.Mutex
+	rate     float64
+	burst    float64
+	tokens   float64
+	last     time.Time
+	pausedTo time.Time
+	now      func() time.Time
+}
+
+func NewTokenBucket(perSecond float64, burst int) *TokenBucket {
+	if perSecond <= 0 {
+		perSecond = defaultRequestsPerSecond
+	}
+	if burst <= 0 {
+		burst = 1
+	}
+	return &TokenBucket{rate: perSecond, burst: float64(burst), tokens: float64(burst), now: time.Now}
+}
+
+// Wait blocks until a token is available or ctx is done and returns how long
+// the caller waited.
+func (b *TokenBucket) Wait(ctx context.Context) (time.Duration, error) {
+	var waited time.Duration
+	for {
+		d := b.reserve()
+		if d <= 0 {
+			return waited, nil
+		}
+		if err := sleepContext(ctx, d); err != nil {
+			return waited, err
+		}
+		waited += d
+	}
+}
+
+// PauseUntil holds back every caller until t.
+func (b *TokenBucket) PauseUntil(t time.Time) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if t.After(b.pausedTo) {
+		b.pausedTo = t
+	}
+}
+
+// Refund returns a token taken for a request that turned out to be free,
+// such as a conditional request answered with 304 Not Modified.
+func (b *TokenBucket) Refund() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens+1 <= b.burst {
+		b.tokens++
+	}
+}
+
+// reserve takes a token and returns 0, or returns how long to wait before
+// trying again.
+func (b *TokenBucket) reserve() time.Duration {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if now.Before(b.pausedTo) {
+		return b.pausedTo.Sub(now)
+	}
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > b.burst {
+			b.tokens = b.burst
+		}
+	}
+	b.last = now
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0
+	}
+	return time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+}
+
+// RetryTransport retries GitHub API requests on 5xx responses, network errors
+// and primary or secondary rate limits, with exponential backoff and jitter.
+// All requests first take a token from Limiter. AttemptTimeout, when set,
+// limits each attempt including reading its body, which is buffered before
+// RoundTrip returns; an attempt that runs out is retried like a network
+// error.
+type RetryTransport struct {
+	Base             http.RoundTripper
+	Limiter          *TokenBucket
+	Stats            *ThrottleStats
+	MaxRetries       int
+	BaseDelay        time.Duration
+	MaxDelay         time.Duration
+	MaxRateLimitWait time.Duration
+	AttemptTimeout   time.Duration
+
+	now    func() time.Time
+	jitter func(time.Duration) time.Duration
+}
+
+func NewRetryTransport(base http.RoundTripper) *RetryTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &RetryTransport{
+		Base:             base,
+		Limiter:          NewTokenBucket(defaultRequestsPerSecond, defaultBurst),
+		Stats:            &ThrottleStats{},
+		MaxRetries:       defaultMaxRetries,
+		BaseDelay:        defaultBaseDelay,
+		MaxDelay:         defaultMaxDelay,
+		MaxRateLimitWait: defaultMaxRateLimitWait,
+		AttemptTimeout:   defaultAttemptTimeout,
+	}
+}
+
+func (t *RetryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if t.Limiter != nil {
+			waited, err := t.Limiter.Wait(ctx)
+			t.Stats.add(0, 0, waited)
+			if err != nil {
+				return nil, err
+			}
+		}
+		if attempt > 0 && req.Body != nil && req.GetBody != nil {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req.Body = body
+		}
+
+		resp, err := t.attempt(req)
+		if ctx.Err() != nil {
+			if resp != nil {
+				_ = resp.Body.Close()
+			}
+			return nil, ctx.Err()
+		}
+		if resp != nil && resp.StatusCode == http.StatusNotModified && t.Limiter != nil {
+			// GitHub does not count 304s against the rate limit, so neither do we.
+			t.Limiter.Refund()
+		}
+		wait, rateLimited, retry := t.decide(resp, err, attempt)
+		if resp != nil && !rateLimited {
+			t.notePrimaryLimit(resp)
+		}
+		if !retry || attempt >= t.MaxRetries {
+			return resp, err
+		}
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, resp.Body)
+			_ = resp.Body.Close()
+		}
+		rl := 0
+		if rateLimited {
+			rl = 1
+			if t.Limiter != nil {
+				t.Limiter.PauseUntil(t.clock().Add(wait))
+			}
+		}
+		if err := sleepContext(ctx, wait); err != nil {
+			return nil, err
+		}
+		t.Stats.add(1, rl, wait)
+	}
+}
+
+// attempt sends req once, within AttemptTimeout. The body is read in full
+// under the same deadline, so a stalled body is retried rather than failing
+// the caller's read.
+func (t *RetryTransport) attempt(req *http.Request) (*http.Response, error) {
+	if t.AttemptTimeout <= 0 {
+		return t.Base.RoundTrip(req)
+	}
+	ctx, cancel := context.WithTimeout(req.Context(), t.AttemptTimeout)
+	defer cancel()
+	resp, err := t.Base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	_ = resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
+}
+
+// decide reports how long to wait before retrying, whether the response was
+// a rate limit and whether a retry should happen at all.
+func (t *RetryTransport) decide(resp *http.Response, err error, attempt int) (time.Duration, bool, bool) {
+	if err != nil {
+		return t.backoff(attempt), false, true
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode == http.StatusForbidden && isRateLimited(resp):
+		wait, ok := t.rateLimitWait(resp, attempt)
+		return wait, true, ok
+	case resp.StatusCode >= 500 && resp.StatusCode != http.StatusNotImplemented:
+		return t.backoff(attempt), false, true
+	default:
+		return 0, false, false
+	}
+}
+
+// rateLimitWait derives the wait from Retry-After, then from the primary
+// limit reset when no requests remain, then falls back to the secondary
+// limit minimum with backoff. Waits beyond MaxRateLimitWait are not retried.
+func (t *RetryTransport) rateLimitWait(resp *http.Response, attempt int) (time.Duration, bool) {
+	var wait time.Duration
+	if sec, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && sec >= 0 {
+		wait = time.Duration(sec) * time.Second
+	} else if reset := resetTime(resp); resp.Header.Get("X-RateLimit-Remaining") == "0" && !reset.IsZero() {
+		wait = reset.Sub(t.clock()) + time.Second
+	} else {
+		wait = secondaryLimitWait + t.backoff(attempt)
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	limit := t.MaxRateLimitWait
+	if limit <= 0 {
+		limit = defaultMaxRateLimitWait
+	}
+	return wait, wait <= limit
+}
+
+// notePrimaryLimit pauses the shared limiter when a successful response says
+// the primary quota is used up, so other workers wait for the reset instead
+// of each collecting a 403.
+func (t *RetryTransport) notePrimaryLimit(resp *http.Response) {
+	if t.Limiter == nil || resp.Header.Get("X-RateLimit-Remaining") != "0" {
+		return
+	}
+	if reset := resetTime(resp); !reset.IsZero() {
+		t.Limiter.PauseUntil(reset)
+	}
+}
+
+func (t *RetryTransport) backoff(attempt int) time.Duration {
+	base := t.BaseDelay
+	if base <= 0 {
+		base = defaultBaseDelay
+	}
+	maxDelay := t.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = defaultMaxDelay
+	}
+	d := base << attempt
+	if d <= 0 || d > maxDelay {
+		d = maxDelay
+	}
+	if t.jitter != nil {
+		return t.jitter(d)
+	}
+	// Full jitter in [d/2, d) spreads workers that failed together.
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+func (t *RetryTransport) clock() time.Time {
+	if t.now != nil {
+		return t.now()
+	}
+	return time.Now()
+}
+
+// isRateLimited tells a rate-limit 403 from a permission 403 using the
+// headers GitHub sets, or the message in the body for secondary limits.
+func isRateLimited(resp *http.Response) bool {
+	if resp.Header.Get("Retry-After") != "" || resp.Header.Get("X-RateLimit-Remaining") == "0" {
+		return true
+	}
+	if resp.Body == nil {
+		return false
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(b))
+	if err != nil {
+		return false
+	}
+	return strings.Contains(strings.ToLower(string(b)), "rate limit")
+}
+
+func resetTime(resp *http.Response) time.Time {
+	epoch, err := strconv.ParseInt(strings.TrimSpace(resp.Header.Get("X-RateLimit-Reset")), 10, 64)
+	if err != nil || epoch <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(epoch, 0)
+}
+
+// sleepContext waits for d or until ctx is done, whichever comes first.
+func sleepContext(ctx context.Context, d time.Duration) error {
+	if d <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,192 @@
+package github
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestTransport() *RetryTransport {
+	t := NewRetryTransport(http.DefaultTransport)
+	t.Limiter = nil
+	t.BaseDelay = time.Millisecond
+	t.MaxDelay = 5 * time.Millisecond
+	t.jitter = func(d time.Duration) time.Duration { return d }
+	return t
+}
+
+func TestRetryTransportRetriesServerErrors(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&hits, 1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	tr := newTestTransport()
+	c := &Client{BaseURL: srv.URL, HTTPClient: &http.Client{Transport: tr}, Throttle: tr.Stats}
+	req, err := c.newRequest(context.Background(), http.MethodGet, srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var payload struct {
+		OK bool `json:"ok"`
+	}
+	if _, err := c.doJSON(req, &payload); err != nil {
+		t.Fatal(err)
+	}
+	if !payload.OK || atomic.LoadInt32(&hits) != 3 {
+		t.Fatalf("expected success on third attempt, hits=%d", hits)
+	}
+	if sum := c.Throttle.Summary(); sum.Retries != 2 || sum.RateLimited != 0 {
+		t.Fatalf("unexpected throttle summary: %+v", sum)
+	}
+}
+
+func TestRetryTransportRateLimits(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/secondary":
+			if atomic.AddInt32(&hits, 1) == 1 {
+				w.Header().Set("Retry-After", "0")
+				w.WriteHeader(http.StatusForbidden)
+				_, _ = w.Write([]byte(`{"message":"You have exceeded a secondary rate limit."}`))
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		case "/forbidden":
+			w.WriteHeader(http.StatusForbidden)
+			_, _ = w.Write([]byte(`{"message":"Resource not accessible by integration"}`))
+		case "/too-long":
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(3*time.Hour).Unix(), 10))
+			w.WriteHeader(http.StatusTooManyRequests)
+		}
+	}))
+	defer srv.Close()
+
+	tr := newTestTransport()
+	client := &http.Client{Transport: tr}
+
+	resp, err := client.Get(srv.URL + "/secondary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected retry after secondary limit, got %d", resp.StatusCode)
+	}
+	if sum := tr.Stats.Summary(); sum.RateLimited != 1 || sum.Retries != 1 {
+		t.Fatalf("unexpected throttle summary: %+v", sum)
+	}
+
+	resp, err = client.Get(srv.URL + "/forbidden")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden || tr.Stats.Summary().Retries != 1 {
+		t.Fatalf("expected permission 403 returned without retry, got %d %+v", resp.StatusCode, tr.Stats.Summary())
+	}
+
+	resp, err = client.Get(srv.URL + "/too-long")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusTooManyRequests || tr.Stats.Summary().Retries != 1 {
+		t.Fatalf("expected reset beyond MaxRateLimitWait returned as-is, got %d", resp.StatusCode)
+	}
+}
+
+func TestTokenBucketPacesAndPauses(t *testing.T) {
+	now := time.Date(2026, 2, 26, 10, 0, 0, 0, time.UTC)
+	b := NewTokenBucket(2, 2)
+	b.now = func() time.Time { return now }
+
+	if b.reserve() != 0 || b.reserve() != 0 {
+		t.Fatal("expected burst of 2 to be free")
+	}
+	if d := b.reserve(); d != 500*time.Millisecond {
+		t.Fatalf("expected 500ms wait for next token, got %s", d)
+	}
+	now = now.Add(500 * time.Millisecond)
+	if d := b.reserve(); d != 0 {
+		t.Fatalf("expected refilled token, got wait %s", d)
+	}
+
+	b.PauseUntil(now.Add(time.Minute))
+	if d := b.reserve(); d != time.Minute {
+		t.Fatalf("expected pause to hold callers for 1m, got %s", d)
+	}
+}
+
+func TestRetryTransportWaitsBeyondAttemptTimeout(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&hits, 1) {
+		case 1:
+			// Longer than the attempt timeout: retried as a network error.
+			time.Sleep(400 * time.Millisecond)
+		case 2:
+			w.Header().Set("Retry-After", "1")
+			w.WriteHeader(http.StatusTooManyRequests)
+			return
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tr := newTestTransport()
+	tr.AttemptTimeout = 200 * time.Millisecond
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || atomic.LoadInt32(&hits) != 3 {
+		t.Fatalf("expected success on the third attempt, got %d after %d", resp.StatusCode, hits)
+	}
+	if sum := tr.Stats.Summary(); sum.Retries != 2 || sum.RateLimited != 1 || sum.Waited < time.Second {
+		t.Fatalf("unexpected throttle summary: %+v", sum)
+	}
+}
+
+func TestRetryTransportRetriesStalledBody(t *testing.T) {
+	var hits int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Length", "2")
+		w.WriteHeader(http.StatusOK)
+		if atomic.AddInt32(&hits, 1) == 1 {
+			// Headers arrive in time, the body does not.
+			w.(http.Flusher).Flush()
+			time.Sleep(400 * time.Millisecond)
+		}
+		_, _ = w.Write([]byte(`{}`))
+	}))
+	defer srv.Close()
+
+	tr := newTestTransport()
+	tr.AttemptTimeout = 200 * time.Millisecond
+	resp, err := (&http.Client{Transport: tr}).Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil || string(body) != "{}" {
+		t.Fatalf("expected the retried body, got %q %v", body, err)
+	}
+	if atomic.LoadInt32(&hits) != 2 || tr.Stats.Summary().Retries != 1 {
+		t.Fatalf("expected one retry, got %d hits and %+v", hits, tr.Stats.Summary())
+	}
+}
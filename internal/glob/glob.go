@@ -0,0 +1,110 @@
+// Package glob matches entity names, such as workflows, jobs, branches and
+// runners, against the globs of policy and budget files.
+//
+// Unlike path.Match, * matches / too: job entities are named
+// "Workflow / job", branches "feature/x" and runners "self-hosted/gpu", and
+// a "*" that stops at / would silently match none of them. ** is accepted
+// as well, so GitHub branch filters such as release/** work unchanged.
+package glob
+
+import (
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ErrBadPattern reports a malformed pattern.
+var ErrBadPattern = path.ErrBadPattern
+
+// Match reports whether name matches pattern. * and ** match any run of
+// characters, ? matches one, [...] matches a class as in path.Match, negated
+// by [!...] or [^...], and \ escapes the next character.
+func Match(pattern, name string) (bool, error) {
+	re, err := compile(pattern)
+	if err != nil {
+		return false, err
+	}
+	return re.MatchString(name), nil
+}
+
+// Validate returns ErrBadPattern when pattern is malformed.
+func Validate(pattern string) error {
+	_, err := compile(pattern)
+	return err
+}
+
+func compile(pattern string) (*regexp.Regexp, error) {
+	var b strings.Builder
+	b.WriteString(`^(?s:`)
+	runes := []rune(pattern)
+	for i := 0; i < len(runes); i++ {
+		switch c := runes[i]; c {
+		case '*':
+			for i+1 < len(runes) && runes[i+1] == '*' {
+				i++
+			}
+			b.WriteString(`.*`)
+		case '?':
+			b.WriteString(`.`)
+		case '\\':
+			if i+1 == len(runes) {
+				return nil, ErrBadPattern
+			}
+			i++
+			b.WriteString(regexp.QuoteMeta(string(runes[i])))
+		case '[':
+			end, class, err := compileClass(runes, i)
+			if err != nil {
+				return nil, err
+			}
+			b.WriteString(class)
+			i = end
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString(`)$`)
+	re, err := regexp.Compile(b.String())
+	if err != nil {
+		return nil, ErrBadPattern
+	}
+	return re, nil
+}
+
+// compileClass turns the class starting at runes[start] into a regexp class
+// and returns the index of its closing ].
+func compileClass(runes []rune, start int) (int, string, error) {
+	var b strings.Builder
+	b.WriteString(`[`)
+	i := start + 1
+	if i < len(runes) && (runes[i] == '!' || runes[i] == '^') {
+		b.WriteString(`^`)
+		i++
+	}
+	empty := true
+	for ; i < len(runes); i++ {
+		c := runes[i]
+		switch {
+		case c == ']' && !empty:
+			b.WriteString(`]`)
+			return i, b.String(), nil
+		case c == ']':
+			return 0, "", ErrBadPattern
+		case c == '\\':
+			if i+1 == len(runes) {
+				return 0, "", ErrBadPattern
+			}
+			i++
+			c = runes[i]
+		case c == '-' && !empty && i+1 < len(runes) && runes[i+1] != ']':
+			b.WriteString(`-`)
+			continue
+		}
+		if strings.ContainsRune(`\[]^-`, c) {
+			b.WriteString(`\`)
+		}
+		b.WriteRune(c)
+		empty = false
+	}
+	return 0, "", ErrBadPattern
+}
@@ -0,0 +1,40 @@
+package glob
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	cases := []struct {
+		pattern, name string
+		want          bool
+	}{
+		{"*", "CI / build", true},
+		{"*deploy*", "Release / deploy-prod", true},
+		{"CI / *", "CI / build", true},
+		{"CI / *", "Release / build", false},
+		{"*", "feature/x", true},
+		{"release/**", "release/1.2/hotfix", true},
+		{"release/**", "main", false},
+		{"self-hosted/*", "self-hosted/gpu", true},
+		{"v?.x", "v1.x", true},
+		{"v?.x", "v10.x", false},
+		{"[a-c]i", "ci", true},
+		{"[!a-c]i", "ci", false},
+		{"[^a-c]i", "di", true},
+		{`\*`, "*", true},
+		{`\*`, "x", false},
+		{"a.b", "axb", false},
+		{"", "", true},
+		{"", "x", false},
+	}
+	for _, c := range cases {
+		got, err := Match(c.pattern, c.name)
+		if err != nil || got != c.want {
+			t.Errorf("Match(%q, %q) = %v, %v; want %v", c.pattern, c.name, got, err, c.want)
+		}
+	}
+	for _, bad := range []string{"[", "[]", "[a", `a\`, `[a\`} {
+		if err := Validate(bad); err != ErrBadPattern {
+			t.Errorf("Validate(%q) = %v, want ErrBadPattern", bad, err)
+		}
+	}
+}
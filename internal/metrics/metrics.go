@@ -0,0 +1,125 @@
+// Package metrics is the registry of named usage metrics that policy rules
+// read. analytics.RegisterMetrics adds each metric with a description and
+// unit; policy lint checks rule expressions against the registry, policy check
+// computes the values and policy explain lists them.
+package metrics
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+// Units of metric values.
+const (
+	UnitUSD     = "usd"
+	UnitPercent = "percent"
+	UnitCount   = "count"
+	UnitSeconds = "seconds"
+	UnitMinutes = "minutes"
+)
+
+// Metric describes one named value.
+type Metric struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	Unit        string `json:"unit"`
+	// Source is the name of the Source that computes the metric.
+	Source string `json:"source"`
+}
+
+// Input is the usage metrics are computed from: the runs and jobs of a
+// repository or of one entity in it, over Start to End.
+type Input struct {
+	Runs []model.WorkflowRun
+	Jobs []model.Job
+	// AccountJobs are every job of the billing account since the start of
+	// the first billing cycle covered, over which the free tier is
+	// allocated. Nil treats Jobs as the whole account.
+	AccountJobs []model.Job
+	// History are the jobs of the same repository or entity reaching back
+	// far enough for trend metrics, which may be before Start. Nil uses
+	// Jobs.
+	History []model.Job
+	Pricing pricing.Config
+	Start   time.Time
+	End     time.Time
+}
+
+// Source computes a group of related metrics in one pass, such as every
+// cost metric from a single pricing of the jobs.
+type Source struct {
+	Name    string
+	Metrics []Metric
+	// Compute returns a value for each of Metrics; missing ones are 0.
+	Compute func(Input) (map[string]float64, error)
+}
+
+var (
+	mu      sync.RWMutex
+	sources []Source
+	byName  = map[string]Metric{}
+)
+
+// Register adds s to the registry. It panics when a metric name is taken or
+// s is incomplete.
+func Register(s Source) {
+	mu.Lock()
+	defer mu.Unlock()
+	if s.Name == "" || s.Compute == nil || len(s.Metrics) == 0 {
+		panic("metrics: Register needs a name, metrics and a Compute function")
+	}
+	for i, m := range s.Metrics {
+		if m.Name == "" {
+			panic(fmt.Sprintf("metrics: source %q registers a metric without a name", s.Name))
+		}
+		if prev, dup := byName[m.Name]; dup {
+			panic(fmt.Sprintf("metrics: %q registered by %q and %q", m.Name, prev.Source, s.Name))
+		}
+		m.Source = s.Name
+		s.Metrics[i] = m
+		byName[m.Name] = m
+	}
+	sources = append(sources, s)
+}
+
+// Lookup returns the registered metric called name.
+func Lookup(name string) (Metric, bool) {
+	mu.RLock()
+	defer mu.RUnlock()
+	m, ok := byName[name]
+	return m, ok
+}
+
+// All returns every registered metric in name order.
+func All() []Metric {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make([]Metric, 0, len(byName))
+	for _, m := range byName {
+		out = append(out, m)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Compute evaluates every registered metric over in.
+func Compute(in Input) (map[string]float64, error) {
+	mu.RLock()
+	defer mu.RUnlock()
+	out := make(map[string]float64, len(byName))
+	for _, s := range sources {
+		values, err := s.Compute(in)
+		if err != nil {
+			return nil, fmt.Errorf("%s metrics: %w", s.Name, err)
+		}
+		for _, m := range s.Metrics {
+			out[m.Name] = values[m.Name]
+		}
+	}
+	return out, nil
+}
@@ -0,0 +1,34 @@
+package metrics
+
+import "testing"
+
+func TestRegisterComputeAndLookup(t *testing.T) {
+	Register(Source{
+		Name: "test",
+		Metrics: []Metric{
+			{Name: "test_jobs", Unit: UnitCount, Description: "jobs in the input"},
+			{Name: "test_unset", Unit: UnitCount, Description: "never computed"},
+		},
+		Compute: func(in Input) (map[string]float64, error) {
+			return map[string]float64{"test_jobs": float64(len(in.Jobs))}, nil
+		},
+	})
+	m, ok := Lookup("test_jobs")
+	if !ok || m.Source != "test" || m.Unit != UnitCount {
+		t.Fatalf("unexpected lookup %+v %v", m, ok)
+	}
+	got, err := Compute(Input{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v, ok := got["test_unset"]; !ok || v != 0 || got["test_jobs"] != 0 {
+		t.Fatalf("expected every metric with a value, got %v", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Fatal("expected a panic for a duplicate metric")
+		}
+	}()
+	Register(Source{Name: "again", Metrics: []Metric{{Name: "test_jobs"}}, Compute: func(Input) (map[string]float64, error) { return nil, nil }})
+}
@@ -7,6 +7,7 @@ type WorkflowRun struct {
 	Repo         string    `json:"repo"`
 	WorkflowID   int64     `json:"workflow_id"`
 	WorkflowName string    `json:"workflow_name"`
+	WorkflowPath string    `json:"workflow_path,omitempty"`
 	HeadBranch   string    `json:"head_branch"`
 	Event        string    `json:"event"`
 	Status       string    `json:"status"`
@@ -32,6 +33,51 @@ type Job struct {
 	RunnerGroup  string    `json:"runner_group"`
 	IsSelfHosted bool      `json:"is_self_hosted"`
 	DurationSec  int       `json:"duration_sec"`
+	Labels       []string  `json:"labels,omitempty"`
+	Steps        []Step    `json:"steps,omitempty"`
+}
+
+// Step is one step of a job, as reported in the jobs API `steps` array.
+type Step struct {
+	JobID       int64     `json:"job_id"`
+	RunAttempt  int       `json:"run_attempt"`
+	Number      int       `json:"number"`
+	Name        string    `json:"name"`
+	Status      string    `json:"status"`
+	Conclusion  string    `json:"conclusion"`
+	StartedAt   time.Time `json:"started_at"`
+	CompletedAt time.Time `json:"completed_at"`
+	DurationSec int       `json:"duration_sec"`
+}
+
+// Artifact is a workflow artifact as listed by the artifacts API. ExpiresAt
+// minus CreatedAt is its retention. RemovedAt is set for an artifact deleted
+// before it expired: a full listing no longer returned it, and it is when a
+// scan last saw it.
+type Artifact struct {
+	ID        int64     `json:"id"`
+	Repo      string    `json:"repo"`
+	Name      string    `json:"name"`
+	RunID     int64     `json:"run_id"`
+	SizeBytes int64     `json:"size_bytes"`
+	Expired   bool      `json:"expired"`
+	CreatedAt time.Time `json:"created_at"`
+	ExpiresAt time.Time `json:"expires_at"`
+	RemovedAt time.Time `json:"removed_at"`
+}
+
+// ActionsCache is a dependency cache entry as listed by the caches API.
+// LastSeenAt is when a scan last found it; caches are evicted without a
+// trace, so that bounds how long it was stored.
+type ActionsCache struct {
+	ID             int64     `json:"id"`
+	Repo           string    `json:"repo"`
+	Key            string    `json:"key"`
+	Ref            string    `json:"ref"`
+	SizeBytes      int64     `json:"size_bytes"`
+	CreatedAt      time.Time `json:"created_at"`
+	LastAccessedAt time.Time `json:"last_accessed_at"`
+	LastSeenAt     time.Time `json:"last_seen_at"`
 }
 
 type OSCost struct {
@@ -42,14 +88,57 @@ type OSCost struct {
 	Percentage float64 `json:"percentage"`
 }
 
+// SKUCost is gross (pre free tier) spend on one canonical billing SKU.
+type SKUCost struct {
+	SKU     string  `json:"sku"`
+	OS      string  `json:"os"`
+	Minutes float64 `json:"minutes"`
+	CostUSD float64 `json:"cost_usd"`
+}
+
+type SelfHostedGroupCost struct {
+	Group   string  `json:"group"`
+	Minutes float64 `json:"minutes"`
+	CostUSD float64 `json:"cost_usd"`
+	Jobs    int     `json:"jobs"`
+}
+
+// SelfHostedCost is spend on self-hosted runners. It is not part of the
+// GitHub bill and is therefore kept out of CostResult.TotalCostUSD.
+type SelfHostedCost struct {
+	Minutes float64                        `json:"minutes"`
+	CostUSD float64                        `json:"cost_usd"`
+	Jobs    int                            `json:"jobs"`
+	ByGroup map[string]SelfHostedGroupCost `json:"by_group,omitempty"`
+}
+
+// StorageCost is artifact and cache storage over a period in GB-months.
+// FreeGBMonths is the plan's included storage applied to artifacts and
+// CacheIncludedGBMonths the free cache allowance. Storage is billed apart
+// from minutes, so it is not part of CostResult.TotalCostUSD.
+type StorageCost struct {
+	ArtifactGBMonths      float64 `json:"artifact_gb_months"`
+	CacheGBMonths         float64 `json:"cache_gb_months"`
+	FreeGBMonths          float64 `json:"free_gb_months"`
+	CacheIncludedGBMonths float64 `json:"cache_included_gb_months"`
+	BillableGBMonths      float64 `json:"billable_gb_months"`
+	CostUSD               float64 `json:"cost_usd"`
+	Artifacts             int     `json:"artifacts"`
+	Caches                int     `json:"caches"`
+}
+
 type CostResult struct {
-	TotalMinutes     float64           `json:"total_minutes"`
-	BillableMinutes  float64           `json:"billable_minutes"`
-	TotalCostUSD     float64           `json:"total_cost_usd"`
-	FreeTierUsed     float64           `json:"free_tier_used_min"`
-	ByOS             map[string]OSCost `json:"by_os"`
-	DataCompleteness float64           `json:"data_completeness"`
-	Disclaimer       string            `json:"disclaimer"`
+	TotalMinutes     float64            `json:"total_minutes"`
+	BillableMinutes  float64            `json:"billable_minutes"`
+	TotalCostUSD     float64            `json:"total_cost_usd"`
+	GrossCostUSD     float64            `json:"gross_cost_usd"`
+	FreeTierUsed     float64            `json:"free_tier_used_min"`
+	ByOS             map[string]OSCost  `json:"by_os"`
+	BySKU            map[string]SKUCost `json:"by_sku,omitempty"`
+	SelfHosted       SelfHostedCost     `json:"self_hosted"`
+	Storage          StorageCost        `json:"storage"`
+	DataCompleteness float64            `json:"data_completeness"`
+	Disclaimer       string             `json:"disclaimer"`
 }
 
 type WasteMetrics struct {
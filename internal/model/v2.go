@@ -5,6 +5,8 @@ import "time"
 type BillingSnapshot struct {
 	Repo          string    `json:"repo"`
 	Period        string    `json:"period"`
+	SKU           string    `json:"sku,omitempty"`
+	Workflow      string    `json:"workflow,omitempty"`
 	ActualCostUSD float64   `json:"actual_cost_usd"`
 	Source        string    `json:"source"`
 	FetchedAt     time.Time `json:"fetched_at"`
@@ -13,6 +15,7 @@ type BillingSnapshot struct {
 type ReconcileResult struct {
 	Repo              string    `json:"repo"`
 	Period            string    `json:"period"`
+	SKU               string    `json:"sku,omitempty"`
 	EstimatedCostUSD  float64   `json:"estimated_cost_usd"`
 	ActualCostUSD     float64   `json:"actual_cost_usd"`
 	DeltaRatio        float64   `json:"delta_ratio"`
@@ -26,6 +29,8 @@ type PolicyRun struct {
 	PeriodStart   time.Time `json:"period_start"`
 	PeriodEnd     time.Time `json:"period_end"`
 	RuleID        string    `json:"rule_id"`
+	Scope         string    `json:"scope"`
+	Entity        string    `json:"entity,omitempty"`
 	Severity      string    `json:"severity"`
 	Matched       bool      `json:"matched"`
 	EvidenceKey   string    `json:"evidence_key"`
@@ -44,3 +49,28 @@ type SuggestionRecord struct {
 	EvidenceJSON       string    `json:"evidence_json"`
 	CreatedAt          time.Time `json:"created_at"`
 }
+
+// Rollup levels, from the billing account down to a single workflow.
+const (
+	RollupEnterprise = "enterprise"
+	RollupOrg        = "org"
+	RollupRepo       = "repo"
+	RollupWorkflow   = "workflow"
+)
+
+// RollupNode is one level of a cost rollup with subtotals over its children.
+// GrossCostUSD is before the free tier. The free tier is allocated once per
+// billing account (the node with Account set) and subtotalled from jobs.
+type RollupNode struct {
+	Level           string       `json:"level"`
+	Name            string       `json:"name"`
+	Runs            int          `json:"runs"`
+	Minutes         float64      `json:"minutes"`
+	BillableMinutes float64      `json:"billable_minutes"`
+	GrossCostUSD    float64      `json:"gross_cost_usd"`
+	FreeTierMinutes float64      `json:"free_tier_minutes,omitempty"`
+	NetCostUSD      float64      `json:"net_cost_usd"`
+	Account         bool         `json:"account,omitempty"`
+	SelfHostedUSD   float64      `json:"self_hosted_usd,omitempty"`
+	Children        []RollupNode `json:"children,omitempty"`
+}
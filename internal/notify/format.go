@@ -0,0 +1,140 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/template"
+)
+
+// encoder renders a message as a request body and its content type.
+type encoder func(Message) ([]byte, string, error)
+
+const (
+	// Slack rejects header text over 150 characters, section text over 3000
+	// and sections with more than 10 fields.
+	slackHeaderLimit  = 150
+	slackTextLimit    = 3000
+	slackFieldsPerRow = 10
+)
+
+func encodeJSON(m Message) ([]byte, string, error) {
+	var v any = m
+	if m.Data != nil {
+		v = m.Data
+	}
+	b, err := json.Marshal(v)
+	return b, "application/json", err
+}
+
+// encodeSlack renders m as Block Kit for a Slack incoming webhook: a header,
+// the facts as fields, the report as preformatted text and the severity as
+// context.
+func encodeSlack(m Message) ([]byte, string, error) {
+	type text struct {
+		Type string `json:"type"`
+		Text string `json:"text"`
+	}
+	type block struct {
+		Type     string `json:"type"`
+		Text     *text  `json:"text,omitempty"`
+		Fields   []text `json:"fields,omitempty"`
+		Elements []text `json:"elements,omitempty"`
+	}
+	blocks := []block{{Type: "header", Text: &text{Type: "plain_text", Text: truncate(m.Title, slackHeaderLimit)}}}
+	for i := 0; i < len(m.Facts); i += slackFieldsPerRow {
+		var fields []text
+		for _, f := range m.Facts[i:min(i+slackFieldsPerRow, len(m.Facts))] {
+			fields = append(fields, text{Type: "mrkdwn", Text: "*" + f.Name + "*\n" + f.Value})
+		}
+		blocks = append(blocks, block{Type: "section", Fields: fields})
+	}
+	if body := strings.TrimRight(m.Text, "\n"); body != "" {
+		// Leave room for the code fences.
+		blocks = append(blocks, block{Type: "section", Text: &text{Type: "mrkdwn", Text: "```" + truncate(body, slackTextLimit-6) + "```"}})
+	}
+	blocks = append(blocks, block{Type: "context", Elements: []text{{Type: "mrkdwn", Text: fmt.Sprintf("cicost %s · %s", m.Kind, severityOrInfo(m.Severity))}}})
+	b, err := json.Marshal(map[string]any{"text": m.Title, "blocks": blocks})
+	return b, "application/json", err
+}
+
+// encodeTeams renders m as an Adaptive Card message for a Teams workflow or
+// incoming webhook.
+func encodeTeams(m Message) ([]byte, string, error) {
+	color := map[string]string{SeverityError: "attention", SeverityWarning: "warning"}[m.Severity]
+	if color == "" {
+		color = "good"
+	}
+	body := []map[string]any{{
+		"type": "TextBlock", "text": m.Title, "weight": "bolder", "size": "medium", "color": color, "wrap": true,
+	}}
+	if len(m.Facts) > 0 {
+		facts := make([]map[string]string, 0, len(m.Facts))
+		for _, f := range m.Facts {
+			facts = append(facts, map[string]string{"title": f.Name, "value": f.Value})
+		}
+		body = append(body, map[string]any{"type": "FactSet", "facts": facts})
+	}
+	if text := strings.TrimRight(m.Text, "\n"); text != "" {
+		body = append(body, map[string]any{"type": "TextBlock", "text": text, "fontType": "monospace", "wrap": true})
+	}
+	card := map[string]any{
+		"$schema": "http://adaptivecards.io/schemas/adaptive-card.json",
+		"type":    "AdaptiveCard",
+		"version": "1.4",
+		"body":    body,
+	}
+	b, err := json.Marshal(map[string]any{
+		"type": "message",
+		"attachments": []map[string]any{{
+			"contentType": "application/vnd.microsoft.card.adaptive",
+			"content":     card,
+		}},
+	})
+	return b, "application/json", err
+}
+
+// templateEncoder parses src as a text/template executed with the Message.
+// The json function quotes a value for use inside a JSON body.
+func templateEncoder(name, src, contentType string) (encoder, error) {
+	if strings.TrimSpace(src) == "" {
+		return nil, fmt.Errorf("notifier %q: template is required for type template", name)
+	}
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(template.FuncMap{
+		"json": func(v any) (string, error) {
+			b, err := json.Marshal(v)
+			return string(b), err
+		},
+		"upper": strings.ToUpper,
+		"lower": strings.ToLower,
+	}).Parse(src)
+	if err != nil {
+		return nil, fmt.Errorf("notifier %q: invalid template: %w", name, err)
+	}
+	if contentType == "" {
+		contentType = "application/json"
+	}
+	return func(m Message) ([]byte, string, error) {
+		var buf bytes.Buffer
+		if err := tmpl.Execute(&buf, m); err != nil {
+			return nil, "", err
+		}
+		return buf.Bytes(), contentType, nil
+	}, nil
+}
+
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n-1]) + "…"
+}
+
+func severityOrInfo(s string) string {
+	if s == "" {
+		return SeverityInfo
+	}
+	return s
+}
@@ -0,0 +1,245 @@
+// Package notify delivers budget, anomaly and policy results to chat and
+// webhook receivers.
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Notifier types.
+const (
+	TypeWebhook  = "webhook"
+	TypeSlack    = "slack"
+	TypeTeams    = "teams"
+	TypeTemplate = "template"
+)
+
+// Message severities.
+const (
+	SeverityInfo    = "info"
+	SeverityWarning = "warning"
+	SeverityError   = "error"
+)
+
+// DefaultSignatureHeader carries the HMAC-SHA256 of the request body when a
+// notifier has a secret.
+const DefaultSignatureHeader = "X-CICost-Signature"
+
+const (
+	defaultRetries   = 2
+	defaultBaseDelay = 500 * time.Millisecond
+	defaultMaxDelay  = 10 * time.Second
+	defaultTimeout   = 10 * time.Second
+)
+
+// Message is one notification. Notifiers render it in their own format;
+// the plain webhook posts Data unchanged so existing receivers keep the
+// payload they already parse.
+type Message struct {
+	Kind     string `json:"kind"`
+	Title    string `json:"title"`
+	Severity string `json:"severity"`
+	Text     string `json:"text"`
+	Facts    []Fact `json:"facts,omitempty"`
+	Data     any    `json:"data,omitempty"`
+}
+
+// Fact is a name/value pair shown in card layouts.
+type Fact struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// Notifier sends messages to one receiver.
+type Notifier interface {
+	Name() string
+	Send(ctx context.Context, m Message) error
+}
+
+// Config describes one notifier. Template is a text/template executed with
+// the Message and is required for TypeTemplate. Secret, when set, signs
+// each body into SignatureHeader as "sha256=<hex>".
+type Config struct {
+	Name            string
+	Type            string
+	URL             string
+	Template        string
+	ContentType     string
+	Headers         map[string]string
+	Secret          string
+	SignatureHeader string
+	// Retries is how often a failed delivery is retried; negative disables
+	// retries and 0 uses the default.
+	Retries   int
+	BaseDelay time.Duration
+	Timeout   time.Duration
+	Client    *http.Client
+}
+
+// New builds the notifier c describes.
+func New(c Config) (Notifier, error) {
+	if strings.TrimSpace(c.URL) == "" {
+		return nil, fmt.Errorf("notifier %q: url is required", c.Name)
+	}
+	typ := strings.ToLower(strings.TrimSpace(c.Type))
+	var enc encoder
+	switch typ {
+	case "", TypeWebhook, "json":
+		typ, enc = TypeWebhook, encodeJSON
+	case TypeSlack:
+		enc = encodeSlack
+	case TypeTeams:
+		enc = encodeTeams
+	case TypeTemplate:
+		var err error
+		if enc, err = templateEncoder(c.Name, c.Template, c.ContentType); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("notifier %q: unknown type %q (use webhook|slack|teams|template)", c.Name, c.Type)
+	}
+	w := &Webhook{
+		name:            c.Name,
+		typ:             typ,
+		url:             c.URL,
+		encode:          enc,
+		headers:         c.Headers,
+		secret:          c.Secret,
+		signatureHeader: c.SignatureHeader,
+		retries:         c.Retries,
+		baseDelay:       c.BaseDelay,
+		client:          c.Client,
+	}
+	if w.name == "" {
+		w.name = typ
+	}
+	if w.signatureHeader == "" {
+		w.signatureHeader = DefaultSignatureHeader
+	}
+	switch {
+	case w.retries == 0:
+		w.retries = defaultRetries
+	case w.retries < 0:
+		w.retries = 0
+	}
+	if w.baseDelay <= 0 {
+		w.baseDelay = defaultBaseDelay
+	}
+	if w.client == nil {
+		timeout := c.Timeout
+		if timeout <= 0 {
+			timeout = defaultTimeout
+		}
+		w.client = &http.Client{Timeout: timeout}
+	}
+	return w, nil
+}
+
+// Webhook posts encoded messages over HTTP, retrying network errors, 429s
+// and 5xx responses with exponential backoff.
+type Webhook struct {
+	name            string
+	typ             string
+	url             string
+	encode          encoder
+	headers         map[string]string
+	secret          string
+	signatureHeader string
+	retries         int
+	baseDelay       time.Duration
+	client          *http.Client
+}
+
+func (w *Webhook) Name() string { return w.name }
+
+// Type is the format the webhook posts in.
+func (w *Webhook) Type() string { return w.typ }
+
+func (w *Webhook) Send(ctx context.Context, m Message) error {
+	body, contentType, err := w.encode(m)
+	if err != nil {
+		return fmt.Errorf("notifier %s: %w", w.name, err)
+	}
+	var lastErr error
+	for attempt := 0; ; attempt++ {
+		wait, retry, err := w.post(ctx, body, contentType)
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+		if !retry || attempt >= w.retries {
+			break
+		}
+		if wait <= 0 {
+			wait = w.backoff(attempt)
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("notifier %s: %w", w.name, ctx.Err())
+		case <-time.After(wait):
+		}
+	}
+	return fmt.Errorf("notifier %s: %w", w.name, lastErr)
+}
+
+// post sends body once and reports how long the receiver asked to wait and
+// whether a failure is worth retrying.
+func (w *Webhook) post(ctx context.Context, body []byte, contentType string) (time.Duration, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return 0, false, err
+	}
+	req.Header.Set("Content-Type", contentType)
+	req.Header.Set("User-Agent", "cicost")
+	for k, v := range w.headers {
+		req.Header.Set(k, v)
+	}
+	if w.secret != "" {
+		req.Header.Set(w.signatureHeader, Sign(w.secret, body))
+	}
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return 0, ctx.Err() == nil, fmt.Errorf("send failed: %w", err)
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+	_ = resp.Body.Close()
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return 0, false, nil
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		var wait time.Duration
+		if sec, err := strconv.Atoi(strings.TrimSpace(resp.Header.Get("Retry-After"))); err == nil && sec >= 0 {
+			wait = min(time.Duration(sec)*time.Second, defaultMaxDelay)
+		}
+		return wait, true, fmt.Errorf("receiver returned status %d", resp.StatusCode)
+	default:
+		return 0, false, fmt.Errorf("receiver returned status %d", resp.StatusCode)
+	}
+}
+
+func (w *Webhook) backoff(attempt int) time.Duration {
+	d := w.baseDelay << attempt
+	if d <= 0 || d > defaultMaxDelay {
+		d = defaultMaxDelay
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
+
+// Sign returns the signature header value for body: "sha256=" and the hex
+// HMAC-SHA256 of body under secret, as GitHub signs its webhooks.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
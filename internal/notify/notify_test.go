@@ -0,0 +1,176 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+type receiver struct {
+	mu       sync.Mutex
+	bodies   [][]byte
+	headers  []http.Header
+	statuses []int
+}
+
+func (r *receiver) handler(w http.ResponseWriter, req *http.Request) {
+	b, _ := io.ReadAll(req.Body)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bodies = append(r.bodies, b)
+	r.headers = append(r.headers, req.Header.Clone())
+	status := http.StatusOK
+	if len(r.statuses) > 0 {
+		status, r.statuses = r.statuses[0], r.statuses[1:]
+	}
+	w.WriteHeader(status)
+}
+
+func newReceiver(t *testing.T, statuses ...int) (*receiver, string) {
+	t.Helper()
+	r := &receiver{statuses: statuses}
+	srv := httptest.NewServer(http.HandlerFunc(r.handler))
+	t.Cleanup(srv.Close)
+	return r, srv.URL
+}
+
+var testMessage = Message{
+	Kind:     "budget",
+	Title:    "Budget EXCEEDED for owner/repo",
+	Severity: SeverityError,
+	Text:     "Budget EXCEEDED for owner/repo\n  Actual   : $12.00\n",
+	Facts:    []Fact{{Name: "Budget", Value: "$10.00"}, {Name: "Actual", Value: "$12.00"}},
+	Data:     map[string]any{"status": "exceeded", "actual_usd": 12.0},
+}
+
+func TestWebhookPostsDataSignedAndRetries(t *testing.T) {
+	r, url := newReceiver(t, http.StatusServiceUnavailable, http.StatusOK)
+	n, err := New(Config{URL: url, Secret: "s3cret", BaseDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	if len(r.bodies) != 2 {
+		t.Fatalf("expected a retry after 503, got %d requests", len(r.bodies))
+	}
+	var got map[string]any
+	if err := json.Unmarshal(r.bodies[1], &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["status"] != "exceeded" || got["actual_usd"] != 12.0 {
+		t.Fatalf("plain webhook must post Data unchanged, got %v", got)
+	}
+	if sig := r.headers[1].Get(DefaultSignatureHeader); sig != Sign("s3cret", r.bodies[1]) || !strings.HasPrefix(sig, "sha256=") {
+		t.Fatalf("unexpected signature %q", sig)
+	}
+}
+
+func TestWebhookDoesNotRetryClientErrors(t *testing.T) {
+	r, url := newReceiver(t, http.StatusBadRequest, http.StatusOK)
+	n, err := New(Config{URL: url, BaseDelay: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = n.Send(context.Background(), testMessage)
+	if err == nil || !strings.Contains(err.Error(), "status 400") {
+		t.Fatalf("expected a 400 error, got %v", err)
+	}
+	if len(r.bodies) != 1 {
+		t.Fatalf("400 must not be retried, got %d requests", len(r.bodies))
+	}
+
+	r, url = newReceiver(t, http.StatusInternalServerError, http.StatusInternalServerError, http.StatusInternalServerError)
+	n, _ = New(Config{URL: url, Retries: 1, BaseDelay: time.Millisecond})
+	if err := n.Send(context.Background(), testMessage); err == nil {
+		t.Fatal("expected an error once retries run out")
+	}
+	if len(r.bodies) != 2 {
+		t.Fatalf("expected 1 retry, got %d requests", len(r.bodies))
+	}
+}
+
+func TestSlackAndTeamsPayloads(t *testing.T) {
+	r, url := newReceiver(t)
+	slack, _ := New(Config{Type: TypeSlack, URL: url})
+	teams, _ := New(Config{Type: TypeTeams, URL: url})
+	for _, n := range []Notifier{slack, teams} {
+		if err := n.Send(context.Background(), testMessage); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var s struct {
+		Text   string `json:"text"`
+		Blocks []struct {
+			Type   string `json:"type"`
+			Fields []struct {
+				Text string `json:"text"`
+			} `json:"fields"`
+		} `json:"blocks"`
+	}
+	if err := json.Unmarshal(r.bodies[0], &s); err != nil {
+		t.Fatal(err)
+	}
+	if s.Text != testMessage.Title || len(s.Blocks) != 4 || s.Blocks[0].Type != "header" || s.Blocks[1].Fields[1].Text != "*Actual*\n$12.00" {
+		t.Fatalf("unexpected slack payload: %s", r.bodies[0])
+	}
+
+	var tm struct {
+		Type        string `json:"type"`
+		Attachments []struct {
+			ContentType string `json:"contentType"`
+			Content     struct {
+				Type string           `json:"type"`
+				Body []map[string]any `json:"body"`
+			} `json:"content"`
+		} `json:"attachments"`
+	}
+	if err := json.Unmarshal(r.bodies[1], &tm); err != nil {
+		t.Fatal(err)
+	}
+	if tm.Type != "message" || len(tm.Attachments) != 1 || tm.Attachments[0].Content.Type != "AdaptiveCard" {
+		t.Fatalf("unexpected teams payload: %s", r.bodies[1])
+	}
+	if body := tm.Attachments[0].Content.Body; len(body) != 3 || body[0]["color"] != "attention" || body[1]["type"] != "FactSet" {
+		t.Fatalf("unexpected card body: %v", body)
+	}
+}
+
+func TestTemplateNotifier(t *testing.T) {
+	r, url := newReceiver(t)
+	n, err := New(Config{
+		Type:        TypeTemplate,
+		URL:         url,
+		Template:    `{"summary":{{json .Title}},"level":"{{upper .Severity}}","facts":{{len .Facts}}}`,
+		ContentType: "application/vnd.example+json",
+		Headers:     map[string]string{"X-Team": "ci"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := n.Send(context.Background(), testMessage); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"summary":"Budget EXCEEDED for owner/repo","level":"ERROR","facts":2}`
+	if string(r.bodies[0]) != want {
+		t.Fatalf("got %s, want %s", r.bodies[0], want)
+	}
+	if h := r.headers[0]; h.Get("Content-Type") != "application/vnd.example+json" || h.Get("X-Team") != "ci" {
+		t.Fatalf("unexpected headers: %v", h)
+	}
+
+	if _, err := New(Config{Type: TypeTemplate, URL: url, Template: "{{.Nope"}); err == nil {
+		t.Fatal("expected a template parse error")
+	}
+	if _, err := New(Config{Type: "pager", URL: url}); err == nil {
+		t.Fatal("expected an unknown type error")
+	}
+}
@@ -21,6 +21,8 @@ func RenderReportCSV(v ReportView) (string, error) {
 		{"summary", "total_minutes_raw", fmt.Sprintf("%.2f", v.Cost.TotalMinutes)},
 		{"summary", "total_minutes_billable", fmt.Sprintf("%.2f", v.Cost.BillableMinutes)},
 		{"summary", "estimated_cost_usd", fmt.Sprintf("%.2f", v.Cost.TotalCostUSD)},
+		{"summary", "gross_cost_usd", fmt.Sprintf("%.2f", v.Cost.GrossCostUSD)},
+		{"summary", "free_tier_used_min", fmt.Sprintf("%.2f", v.Cost.FreeTierUsed)},
 		{"pricing", "pricing_source", v.PricingSource},
 		{"pricing", "pricing_snapshot_version", v.PricingSnapshotVersion},
 		{"pricing", "pricing_effective_from", v.PricingEffectiveFrom},
@@ -44,6 +46,28 @@ func RenderReportCSV(v ReportView) (string, error) {
 			[]string{"by_os", osv.OS + "_cost_pct", fmt.Sprintf("%.2f", osv.Percentage)},
 		)
 	}
+	if v.Cost.SelfHosted.Jobs > 0 {
+		rows = append(rows,
+			[]string{"self_hosted", "minutes", fmt.Sprintf("%.2f", v.Cost.SelfHosted.Minutes)},
+			[]string{"self_hosted", "cost_usd", fmt.Sprintf("%.2f", v.Cost.SelfHosted.CostUSD)},
+		)
+		for _, g := range sortedSelfHostedGroups(v.Cost.SelfHosted) {
+			rows = append(rows,
+				[]string{"self_hosted", g.Group + "_minutes", fmt.Sprintf("%.2f", g.Minutes)},
+				[]string{"self_hosted", g.Group + "_cost_usd", fmt.Sprintf("%.2f", g.CostUSD)},
+			)
+		}
+	}
+	if st := v.Cost.Storage; st.Artifacts+st.Caches > 0 {
+		rows = append(rows,
+			[]string{"storage", "artifacts", fmt.Sprintf("%d", st.Artifacts)},
+			[]string{"storage", "artifact_gb_months", fmt.Sprintf("%.4f", st.ArtifactGBMonths)},
+			[]string{"storage", "caches", fmt.Sprintf("%d", st.Caches)},
+			[]string{"storage", "cache_gb_months", fmt.Sprintf("%.4f", st.CacheGBMonths)},
+			[]string{"storage", "billable_gb_months", fmt.Sprintf("%.4f", st.BillableGBMonths)},
+			[]string{"storage", "cost_usd", fmt.Sprintf("%.2f", st.CostUSD)},
+		)
+	}
 	if err := w.WriteAll(rows); err != nil {
 		return "", err
 	}
@@ -18,6 +18,7 @@ func RenderReportMarkdown(v ReportView) string {
 	fmt.Fprintf(&b, "| Metric | Value |\n|---|---:|\n")
 	fmt.Fprintf(&b, "| Total Minutes (raw) | %.2f |\n", v.Cost.TotalMinutes)
 	fmt.Fprintf(&b, "| Total Minutes (billable) | %.2f |\n", v.Cost.BillableMinutes)
+	fmt.Fprintf(&b, "| Gross Cost (USD) | $%.2f |\n", v.Cost.GrossCostUSD)
 	fmt.Fprintf(&b, "| Estimated Cost (USD) | $%.2f |\n", v.Cost.TotalCostUSD)
 	fmt.Fprintf(&b, "| Free Tier Used | %.2f min |\n\n", v.Cost.FreeTierUsed)
 	if v.PricingSnapshotVersion != "" || v.PricingSource != "" {
@@ -31,6 +32,13 @@ func RenderReportMarkdown(v ReportView) string {
 			fmt.Fprintf(&b, "| Calibration Factor | %.4f |\n", v.CalibrationFactor)
 		}
 		fmt.Fprintf(&b, "\n")
+		if v.Calibrated && len(v.CalibrationBySKU) > 0 {
+			fmt.Fprintf(&b, "| SKU | Factor | Delta | Confidence |\n|---|---:|---:|---|\n")
+			for _, r := range v.CalibrationBySKU {
+				fmt.Fprintf(&b, "| %s | %.4f | %.2f%% | %s |\n", r.SKU, r.CalibrationFactor, r.DeltaRatio*100, r.Confidence)
+			}
+			fmt.Fprintf(&b, "\n")
+		}
 	}
 
 	fmt.Fprintf(&b, "## Waste\n\n")
@@ -52,6 +60,21 @@ func RenderReportMarkdown(v ReportView) string {
 		osv := v.Cost.ByOS[k]
 		fmt.Fprintf(&b, "| %s | %.2f | %.2f | %.2f%% |\n", osv.OS, osv.Minutes, osv.CostUSD, osv.Percentage)
 	}
+	if v.Cost.SelfHosted.Jobs > 0 {
+		fmt.Fprintf(&b, "\n## Self-Hosted Runners\n\n")
+		fmt.Fprintf(&b, "| Runner Group | Jobs | Minutes | Cost(USD) |\n|---|---:|---:|---:|\n")
+		for _, g := range sortedSelfHostedGroups(v.Cost.SelfHosted) {
+			fmt.Fprintf(&b, "| %s | %d | %.2f | %.2f |\n", g.Group, g.Jobs, g.Minutes, g.CostUSD)
+		}
+		fmt.Fprintf(&b, "| **Total** | %d | %.2f | %.2f |\n", v.Cost.SelfHosted.Jobs, v.Cost.SelfHosted.Minutes, v.Cost.SelfHosted.CostUSD)
+	}
+	if st := v.Cost.Storage; st.Artifacts+st.Caches > 0 {
+		fmt.Fprintf(&b, "\n## Storage\n\n")
+		fmt.Fprintf(&b, "| Kind | Count | GB-months | Included GB-months |\n|---|---:|---:|---:|\n")
+		fmt.Fprintf(&b, "| Artifacts | %d | %.4f | %.4f |\n", st.Artifacts, st.ArtifactGBMonths, st.FreeGBMonths)
+		fmt.Fprintf(&b, "| Caches | %d | %.4f | %.4f |\n", st.Caches, st.CacheGBMonths, st.CacheIncludedGBMonths)
+		fmt.Fprintf(&b, "| **Billable** | | %.4f | $%.2f |\n", st.BillableGBMonths, st.CostUSD)
+	}
 	fmt.Fprintf(&b, "\n> Data completeness: %.1f%%\n", v.Cost.DataCompleteness*100)
 	fmt.Fprintf(&b, "> Disclaimer: %s\n", v.Cost.Disclaimer)
 	return b.String()
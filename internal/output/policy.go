@@ -0,0 +1,271 @@
+package output
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/peter941221/CICost/internal/policy"
+)
+
+// PolicyView is a policy check as rendered for code scanning, test report
+// and workflow annotation consumers. Findings are the reported ones, with
+// ignored findings left out.
+type PolicyView struct {
+	Repo        string
+	PolicyPath  string
+	PeriodStart time.Time
+	PeriodEnd   time.Time
+	ToolVersion string
+	Rules       []policy.Rule
+	Findings    []policy.Finding
+}
+
+func (v PolicyView) rule(id string) policy.Rule {
+	for _, r := range v.Rules {
+		if r.ID == id {
+			return r
+		}
+	}
+	return policy.Rule{ID: id}
+}
+
+func findingMessage(f policy.Finding) string {
+	return fmt.Sprintf("%s: %s (when: %s)", f.Subject(), f.Evidence(), f.When)
+}
+
+// sarifLevel maps a severity to a SARIF result level.
+func sarifLevel(s policy.Severity) string {
+	switch s {
+	case policy.SeverityError:
+		return "error"
+	case policy.SeverityWarn:
+		return "warning"
+	default:
+		return "note"
+	}
+}
+
+type sarifLog struct {
+	Schema  string     `json:"$schema"`
+	Version string     `json:"version"`
+	Runs    []sarifRun `json:"runs"`
+}
+
+type sarifRun struct {
+	Tool    sarifTool     `json:"tool"`
+	Results []sarifResult `json:"results"`
+}
+
+type sarifTool struct {
+	Driver sarifDriver `json:"driver"`
+}
+
+type sarifDriver struct {
+	Name           string      `json:"name"`
+	Version        string      `json:"version,omitempty"`
+	InformationURI string      `json:"informationUri"`
+	Rules          []sarifRule `json:"rules"`
+}
+
+type sarifText struct {
+	Text string `json:"text"`
+}
+
+type sarifRule struct {
+	ID                   string         `json:"id"`
+	Name                 string         `json:"name"`
+	ShortDescription     sarifText      `json:"shortDescription"`
+	DefaultConfiguration sarifRuleLevel `json:"defaultConfiguration"`
+	Properties           map[string]any `json:"properties"`
+}
+
+type sarifRuleLevel struct {
+	Level string `json:"level"`
+}
+
+type sarifResult struct {
+	RuleID              string            `json:"ruleId"`
+	RuleIndex           int               `json:"ruleIndex"`
+	Level               string            `json:"level"`
+	Message             sarifText         `json:"message"`
+	Locations           []sarifLocation   `json:"locations"`
+	PartialFingerprints map[string]string `json:"partialFingerprints"`
+	Properties          map[string]any    `json:"properties"`
+}
+
+type sarifLocation struct {
+	PhysicalLocation sarifPhysicalLocation `json:"physicalLocation"`
+}
+
+type sarifPhysicalLocation struct {
+	ArtifactLocation sarifArtifact `json:"artifactLocation"`
+	Region           *sarifRegion  `json:"region,omitempty"`
+}
+
+type sarifArtifact struct {
+	URI string `json:"uri"`
+}
+
+type sarifRegion struct {
+	StartLine int `json:"startLine"`
+}
+
+// RenderPolicySARIF renders v as a SARIF 2.1.0 log for code scanning. Each
+// finding is a result located at its rule in the policy file, since the
+// cost it reports has no place in the source.
+func RenderPolicySARIF(v PolicyView) (string, error) {
+	driver := sarifDriver{
+		Name:           "CICost",
+		Version:        v.ToolVersion,
+		InformationURI: "https://github.com/peter941221/CICost",
+		Rules:          make([]sarifRule, 0, len(v.Rules)),
+	}
+	index := make(map[string]int, len(v.Rules))
+	for i, r := range v.Rules {
+		index[r.ID] = i
+		props := map[string]any{"severity": string(r.Severity), "scope": r.NormalizedScope(), "tags": []string{"ci-cost", "policy"}}
+		if len(r.Match) > 0 {
+			props["match"] = []string(r.Match)
+		}
+		driver.Rules = append(driver.Rules, sarifRule{
+			ID:                   r.ID,
+			Name:                 r.ID,
+			ShortDescription:     sarifText{Text: r.When},
+			DefaultConfiguration: sarifRuleLevel{Level: sarifLevel(r.Severity)},
+			Properties:           props,
+		})
+	}
+	results := make([]sarifResult, 0, len(v.Findings))
+	for _, f := range v.Findings {
+		loc := sarifPhysicalLocation{ArtifactLocation: sarifArtifact{URI: v.PolicyPath}}
+		if line := v.rule(f.RuleID).Line; line > 0 {
+			loc.Region = &sarifRegion{StartLine: line}
+		}
+		results = append(results, sarifResult{
+			RuleID:    f.RuleID,
+			RuleIndex: index[f.RuleID],
+			Level:     sarifLevel(f.Severity),
+			Message:   sarifText{Text: fmt.Sprintf("%s in %s", findingMessage(f), v.Repo)},
+			Locations: []sarifLocation{{PhysicalLocation: loc}},
+			// Keeps one alert per repo, rule and entity across runs.
+			PartialFingerprints: map[string]string{"cicostFinding/v1": v.Repo + "|" + f.RuleID + "|" + f.Scope + "|" + f.Entity},
+			Properties: map[string]any{
+				"repo":    v.Repo,
+				"scope":   f.Scope,
+				"entity":  f.Entity,
+				"metrics": f.Metrics,
+				"actions": f.Actions,
+			},
+		})
+	}
+	b, err := json.MarshalIndent(sarifLog{
+		Schema:  "https://json.schemastore.org/sarif-2.1.0.json",
+		Version: "2.1.0",
+		Runs:    []sarifRun{{Tool: sarifTool{Driver: driver}, Results: results}},
+	}, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(b) + "\n", nil
+}
+
+type junitSuite struct {
+	XMLName  xml.Name    `xml:"testsuite"`
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Time     string      `xml:"time,attr"`
+	Stamp    string      `xml:"timestamp,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	ClassName string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Type    string `xml:"type,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// RenderPolicyJUnit renders v as a JUnit test suite: a failed test case per
+// finding and a passed one per rule without findings.
+func RenderPolicyJUnit(v PolicyView) (string, error) {
+	suite := junitSuite{
+		Name:  "cicost policy " + v.Repo,
+		Time:  "0",
+		Stamp: v.PeriodEnd.UTC().Format("2006-01-02T15:04:05"),
+	}
+	byRule := map[string][]policy.Finding{}
+	for _, f := range v.Findings {
+		byRule[f.RuleID] = append(byRule[f.RuleID], f)
+	}
+	for _, r := range v.Rules {
+		className := "cicost.policy." + r.NormalizedScope()
+		findings := byRule[r.ID]
+		if len(findings) == 0 {
+			suite.Cases = append(suite.Cases, junitCase{Name: r.ID, ClassName: className, Time: "0"})
+			continue
+		}
+		for _, f := range findings {
+			suite.Cases = append(suite.Cases, junitCase{
+				Name:      f.Subject(),
+				ClassName: className,
+				Time:      "0",
+				Failure: &junitFailure{
+					Message: findingMessage(f),
+					Type:    string(f.Severity),
+					Text: fmt.Sprintf("repo: %s\nperiod: %s ~ %s\nwhen: %s\nevidence: %s\nactions: %s\n",
+						v.Repo, v.PeriodStart.Format("2006-01-02"), v.PeriodEnd.Format("2006-01-02"), f.When, f.Evidence(), strings.Join(f.Actions, ", ")),
+				},
+			})
+			suite.Failures++
+		}
+	}
+	suite.Tests = len(suite.Cases)
+	b, err := xml.MarshalIndent(suite, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return xml.Header + string(b) + "\n", nil
+}
+
+// RenderPolicyGitHub renders v as GitHub Actions workflow commands, one
+// ::error, ::warning or ::notice annotation per finding by severity,
+// pointing at the rule in the policy file.
+func RenderPolicyGitHub(v PolicyView) string {
+	var b strings.Builder
+	for _, f := range v.Findings {
+		command := "notice"
+		switch f.Severity {
+		case policy.SeverityError:
+			command = "error"
+		case policy.SeverityWarn:
+			command = "warning"
+		}
+		props := []string{"file=" + escapeProperty(v.PolicyPath)}
+		if line := v.rule(f.RuleID).Line; line > 0 {
+			props = append(props, fmt.Sprintf("line=%d", line))
+		}
+		props = append(props, "title="+escapeProperty("CICost policy "+f.Subject()))
+		fmt.Fprintf(&b, "::%s %s::%s\n", command, strings.Join(props, ","), escapeData(findingMessage(f)+" in "+v.Repo))
+	}
+	return b.String()
+}
+
+// escapeData and escapeProperty escape workflow command values as the
+// GitHub Actions toolkit does.
+func escapeData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
@@ -10,19 +10,20 @@ import (
 )
 
 type ReportView struct {
-	Repo                   string               `json:"repo"`
-	Start                  time.Time            `json:"start"`
-	End                    time.Time            `json:"end"`
-	Days                   int                  `json:"days"`
-	TotalRuns              int                  `json:"total_runs"`
-	Cost                   model.CostResult     `json:"cost"`
-	Waste                  model.WasteMetrics   `json:"waste"`
-	Hotspots               []model.HotspotEntry `json:"hotspots,omitempty"`
-	PricingSnapshotVersion string               `json:"pricing_snapshot_version,omitempty"`
-	PricingEffectiveFrom   string               `json:"pricing_effective_from,omitempty"`
-	PricingSource          string               `json:"pricing_source,omitempty"`
-	Calibrated             bool                 `json:"calibrated"`
-	CalibrationFactor      float64              `json:"calibration_factor,omitempty"`
+	Repo                   string                  `json:"repo"`
+	Start                  time.Time               `json:"start"`
+	End                    time.Time               `json:"end"`
+	Days                   int                     `json:"days"`
+	TotalRuns              int                     `json:"total_runs"`
+	Cost                   model.CostResult        `json:"cost"`
+	Waste                  model.WasteMetrics      `json:"waste"`
+	Hotspots               []model.HotspotEntry    `json:"hotspots,omitempty"`
+	PricingSnapshotVersion string                  `json:"pricing_snapshot_version,omitempty"`
+	PricingEffectiveFrom   string                  `json:"pricing_effective_from,omitempty"`
+	PricingSource          string                  `json:"pricing_source,omitempty"`
+	Calibrated             bool                    `json:"calibrated"`
+	CalibrationFactor      float64                 `json:"calibration_factor,omitempty"`
+	CalibrationBySKU       []model.ReconcileResult `json:"calibration_by_sku,omitempty"`
 }
 
 func RenderReportTable(v ReportView) string {
@@ -33,6 +34,7 @@ func RenderReportTable(v ReportView) string {
 	fmt.Fprintf(&b, "  Total Runs: %d\n", v.TotalRuns)
 	fmt.Fprintf(&b, "  Total Minutes (raw): %.2f\n", v.Cost.TotalMinutes)
 	fmt.Fprintf(&b, "  Total Minutes (billable): %.2f\n", v.Cost.BillableMinutes)
+	fmt.Fprintf(&b, "  Gross Cost: $%.2f\n", v.Cost.GrossCostUSD)
 	fmt.Fprintf(&b, "  Estimated Cost: $%.2f\n", v.Cost.TotalCostUSD)
 	fmt.Fprintf(&b, "  Free Tier Used: %.2f min\n\n", v.Cost.FreeTierUsed)
 	if v.PricingSnapshotVersion != "" || v.PricingSource != "" {
@@ -46,6 +48,9 @@ func RenderReportTable(v ReportView) string {
 		}
 		if v.Calibrated {
 			fmt.Fprintf(&b, "  Calibrated: yes (factor=%.4f)\n", v.CalibrationFactor)
+			for _, r := range v.CalibrationBySKU {
+				fmt.Fprintf(&b, "    %-16s factor=%.4f confidence=%s\n", r.SKU, r.CalibrationFactor, r.Confidence)
+			}
 		}
 		fmt.Fprintf(&b, "\n")
 	}
@@ -66,6 +71,19 @@ func RenderReportTable(v ReportView) string {
 		osv := v.Cost.ByOS[k]
 		fmt.Fprintf(&b, "  %-8s minutes=%8.2f cost=$%8.2f pct=%6.2f%%\n", osv.OS, osv.Minutes, osv.CostUSD, osv.Percentage)
 	}
+	if v.Cost.SelfHosted.Jobs > 0 {
+		fmt.Fprintf(&b, "\nSELF-HOSTED (not billed by GitHub)\n")
+		fmt.Fprintf(&b, "  Total    minutes=%8.2f cost=$%8.2f jobs=%d\n", v.Cost.SelfHosted.Minutes, v.Cost.SelfHosted.CostUSD, v.Cost.SelfHosted.Jobs)
+		for _, g := range sortedSelfHostedGroups(v.Cost.SelfHosted) {
+			fmt.Fprintf(&b, "  %-8s minutes=%8.2f cost=$%8.2f jobs=%d\n", g.Group, g.Minutes, g.CostUSD, g.Jobs)
+		}
+	}
+	if st := v.Cost.Storage; st.Artifacts+st.Caches > 0 {
+		fmt.Fprintf(&b, "\nSTORAGE (billed separately from minutes)\n")
+		fmt.Fprintf(&b, "  Artifacts gb_months=%8.4f count=%d (%.4f included)\n", st.ArtifactGBMonths, st.Artifacts, st.FreeGBMonths)
+		fmt.Fprintf(&b, "  Caches    gb_months=%8.4f count=%d (%.4f included)\n", st.CacheGBMonths, st.Caches, st.CacheIncludedGBMonths)
+		fmt.Fprintf(&b, "  Billable  gb_months=%8.4f cost=$%.2f\n", st.BillableGBMonths, st.CostUSD)
+	}
 	fmt.Fprintf(&b, "\nData completeness: %.1f%%\n", v.Cost.DataCompleteness*100)
 	fmt.Fprintf(&b, "Disclaimer: %s\n", v.Cost.Disclaimer)
 	return b.String()
@@ -82,3 +100,17 @@ func RenderHotspotsTable(repo string, days int, groupBy string, entries []model.
 	}
 	return b.String()
 }
+
+func sortedSelfHostedGroups(sh model.SelfHostedCost) []model.SelfHostedGroupCost {
+	out := make([]model.SelfHostedGroupCost, 0, len(sh.ByGroup))
+	for _, g := range sh.ByGroup {
+		out = append(out, g)
+	}
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].CostUSD != out[j].CostUSD {
+			return out[i].CostUSD > out[j].CostUSD
+		}
+		return out[i].Group < out[j].Group
+	})
+	return out
+}
@@ -0,0 +1,204 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Action kinds. A finding's actions come from its rule's actions, else
+// from on_error, on_warn or on_info by severity.
+const (
+	// ActionFail makes the check exit with code 3.
+	ActionFail = "fail"
+	// ActionWarn reports the finding without failing.
+	ActionWarn = "warn"
+	// ActionNotify sends the finding to the notifier named after the colon,
+	// as in notify:ci-slack.
+	ActionNotify = "notify"
+	// ActionComment adds the finding to the pull request comment file and
+	// the GitHub step summary.
+	ActionComment = "comment"
+	// ActionIgnore drops the finding from the report.
+	ActionIgnore = "ignore"
+)
+
+// actionAliases keeps the values of early policy files working.
+var actionAliases = map[string]string{
+	"fail_ci":    ActionFail,
+	"comment_pr": ActionComment,
+}
+
+// defaultActions apply when neither the rule nor the policy sets any.
+var defaultActions = map[Severity]ActionList{
+	SeverityError: {ActionFail},
+	SeverityWarn:  {ActionWarn},
+	SeverityInfo:  {ActionWarn},
+}
+
+// ActionList is a list of actions that may be written as one string,
+// comma-separated: "fail, notify:ci-slack".
+type ActionList []string
+
+func (l *ActionList) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*l = nil
+		for _, part := range strings.Split(value.Value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				*l = append(*l, part)
+			}
+		}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// normalizeActions validates l and returns its actions in canonical form.
+func normalizeActions(l ActionList) ([]string, error) {
+	out := make([]string, 0, len(l))
+	for _, raw := range l {
+		a := strings.TrimSpace(raw)
+		kind, target, hasTarget := strings.Cut(a, ":")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		if alias, ok := actionAliases[kind]; ok && !hasTarget {
+			kind = alias
+		}
+		switch kind {
+		case ActionFail, ActionWarn, ActionComment, ActionIgnore:
+			if hasTarget {
+				return nil, fmt.Errorf("action %q takes no target", raw)
+			}
+			a = kind
+		case ActionNotify:
+			target = strings.TrimSpace(target)
+			if target == "" {
+				return nil, fmt.Errorf("action %q needs a notifier, as in notify:<name>", raw)
+			}
+			a = ActionNotify + ":" + target
+		default:
+			return nil, fmt.Errorf("unknown action %q (use fail|warn|notify:<name>|comment|ignore)", raw)
+		}
+		if !slices.Contains(out, a) {
+			out = append(out, a)
+		}
+	}
+	if slices.Contains(out, ActionIgnore) && len(out) > 1 {
+		return nil, fmt.Errorf("ignore cannot be combined with other actions")
+	}
+	return out, nil
+}
+
+// actionsFor resolves the actions of a finding of rule.
+func (c Config) actionsFor(rule Rule) ([]string, error) {
+	if len(rule.Actions) > 0 {
+		return normalizeActions(rule.Actions)
+	}
+	var l ActionList
+	switch rule.Severity {
+	case SeverityError:
+		l = c.Actions.OnError
+	case SeverityWarn:
+		l = c.Actions.OnWarn
+	case SeverityInfo:
+		l = c.Actions.OnInfo
+	}
+	if len(l) == 0 {
+		l = defaultActions[rule.Severity]
+	}
+	return normalizeActions(l)
+}
+
+func lintActions(c Config) error {
+	for _, a := range []struct {
+		key  string
+		list ActionList
+	}{{"on_error", c.Actions.OnError}, {"on_warn", c.Actions.OnWarn}, {"on_info", c.Actions.OnInfo}} {
+		if _, err := normalizeActions(a.list); err != nil {
+			return fmt.Errorf("actions.%s: %w", a.key, err)
+		}
+	}
+	return nil
+}
+
+// Summary is the outcome of the actions of a policy check. Findings have
+// Actions set and include ignored ones; Actions counts findings per action.
+type Summary struct {
+	Findings []Finding      `json:"findings"`
+	Actions  map[string]int `json:"actions"`
+	Failed   bool           `json:"failed"`
+	Ignored  int            `json:"ignored"`
+}
+
+// Resolve assigns each finding its actions.
+func Resolve(cfg Config, findings []Finding) (Summary, error) {
+	rules := make(map[string]Rule, len(cfg.Rules))
+	for _, r := range cfg.Rules {
+		rules[r.ID] = r
+	}
+	s := Summary{Findings: make([]Finding, 0, len(findings)), Actions: map[string]int{}}
+	for _, f := range findings {
+		rule, ok := rules[f.RuleID]
+		if !ok {
+			return Summary{}, fmt.Errorf("finding for unknown rule %q", f.RuleID)
+		}
+		actions, err := cfg.actionsFor(rule)
+		if err != nil {
+			return Summary{}, fmt.Errorf("rule[%s] %w", rule.ID, err)
+		}
+		f.Actions = actions
+		for _, a := range actions {
+			s.Actions[a]++
+			switch a {
+			case ActionFail:
+				s.Failed = true
+			case ActionIgnore:
+				s.Ignored++
+			}
+		}
+		s.Findings = append(s.Findings, f)
+	}
+	return s, nil
+}
+
+// Reported returns the findings that are not ignored.
+func (s Summary) Reported() []Finding {
+	out := make([]Finding, 0, len(s.Findings))
+	for _, f := range s.Findings {
+		if !f.Has(ActionIgnore) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+// Notifiers returns the notifiers named by notify actions, each with its
+// findings, in order of first use.
+func (s Summary) Notifiers() ([]string, map[string][]Finding) {
+	var names []string
+	byName := map[string][]Finding{}
+	for _, f := range s.Findings {
+		for _, a := range f.Actions {
+			name, ok := strings.CutPrefix(a, ActionNotify+":")
+			if !ok {
+				continue
+			}
+			if _, seen := byName[name]; !seen {
+				names = append(names, name)
+			}
+			byName[name] = append(byName[name], f)
+		}
+	}
+	return names, byName
+}
+
+// Has reports whether action is among f's actions.
+func (f Finding) Has(action string) bool {
+	return slices.Contains(f.Actions, action)
+}
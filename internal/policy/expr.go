@@ -0,0 +1,617 @@
+package policy
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// ParseError is a syntax or type error in a rule expression. Column is
+// 1-based and counts characters of the expression as written.
+type ParseError struct {
+	Column int
+	Msg    string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Msg)
+}
+
+// kind is the static type of an expression node.
+type kind int
+
+const (
+	kindNumber kind = iota
+	kindBool
+)
+
+func (k kind) String() string {
+	if k == kindBool {
+		return "boolean"
+	}
+	return "number"
+}
+
+// node is a parsed expression; column is where it starts. Booleans
+// evaluate to 1 or 0; the parser checks types, so eval never sees a
+// mismatch.
+type node interface {
+	kind() kind
+	column() int
+	eval(metrics map[string]float64) (float64, error)
+}
+
+type numberNode struct {
+	col   int
+	value float64
+}
+
+type boolNode struct {
+	col   int
+	value bool
+}
+
+type metricNode struct {
+	col  int
+	name string
+}
+
+type unaryNode struct {
+	col int
+	op  string
+	x   node
+}
+
+type binaryNode struct {
+	col  int
+	op   string
+	l, r node
+}
+
+type callNode struct {
+	col  int
+	name string
+	args []node
+}
+
+// groupNode is a parenthesized expression, so errors point at its "(".
+type groupNode struct {
+	node
+	col int
+}
+
+func (n numberNode) kind() kind  { return kindNumber }
+func (n boolNode) kind() kind    { return kindBool }
+func (n metricNode) kind() kind  { return kindNumber }
+func (n callNode) kind() kind    { return kindNumber }
+func (n numberNode) column() int { return n.col }
+func (n boolNode) column() int   { return n.col }
+func (n metricNode) column() int { return n.col }
+func (n unaryNode) column() int  { return n.col }
+func (n binaryNode) column() int { return n.col }
+func (n callNode) column() int   { return n.col }
+func (n groupNode) column() int  { return n.col }
+
+func (n unaryNode) kind() kind {
+	if n.op == "not" {
+		return kindBool
+	}
+	return kindNumber
+}
+
+func (n binaryNode) kind() kind {
+	switch n.op {
+	case "+", "-", "*", "/", "%":
+		return kindNumber
+	}
+	return kindBool
+}
+
+func (n numberNode) eval(map[string]float64) (float64, error) { return n.value, nil }
+
+func (n boolNode) eval(map[string]float64) (float64, error) { return truth(n.value), nil }
+
+func (n metricNode) eval(metrics map[string]float64) (float64, error) {
+	v, ok := metrics[n.name]
+	if !ok {
+		return 0, fmt.Errorf("missing metric %q", n.name)
+	}
+	return v, nil
+}
+
+func (n unaryNode) eval(metrics map[string]float64) (float64, error) {
+	v, err := n.x.eval(metrics)
+	if err != nil {
+		return 0, err
+	}
+	if n.op == "not" {
+		return truth(v == 0), nil
+	}
+	return -v, nil
+}
+
+func (n binaryNode) eval(metrics map[string]float64) (float64, error) {
+	l, err := n.l.eval(metrics)
+	if err != nil {
+		return 0, err
+	}
+	// and/or short-circuit, so a guard such as `total_runs > 0 and ...`
+	// keeps the right side from mattering when it does not hold.
+	switch {
+	case n.op == "and" && l == 0:
+		return 0, nil
+	case n.op == "or" && l != 0:
+		return 1, nil
+	}
+	r, err := n.r.eval(metrics)
+	if err != nil {
+		return 0, err
+	}
+	switch n.op {
+	case "and", "or":
+		return truth(r != 0), nil
+	case "+":
+		return l + r, nil
+	case "-":
+		return l - r, nil
+	case "*":
+		return l * r, nil
+	case "/":
+		return l / r, nil
+	case "%":
+		return math.Mod(l, r), nil
+	}
+	return truth(compare(l, n.op, r)), nil
+}
+
+func (n callNode) eval(metrics map[string]float64) (float64, error) {
+	args := make([]float64, len(n.args))
+	for i, a := range n.args {
+		v, err := a.eval(metrics)
+		if err != nil {
+			return 0, err
+		}
+		args[i] = v
+	}
+	return functions[n.name].fn(args), nil
+}
+
+type function struct {
+	minArgs, maxArgs int // maxArgs < 0 means variadic
+	fn               func([]float64) float64
+}
+
+// functions callable from rule expressions.
+var functions = map[string]function{
+	"abs": {1, 1, func(a []float64) float64 { return math.Abs(a[0]) }},
+	"min": {1, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Min(m, v)
+		}
+		return m
+	}},
+	"max": {1, -1, func(a []float64) float64 {
+		m := a[0]
+		for _, v := range a[1:] {
+			m = math.Max(m, v)
+		}
+		return m
+	}},
+	"round": {1, 1, func(a []float64) float64 { return math.Round(a[0]) }},
+	// pct_change(current, previous) is the change in percent. From a zero
+	// previous value it is 0 when nothing changed and 100 otherwise, so a
+	// metric appearing from nothing still counts as growth.
+	"pct_change": {2, 2, func(a []float64) float64 {
+		cur, prev := a[0], a[1]
+		if prev == 0 {
+			if cur == 0 {
+				return 0
+			}
+			return math.Copysign(100, cur)
+		}
+		return (cur - prev) / math.Abs(prev) * 100
+	}},
+}
+
+func truth(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+type token struct {
+	typ  tokenType
+	text string
+	col  int
+}
+
+func (t token) describe() string {
+	if t.typ == tokEOF {
+		return "end of expression"
+	}
+	return strconv.Quote(t.text)
+}
+
+// keywords maps word operators to their canonical form.
+var keywords = map[string]string{
+	"and": "and", "or": "or", "not": "not", "true": "true", "false": "false",
+}
+
+// symbols maps symbolic operators to their canonical form, longest first
+// where they share a prefix.
+var symbols = []struct{ text, op string }{
+	{"&&", "and"}, {"||", "or"}, {"<=", "<="}, {">=", ">="}, {"==", "=="}, {"!=", "!="},
+	{"<", "<"}, {">", ">"}, {"!", "not"}, {"+", "+"}, {"-", "-"}, {"*", "*"}, {"/", "/"},
+	{"%", "%"}, {"(", "("}, {")", ")"}, {",", ","},
+}
+
+func tokenize(input string) ([]token, error) {
+	rs := []rune(input)
+	var out []token
+	for i := 0; i < len(rs); {
+		r := rs[i]
+		col := i + 1
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsDigit(r) || r == '.' && i+1 < len(rs) && unicode.IsDigit(rs[i+1]):
+			j := i
+			for j < len(rs) && (unicode.IsDigit(rs[j]) || rs[j] == '.' || rs[j] == '_') {
+				j++
+			}
+			// Exponents: 1e3, 2.5E-2.
+			if j < len(rs) && (rs[j] == 'e' || rs[j] == 'E') {
+				k := j + 1
+				if k < len(rs) && (rs[k] == '+' || rs[k] == '-') {
+					k++
+				}
+				if k < len(rs) && unicode.IsDigit(rs[k]) {
+					for j = k; j < len(rs) && unicode.IsDigit(rs[j]); j++ {
+					}
+				}
+			}
+			if j < len(rs) && (unicode.IsLetter(rs[j]) || rs[j] == '_') {
+				return nil, &ParseError{Column: j + 1, Msg: fmt.Sprintf("invalid number %q", string(rs[i:j+1]))}
+			}
+			out = append(out, token{typ: tokNumber, text: string(rs[i:j]), col: col})
+			i = j
+		case unicode.IsLetter(r) || r == '_':
+			j := i
+			for j < len(rs) && (unicode.IsLetter(rs[j]) || unicode.IsDigit(rs[j]) || rs[j] == '_') {
+				j++
+			}
+			word := string(rs[i:j])
+			if op, ok := keywords[strings.ToLower(word)]; ok {
+				out = append(out, token{typ: tokOp, text: op, col: col})
+			} else {
+				out = append(out, token{typ: tokIdent, text: word, col: col})
+			}
+			i = j
+		default:
+			matched := false
+			for _, s := range symbols {
+				if strings.HasPrefix(string(rs[i:]), s.text) {
+					out = append(out, token{typ: tokOp, text: s.op, col: col})
+					i += len([]rune(s.text))
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				msg := fmt.Sprintf("unexpected character %q", r)
+				if r == '=' {
+					msg += " (use == to compare)"
+				}
+				return nil, &ParseError{Column: col, Msg: msg}
+			}
+		}
+	}
+	return append(out, token{typ: tokEOF, col: len(rs) + 1}), nil
+}
+
+// parser is a recursive-descent parser over, loosest first:
+//
+//	or      := and { ("or" | "||") and }
+//	and     := not { ("and" | "&&") not }
+//	not     := ("not" | "!") not | compare
+//	compare := sum [ ("<" | "<=" | ">" | ">=" | "==" | "!=") sum ]
+//	sum     := product { ("+" | "-") product }
+//	product := unary { ("*" | "/" | "%") unary }
+//	unary   := "-" unary | primary
+//	primary := number | "true" | "false" | metric | func "(" [or { "," or }] ")" | "(" or ")"
+type parser struct {
+	toks []token
+	pos  int
+}
+
+func (p *parser) peek() token { return p.toks[p.pos] }
+
+func (p *parser) next() token {
+	t := p.toks[p.pos]
+	if t.typ != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) isOp(ops ...string) bool {
+	t := p.peek()
+	if t.typ != tokOp {
+		return false
+	}
+	for _, op := range ops {
+		if t.text == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *parser) expect(op string) error {
+	if !p.isOp(op) {
+		t := p.peek()
+		return &ParseError{Column: t.col, Msg: fmt.Sprintf("expected %q, found %s", op, t.describe())}
+	}
+	p.next()
+	return nil
+}
+
+func want(n node, k kind, what string) error {
+	if n.kind() != k {
+		return &ParseError{Column: n.column(), Msg: fmt.Sprintf("%s needs a %s, found a %s", what, k, n.kind())}
+	}
+	return nil
+}
+
+func (p *parser) parseOr() (node, error) {
+	return p.parseLogical("or", p.parseAnd)
+}
+
+func (p *parser) parseAnd() (node, error) {
+	return p.parseLogical("and", p.parseNot)
+}
+
+func (p *parser) parseLogical(op string, operand func() (node, error)) (node, error) {
+	l, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(op) {
+		p.next()
+		r, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range []node{l, r} {
+			if err := want(x, kindBool, fmt.Sprintf("%q", op)); err != nil {
+				return nil, err
+			}
+		}
+		l = binaryNode{col: l.column(), op: op, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseNot() (node, error) {
+	if p.isOp("not") {
+		t := p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		if err := want(x, kindBool, `"not"`); err != nil {
+			return nil, err
+		}
+		return unaryNode{col: t.col, op: "not", x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+func (p *parser) parseCompare() (node, error) {
+	l, err := p.parseArith(0)
+	if err != nil {
+		return nil, err
+	}
+	if !p.isOp("<", "<=", ">", ">=", "==", "!=") {
+		return l, nil
+	}
+	t := p.next()
+	r, err := p.parseArith(0)
+	if err != nil {
+		return nil, err
+	}
+	for _, x := range []node{l, r} {
+		if err := want(x, kindNumber, fmt.Sprintf("%q", t.text)); err != nil {
+			return nil, err
+		}
+	}
+	if p.isOp("<", "<=", ">", ">=", "==", "!=") {
+		c := p.peek()
+		return nil, &ParseError{Column: c.col, Msg: fmt.Sprintf("comparisons cannot be chained, join them with and (found %q)", c.text)}
+	}
+	return binaryNode{col: l.column(), op: t.text, l: l, r: r}, nil
+}
+
+// arithLevels are the arithmetic operators by precedence, loosest first.
+var arithLevels = [][]string{{"+", "-"}, {"*", "/", "%"}}
+
+func (p *parser) parseArith(level int) (node, error) {
+	if level == len(arithLevels) {
+		return p.parseUnary()
+	}
+	l, err := p.parseArith(level + 1)
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp(arithLevels[level]...) {
+		t := p.next()
+		r, err := p.parseArith(level + 1)
+		if err != nil {
+			return nil, err
+		}
+		for _, x := range []node{l, r} {
+			if err := want(x, kindNumber, fmt.Sprintf("%q", t.text)); err != nil {
+				return nil, err
+			}
+		}
+		l = binaryNode{col: l.column(), op: t.text, l: l, r: r}
+	}
+	return l, nil
+}
+
+func (p *parser) parseUnary() (node, error) {
+	if p.isOp("-") {
+		t := p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		if err := want(x, kindNumber, `unary "-"`); err != nil {
+			return nil, err
+		}
+		return unaryNode{col: t.col, op: "-", x: x}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.typ {
+	case tokNumber:
+		v, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, &ParseError{Column: t.col, Msg: fmt.Sprintf("invalid number %q", t.text)}
+		}
+		return numberNode{col: t.col, value: v}, nil
+	case tokIdent:
+		if !p.isOp("(") {
+			return metricNode{col: t.col, name: t.text}, nil
+		}
+		return p.parseCall(t)
+	case tokOp:
+		switch t.text {
+		case "true", "false":
+			return boolNode{col: t.col, value: t.text == "true"}, nil
+		case "(":
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return groupNode{node: x, col: t.col}, nil
+		}
+	}
+	return nil, &ParseError{Column: t.col, Msg: fmt.Sprintf("expected a metric, number or \"(\", found %s", t.describe())}
+}
+
+func (p *parser) parseCall(name token) (node, error) {
+	f, ok := functions[name.text]
+	if !ok {
+		return nil, &ParseError{Column: name.col, Msg: fmt.Sprintf("unknown function %q", name.text)}
+	}
+	p.next() // (
+	var args []node
+	if !p.isOp(")") {
+		for {
+			a, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := want(a, kindNumber, name.text+"()"); err != nil {
+				return nil, err
+			}
+			args = append(args, a)
+			if !p.isOp(",") {
+				break
+			}
+			p.next()
+		}
+	}
+	if err := p.expect(")"); err != nil {
+		return nil, err
+	}
+	if len(args) < f.minArgs || f.maxArgs >= 0 && len(args) > f.maxArgs {
+		n := fmt.Sprintf("%d", f.minArgs)
+		switch {
+		case f.maxArgs < 0:
+			n = fmt.Sprintf("at least %d", f.minArgs)
+		case f.maxArgs != f.minArgs:
+			n = fmt.Sprintf("%d to %d", f.minArgs, f.maxArgs)
+		}
+		return nil, &ParseError{Column: name.col, Msg: fmt.Sprintf("%s() takes %s arguments, got %d", name.text, n, len(args))}
+	}
+	return callNode{col: name.col, name: name.text, args: args}, nil
+}
+
+// expression is a parsed rule condition and the metrics it reads, in order
+// of first use.
+type expression struct {
+	root    node
+	metrics []metricNode
+}
+
+func parseExpression(input string) (expression, error) {
+	toks, err := tokenize(input)
+	if err != nil {
+		return expression{}, err
+	}
+	p := &parser{toks: toks}
+	root, err := p.parseOr()
+	if err != nil {
+		return expression{}, err
+	}
+	if t := p.peek(); t.typ != tokEOF {
+		return expression{}, &ParseError{Column: t.col, Msg: fmt.Sprintf("unexpected %s", t.describe())}
+	}
+	if root.kind() != kindBool {
+		return expression{}, &ParseError{Column: root.column(), Msg: "expression must be a condition such as `total_cost_usd > 100`, found a number"}
+	}
+	expr := expression{root: root}
+	seen := map[string]bool{}
+	collectMetrics(root, func(m metricNode) {
+		if !seen[m.name] {
+			seen[m.name] = true
+			expr.metrics = append(expr.metrics, m)
+		}
+	})
+	return expr, nil
+}
+
+func collectMetrics(n node, visit func(metricNode)) {
+	switch n := n.(type) {
+	case metricNode:
+		visit(n)
+	case unaryNode:
+		collectMetrics(n.x, visit)
+	case binaryNode:
+		collectMetrics(n.l, visit)
+		collectMetrics(n.r, visit)
+	case groupNode:
+		collectMetrics(n.node, visit)
+	case callNode:
+		for _, a := range n.args {
+			collectMetrics(a, visit)
+		}
+	}
+}
+
+// matches evaluates the condition against metrics.
+func (e expression) matches(metrics map[string]float64) (bool, error) {
+	v, err := e.root.eval(metrics)
+	return v != 0, err
+}
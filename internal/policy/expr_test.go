@@ -0,0 +1,117 @@
+package policy
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestExpressionEvaluation(t *testing.T) {
+	metrics := map[string]float64{
+		"total_cost_usd":   80,
+		"monthly_cost_usd": 120,
+		"waste_percentage": 12,
+		"fail_rate":        5,
+		"total_runs":       0,
+	}
+	cases := []struct {
+		when string
+		want bool
+	}{
+		{"waste_percentage > 10 and total_cost_usd > 50", true},
+		{"waste_percentage > 10 AND total_cost_usd > 100", false},
+		{"waste_percentage > 20 or fail_rate >= 5", true},
+		{"not (fail_rate > 1)", false},
+		{"!(fail_rate > 10) && total_cost_usd != 0", true},
+		{"monthly_cost_usd > total_cost_usd", true},
+		{"waste_percentage / 100 * total_cost_usd > 9.5", true},
+		{"total_cost_usd - monthly_cost_usd < -39.5", true},
+		{"1 + 2 * 3 == 7", true},
+		{"(1 + 2) * 3 == 9", true},
+		{"-fail_rate % 3 == -2", true},
+		{"pct_change(monthly_cost_usd, total_cost_usd) == 50", true},
+		{"pct_change(total_runs, 0) == 0", true},
+		{"abs(total_cost_usd - monthly_cost_usd) == 40", true},
+		{"max(fail_rate, waste_percentage, 3) == 12 and min(fail_rate, 3) == 3", true},
+		{"round(2.5e1 / 10) == 3", true},
+		// The guard keeps the division by zero from deciding the result.
+		{"total_runs > 0 and fail_rate / total_runs > 1", false},
+		{"true or total_cost_usd / total_runs > 1", true},
+	}
+	for _, c := range cases {
+		expr, err := parseExpression(c.when)
+		if err != nil {
+			t.Fatalf("%q: %v", c.when, err)
+		}
+		got, err := expr.matches(metrics)
+		if err != nil {
+			t.Fatalf("%q: %v", c.when, err)
+		}
+		if got != c.want {
+			t.Errorf("%q = %v, want %v", c.when, got, c.want)
+		}
+	}
+}
+
+func TestExpressionParseErrorColumns(t *testing.T) {
+	cases := []struct {
+		when   string
+		column int
+		msg    string
+	}{
+		{"monthly_cost_usd >> 200", 19, `expected a metric, number or "(", found ">"`},
+		{"total_cost_usd = 5", 16, "use == to compare"},
+		{"(total_cost_usd > 5", 20, `expected ")", found end of expression`},
+		{"total_cost_usd > 5 and", 23, "found end of expression"},
+		{"total_cost_usd + 5", 1, "must be a condition"},
+		{"total_cost_usd and fail_rate > 1", 1, `"and" needs a boolean, found a number`},
+		{"1 < fail_rate < 3", 15, "cannot be chained"},
+		{"median(fail_rate) > 1", 1, `unknown function "median"`},
+		{"pct_change(fail_rate) > 1", 1, "pct_change() takes 2 arguments, got 1"},
+		{"fail_rate > 10 total_runs", 16, `unexpected "total_runs"`},
+		{"fail_rate > 1.2.3", 13, `invalid number "1.2.3"`},
+		{"fail_rate > 3x", 14, `invalid number "3x"`},
+		{"fail_rate > 1 and (fail_rate > 2) > 1", 19, `">" needs a number, found a boolean`},
+	}
+	for _, c := range cases {
+		_, err := parseExpression(c.when)
+		var pe *ParseError
+		if !errors.As(err, &pe) {
+			t.Fatalf("%q: expected a parse error, got %v", c.when, err)
+		}
+		if pe.Column != c.column || !strings.Contains(pe.Msg, c.msg) {
+			t.Errorf("%q: got column %d %q, want column %d containing %q", c.when, pe.Column, pe.Msg, c.column, c.msg)
+		}
+	}
+}
+
+func TestLintPointsAtUnknownMetric(t *testing.T) {
+	err := Lint(Config{Rules: []Rule{{ID: "typo", When: "fail_rate > 1 and total_cots_usd > 5", Severity: SeverityWarn}}})
+	if err == nil {
+		t.Fatal("expected lint error")
+	}
+	want := "rule[typo] invalid expression at column 19: unsupported metric \"total_cots_usd\"\n" +
+		"  fail_rate > 1 and total_cots_usd > 5\n" +
+		"                    ^"
+	if err.Error() != want {
+		t.Fatalf("got:\n%s\nwant:\n%s", err, want)
+	}
+}
+
+func TestFindingCarriesEveryMetric(t *testing.T) {
+	cfg := Config{Rules: []Rule{{ID: "rerun_share", When: "waste_percentage > 10 and total_cost_usd / max(total_runs, 1) > 2", Severity: SeverityWarn}}}
+	findings, err := Evaluate(cfg, map[string]float64{"waste_percentage": 15, "total_cost_usd": 90, "total_runs": 30, "fail_rate": 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("expected 1 finding, got %d", len(findings))
+	}
+	f := findings[0]
+	if f.EvidenceKey != "waste_percentage" || f.EvidenceValue != 15 {
+		t.Fatalf("unexpected evidence %s=%v", f.EvidenceKey, f.EvidenceValue)
+	}
+	if len(f.Metrics) != 3 || f.Metrics["total_cost_usd"] != 90 || f.Metrics["total_runs"] != 30 {
+		t.Fatalf("unexpected metrics %v", f.Metrics)
+	}
+}
@@ -1,12 +1,14 @@
 package policy
 
 import (
+	"errors"
 	"fmt"
 	"os"
-	"regexp"
-	"strconv"
+	"slices"
 	"strings"
 
+	"github.com/peter941221/CICost/internal/glob"
+	"github.com/peter941221/CICost/internal/metrics"
 	"gopkg.in/yaml.v3"
 )
 
@@ -23,39 +25,144 @@ type Config struct {
 	Actions Actions `yaml:"actions"`
 }
 
+// Rule scopes. A repo rule is evaluated once against repo-wide metrics;
+// the others once per workflow, job, branch, runner or event.
+const (
+	ScopeRepo     = "repo"
+	ScopeWorkflow = "workflow"
+	ScopeJob      = "job"
+	ScopeBranch   = "branch"
+	ScopeRunner   = "runner"
+	ScopeEvent    = "event"
+)
+
+var scopes = []string{ScopeRepo, ScopeWorkflow, ScopeJob, ScopeBranch, ScopeRunner, ScopeEvent}
+
 type Rule struct {
 	ID       string   `yaml:"id"`
 	When     string   `yaml:"when"`
 	Severity Severity `yaml:"severity"`
+	// Scope defaults to repo. Match, when set, keeps the entities whose
+	// name matches one of its globs, in which * also matches /, so
+	// "*deploy*" matches the job "CI / deploy".
+	Scope string `yaml:"scope"`
+	Match Globs  `yaml:"match"`
+	// Actions, when set, replace the policy's actions for the rule's
+	// severity.
+	Actions ActionList `yaml:"actions"`
+	// Line is where the rule starts in its policy file, 0 when it was not
+	// loaded from one.
+	Line int `yaml:"-"`
+}
+
+func (r *Rule) UnmarshalYAML(value *yaml.Node) error {
+	type plain Rule
+	if err := value.Decode((*plain)(r)); err != nil {
+		return err
+	}
+	r.Line = value.Line
+	return nil
+}
+
+// Globs is a list of glob patterns that may be written as a single string.
+type Globs []string
+
+func (g *Globs) UnmarshalYAML(value *yaml.Node) error {
+	if value.Kind == yaml.ScalarNode {
+		*g = Globs{value.Value}
+		return nil
+	}
+	var list []string
+	if err := value.Decode(&list); err != nil {
+		return err
+	}
+	*g = list
+	return nil
 }
 
+// Matches reports whether name matches one of the globs, or whether there
+// are none.
+func (g Globs) Matches(name string) bool {
+	if len(g) == 0 {
+		return true
+	}
+	for _, pattern := range g {
+		if ok, err := glob.Match(pattern, name); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizedScope is the rule's scope, lower-cased and repo when unset.
+func (r Rule) NormalizedScope() string {
+	s := strings.ToLower(strings.TrimSpace(r.Scope))
+	if s == "" {
+		return ScopeRepo
+	}
+	return s
+}
+
+// Actions are the default actions per severity; see ActionFail and the
+// other action kinds.
 type Actions struct {
-	OnError string `yaml:"on_error"`
-	OnWarn  string `yaml:"on_warn"`
+	OnError ActionList `yaml:"on_error"`
+	OnWarn  ActionList `yaml:"on_warn"`
+	OnInfo  ActionList `yaml:"on_info"`
 }
 
+// Finding is a rule whose condition held, for Entity of Scope when the
+// rule is not repo-wide. EvidenceKey and EvidenceValue are the first metric
+// of the condition; Metrics holds every metric it read. Actions is set by
+// Resolve.
 type Finding struct {
-	RuleID        string   `json:"rule_id"`
-	Severity      Severity `json:"severity"`
-	When          string   `json:"when"`
-	EvidenceKey   string   `json:"evidence_key"`
-	EvidenceValue float64  `json:"evidence_value"`
+	RuleID        string             `json:"rule_id"`
+	Severity      Severity           `json:"severity"`
+	When          string             `json:"when"`
+	Scope         string             `json:"scope"`
+	Entity        string             `json:"entity,omitempty"`
+	EvidenceKey   string             `json:"evidence_key"`
+	EvidenceValue float64            `json:"evidence_value"`
+	Metrics       map[string]float64 `json:"metrics"`
+	Actions       []string           `json:"actions,omitempty"`
 }
 
-type expression struct {
-	variable string
-	operator string
-	value    float64
+// Subject names the finding: its rule, and entity when scoped.
+func (f Finding) Subject() string {
+	if f.Entity == "" {
+		return f.RuleID
+	}
+	return fmt.Sprintf("%s %s=%q", f.RuleID, f.Scope, f.Entity)
+}
+
+// Evidence lists the metrics f read as name=value, in name order.
+func (f Finding) Evidence() string {
+	if len(f.Metrics) == 0 {
+		return fmt.Sprintf("%s=%.4f", f.EvidenceKey, f.EvidenceValue)
+	}
+	names := make([]string, 0, len(f.Metrics))
+	for k := range f.Metrics {
+		names = append(names, k)
+	}
+	slices.Sort(names)
+	parts := make([]string, 0, len(names))
+	for _, k := range names {
+		parts = append(parts, fmt.Sprintf("%s=%.4f", k, f.Metrics[k]))
+	}
+	return strings.Join(parts, ", ")
 }
 
-var exprRe = regexp.MustCompile(`^\s*([a-zA-Z_][a-zA-Z0-9_]*)\s*(<=|>=|==|!=|>|<)\s*([0-9]+(?:\.[0-9]+)?)\s*$`)
+// Entity is one workflow, job, branch, runner or event and its metrics.
+type Entity struct {
+	Name    string
+	Metrics map[string]float64
+}
 
-var allowedMetrics = map[string]struct{}{
-	"monthly_cost_usd": {},
-	"waste_percentage": {},
-	"fail_rate":        {},
-	"total_runs":       {},
-	"total_cost_usd":   {},
+// Input is what rules are evaluated against: repo-wide metrics and, per
+// scope, every entity.
+type Input struct {
+	Metrics  map[string]float64
+	Entities map[string][]Entity
 }
 
 func LoadFromFile(path string) (Config, error) {
@@ -74,6 +181,10 @@ func Lint(cfg Config) error {
 	if len(cfg.Rules) == 0 {
 		return fmt.Errorf("policy has no rules")
 	}
+	if err := lintActions(cfg); err != nil {
+		return err
+	}
+	ids := map[string]struct{}{}
 	for i, rule := range cfg.Rules {
 		if strings.TrimSpace(rule.ID) == "" {
 			return fmt.Errorf("rule[%d] missing id", i)
@@ -81,58 +192,149 @@ func Lint(cfg Config) error {
 		if strings.TrimSpace(rule.When) == "" {
 			return fmt.Errorf("rule[%s] missing when", rule.ID)
 		}
+		if _, dup := ids[rule.ID]; dup {
+			return fmt.Errorf("rule[%s] duplicate id", rule.ID)
+		}
+		ids[rule.ID] = struct{}{}
 		if !isValidSeverity(rule.Severity) {
 			return fmt.Errorf("rule[%s] invalid severity %q", rule.ID, rule.Severity)
 		}
-		expr, err := parseExpression(rule.When)
-		if err != nil {
-			return fmt.Errorf("rule[%s] invalid expression: %w", rule.ID, err)
+		if _, err := normalizeActions(rule.Actions); err != nil {
+			return fmt.Errorf("rule[%s] %w", rule.ID, err)
+		}
+		if !slices.Contains(scopes, rule.NormalizedScope()) {
+			return fmt.Errorf("rule[%s] invalid scope %q (use %s)", rule.ID, rule.Scope, strings.Join(scopes, "|"))
+		}
+		if len(rule.Match) > 0 && rule.NormalizedScope() == ScopeRepo {
+			return fmt.Errorf("rule[%s] match needs a scope other than repo", rule.ID)
+		}
+		for _, pattern := range rule.Match {
+			if err := glob.Validate(pattern); err != nil {
+				return fmt.Errorf("rule[%s] invalid match pattern %q: %w", rule.ID, pattern, err)
+			}
 		}
-		if _, ok := allowedMetrics[expr.variable]; !ok {
-			return fmt.Errorf("rule[%s] uses unsupported variable %q", rule.ID, expr.variable)
+		if _, err := compileRule(rule); err != nil {
+			return err
 		}
 	}
 	return nil
 }
 
+// EntityScopes lists the scopes other than repo that cfg's rules use, in
+// the order of first use.
+func (c Config) EntityScopes() []string {
+	var out []string
+	for _, rule := range c.Rules {
+		if s := rule.NormalizedScope(); s != ScopeRepo && !slices.Contains(out, s) {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+// compileRule parses rule.When and checks its metrics against the metrics
+// registry, pointing errors at the offending column.
+func compileRule(rule Rule) (expression, error) {
+	expr, err := parseExpression(rule.When)
+	if err != nil {
+		return expression{}, ruleError(rule, "invalid expression", err)
+	}
+	for _, m := range expr.metrics {
+		if _, ok := metrics.Lookup(m.name); !ok {
+			msg := fmt.Sprintf("unsupported metric %q", m.name)
+			if len(metrics.All()) == 0 {
+				msg += " (no metrics are registered; call analytics.RegisterMetrics first)"
+			}
+			return expression{}, ruleError(rule, "invalid expression", &ParseError{Column: m.col, Msg: msg})
+		}
+	}
+	return expr, nil
+}
+
+// ruleError formats err for rule, quoting the expression under a caret
+// when err has a column.
+func ruleError(rule Rule, what string, err error) error {
+	var pe *ParseError
+	if !errors.As(err, &pe) {
+		return fmt.Errorf("rule[%s] %s: %w", rule.ID, what, err)
+	}
+	return fmt.Errorf("rule[%s] %s at %w\n  %s\n  %s^", rule.ID, what, err, rule.When, strings.Repeat(" ", pe.Column-1))
+}
+
+// Evaluate evaluates repo-wide rules against metrics.
 func Evaluate(cfg Config, metrics map[string]float64) ([]Finding, error) {
+	return EvaluateInput(cfg, Input{Metrics: metrics})
+}
+
+// EvaluateInput evaluates every rule, scoped ones once per entity of their
+// scope that Match selects.
+func EvaluateInput(cfg Config, in Input) ([]Finding, error) {
 	if err := Lint(cfg); err != nil {
 		return nil, err
 	}
 	out := make([]Finding, 0, len(cfg.Rules))
 	for _, rule := range cfg.Rules {
-		expr, _ := parseExpression(rule.When)
-		v, ok := metrics[expr.variable]
+		expr, _ := compileRule(rule)
+		scope := rule.NormalizedScope()
+		if scope == ScopeRepo {
+			f, ok, err := evaluateRule(rule, expr, in.Metrics)
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				out = append(out, f)
+			}
+			continue
+		}
+		entities, ok := in.Entities[scope]
 		if !ok {
-			return nil, fmt.Errorf("missing metric %q for rule %s", expr.variable, rule.ID)
+			return nil, fmt.Errorf("missing %s metrics for rule %s", scope, rule.ID)
 		}
-		if compare(v, expr.operator, expr.value) {
-			out = append(out, Finding{
-				RuleID:        rule.ID,
-				Severity:      rule.Severity,
-				When:          rule.When,
-				EvidenceKey:   expr.variable,
-				EvidenceValue: v,
-			})
+		for _, e := range entities {
+			if !rule.Match.Matches(e.Name) {
+				continue
+			}
+			f, ok, err := evaluateRule(rule, expr, e.Metrics)
+			if err != nil {
+				return nil, fmt.Errorf("%w (%s %s)", err, scope, e.Name)
+			}
+			if ok {
+				f.Entity = e.Name
+				out = append(out, f)
+			}
 		}
 	}
 	return out, nil
 }
 
-func parseExpression(input string) (expression, error) {
-	m := exprRe.FindStringSubmatch(strings.TrimSpace(input))
-	if len(m) != 4 {
-		return expression{}, fmt.Errorf("expected format `<metric> <op> <number>`, got %q", input)
+func evaluateRule(rule Rule, expr expression, metrics map[string]float64) (Finding, bool, error) {
+	used := make(map[string]float64, len(expr.metrics))
+	for _, m := range expr.metrics {
+		v, ok := metrics[m.name]
+		if !ok {
+			return Finding{}, false, fmt.Errorf("missing metric %q for rule %s", m.name, rule.ID)
+		}
+		used[m.name] = v
 	}
-	value, err := strconv.ParseFloat(m[3], 64)
+	matched, err := expr.matches(metrics)
 	if err != nil {
-		return expression{}, err
+		return Finding{}, false, fmt.Errorf("rule %s: %w", rule.ID, err)
+	}
+	if !matched {
+		return Finding{}, false, nil
+	}
+	f := Finding{
+		RuleID:   rule.ID,
+		Severity: rule.Severity,
+		When:     rule.When,
+		Scope:    rule.NormalizedScope(),
+		Metrics:  used,
+	}
+	if len(expr.metrics) > 0 {
+		f.EvidenceKey = expr.metrics[0].name
+		f.EvidenceValue = used[f.EvidenceKey]
 	}
-	return expression{
-		variable: m[1],
-		operator: m[2],
-		value:    value,
-	}, nil
+	return f, true, nil
 }
 
 func compare(left float64, op string, right float64) bool {
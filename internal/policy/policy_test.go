@@ -3,9 +3,17 @@ package policy
 import (
 	"os"
 	"path/filepath"
+	"slices"
 	"testing"
+
+	"github.com/peter941221/CICost/internal/analytics"
 )
 
+func TestMain(m *testing.M) {
+	analytics.RegisterMetrics()
+	os.Exit(m.Run())
+}
+
 func TestLoadLintAndEvaluate(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, ".cicost.policy.yml")
@@ -60,3 +68,190 @@ func TestLintInvalidExpression(t *testing.T) {
 		t.Fatal("expected lint error")
 	}
 }
+
+func TestScopedRulesFindEachMatchingEntity(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".cicost.policy.yml")
+	content := `rules:
+  - id: workflow_cap
+    scope: workflow
+    when: monthly_cost_usd > 40
+    severity: error
+  - id: release_branches
+    scope: branch
+    match: release/*
+    when: total_runs > 1
+    severity: warn
+  - id: repo_cap
+    when: total_cost_usd > 1000
+    severity: error
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.EntityScopes(); len(got) != 2 || got[0] != ScopeWorkflow || got[1] != ScopeBranch {
+		t.Fatalf("unexpected scopes %v", got)
+	}
+
+	metrics := func(cost, runs float64) map[string]float64 {
+		return map[string]float64{"monthly_cost_usd": cost, "total_cost_usd": cost, "total_runs": runs}
+	}
+	findings, err := EvaluateInput(cfg, Input{
+		Metrics: metrics(120, 9),
+		Entities: map[string][]Entity{
+			ScopeWorkflow: {{Name: "ci", Metrics: metrics(55, 5)}, {Name: "docs", Metrics: metrics(5, 2)}, {Name: "release", Metrics: metrics(60, 2)}},
+			ScopeBranch:   {{Name: "main", Metrics: metrics(100, 7)}, {Name: "release/1.2", Metrics: metrics(20, 2)}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.RuleID+":"+f.Scope+":"+f.Entity)
+	}
+	want := []string{"workflow_cap:workflow:ci", "workflow_cap:workflow:release", "release_branches:branch:release/1.2"}
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+
+	if _, err := Evaluate(cfg, metrics(1, 1)); err == nil {
+		t.Fatal("expected an error without workflow entities")
+	}
+}
+
+func TestMatchGlobsCrossSlashes(t *testing.T) {
+	cfg := Config{Rules: []Rule{
+		{ID: "deploy_jobs", Scope: ScopeJob, Match: Globs{"*deploy*"}, When: "total_runs > 0", Severity: SeverityWarn},
+		{ID: "all_jobs", Scope: ScopeJob, Match: Globs{"*"}, When: "total_runs > 3", Severity: SeverityWarn},
+		{ID: "features", Scope: ScopeBranch, Match: Globs{"feature/*"}, When: "total_runs > 0", Severity: SeverityWarn},
+		{ID: "releases", Scope: ScopeBranch, Match: Globs{"release/**"}, When: "total_runs > 0", Severity: SeverityWarn},
+		{ID: "gpu", Scope: ScopeRunner, Match: Globs{"self-hosted*"}, When: "total_runs > 0", Severity: SeverityWarn},
+	}}
+	if err := Lint(cfg); err != nil {
+		t.Fatal(err)
+	}
+	runs := func(n float64) map[string]float64 { return map[string]float64{"total_runs": n} }
+	findings, err := EvaluateInput(cfg, Input{
+		Metrics: runs(10),
+		Entities: map[string][]Entity{
+			ScopeJob:    {{Name: "CI / build", Metrics: runs(4)}, {Name: "Release / deploy-prod", Metrics: runs(1)}},
+			ScopeBranch: {{Name: "main", Metrics: runs(5)}, {Name: "feature/x", Metrics: runs(2)}, {Name: "release/1.2/rc", Metrics: runs(1)}},
+			ScopeRunner: {{Name: "ubuntu-latest", Metrics: runs(5)}, {Name: "self-hosted/gpu", Metrics: runs(3)}},
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, f := range findings {
+		got = append(got, f.RuleID+":"+f.Entity)
+	}
+	want := []string{"deploy_jobs:Release / deploy-prod", "all_jobs:CI / build", "features:feature/x", "releases:release/1.2/rc", "gpu:self-hosted/gpu"}
+	if !slices.Equal(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestLintRejectsBadScopes(t *testing.T) {
+	for _, rule := range []Rule{
+		{ID: "scope", When: "total_runs > 1", Severity: SeverityWarn, Scope: "step"},
+		{ID: "repo_match", When: "total_runs > 1", Severity: SeverityWarn, Match: Globs{"ci"}},
+		{ID: "pattern", When: "total_runs > 1", Severity: SeverityWarn, Scope: "workflow", Match: Globs{"[ci"}},
+	} {
+		if err := Lint(Config{Rules: []Rule{rule}}); err == nil {
+			t.Fatalf("rule %s: expected lint error", rule.ID)
+		}
+	}
+}
+
+func TestResolveAppliesRuleActionsOverDefaults(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, ".cicost.policy.yml")
+	content := `rules:
+  - id: budget_monthly
+    when: monthly_cost_usd > 200
+    severity: error
+    actions: warn, notify:ci-slack
+  - id: waste_ratio
+    when: waste_percentage > 25
+    severity: warn
+  - id: noisy
+    when: fail_rate > 1
+    severity: error
+    actions: [ignore]
+  - id: cap
+    when: total_cost_usd > 100
+    severity: info
+    actions: [fail, comment]
+actions:
+  on_error: fail_ci
+  on_warn: comment_pr
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := Lint(cfg); err != nil {
+		t.Fatal(err)
+	}
+	findings, err := Evaluate(cfg, map[string]float64{
+		"monthly_cost_usd": 300, "waste_percentage": 30, "fail_rate": 5, "total_cost_usd": 50,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s, err := Resolve(cfg, findings)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Failed {
+		t.Fatal("no finding should fail: budget_monthly is overridden to warn")
+	}
+	want := map[string]int{ActionWarn: 1, "notify:ci-slack": 1, ActionComment: 1, ActionIgnore: 1}
+	if len(s.Actions) != len(want) {
+		t.Fatalf("got actions %v, want %v", s.Actions, want)
+	}
+	for a, n := range want {
+		if s.Actions[a] != n {
+			t.Fatalf("got actions %v, want %v", s.Actions, want)
+		}
+	}
+	if s.Ignored != 1 || len(s.Reported()) != 2 {
+		t.Fatalf("expected 1 ignored and 2 reported findings, got %d and %d", s.Ignored, len(s.Reported()))
+	}
+	names, byName := s.Notifiers()
+	if len(names) != 1 || names[0] != "ci-slack" || byName["ci-slack"][0].RuleID != "budget_monthly" {
+		t.Fatalf("unexpected notifiers %v %v", names, byName)
+	}
+}
+
+func TestLintRejectsBadActions(t *testing.T) {
+	rule := func(actions ...string) Rule {
+		return Rule{ID: "r", When: "total_runs > 1", Severity: SeverityWarn, Actions: actions}
+	}
+	for _, cfg := range []Config{
+		{Rules: []Rule{rule("page")}},
+		{Rules: []Rule{rule("notify")}},
+		{Rules: []Rule{rule("fail:now")}},
+		{Rules: []Rule{rule("ignore", "warn")}},
+		{Rules: []Rule{rule("warn")}, Actions: Actions{OnError: ActionList{"explode"}}},
+		{Rules: []Rule{rule("warn"), rule("fail")}},
+	} {
+		if err := Lint(cfg); err == nil {
+			t.Fatalf("expected lint error for %+v", cfg)
+		}
+	}
+}
@@ -8,8 +8,9 @@ import (
 )
 
 const (
-	PricingSourceSKU    = "sku_direct"
-	PricingSourceLegacy = "legacy_multiplier"
+	PricingSourceSKU          = "sku_direct"
+	PricingSourceLargerRunner = "larger_runner"
+	PricingSourceLegacy       = "legacy_multiplier"
 )
 
 type Config struct {
@@ -19,9 +20,14 @@ type Config struct {
 	WindowsMultiplier   float64
 	MacOSMultiplier     float64
 	FreeTierPerMonth    float64
+	FreeStorageGB       float64
+	BillingCycleDay     int
 	AlreadyUsedThisMon  float64
-	FreeTierByPlan      map[string]float64
+	FreeTierAllocation  string
+	FreeTierByPlan      map[string]PlanAllowance
 	LargerRunnersPerMin map[string]float64
+	SelfHosted          SelfHostedModel
+	Storage             StorageRates
 	Snapshots           []Snapshot
 }
 
@@ -99,12 +105,21 @@ func SelectSnapshot(cfg Config, at time.Time) (Snapshot, error) {
 	return *chosen, nil
 }
 
-func ResolveRate(cfg Config, at time.Time, runnerOS, runnerName string) (rate float64, sku string, source string, snapshot Snapshot, err error) {
+func ResolveRate(cfg Config, at time.Time, runnerOS, runnerName string, labels []string) (rate float64, sku string, source string, snapshot Snapshot, err error) {
+	spec := ParseRunnerLabels(runnerOS, labels)
 	if len(cfg.Snapshots) > 0 {
 		snapshot, err = SelectSnapshot(cfg, at)
 		if err != nil {
 			return 0, "", "", Snapshot{}, err
 		}
+		for _, k := range spec.SKUCandidates() {
+			if v, ok := snapshot.SKUs[k]; ok && v > 0 {
+				return v, k, PricingSourceSKU, snapshot, nil
+			}
+		}
+		if v, k, ok := largerRunnerRate(cfg, spec); ok {
+			return v, k, PricingSourceLargerRunner, snapshot, nil
+		}
 		candidates := skuCandidates(runnerOS, runnerName)
 		for _, k := range candidates {
 			if v, ok := snapshot.SKUs[k]; ok && v > 0 {
@@ -114,15 +129,19 @@ func ResolveRate(cfg Config, at time.Time, runnerOS, runnerName string) (rate fl
 		return 0, "", "", Snapshot{}, fmt.Errorf("no SKU rate matched runner_os=%q runner_name=%q under snapshot %s", runnerOS, runnerName, snapshot.Version)
 	}
 
+	legacySnapshot := Snapshot{
+		Version:       cfg.Version,
+		EffectiveFrom: cfg.EffectiveFrom,
+	}
+	if v, k, ok := largerRunnerRate(cfg, spec); ok {
+		return v, k, PricingSourceLargerRunner, legacySnapshot, nil
+	}
 	base := cfg.PerMinuteUSD
 	if base <= 0 {
 		base = 0.008
 	}
 	mult := LegacyMultiplier(runnerOS, cfg)
-	return base * mult, normalizeSKU(runnerOS), PricingSourceLegacy, Snapshot{
-		Version:       cfg.Version,
-		EffectiveFrom: cfg.EffectiveFrom,
-	}, nil
+	return base * mult, normalizeSKU(runnerOS), PricingSourceLegacy, legacySnapshot, nil
 }
 
 func skuCandidates(runnerOS, runnerName string) []string {
@@ -169,12 +188,12 @@ func normalizeSKU(v string) string {
 	return s
 }
 
-func PriceJob(durationSec int, runnerOS, runnerName string, startedAt time.Time, cfg Config) (JobPrice, error) {
+func PriceJob(durationSec int, runnerOS, runnerName string, labels []string, startedAt time.Time, cfg Config) (JobPrice, error) {
 	ref := startedAt
 	if ref.IsZero() {
 		ref = time.Now().UTC()
 	}
-	rate, sku, source, snapshot, err := ResolveRate(cfg, ref, runnerOS, runnerName)
+	rate, sku, source, snapshot, err := ResolveRate(cfg, ref, runnerOS, runnerName, labels)
 	if err != nil {
 		return JobPrice{}, err
 	}
@@ -1,6 +1,7 @@
 package pricing
 
 import (
+	"math"
 	"os"
 	"path/filepath"
 	"testing"
@@ -106,7 +107,7 @@ func TestResolveRateBySKU(t *testing.T) {
 			},
 		},
 	}
-	rate, sku, source, snap, err := ResolveRate(cfg, time.Date(2026, 2, 26, 0, 0, 0, 0, time.UTC), "macOS", "")
+	rate, sku, source, snap, err := ResolveRate(cfg, time.Date(2026, 2, 26, 0, 0, 0, 0, time.UTC), "macOS", "", nil)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -134,8 +135,196 @@ func TestResolveRateMissingSnapshot(t *testing.T) {
 			},
 		},
 	}
-	_, _, _, _, err := ResolveRate(cfg, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), "Linux", "")
+	_, _, _, _, err := ResolveRate(cfg, time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC), "Linux", "", nil)
 	if err == nil {
 		t.Fatal("expected error for missing snapshot")
 	}
 }
+
+func TestParseRunnerLabels(t *testing.T) {
+	tests := []struct {
+		name   string
+		os     string
+		labels []string
+		want   []string
+	}{
+		{"standard", "Linux", []string{"ubuntu-latest"}, nil},
+		{"linux_cores", "Linux", []string{"ubuntu-latest-8-cores"}, []string{"linux-8core"}},
+		{"linux_arm", "Linux", []string{"linux-16core-arm64"}, []string{"linux-16core-arm", "linux-arm", "linux-16core"}},
+		{"gpu", "Linux", []string{"gpu-t4-4-core"}, []string{"linux-4core-gpu", "linux-gpu", "linux-4core"}},
+		{"windows_cores", "Windows", []string{"windows-latest-16-cores"}, []string{"windows-16core"}},
+		{"macos_xlarge", "macOS", []string{"macos-14-xlarge"}, []string{"macos-xlarge"}},
+		{"macos_large", "macOS", []string{"macos-latest-large"}, []string{"macos-large", "macos-12core"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ParseRunnerLabels(tt.os, tt.labels).SKUCandidates()
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestResolveRateLargerRunner(t *testing.T) {
+	cfg := Config{
+		LargerRunnersPerMin: map[string]float64{"linux-16core": 0.064},
+		Snapshots: []Snapshot{
+			{
+				Version:       "2026.02",
+				EffectiveFrom: time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
+				SKUs:          map[string]float64{"linux": 0.008, "linux-8core": 0.032},
+			},
+		},
+	}
+	at := time.Date(2026, 2, 26, 0, 0, 0, 0, time.UTC)
+
+	rate, sku, source, _, err := ResolveRate(cfg, at, "Linux", "", []string{"ubuntu-latest-8-cores"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sku != "linux-8core" || source != PricingSourceSKU || rate != 0.032 {
+		t.Fatalf("expected snapshot linux-8core sku, got sku=%s source=%s rate=%f", sku, source, rate)
+	}
+
+	rate, sku, source, _, err = ResolveRate(cfg, at, "Linux", "", []string{"ubuntu-22.04-16core"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sku != "linux-16core" || source != PricingSourceLargerRunner || rate != 0.064 {
+		t.Fatalf("expected larger_runners fallback, got sku=%s source=%s rate=%f", sku, source, rate)
+	}
+
+	quote, err := PriceJob(600, "Linux", "", []string{"ubuntu-22.04-16core"}, at, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if quote.BillableMinutes != 10 || quote.CostUSD != 0.64 {
+		t.Fatalf("expected 10 billable minutes at 0.064, got %+v", quote)
+	}
+}
+
+func TestPriceSelfHostedJob(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pricing.yml")
+	content := `per_minute_usd: 0.008
+self_hosted:
+  default:
+    hourly_usd: 0.60
+  groups:
+    gpu-pool:
+      hourly_usd: 1.20
+      hardware_cost_usd: 26280
+      amortization_months: 36
+      idle_overhead: 1.5
+  runners:
+    mac-mini-01:
+      hourly_usd: 0.30
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// (1.20 + 26280/(36*730)) * 1.5 = 3.30 per hour.
+	gpu := PriceSelfHostedJob(3600, "GPU-Pool", "runner-7", cfg)
+	if gpu.SKU != "self-hosted:gpu-pool" || math.Abs(gpu.CostUSD-3.30) > 1e-9 {
+		t.Fatalf("unexpected gpu quote: %+v", gpu)
+	}
+	mac := PriceSelfHostedJob(1800, "gpu-pool", "mac-mini-01", cfg)
+	if mac.SKU != "self-hosted:mac-mini-01" || math.Abs(mac.CostUSD-0.15) > 1e-9 {
+		t.Fatalf("expected runner override, got %+v", mac)
+	}
+	def := PriceSelfHostedJob(90, "", "other", cfg)
+	if def.SKU != "self-hosted:default" || def.BillableMinutes != 1.5 {
+		t.Fatalf("expected default model on unrounded minutes, got %+v", def)
+	}
+	none := PriceSelfHostedJob(600, "pool", "x", Config{})
+	if none.CostUSD != 0 || none.BillableMinutes != 10 {
+		t.Fatalf("expected zero-cost quote without model, got %+v", none)
+	}
+}
+
+func TestCanonicalSKU(t *testing.T) {
+	tests := map[string]string{
+		"Actions Linux":            "linux",
+		"ubuntu-latest":            "linux",
+		"Actions Linux 8-core":     "linux-8core",
+		"linux-8core":              "linux-8core",
+		"actions_linux_4_core_arm": "linux-4core-arm",
+		"Actions macOS 3-core":     "macos",
+		"macos-12core":             "macos-large",
+		"Actions macOS 12-core":    "macos-large",
+		"macos_xlarge":             "macos-xlarge",
+		"Actions Windows":          "windows",
+		"":                         "",
+	}
+	for in, want := range tests {
+		if got := CanonicalSKU(in); got != want {
+			t.Fatalf("CanonicalSKU(%q): expected %q, got %q", in, want, got)
+		}
+	}
+}
+
+func TestLoadFreeTierPlans(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pricing.yml")
+	content := `free_tiers:
+  Free: 2000
+  team:
+    minutes: 3000
+    storage_gb: 2
+`
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	free, err := cfg.Plan("free")
+	if err != nil || free.Minutes != 2000 || free.StorageGB != 0 {
+		t.Fatalf("expected bare number to set minutes only, got %+v err=%v", free, err)
+	}
+	team, err := cfg.Plan("Team")
+	if err != nil || team.Minutes != 3000 || team.StorageGB != 2 {
+		t.Fatalf("expected team plan with storage, got %+v err=%v", team, err)
+	}
+	if _, err := cfg.Plan("pro"); err == nil {
+		t.Fatal("expected unknown plan error")
+	}
+	if ent, err := (Config{}).Plan("enterprise"); err != nil || ent.Minutes != 50000 {
+		t.Fatalf("expected built-in plans without free_tiers, got %+v err=%v", ent, err)
+	}
+}
+
+func TestCycleStart(t *testing.T) {
+	tests := []struct {
+		at        time.Time
+		day       int
+		wantStart time.Time
+		wantEnd   time.Time
+	}{
+		{time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC), 1, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 20, 8, 0, 0, 0, time.UTC), 15, time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 15, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 10, 8, 0, 0, 0, time.UTC), 15, time.Date(2026, 2, 15, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 15, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 3, 1, 8, 0, 0, 0, time.UTC), 31, time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 31, 0, 0, 0, 0, time.UTC)},
+		{time.Date(2026, 1, 5, 0, 0, 0, 0, time.UTC), 10, time.Date(2025, 12, 10, 0, 0, 0, 0, time.UTC), time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := CycleStart(tt.at, tt.day); !got.Equal(tt.wantStart) {
+			t.Fatalf("CycleStart(%s, %d) = %s, want %s", tt.at, tt.day, got, tt.wantStart)
+		}
+		if got := CycleEnd(tt.at, tt.day); !got.Equal(tt.wantEnd) {
+			t.Fatalf("CycleEnd(%s, %d) = %s, want %s", tt.at, tt.day, got, tt.wantEnd)
+		}
+	}
+}
@@ -1,5 +1,88 @@
 package pricing
 
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// PlanAllowance is what a GitHub plan includes every billing cycle.
+type PlanAllowance struct {
+	Minutes   float64 `yaml:"minutes"`
+	StorageGB float64 `yaml:"storage_gb"`
+}
+
+// UnmarshalYAML accepts either a bare number of minutes or a mapping with
+// minutes and storage_gb, so older pricing files keep loading.
+func (p *PlanAllowance) UnmarshalYAML(n *yaml.Node) error {
+	if n.Kind == yaml.ScalarNode {
+		*p = PlanAllowance{}
+		return n.Decode(&p.Minutes)
+	}
+	type plain PlanAllowance
+	return n.Decode((*plain)(p))
+}
+
+// DefaultPlans are GitHub's included Actions minutes and storage, used when
+// the pricing file has no free_tiers section.
+var DefaultPlans = map[string]PlanAllowance{
+	"free":       {Minutes: 2000, StorageGB: 0.5},
+	"pro":        {Minutes: 3000, StorageGB: 1},
+	"team":       {Minutes: 3000, StorageGB: 2},
+	"enterprise": {Minutes: 50000, StorageGB: 50},
+}
+
+// Plan returns the allowance of plan, looked up case-insensitively in
+// cfg.FreeTierByPlan or DefaultPlans when that is empty.
+func (cfg Config) Plan(plan string) (PlanAllowance, error) {
+	plans := cfg.FreeTierByPlan
+	if len(plans) == 0 {
+		plans = DefaultPlans
+	}
+	key := strings.ToLower(strings.TrimSpace(plan))
+	if a, ok := plans[key]; ok {
+		return a, nil
+	}
+	known := make([]string, 0, len(plans))
+	for k := range plans {
+		known = append(known, k)
+	}
+	sort.Strings(known)
+	return PlanAllowance{}, fmt.Errorf("unknown free_tier.plan %q (use %s)", plan, strings.Join(known, "|"))
+}
+
+// CycleStart is the start of the billing cycle containing t, for cycles that
+// reset on day of each month (UTC). Days past the end of a short month
+// reset on its last day; day < 1 means calendar months.
+func CycleStart(t time.Time, day int) time.Time {
+	t = t.UTC()
+	start := cycleDay(t.Year(), t.Month(), day)
+	if t.Before(start) {
+		start = cycleDay(t.Year(), t.Month()-1, day)
+	}
+	return start
+}
+
+// CycleEnd is the start of the billing cycle after the one containing t.
+func CycleEnd(t time.Time, day int) time.Time {
+	start := CycleStart(t, day)
+	return cycleDay(start.Year(), start.Month()+1, day)
+}
+
+func cycleDay(year int, month time.Month, day int) time.Time {
+	first := time.Date(year, month, 1, 0, 0, 0, 0, time.UTC)
+	if day < 1 {
+		day = 1
+	}
+	if last := first.AddDate(0, 1, -1).Day(); day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
 func ChargedMinutes(totalBillable, freeTier, alreadyUsed float64) float64 {
 	remainingFree := freeTier - alreadyUsed
 	if remainingFree < 0 {
@@ -14,13 +14,48 @@ import (
 var ErrPricingLoaderNotImplemented = errors.New("pricing loader not implemented")
 
 type fileConfig struct {
-	Version          string             `yaml:"version"`
-	EffectiveFrom    string             `yaml:"effective_from"`
-	PerMinuteUSD     float64            `yaml:"per_minute_usd"`
-	Multipliers      map[string]float64 `yaml:"multipliers"`
-	LargerRunners    map[string]float64 `yaml:"larger_runners"`
-	FreeTiers        map[string]float64 `yaml:"free_tiers"`
-	PricingSnapshots []snapshotFile     `yaml:"pricing_snapshots"`
+	Version          string                   `yaml:"version"`
+	EffectiveFrom    string                   `yaml:"effective_from"`
+	PerMinuteUSD     float64                  `yaml:"per_minute_usd"`
+	Multipliers      map[string]float64       `yaml:"multipliers"`
+	LargerRunners    map[string]float64       `yaml:"larger_runners"`
+	FreeTiers        map[string]PlanAllowance `yaml:"free_tiers"`
+	SelfHosted       selfHostedFile           `yaml:"self_hosted"`
+	Storage          *storageFile             `yaml:"storage"`
+	PricingSnapshots []snapshotFile           `yaml:"pricing_snapshots"`
+}
+
+type selfHostedFile struct {
+	Default *selfHostedRateFile           `yaml:"default"`
+	Groups  map[string]selfHostedRateFile `yaml:"groups"`
+	Runners map[string]selfHostedRateFile `yaml:"runners"`
+}
+
+type selfHostedRateFile struct {
+	HourlyUSD          float64 `yaml:"hourly_usd"`
+	HardwareCostUSD    float64 `yaml:"hardware_cost_usd"`
+	AmortizationMonths float64 `yaml:"amortization_months"`
+	IdleOverhead       float64 `yaml:"idle_overhead"`
+}
+
+func (f selfHostedRateFile) toRate(name string) (SelfHostedRate, error) {
+	if f.HourlyUSD < 0 || f.HardwareCostUSD < 0 || f.AmortizationMonths < 0 {
+		return SelfHostedRate{}, fmt.Errorf("self_hosted %q has negative cost values", name)
+	}
+	if f.IdleOverhead != 0 && f.IdleOverhead < 1 {
+		return SelfHostedRate{}, fmt.Errorf("self_hosted %q idle_overhead must be >= 1", name)
+	}
+	return SelfHostedRate{
+		HourlyUSD:          f.HourlyUSD,
+		HardwareCostUSD:    f.HardwareCostUSD,
+		AmortizationMonths: f.AmortizationMonths,
+		IdleOverhead:       f.IdleOverhead,
+	}, nil
+}
+
+type storageFile struct {
+	GBMonthUSD      *float64 `yaml:"gb_month_usd"`
+	CacheIncludedGB *float64 `yaml:"cache_included_gb"`
 }
 
 type snapshotFile struct {
@@ -41,8 +76,8 @@ func LoadFromFile(path string) (Config, error) {
 	cfg := Config{
 		Version:             raw.Version,
 		PerMinuteUSD:        raw.PerMinuteUSD,
-		FreeTierByPlan:      raw.FreeTiers,
-		LargerRunnersPerMin: raw.LargerRunners,
+		FreeTierByPlan:      make(map[string]PlanAllowance, len(raw.FreeTiers)),
+		LargerRunnersPerMin: make(map[string]float64, len(raw.LargerRunners)),
 		WindowsMultiplier:   2,
 		MacOSMultiplier:     10,
 	}
@@ -53,6 +88,32 @@ func LoadFromFile(path string) (Config, error) {
 		}
 		cfg.EffectiveFrom = t.UTC()
 	}
+	for k, v := range raw.FreeTiers {
+		if v.Minutes < 0 || v.StorageGB < 0 {
+			return Config{}, fmt.Errorf("free_tiers %q has negative values", k)
+		}
+		cfg.FreeTierByPlan[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+	for k, v := range raw.LargerRunners {
+		if v > 0 {
+			cfg.LargerRunnersPerMin[normalizeSKU(k)] = v
+		}
+	}
+	if err := loadSelfHosted(&cfg, raw.SelfHosted); err != nil {
+		return Config{}, err
+	}
+	cfg.Storage = DefaultStorageRates
+	if raw.Storage != nil {
+		if v := raw.Storage.GBMonthUSD; v != nil {
+			cfg.Storage.GBMonthUSD = *v
+		}
+		if v := raw.Storage.CacheIncludedGB; v != nil {
+			cfg.Storage.CacheIncludedGB = *v
+		}
+		if cfg.Storage.GBMonthUSD < 0 || cfg.Storage.CacheIncludedGB < 0 {
+			return Config{}, fmt.Errorf("storage has negative values")
+		}
+	}
 	if v := raw.Multipliers["Windows"]; v > 0 {
 		cfg.WindowsMultiplier = v
 	}
@@ -98,3 +159,30 @@ func LoadFromFile(path string) (Config, error) {
 	}
 	return cfg, nil
 }
+
+func loadSelfHosted(cfg *Config, raw selfHostedFile) error {
+	if raw.Default != nil {
+		r, err := raw.Default.toRate("default")
+		if err != nil {
+			return err
+		}
+		cfg.SelfHosted.Default = &r
+	}
+	cfg.SelfHosted.Groups = make(map[string]SelfHostedRate, len(raw.Groups))
+	for k, v := range raw.Groups {
+		r, err := v.toRate(k)
+		if err != nil {
+			return err
+		}
+		cfg.SelfHosted.Groups[selfHostedKey(k)] = r
+	}
+	cfg.SelfHosted.Runners = make(map[string]SelfHostedRate, len(raw.Runners))
+	for k, v := range raw.Runners {
+		r, err := v.toRate(k)
+		if err != nil {
+			return err
+		}
+		cfg.SelfHosted.Runners[selfHostedKey(k)] = r
+	}
+	return nil
+}
@@ -0,0 +1,160 @@
+package pricing
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// RunnerSpec is the hardware class a job ran on, inferred from its runs-on labels.
+type RunnerSpec struct {
+	OS    string
+	Cores int
+	ARM   bool
+	GPU   bool
+	Size  string
+}
+
+var coresRe = regexp.MustCompile(`(\d+)[-_ ]?cores?\b`)
+
+// ParseRunnerLabels maps GitHub-hosted larger runner labels such as
+// `ubuntu-latest-8-cores`, `linux-16core-arm64`, `gpu-t4-4-core` or
+// `macos-14-xlarge` onto a RunnerSpec. runnerOS is used when no label names an OS.
+func ParseRunnerLabels(runnerOS string, labels []string) RunnerSpec {
+	spec := RunnerSpec{OS: normalizeSKU(runnerOS)}
+	osFromLabel := ""
+	for _, raw := range labels {
+		l := strings.ToLower(strings.TrimSpace(raw))
+		if l == "" || l == "self-hosted" {
+			continue
+		}
+		switch {
+		case strings.Contains(l, "macos"):
+			osFromLabel = "macos"
+		case strings.Contains(l, "windows"):
+			osFromLabel = "windows"
+		case strings.Contains(l, "ubuntu"), strings.Contains(l, "linux"):
+			osFromLabel = "linux"
+		}
+		if m := coresRe.FindStringSubmatch(l); len(m) == 2 {
+			if n, err := strconv.Atoi(m[1]); err == nil && n > spec.Cores {
+				spec.Cores = n
+			}
+		}
+		for _, tok := range strings.FieldsFunc(l, func(r rune) bool { return r == '-' || r == '_' || r == ' ' }) {
+			switch tok {
+			case "arm", "arm64", "aarch64":
+				spec.ARM = true
+			case "gpu":
+				spec.GPU = true
+			case "large":
+				if spec.Size == "" {
+					spec.Size = "large"
+				}
+			case "xlarge":
+				spec.Size = "xlarge"
+			}
+		}
+	}
+	if osFromLabel != "" {
+		spec.OS = osFromLabel
+	}
+	if spec.OS == "" {
+		spec.OS = "linux"
+	}
+	if spec.OS != "macos" {
+		spec.Size = ""
+	}
+	return spec
+}
+
+// Larger reports whether the spec describes anything other than a standard runner.
+func (s RunnerSpec) Larger() bool {
+	return s.Cores > 2 || s.ARM || s.GPU || s.Size != ""
+}
+
+// SKUCandidates lists snapshot SKU keys for the spec, most specific first.
+// Standard runners return nil so callers fall back to OS-level SKUs.
+func (s RunnerSpec) SKUCandidates() []string {
+	if !s.Larger() {
+		return nil
+	}
+	out := []string{}
+	if s.OS == "macos" {
+		switch s.Size {
+		case "xlarge":
+			out = append(out, "macos-xlarge")
+		case "large":
+			out = append(out, "macos-large", "macos-12core")
+		}
+		if s.Cores > 0 {
+			out = append(out, "macos-"+strconv.Itoa(s.Cores)+"core")
+		}
+		return out
+	}
+	cores := s.Cores
+	if cores == 0 {
+		cores = 2
+	}
+	base := s.OS + "-" + strconv.Itoa(cores) + "core"
+	if s.GPU {
+		out = append(out, base+"-gpu", s.OS+"-gpu")
+	}
+	if s.ARM {
+		out = append(out, base+"-arm", s.OS+"-arm")
+	}
+	if cores > 2 {
+		out = append(out, base)
+	}
+	return out
+}
+
+func largerRunnerRate(cfg Config, spec RunnerSpec) (float64, string, bool) {
+	for _, k := range spec.SKUCandidates() {
+		if v, ok := cfg.LargerRunnersPerMin[k]; ok && v > 0 {
+			return v, k, true
+		}
+	}
+	return 0, "", false
+}
+
+// CanonicalSKU maps an estimate SKU key or a billing report SKU label such as
+// "Actions Linux 8-core" or "actions_macos_xlarge" onto the key used to match
+// estimates against billed amounts: an OS family, or a larger-runner SKU.
+func CanonicalSKU(raw string) string {
+	s := strings.ToLower(strings.TrimSpace(raw))
+	if s == "" {
+		return ""
+	}
+	s = strings.NewReplacer("_", " ", "-", " ").Replace(s)
+	family := ""
+	for _, tok := range strings.Fields(s) {
+		switch tok {
+		case "linux", "ubuntu":
+			family = "linux"
+		case "windows":
+			family = "windows"
+		case "macos", "mac", "osx":
+			family = "macos"
+		}
+	}
+	if family == "" {
+		return strings.Join(strings.Fields(s), "-")
+	}
+	parsed := ParseRunnerLabels(family, []string{strings.Join(strings.Fields(s), "-")})
+	parsed.OS = family
+	if parsed.OS == "macos" {
+		switch {
+		case parsed.Size == "xlarge":
+			return "macos-xlarge"
+		case parsed.Size == "large", parsed.Cores >= 12:
+			return "macos-large"
+		default:
+			return "macos"
+		}
+	}
+	if c := parsed.SKUCandidates(); len(c) > 0 {
+		return c[0]
+	}
+	return parsed.OS
+}
@@ -0,0 +1,84 @@
+package pricing
+
+import "strings"
+
+const PricingSourceSelfHosted = "self_hosted"
+
+// hoursPerMonth is the average number of hours in a month used to amortize hardware.
+const hoursPerMonth = 730
+
+type SelfHostedRate struct {
+	HourlyUSD          float64
+	HardwareCostUSD    float64
+	AmortizationMonths float64
+	IdleOverhead       float64
+}
+
+// SelfHostedModel prices self-hosted runners. Runner names take precedence
+// over runner groups, which take precedence over Default.
+type SelfHostedModel struct {
+	Default *SelfHostedRate
+	Groups  map[string]SelfHostedRate
+	Runners map[string]SelfHostedRate
+}
+
+// PerMinuteUSD folds hourly cost, amortized hardware and idle overhead into a per-minute rate.
+func (r SelfHostedRate) PerMinuteUSD() float64 {
+	hourly := r.HourlyUSD
+	if r.HardwareCostUSD > 0 {
+		months := r.AmortizationMonths
+		if months <= 0 {
+			months = 36
+		}
+		hourly += r.HardwareCostUSD / (months * hoursPerMonth)
+	}
+	overhead := r.IdleOverhead
+	if overhead <= 0 {
+		overhead = 1
+	}
+	return hourly * overhead / 60
+}
+
+func (m SelfHostedModel) lookup(runnerGroup, runnerName string) (SelfHostedRate, string, bool) {
+	if name := selfHostedKey(runnerName); name != "" {
+		if r, ok := m.Runners[name]; ok {
+			return r, name, true
+		}
+	}
+	if group := selfHostedKey(runnerGroup); group != "" {
+		if r, ok := m.Groups[group]; ok {
+			return r, group, true
+		}
+	}
+	if m.Default != nil {
+		return *m.Default, "default", true
+	}
+	return SelfHostedRate{}, "", false
+}
+
+// PriceSelfHostedJob prices a self-hosted job on actual (unrounded) runtime.
+// Jobs without a matching model entry are returned with zero cost so their
+// minutes stay visible.
+func PriceSelfHostedJob(durationSec int, runnerGroup, runnerName string, cfg Config) JobPrice {
+	minutes := 0.0
+	if durationSec > 0 {
+		minutes = float64(durationSec) / 60
+	}
+	out := JobPrice{
+		BillableMinutes: minutes,
+		Source:          PricingSourceSelfHosted,
+		SKU:             "self-hosted",
+	}
+	rate, key, ok := cfg.SelfHosted.lookup(runnerGroup, runnerName)
+	if !ok {
+		return out
+	}
+	out.RatePerMin = rate.PerMinuteUSD()
+	out.CostUSD = minutes * out.RatePerMin
+	out.SKU = "self-hosted:" + key
+	return out
+}
+
+func selfHostedKey(v string) string {
+	return strings.ToLower(strings.TrimSpace(v))
+}
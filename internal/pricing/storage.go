@@ -0,0 +1,39 @@
+package pricing
+
+import (
+	"math"
+	"time"
+)
+
+// bytesPerGB matches GitHub's storage billing, which counts binary gigabytes.
+const bytesPerGB = 1 << 30
+
+// StorageRates prices artifact and cache storage per GB-month. Caches up to
+// CacheIncludedGB per repository are not billed.
+type StorageRates struct {
+	GBMonthUSD      float64
+	CacheIncludedGB float64
+}
+
+// DefaultStorageRates are used when the pricing file has no storage section.
+var DefaultStorageRates = StorageRates{GBMonthUSD: 0.25, CacheIncludedGB: 10}
+
+// GBMonths is the storage used by sizeBytes kept over [from, to] clipped to
+// [start, end], in GB-months of hoursPerMonth hours.
+func GBMonths(sizeBytes int64, from, to, start, end time.Time) float64 {
+	if from.Before(start) {
+		from = start
+	}
+	if to.After(end) {
+		to = end
+	}
+	if sizeBytes <= 0 || !to.After(from) {
+		return 0
+	}
+	return float64(sizeBytes) / bytesPerGB * to.Sub(from).Hours() / hoursPerMonth
+}
+
+// Months is the length of [start, end] in months of hoursPerMonth hours.
+func Months(start, end time.Time) float64 {
+	return math.Max(end.Sub(start).Hours(), 0) / hoursPerMonth
+}
@@ -2,6 +2,7 @@ package reconcile
 
 import (
 	"math"
+	"sort"
 	"time"
 
 	"github.com/peter941221/CICost/internal/model"
@@ -31,6 +32,94 @@ func BuildResult(repo, period string, estimate, actual float64) model.ReconcileR
 	}
 }
 
+// BuildSKUResults reconciles each billed SKU in actual against its estimate.
+// A SKU that was billed but never estimated keeps factor 1 at low confidence.
+func BuildSKUResults(repo, period string, estimate, actual map[string]float64) []model.ReconcileResult {
+	skus := make([]string, 0, len(actual))
+	for sku := range actual {
+		if sku != "" {
+			skus = append(skus, sku)
+		}
+	}
+	sort.Strings(skus)
+	out := make([]model.ReconcileResult, 0, len(skus))
+	for _, sku := range skus {
+		res := BuildResult(repo, period, estimate[sku], actual[sku])
+		res.SKU = sku
+		if estimate[sku] <= 0 || actual[sku] <= 0 {
+			res.CalibrationFactor = 1
+			res.Confidence = "low"
+		}
+		out = append(out, res)
+	}
+	return out
+}
+
+// EstimateBySKU spreads the net estimate over cost.BySKU in proportion to each
+// SKU's gross cost, so per-SKU estimates add up to TotalCostUSD.
+func EstimateBySKU(cost model.CostResult) map[string]float64 {
+	gross := 0.0
+	for _, c := range cost.BySKU {
+		gross += c.CostUSD
+	}
+	out := make(map[string]float64, len(cost.BySKU))
+	if gross <= 0 {
+		return out
+	}
+	for sku, c := range cost.BySKU {
+		out[sku] = cost.TotalCostUSD * c.CostUSD / gross
+	}
+	return out
+}
+
+// ApplySKUCalibration scales cost by the per-SKU factors, using fallback for
+// SKUs without one, and returns the effective repo-wide factor applied.
+func ApplySKUCalibration(cost *model.CostResult, factors map[string]float64, fallback float64) float64 {
+	if fallback <= 0 {
+		fallback = 1
+	}
+	factorFor := func(sku string) float64 {
+		if f, ok := factors[sku]; ok && f > 0 {
+			return f
+		}
+		return fallback
+	}
+	estimates := EstimateBySKU(*cost)
+	if len(estimates) == 0 || cost.TotalCostUSD <= 0 {
+		cost.TotalCostUSD = round2(cost.TotalCostUSD * fallback)
+		cost.GrossCostUSD = round2(cost.GrossCostUSD * fallback)
+		for k, osCost := range cost.ByOS {
+			osCost.CostUSD = round2(osCost.CostUSD * fallback)
+			cost.ByOS[k] = osCost
+		}
+		return fallback
+	}
+
+	calibrated := 0.0
+	osGross := map[string]float64{}
+	osScaled := map[string]float64{}
+	for sku, skuCost := range cost.BySKU {
+		f := factorFor(sku)
+		calibrated += estimates[sku] * f
+		osGross[skuCost.OS] += skuCost.CostUSD
+		osScaled[skuCost.OS] += skuCost.CostUSD * f
+		skuCost.CostUSD = round2(skuCost.CostUSD * f)
+		cost.BySKU[sku] = skuCost
+	}
+	for k, osCost := range cost.ByOS {
+		f := fallback
+		if osGross[k] > 0 {
+			f = osScaled[k] / osGross[k]
+		}
+		osCost.CostUSD = round2(osCost.CostUSD * f)
+		cost.ByOS[k] = osCost
+	}
+	effective := calibrated / cost.TotalCostUSD
+	cost.TotalCostUSD = round2(calibrated)
+	cost.GrossCostUSD = round2(cost.GrossCostUSD * effective)
+	return round4(effective)
+}
+
 func Confidence(delta float64) string {
 	absDelta := math.Abs(delta)
 	switch {
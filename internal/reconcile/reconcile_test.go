@@ -1,6 +1,10 @@
 package reconcile
 
-import "testing"
+import (
+	"testing"
+
+	"github.com/peter941221/CICost/internal/model"
+)
 
 func TestBuildResultAndConfidence(t *testing.T) {
 	res := BuildResult("owner/repo", "2026-02", 110, 100)
@@ -26,3 +30,46 @@ func TestConfidenceBands(t *testing.T) {
 		t.Fatalf("expected low, got %s", got)
 	}
 }
+
+func TestBuildSKUResults(t *testing.T) {
+	got := BuildSKUResults("owner/repo", "2026-02",
+		map[string]float64{"linux": 10, "macos": 20},
+		map[string]float64{"": 1, "linux": 10, "macos": 26, "windows": 5},
+	)
+	if len(got) != 3 {
+		t.Fatalf("expected 3 per-SKU results, got %+v", got)
+	}
+	if got[0].SKU != "linux" || got[0].CalibrationFactor != 1 || got[0].Confidence != "high" {
+		t.Fatalf("unexpected linux result: %+v", got[0])
+	}
+	if got[1].SKU != "macos" || got[1].CalibrationFactor != 1.3 || got[1].Confidence != "low" {
+		t.Fatalf("unexpected macos result: %+v", got[1])
+	}
+	if got[2].SKU != "windows" || got[2].CalibrationFactor != 1 || got[2].Confidence != "low" {
+		t.Fatalf("expected unestimated SKU to keep factor 1, got %+v", got[2])
+	}
+}
+
+func TestApplySKUCalibration(t *testing.T) {
+	cost := model.CostResult{
+		TotalCostUSD: 30,
+		ByOS: map[string]model.OSCost{
+			"Linux": {OS: "Linux", CostUSD: 10},
+			"macOS": {OS: "macOS", CostUSD: 20},
+		},
+		BySKU: map[string]model.SKUCost{
+			"linux": {SKU: "linux", OS: "Linux", CostUSD: 10},
+			"macos": {SKU: "macos", OS: "macOS", CostUSD: 20},
+		},
+	}
+	effective := ApplySKUCalibration(&cost, map[string]float64{"macos": 1.3}, 1)
+	if cost.TotalCostUSD != 36 {
+		t.Fatalf("expected calibrated total 36, got %.2f", cost.TotalCostUSD)
+	}
+	if effective != 1.2 {
+		t.Fatalf("expected effective factor 1.2, got %.4f", effective)
+	}
+	if cost.ByOS["Linux"].CostUSD != 10 || cost.ByOS["macOS"].CostUSD != 26 {
+		t.Fatalf("unexpected calibrated by-OS cost: %+v", cost.ByOS)
+	}
+}
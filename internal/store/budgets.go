@@ -0,0 +1,129 @@
+package store
+
+import (
+	"time"
+)
+
+// BudgetCheckRecord is one evaluation of a named budget, kept so each
+// budget has a history of the levels it reached.
+type BudgetCheckRecord struct {
+	Name         string
+	Scope        string
+	Repos        string
+	CheckType    string
+	PeriodStart  time.Time
+	PeriodEnd    time.Time
+	ThresholdUSD float64
+	ActualUSD    float64
+	ProjectedUSD float64
+	Level        float64
+	Status       string
+	Exceeded     bool
+	CheckedAt    time.Time
+}
+
+func (s *Store) InsertBudgetCheckRecord(r BudgetCheckRecord) error {
+	ex := 0
+	if r.Exceeded {
+		ex = 1
+	}
+	if r.CheckedAt.IsZero() {
+		r.CheckedAt = time.Now()
+	}
+	_, err := s.db.Exec(`
+INSERT INTO budget_checks (repo, check_type, period_start, period_end, threshold_usd, actual_usd, exceeded, checked_at, budget_name, scope, status, level, projected_usd)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		r.Repos, r.CheckType, asRFC3339(r.PeriodStart), asRFC3339(r.PeriodEnd), r.ThresholdUSD, r.ActualUSD, ex,
+		asRFC3339(r.CheckedAt), r.Name, r.Scope, r.Status, r.Level, r.ProjectedUSD)
+	return err
+}
+
+// ListBudgetChecks returns up to limit checks of the budget called name,
+// newest first.
+func (s *Store) ListBudgetChecks(name string, limit int) ([]BudgetCheckRecord, error) {
+	rows, err := s.db.Query(`
+SELECT budget_name, scope, repo, check_type, period_start, period_end, threshold_usd, actual_usd, projected_usd, level, status, exceeded, checked_at
+FROM budget_checks
+WHERE budget_name = ?
+ORDER BY id DESC
+LIMIT ?`, name, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []BudgetCheckRecord
+	for rows.Next() {
+		var r BudgetCheckRecord
+		var periodStart, periodEnd, checkedAt string
+		var ex int
+		if err := rows.Scan(&r.Name, &r.Scope, &r.Repos, &r.CheckType, &periodStart, &periodEnd, &r.ThresholdUSD, &r.ActualUSD, &r.ProjectedUSD, &r.Level, &r.Status, &ex, &checkedAt); err != nil {
+			return nil, err
+		}
+		r.PeriodStart = parseRFC3339(periodStart)
+		r.PeriodEnd = parseRFC3339(periodEnd)
+		r.CheckedAt = parseRFC3339(checkedAt)
+		r.Exceeded = ex == 1
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// NotificationState is the last notification sent for one threshold level
+// of a budget in one period.
+type NotificationState struct {
+	BudgetKey   string
+	PeriodStart time.Time
+	Level       float64
+	Status      string
+	NotifiedAt  time.Time
+}
+
+// ListNotificationStates returns the states of budgetKey in the period
+// starting at periodStart, by level.
+func (s *Store) ListNotificationStates(budgetKey string, periodStart time.Time) ([]NotificationState, error) {
+	rows, err := s.db.Query(`
+SELECT budget_key, period_start, level, status, notified_at
+FROM notification_state
+WHERE budget_key = ? AND period_start = ?
+ORDER BY level`, budgetKey, asRFC3339(periodStart))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []NotificationState
+	for rows.Next() {
+		var n NotificationState
+		var periodStart, notifiedAt string
+		if err := rows.Scan(&n.BudgetKey, &periodStart, &n.Level, &n.Status, &notifiedAt); err != nil {
+			return nil, err
+		}
+		n.PeriodStart = parseRFC3339(periodStart)
+		n.NotifiedAt = parseRFC3339(notifiedAt)
+		out = append(out, n)
+	}
+	return out, rows.Err()
+}
+
+func (s *Store) UpsertNotificationStates(states []NotificationState) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer rollbackIfNeeded(tx, &err)
+	stmt, err := tx.Prepare(`
+INSERT INTO notification_state (budget_key, period_start, level, status, notified_at)
+VALUES (?, ?, ?, ?, ?)
+ON CONFLICT(budget_key, period_start, level) DO UPDATE SET
+	status=excluded.status,
+	notified_at=excluded.notified_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, n := range states {
+		if _, err = stmt.Exec(n.BudgetKey, asRFC3339(n.PeriodStart), n.Level, n.Status, asRFC3339(n.NotifiedAt)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
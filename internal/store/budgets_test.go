@@ -0,0 +1,59 @@
+package store
+
+import (
+	"database/sql"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBudgetCheckHistoryOnLegacyTable(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "cicost.db")
+	legacy, err := sql.Open("sqlite", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := legacy.Exec(`CREATE TABLE budget_checks (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    repo            TEXT NOT NULL,
+    check_type      TEXT NOT NULL,
+    period_start    TEXT NOT NULL,
+    period_end      TEXT NOT NULL,
+    threshold_usd   REAL NOT NULL,
+    actual_usd      REAL NOT NULL,
+    exceeded        INTEGER DEFAULT 0,
+    checked_at      TEXT NOT NULL DEFAULT (datetime('now'))
+)`); err != nil {
+		t.Fatal(err)
+	}
+	_ = legacy.Close()
+
+	st, err := Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	for i, level := range []float64{50, 80} {
+		if err := st.InsertBudgetCheckRecord(BudgetCheckRecord{
+			Name: "ci", Scope: "org=acme", Repos: "acme/api", CheckType: "monthly",
+			PeriodStart: start, PeriodEnd: start.AddDate(0, 1, 0),
+			ThresholdUSD: 100, ActualUSD: level, Level: level, Status: "warning",
+			CheckedAt: start.AddDate(0, 0, i+1),
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := st.InsertBudgetCheck("acme/api", "monthly", start, start, 100, 10, false); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := st.ListBudgetChecks("ci", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Level != 80 || got[1].Level != 50 || !got[0].PeriodStart.Equal(start) || got[0].Scope != "org=acme" {
+		t.Fatalf("unexpected history: %+v", got)
+	}
+}
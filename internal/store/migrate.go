@@ -0,0 +1,126 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+)
+
+// columnMigration adds a column introduced after the owning table first
+// shipped, so databases created by older releases keep working.
+type columnMigration struct {
+	table  string
+	column string
+	ddl    string
+}
+
+var columnMigrations = []columnMigration{
+	{table: "jobs", column: "labels", ddl: "TEXT NOT NULL DEFAULT ''"},
+	{table: "reconcile_results", column: "sku", ddl: "TEXT NOT NULL DEFAULT ''"},
+	{table: "billing_snapshots", column: "workflow", ddl: "TEXT NOT NULL DEFAULT ''"},
+	{table: "workflow_runs", column: "workflow_path", ddl: "TEXT NOT NULL DEFAULT ''"},
+	{table: "budget_checks", column: "budget_name", ddl: "TEXT NOT NULL DEFAULT ''"},
+	{table: "budget_checks", column: "scope", ddl: "TEXT NOT NULL DEFAULT ''"},
+	{table: "budget_checks", column: "status", ddl: "TEXT NOT NULL DEFAULT ''"},
+	{table: "budget_checks", column: "level", ddl: "REAL NOT NULL DEFAULT 0"},
+	{table: "budget_checks", column: "projected_usd", ddl: "REAL NOT NULL DEFAULT 0"},
+	{table: "policy_runs", column: "scope", ddl: "TEXT NOT NULL DEFAULT ''"},
+	{table: "policy_runs", column: "entity", ddl: "TEXT NOT NULL DEFAULT ''"},
+	{table: "artifacts", column: "removed_at", ddl: "TEXT NOT NULL DEFAULT ''"},
+}
+
+// tableRebuild recreates a table whose constraints changed. SQLite cannot
+// alter a table-level UNIQUE, so the old table is renamed, the new shape is
+// created from create and the shared columns are copied across. Rebuilds run
+// before columnMigrations, which then add any columns introduced later.
+type tableRebuild struct {
+	table   string
+	column  string
+	create  string
+	columns string
+}
+
+var tableRebuilds = []tableRebuild{
+	{
+		table:  "billing_snapshots",
+		column: "sku",
+		create: `CREATE TABLE billing_snapshots (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    repo            TEXT NOT NULL,
+    period          TEXT NOT NULL,
+    sku             TEXT NOT NULL DEFAULT '',
+    actual_cost_usd REAL NOT NULL,
+    source          TEXT NOT NULL,
+    fetched_at      TEXT NOT NULL DEFAULT (datetime('now'))
+)`,
+		columns: "id, repo, period, actual_cost_usd, source, fetched_at",
+	},
+}
+
+func migrate(db *sql.DB) error {
+	for _, r := range tableRebuilds {
+		ok, err := hasColumn(db, r.table, r.column)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		if err := rebuildTable(db, r); err != nil {
+			return fmt.Errorf("migrate %s.%s failed: %w", r.table, r.column, err)
+		}
+	}
+	for _, m := range columnMigrations {
+		ok, err := hasColumn(db, m.table, m.column)
+		if err != nil {
+			return err
+		}
+		if ok {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf(`ALTER TABLE %s ADD COLUMN %s %s`, m.table, m.column, m.ddl)); err != nil {
+			return fmt.Errorf("migrate %s.%s failed: %w", m.table, m.column, err)
+		}
+	}
+	return nil
+}
+
+func rebuildTable(db *sql.DB, r tableRebuild) (err error) {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	defer rollbackIfNeeded(tx, &err)
+	legacy := r.table + "_legacy"
+	stmts := []string{
+		fmt.Sprintf(`ALTER TABLE %s RENAME TO %s`, r.table, legacy),
+		r.create,
+		fmt.Sprintf(`INSERT INTO %s (%s) SELECT %s FROM %s`, r.table, r.columns, r.columns, legacy),
+		fmt.Sprintf(`DROP TABLE %s`, legacy),
+	}
+	for _, stmt := range stmts {
+		if _, err = tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+func hasColumn(db *sql.DB, table, column string) (bool, error) {
+	rows, err := db.Query(fmt.Sprintf(`PRAGMA table_info(%s)`, table))
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, typ string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &typ, &notNull, &dflt, &pk); err != nil {
+			return false, err
+		}
+		if name == column {
+			return true, nil
+		}
+	}
+	return false, rows.Err()
+}
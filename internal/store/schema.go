@@ -11,6 +11,7 @@ CREATE TABLE IF NOT EXISTS workflow_runs (
     repo            TEXT NOT NULL,
     workflow_id     INTEGER NOT NULL,
     workflow_name   TEXT NOT NULL,
+    workflow_path   TEXT NOT NULL DEFAULT '',
     head_branch     TEXT,
     event           TEXT,
     status          TEXT,
@@ -38,10 +39,25 @@ CREATE TABLE IF NOT EXISTS jobs (
     runner_group    TEXT,
     is_self_hosted  INTEGER DEFAULT 0,
     duration_sec    INTEGER,
+    labels          TEXT NOT NULL DEFAULT '',
     fetched_at      TEXT NOT NULL DEFAULT (datetime('now')),
     UNIQUE(id, run_attempt)
 );
 
+CREATE TABLE IF NOT EXISTS job_steps (
+    job_id          INTEGER NOT NULL,
+    run_attempt     INTEGER DEFAULT 1,
+    repo            TEXT NOT NULL,
+    number          INTEGER NOT NULL,
+    name            TEXT NOT NULL,
+    status          TEXT,
+    conclusion      TEXT,
+    started_at      TEXT,
+    completed_at    TEXT,
+    duration_sec    INTEGER,
+    UNIQUE(job_id, run_attempt, number)
+);
+
 CREATE TABLE IF NOT EXISTS sync_cursors (
     repo            TEXT PRIMARY KEY,
     last_run_id     INTEGER,
@@ -51,6 +67,61 @@ CREATE TABLE IF NOT EXISTS sync_cursors (
     total_jobs      INTEGER DEFAULT 0
 );
 
+CREATE TABLE IF NOT EXISTS job_fetch_status (
+    repo            TEXT NOT NULL,
+    run_id          INTEGER NOT NULL,
+    run_attempt     INTEGER NOT NULL DEFAULT 1,
+    run_updated_at  TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL,
+    last_error      TEXT NOT NULL DEFAULT '',
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    UNIQUE(run_id, run_attempt)
+);
+
+CREATE TABLE IF NOT EXISTS scan_outcomes (
+    repo            TEXT PRIMARY KEY,
+    owner           TEXT NOT NULL,
+    status          TEXT NOT NULL,
+    runs            INTEGER NOT NULL DEFAULT 0,
+    jobs            INTEGER NOT NULL DEFAULT 0,
+    error           TEXT NOT NULL DEFAULT '',
+    scanned_at      TEXT NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS http_cache (
+    url             TEXT PRIMARY KEY,
+    etag            TEXT NOT NULL DEFAULT '',
+    last_modified   TEXT NOT NULL DEFAULT '',
+    link            TEXT NOT NULL DEFAULT '',
+    body            BLOB NOT NULL,
+    updated_at      TEXT NOT NULL DEFAULT (datetime('now'))
+);
+
+CREATE TABLE IF NOT EXISTS artifacts (
+    id              INTEGER PRIMARY KEY,
+    repo            TEXT NOT NULL,
+    name            TEXT NOT NULL,
+    run_id          INTEGER NOT NULL DEFAULT 0,
+    size_bytes      INTEGER NOT NULL,
+    expired         INTEGER NOT NULL DEFAULT 0,
+    created_at      TEXT NOT NULL,
+    expires_at      TEXT NOT NULL,
+    fetched_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    removed_at      TEXT NOT NULL DEFAULT ''
+);
+
+CREATE TABLE IF NOT EXISTS actions_caches (
+    id                INTEGER NOT NULL,
+    repo              TEXT NOT NULL,
+    cache_key         TEXT NOT NULL,
+    ref               TEXT NOT NULL DEFAULT '',
+    size_bytes        INTEGER NOT NULL,
+    created_at        TEXT NOT NULL,
+    last_accessed_at  TEXT NOT NULL,
+    last_seen_at      TEXT NOT NULL,
+    UNIQUE(repo, id)
+);
+
 CREATE TABLE IF NOT EXISTS budget_checks (
     id              INTEGER PRIMARY KEY AUTOINCREMENT,
     repo            TEXT NOT NULL,
@@ -60,23 +131,39 @@ CREATE TABLE IF NOT EXISTS budget_checks (
     threshold_usd   REAL NOT NULL,
     actual_usd      REAL NOT NULL,
     exceeded        INTEGER DEFAULT 0,
-    checked_at      TEXT NOT NULL DEFAULT (datetime('now'))
+    checked_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    budget_name     TEXT NOT NULL DEFAULT '',
+    scope           TEXT NOT NULL DEFAULT '',
+    status          TEXT NOT NULL DEFAULT '',
+    level           REAL NOT NULL DEFAULT 0,
+    projected_usd   REAL NOT NULL DEFAULT 0
+);
+
+CREATE TABLE IF NOT EXISTS notification_state (
+    budget_key      TEXT NOT NULL,
+    period_start    TEXT NOT NULL,
+    level           REAL NOT NULL,
+    status          TEXT NOT NULL,
+    notified_at     TEXT NOT NULL,
+    PRIMARY KEY (budget_key, period_start, level)
 );
 
 CREATE TABLE IF NOT EXISTS billing_snapshots (
     id              INTEGER PRIMARY KEY AUTOINCREMENT,
     repo            TEXT NOT NULL,
     period          TEXT NOT NULL,
+    sku             TEXT NOT NULL DEFAULT '',
+    workflow        TEXT NOT NULL DEFAULT '',
     actual_cost_usd REAL NOT NULL,
     source          TEXT NOT NULL,
-    fetched_at      TEXT NOT NULL DEFAULT (datetime('now')),
-    UNIQUE(repo, period, source)
+    fetched_at      TEXT NOT NULL DEFAULT (datetime('now'))
 );
 
 CREATE TABLE IF NOT EXISTS reconcile_results (
     id                    INTEGER PRIMARY KEY AUTOINCREMENT,
     repo                  TEXT NOT NULL,
     period                TEXT NOT NULL,
+    sku                   TEXT NOT NULL DEFAULT '',
     estimated_cost_usd    REAL NOT NULL,
     actual_cost_usd       REAL NOT NULL,
     delta_ratio           REAL NOT NULL,
@@ -91,6 +178,8 @@ CREATE TABLE IF NOT EXISTS policy_runs (
     period_start      TEXT NOT NULL,
     period_end        TEXT NOT NULL,
     rule_id           TEXT NOT NULL,
+    scope             TEXT NOT NULL DEFAULT '',
+    entity            TEXT NOT NULL DEFAULT '',
     severity          TEXT NOT NULL,
     matched           INTEGER NOT NULL DEFAULT 0,
     evidence_key      TEXT NOT NULL,
@@ -115,8 +204,21 @@ CREATE INDEX IF NOT EXISTS idx_runs_repo_created ON workflow_runs(repo, created_
 CREATE INDEX IF NOT EXISTS idx_runs_repo_workflow ON workflow_runs(repo, workflow_name);
 CREATE INDEX IF NOT EXISTS idx_jobs_run_id ON jobs(run_id, run_attempt);
 CREATE INDEX IF NOT EXISTS idx_jobs_repo_runner ON jobs(repo, runner_os);
-CREATE INDEX IF NOT EXISTS idx_billing_repo_period ON billing_snapshots(repo, period);
+CREATE INDEX IF NOT EXISTS idx_steps_repo_job ON job_steps(repo, job_id, run_attempt);
+CREATE INDEX IF NOT EXISTS idx_job_fetch_repo_status ON job_fetch_status(repo, status);
+CREATE INDEX IF NOT EXISTS idx_scan_outcomes_owner ON scan_outcomes(owner);
+CREATE INDEX IF NOT EXISTS idx_artifacts_repo_created ON artifacts(repo, created_at);
+CREATE INDEX IF NOT EXISTS idx_caches_repo_created ON actions_caches(repo, created_at);
 CREATE INDEX IF NOT EXISTS idx_reconcile_repo_period ON reconcile_results(repo, period);
 CREATE INDEX IF NOT EXISTS idx_policy_repo_created ON policy_runs(repo, created_at);
 CREATE INDEX IF NOT EXISTS idx_suggestion_repo_created ON suggestion_history(repo, created_at);
 `
+
+// IndexSQL creates indexes over columns that migrate may have added, so it
+// runs after migrate rather than as part of SchemaSQL.
+const IndexSQL = `
+DROP INDEX IF EXISTS idx_billing_unique;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_billing_unique_workflow ON billing_snapshots(repo, period, source, sku, workflow);
+CREATE INDEX IF NOT EXISTS idx_billing_repo_period ON billing_snapshots(repo, period);
+CREATE INDEX IF NOT EXISTS idx_budget_checks_name ON budget_checks(budget_name, id);
+`
@@ -1,8 +1,12 @@
 package store
 
 import (
+	"database/sql"
 	"path/filepath"
 	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
 )
 
 func TestSchemaV2TablesExist(t *testing.T) {
@@ -23,3 +27,91 @@ func TestSchemaV2TablesExist(t *testing.T) {
 		}
 	}
 }
+
+func TestOpenMigratesLegacyJobsTable(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "cicost.db")
+	legacy, err := sql.Open("sqlite", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := legacy.Exec(`CREATE TABLE jobs (
+    id              INTEGER NOT NULL,
+    run_id          INTEGER NOT NULL,
+    run_attempt     INTEGER DEFAULT 1,
+    repo            TEXT NOT NULL,
+    name            TEXT NOT NULL,
+    status          TEXT,
+    conclusion      TEXT,
+    started_at      TEXT,
+    completed_at    TEXT,
+    runner_os       TEXT,
+    runner_name     TEXT,
+    runner_group    TEXT,
+    is_self_hosted  INTEGER DEFAULT 0,
+    duration_sec    INTEGER,
+    fetched_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    UNIQUE(id, run_attempt)
+)`); err != nil {
+		t.Fatal(err)
+	}
+	_ = legacy.Close()
+
+	st, err := Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Date(2026, 2, 26, 10, 0, 0, 0, time.UTC)
+	if _, _, err := st.UpsertRuns([]model.WorkflowRun{{ID: 1, Repo: "owner/repo", WorkflowName: "ci", RunAttempt: 1, CreatedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := st.UpsertJobs([]model.Job{{ID: 2, RunID: 1, RunAttempt: 1, Repo: "owner/repo", Name: "build", Labels: []string{"ubuntu-latest-8-cores"}}}); err != nil {
+		t.Fatal(err)
+	}
+	jobs, err := st.ListJobs("owner/repo", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || len(jobs[0].Labels) != 1 || jobs[0].Labels[0] != "ubuntu-latest-8-cores" {
+		t.Fatalf("expected labels roundtrip after migration, got %+v", jobs)
+	}
+}
+
+func TestOpenMigratesLegacyBillingSnapshots(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "cicost.db")
+	legacy, err := sql.Open("sqlite", db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := legacy.Exec(`CREATE TABLE billing_snapshots (
+    id              INTEGER PRIMARY KEY AUTOINCREMENT,
+    repo            TEXT NOT NULL,
+    period          TEXT NOT NULL,
+    actual_cost_usd REAL NOT NULL,
+    source          TEXT NOT NULL,
+    fetched_at      TEXT NOT NULL DEFAULT (datetime('now')),
+    UNIQUE(repo, period, source)
+);
+INSERT INTO billing_snapshots (repo, period, actual_cost_usd, source) VALUES ('owner/repo', '2026-02', 42, 'csv');`); err != nil {
+		t.Fatal(err)
+	}
+	_ = legacy.Close()
+
+	st, err := Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	if err := st.UpsertBillingSnapshot(model.BillingSnapshot{Repo: "owner/repo", Period: "2026-02", SKU: "macos", ActualCostUSD: 8, Source: "csv"}); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := st.ListBillingSnapshots("owner/repo", "2026-02", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[0].SKU != "" || rows[0].ActualCostUSD != 42 || rows[1].SKU != "macos" {
+		t.Fatalf("expected legacy row kept alongside per-SKU row, got %+v", rows)
+	}
+}
@@ -2,9 +2,11 @@ package store
 
 import (
 	"database/sql"
+	"encoding/json"
 	"errors"
 	"os"
 	"path/filepath"
+	"strconv"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -22,7 +24,10 @@ func Open(path string) (*Store, error) {
 	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
 		return nil, err
 	}
-	db, err := sql.Open("sqlite", path)
+	// Scans write from several goroutines; wait for the lock instead of
+	// failing with SQLITE_BUSY, and take it up front so transactions that
+	// read before writing cannot deadlock each other.
+	db, err := sql.Open("sqlite", path+"?_pragma=busy_timeout(10000)&_txlock=immediate")
 	if err != nil {
 		return nil, err
 	}
@@ -30,6 +35,14 @@ func Open(path string) (*Store, error) {
 		_ = db.Close()
 		return nil, err
 	}
+	if err := migrate(db); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	if _, err := db.Exec(IndexSQL); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
 	return &Store{db: db}, nil
 }
 
@@ -52,13 +65,14 @@ func (s *Store) UpsertRuns(runs []model.WorkflowRun) (newCount, updatedCount int
 
 	upsertStmt, err := tx.Prepare(`
 INSERT INTO workflow_runs (
-    id, repo, workflow_id, workflow_name, head_branch, event, status, conclusion, run_attempt,
+    id, repo, workflow_id, workflow_name, workflow_path, head_branch, event, status, conclusion, run_attempt,
     run_started_at, updated_at, created_at
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(id, run_attempt) DO UPDATE SET
     repo=excluded.repo,
     workflow_id=excluded.workflow_id,
     workflow_name=excluded.workflow_name,
+    workflow_path=excluded.workflow_path,
     head_branch=excluded.head_branch,
     event=excluded.event,
     status=excluded.status,
@@ -82,7 +96,7 @@ ON CONFLICT(id, run_attempt) DO UPDATE SET
 			newCount++
 		}
 		_, err = upsertStmt.Exec(
-			r.ID, r.Repo, r.WorkflowID, r.WorkflowName, r.HeadBranch, r.Event, r.Status, r.Conclusion, r.RunAttempt,
+			r.ID, r.Repo, r.WorkflowID, r.WorkflowName, r.WorkflowPath, r.HeadBranch, r.Event, r.Status, r.Conclusion, r.RunAttempt,
 			asRFC3339(r.RunStartedAt), asRFC3339(r.UpdatedAt), asRFC3339(r.CreatedAt),
 		)
 		if err != nil {
@@ -111,8 +125,8 @@ func (s *Store) UpsertJobs(jobs []model.Job) (newCount, updatedCount int, err er
 	upsertStmt, err := tx.Prepare(`
 INSERT INTO jobs (
     id, run_id, run_attempt, repo, name, status, conclusion, started_at, completed_at,
-    runner_os, runner_name, runner_group, is_self_hosted, duration_sec
-) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+    runner_os, runner_name, runner_group, is_self_hosted, duration_sec, labels
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 ON CONFLICT(id, run_attempt) DO UPDATE SET
     run_id=excluded.run_id,
     repo=excluded.repo,
@@ -125,12 +139,28 @@ ON CONFLICT(id, run_attempt) DO UPDATE SET
     runner_name=excluded.runner_name,
     runner_group=excluded.runner_group,
     is_self_hosted=excluded.is_self_hosted,
-    duration_sec=excluded.duration_sec`)
+    duration_sec=excluded.duration_sec,
+    labels=excluded.labels`)
 	if err != nil {
 		return 0, 0, err
 	}
 	defer upsertStmt.Close()
 
+	deleteStepsStmt, err := tx.Prepare(`DELETE FROM job_steps WHERE job_id = ? AND run_attempt = ?`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer deleteStepsStmt.Close()
+
+	insertStepStmt, err := tx.Prepare(`
+INSERT INTO job_steps (
+    job_id, run_attempt, repo, number, name, status, conclusion, started_at, completed_at, duration_sec
+) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return 0, 0, err
+	}
+	defer insertStepStmt.Close()
+
 	for _, j := range jobs {
 		var exists int
 		if err = checkStmt.QueryRow(j.ID, j.RunAttempt).Scan(&exists); err != nil {
@@ -147,11 +177,26 @@ ON CONFLICT(id, run_attempt) DO UPDATE SET
 		}
 		_, err = upsertStmt.Exec(
 			j.ID, j.RunID, j.RunAttempt, j.Repo, j.Name, j.Status, j.Conclusion, asRFC3339(j.StartedAt), asRFC3339(j.CompletedAt),
-			j.RunnerOS, j.RunnerName, j.RunnerGroup, selfHosted, j.DurationSec,
+			j.RunnerOS, j.RunnerName, j.RunnerGroup, selfHosted, j.DurationSec, encodeLabels(j.Labels),
 		)
 		if err != nil {
 			return 0, 0, err
 		}
+		if len(j.Steps) == 0 {
+			continue
+		}
+		if _, err = deleteStepsStmt.Exec(j.ID, j.RunAttempt); err != nil {
+			return 0, 0, err
+		}
+		for _, st := range j.Steps {
+			_, err = insertStepStmt.Exec(
+				j.ID, j.RunAttempt, j.Repo, st.Number, st.Name, st.Status, st.Conclusion,
+				asRFC3339(st.StartedAt), asRFC3339(st.CompletedAt), st.DurationSec,
+			)
+			if err != nil {
+				return 0, 0, err
+			}
+		}
 	}
 	if err := tx.Commit(); err != nil {
 		return 0, 0, err
@@ -161,7 +206,7 @@ ON CONFLICT(id, run_attempt) DO UPDATE SET
 
 func (s *Store) ListRuns(repo string, start, end time.Time) ([]model.WorkflowRun, error) {
 	rows, err := s.db.Query(`
-SELECT id, repo, workflow_id, workflow_name, head_branch, event, status, conclusion, run_attempt,
+SELECT id, repo, workflow_id, workflow_name, workflow_path, head_branch, event, status, conclusion, run_attempt,
        run_started_at, updated_at, created_at
 FROM workflow_runs
 WHERE repo = ? AND created_at >= ? AND created_at <= ?
@@ -175,7 +220,7 @@ ORDER BY created_at DESC`, repo, start.UTC().Format(time.RFC3339), end.UTC().For
 	for rows.Next() {
 		var r model.WorkflowRun
 		var runStartedAt, updatedAt, createdAt sql.NullString
-		if err := rows.Scan(&r.ID, &r.Repo, &r.WorkflowID, &r.WorkflowName, &r.HeadBranch, &r.Event, &r.Status, &r.Conclusion, &r.RunAttempt,
+		if err := rows.Scan(&r.ID, &r.Repo, &r.WorkflowID, &r.WorkflowName, &r.WorkflowPath, &r.HeadBranch, &r.Event, &r.Status, &r.Conclusion, &r.RunAttempt,
 			&runStartedAt, &updatedAt, &createdAt); err != nil {
 			return nil, err
 		}
@@ -190,7 +235,7 @@ ORDER BY created_at DESC`, repo, start.UTC().Format(time.RFC3339), end.UTC().For
 func (s *Store) ListJobs(repo string, start, end time.Time) ([]model.Job, error) {
 	rows, err := s.db.Query(`
 SELECT j.id, j.run_id, j.run_attempt, j.repo, j.name, j.status, j.conclusion, j.started_at, j.completed_at,
-       j.runner_os, j.runner_name, j.runner_group, j.is_self_hosted, j.duration_sec
+       j.runner_os, j.runner_name, j.runner_group, j.is_self_hosted, j.duration_sec, j.labels
 FROM jobs j
 JOIN workflow_runs r ON r.id = j.run_id AND r.run_attempt = j.run_attempt
 WHERE j.repo = ? AND r.created_at >= ? AND r.created_at <= ?
@@ -198,25 +243,102 @@ ORDER BY r.created_at DESC`, repo, start.UTC().Format(time.RFC3339), end.UTC().F
 	if err != nil {
 		return nil, err
 	}
-	defer rows.Close()
+	out, err := scanJobs(rows)
+	if err != nil {
+		return nil, err
+	}
 
+	steps, err := s.ListSteps(repo, start, end)
+	if err != nil {
+		return nil, err
+	}
+	byJob := map[string][]model.Step{}
+	for _, st := range steps {
+		key := stepJobKey(st.JobID, st.RunAttempt)
+		byJob[key] = append(byJob[key], st)
+	}
+	for i := range out {
+		out[i].Steps = byJob[stepJobKey(out[i].ID, out[i].RunAttempt)]
+	}
+	return out, nil
+}
+
+// ListAccountJobs returns the jobs of every repository owned by owner whose
+// run was created in [start, end], without steps. It feeds free-tier
+// allocation, which needs the whole account's usage.
+func (s *Store) ListAccountJobs(owner string, start, end time.Time) ([]model.Job, error) {
+	// "0" sorts right after "/", so this is a prefix match on "owner/".
+	rows, err := s.db.Query(`
+SELECT j.id, j.run_id, j.run_attempt, j.repo, j.name, j.status, j.conclusion, j.started_at, j.completed_at,
+       j.runner_os, j.runner_name, j.runner_group, j.is_self_hosted, j.duration_sec, j.labels
+FROM jobs j
+JOIN workflow_runs r ON r.id = j.run_id AND r.run_attempt = j.run_attempt
+WHERE j.repo >= ? AND j.repo < ? AND r.created_at >= ? AND r.created_at <= ?
+ORDER BY r.created_at`, owner+"/", owner+"0", start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	return scanJobs(rows)
+}
+
+func scanJobs(rows *sql.Rows) ([]model.Job, error) {
+	defer rows.Close()
 	out := make([]model.Job, 0, 512)
 	for rows.Next() {
 		var j model.Job
 		var startedAt, completedAt sql.NullString
 		var selfHosted int
+		var labels string
 		if err := rows.Scan(&j.ID, &j.RunID, &j.RunAttempt, &j.Repo, &j.Name, &j.Status, &j.Conclusion, &startedAt, &completedAt,
-			&j.RunnerOS, &j.RunnerName, &j.RunnerGroup, &selfHosted, &j.DurationSec); err != nil {
+			&j.RunnerOS, &j.RunnerName, &j.RunnerGroup, &selfHosted, &j.DurationSec, &labels); err != nil {
 			return nil, err
 		}
 		j.StartedAt = parseRFC3339(startedAt.String)
 		j.CompletedAt = parseRFC3339(completedAt.String)
 		j.IsSelfHosted = selfHosted == 1
+		j.Labels = decodeLabels(labels)
 		out = append(out, j)
 	}
 	return out, rows.Err()
 }
 
+// ListSteps returns the steps of jobs whose run was created in [start, end],
+// ordered by job and step number.
+func (s *Store) ListSteps(repo string, start, end time.Time) ([]model.Step, error) {
+	rows, err := s.db.Query(`
+SELECT st.job_id, st.run_attempt, st.number, st.name, st.status, st.conclusion, st.started_at, st.completed_at, st.duration_sec
+FROM job_steps st
+JOIN jobs j ON j.id = st.job_id AND j.run_attempt = st.run_attempt
+JOIN workflow_runs r ON r.id = j.run_id AND r.run_attempt = j.run_attempt
+WHERE st.repo = ? AND r.created_at >= ? AND r.created_at <= ?
+ORDER BY st.job_id, st.run_attempt, st.number`, repo, start.UTC().Format(time.RFC3339), end.UTC().Format(time.RFC3339))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	out := make([]model.Step, 0, 1024)
+	for rows.Next() {
+		var st model.Step
+		var status, conclusion, startedAt, completedAt sql.NullString
+		var dur sql.NullInt64
+		if err := rows.Scan(&st.JobID, &st.RunAttempt, &st.Number, &st.Name, &status, &conclusion, &startedAt, &completedAt, &dur); err != nil {
+			return nil, err
+		}
+		st.Status = status.String
+		st.Conclusion = conclusion.String
+		st.StartedAt = parseRFC3339(startedAt.String)
+		st.CompletedAt = parseRFC3339(completedAt.String)
+		st.DurationSec = int(dur.Int64)
+		out = append(out, st)
+	}
+	return out, rows.Err()
+}
+
+func stepJobKey(jobID int64, attempt int) string {
+	return strconv.FormatInt(jobID, 10) + "#" + strconv.Itoa(attempt)
+}
+
 func (s *Store) CountRuns(repo string, start, end time.Time) (int, error) {
 	var n int
 	err := s.db.QueryRow(`SELECT COUNT(1) FROM workflow_runs WHERE repo = ? AND created_at >= ? AND created_at <= ?`,
@@ -249,6 +371,28 @@ func asRFC3339(t time.Time) string {
 	return t.UTC().Format(time.RFC3339)
 }
 
+func encodeLabels(labels []string) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	b, err := json.Marshal(labels)
+	if err != nil {
+		return ""
+	}
+	return string(b)
+}
+
+func decodeLabels(v string) []string {
+	if v == "" {
+		return nil
+	}
+	var out []string
+	if err := json.Unmarshal([]byte(v), &out); err != nil {
+		return nil
+	}
+	return out
+}
+
 func parseRFC3339(v string) time.Time {
 	if v == "" {
 		return time.Time{}
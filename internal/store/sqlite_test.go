@@ -0,0 +1,49 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+)
+
+func TestJobStepsRoundtrip(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "cicost.db")
+	st, err := Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Date(2026, 2, 26, 10, 0, 0, 0, time.UTC)
+	if _, _, err := st.UpsertRuns([]model.WorkflowRun{{ID: 1, Repo: "owner/repo", WorkflowName: "ci", RunAttempt: 1, CreatedAt: now}}); err != nil {
+		t.Fatal(err)
+	}
+	job := model.Job{
+		ID: 2, RunID: 1, RunAttempt: 1, Repo: "owner/repo", Name: "build", Status: "completed", DurationSec: 100,
+		Steps: []model.Step{
+			{Number: 1, Name: "Set up job", Conclusion: "success", StartedAt: now, CompletedAt: now.Add(5 * time.Second), DurationSec: 5},
+			{Number: 2, Name: "npm ci", Conclusion: "success", StartedAt: now.Add(5 * time.Second), CompletedAt: now.Add(65 * time.Second), DurationSec: 60},
+		},
+	}
+	if _, _, err := st.UpsertJobs([]model.Job{job}); err != nil {
+		t.Fatal(err)
+	}
+	job.Steps = job.Steps[1:]
+	if _, _, err := st.UpsertJobs([]model.Job{job}); err != nil {
+		t.Fatal(err)
+	}
+
+	jobs, err := st.ListJobs("owner/repo", now.Add(-time.Hour), now.Add(time.Hour))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 1 || len(jobs[0].Steps) != 1 {
+		t.Fatalf("expected re-upsert to replace steps, got %+v", jobs)
+	}
+	got := jobs[0].Steps[0]
+	if got.JobID != 2 || got.Number != 2 || got.Name != "npm ci" || got.DurationSec != 60 || !got.CompletedAt.Equal(now.Add(65*time.Second)) {
+		t.Fatalf("unexpected step: %+v", got)
+	}
+}
@@ -0,0 +1,154 @@
+package store
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+)
+
+func (s *Store) UpsertArtifacts(artifacts []model.Artifact) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer rollbackIfNeeded(tx, &err)
+	stmt, err := tx.Prepare(`
+INSERT INTO artifacts (id, repo, name, run_id, size_bytes, expired, created_at, expires_at, fetched_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(id) DO UPDATE SET
+	name=excluded.name,
+	size_bytes=excluded.size_bytes,
+	expired=excluded.expired,
+	expires_at=excluded.expires_at,
+	fetched_at=excluded.fetched_at,
+	removed_at=''`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	now := asRFC3339(time.Now())
+	for _, a := range artifacts {
+		expired := 0
+		if a.Expired {
+			expired = 1
+		}
+		if _, err = stmt.Exec(a.ID, a.Repo, a.Name, a.RunID, a.SizeBytes, expired, asRFC3339(a.CreatedAt), asRFC3339(a.ExpiresAt), now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// MarkRemovedArtifacts marks the unexpired artifacts of repo that no listing
+// since listedAt returned as removed when a scan last saw them. Call it after
+// storing a full listing made at listedAt.
+func (s *Store) MarkRemovedArtifacts(repo string, listedAt time.Time) error {
+	at := asRFC3339(listedAt)
+	_, err := s.db.Exec(`
+UPDATE artifacts SET removed_at = fetched_at
+WHERE repo = ? AND removed_at = '' AND fetched_at < ? AND expires_at > ?`, repo, at, at)
+	return err
+}
+
+// UpsertCaches stores caches seen by a scan. A cache keeps its first
+// created_at; access and last-seen times move forward.
+func (s *Store) UpsertCaches(caches []model.ActionsCache) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer rollbackIfNeeded(tx, &err)
+	stmt, err := tx.Prepare(`
+INSERT INTO actions_caches (id, repo, cache_key, ref, size_bytes, created_at, last_accessed_at, last_seen_at)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(repo, id) DO UPDATE SET
+	size_bytes=excluded.size_bytes,
+	last_accessed_at=excluded.last_accessed_at,
+	last_seen_at=excluded.last_seen_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, c := range caches {
+		if _, err = stmt.Exec(c.ID, c.Repo, c.Key, c.Ref, c.SizeBytes, asRFC3339(c.CreatedAt), asRFC3339(c.LastAccessedAt), asRFC3339(c.LastSeenAt)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListArtifacts returns the artifacts of repo stored at some point in
+// [start, end], until they expired or were removed, ordered by creation
+// time.
+func (s *Store) ListArtifacts(repo string, start, end time.Time) ([]model.Artifact, error) {
+	rows, err := s.db.Query(`
+SELECT id, repo, name, run_id, size_bytes, expired, created_at, expires_at, removed_at
+FROM artifacts
+WHERE repo = ? AND created_at <= ? AND MIN(expires_at, CASE removed_at WHEN '' THEN expires_at ELSE removed_at END) >= ?
+ORDER BY created_at, id`, repo, asRFC3339(end), asRFC3339(start))
+	if err != nil {
+		return nil, err
+	}
+	return scanArtifacts(rows)
+}
+
+// ListAccountArtifacts is ListArtifacts over every repository of owner, for
+// sharing the plan's included storage.
+func (s *Store) ListAccountArtifacts(owner string, start, end time.Time) ([]model.Artifact, error) {
+	rows, err := s.db.Query(`
+SELECT id, repo, name, run_id, size_bytes, expired, created_at, expires_at, removed_at
+FROM artifacts
+WHERE repo >= ? AND repo < ? AND created_at <= ? AND MIN(expires_at, CASE removed_at WHEN '' THEN expires_at ELSE removed_at END) >= ?
+ORDER BY created_at, id`, owner+"/", owner+"0", asRFC3339(end), asRFC3339(start))
+	if err != nil {
+		return nil, err
+	}
+	return scanArtifacts(rows)
+}
+
+func scanArtifacts(rows *sql.Rows) ([]model.Artifact, error) {
+	defer rows.Close()
+	var out []model.Artifact
+	for rows.Next() {
+		var a model.Artifact
+		var expired int
+		var createdAt, expiresAt, removedAt string
+		if err := rows.Scan(&a.ID, &a.Repo, &a.Name, &a.RunID, &a.SizeBytes, &expired, &createdAt, &expiresAt, &removedAt); err != nil {
+			return nil, err
+		}
+		a.Expired = expired == 1
+		a.CreatedAt = parseRFC3339(createdAt)
+		a.ExpiresAt = parseRFC3339(expiresAt)
+		a.RemovedAt = parseRFC3339(removedAt)
+		out = append(out, a)
+	}
+	return out, rows.Err()
+}
+
+// ListCaches returns the caches of repo stored at some point in
+// [start, end], ordered by creation time.
+func (s *Store) ListCaches(repo string, start, end time.Time) ([]model.ActionsCache, error) {
+	rows, err := s.db.Query(`
+SELECT id, repo, cache_key, ref, size_bytes, created_at, last_accessed_at, last_seen_at
+FROM actions_caches
+WHERE repo = ? AND created_at <= ? AND last_seen_at >= ?
+ORDER BY created_at, id`, repo, asRFC3339(end), asRFC3339(start))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []model.ActionsCache
+	for rows.Next() {
+		var c model.ActionsCache
+		var createdAt, accessedAt, seenAt string
+		if err := rows.Scan(&c.ID, &c.Repo, &c.Key, &c.Ref, &c.SizeBytes, &createdAt, &accessedAt, &seenAt); err != nil {
+			return nil, err
+		}
+		c.CreatedAt = parseRFC3339(createdAt)
+		c.LastAccessedAt = parseRFC3339(accessedAt)
+		c.LastSeenAt = parseRFC3339(seenAt)
+		out = append(out, c)
+	}
+	return out, rows.Err()
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/peter941221/CICost/internal/model"
+)
+
+func TestArtifactsAndCachesByWindow(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "cicost.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	day := func(d int) time.Time { return time.Date(2026, 3, d, 0, 0, 0, 0, time.UTC) }
+	if err := st.UpsertArtifacts([]model.Artifact{
+		{ID: 1, Repo: "acme/a", Name: "old", SizeBytes: 10, CreatedAt: day(1), ExpiresAt: day(3)},
+		{ID: 2, Repo: "acme/a", Name: "live", SizeBytes: 20, CreatedAt: day(5), ExpiresAt: day(25)},
+		{ID: 3, Repo: "acme/b", Name: "other", SizeBytes: 30, CreatedAt: day(6), ExpiresAt: day(7)},
+		{ID: 4, Repo: "acmecorp/c", Name: "not-mine", SizeBytes: 40, CreatedAt: day(6), ExpiresAt: day(7)},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.ListArtifacts("acme/a", day(4), day(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Name != "live" || got[0].SizeBytes != 20 {
+		t.Fatalf("expected only the artifact stored during the window, got %+v", got)
+	}
+	account, err := st.ListAccountArtifacts("acme", day(4), day(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(account) != 2 {
+		t.Fatalf("expected 2 account artifacts, got %+v", account)
+	}
+
+	first := model.ActionsCache{ID: 9, Repo: "acme/a", Key: "npm-1", SizeBytes: 100, CreatedAt: day(2), LastAccessedAt: day(2), LastSeenAt: day(4)}
+	if err := st.UpsertCaches([]model.ActionsCache{first}); err != nil {
+		t.Fatal(err)
+	}
+	again := first
+	again.CreatedAt = day(3)
+	again.LastAccessedAt = day(8)
+	again.LastSeenAt = day(9)
+	if err := st.UpsertCaches([]model.ActionsCache{again}); err != nil {
+		t.Fatal(err)
+	}
+	caches, err := st.ListCaches("acme/a", day(5), day(10))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(caches) != 1 || !caches[0].CreatedAt.Equal(day(2)) || !caches[0].LastAccessedAt.Equal(day(8)) || !caches[0].LastSeenAt.Equal(day(9)) {
+		t.Fatalf("expected cache to keep created_at and move access times, got %+v", caches)
+	}
+}
+
+func TestMarkRemovedArtifacts(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "cicost.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	now := time.Now().UTC().Truncate(time.Second)
+	kept := model.Artifact{ID: 1, Repo: "acme/a", Name: "kept", SizeBytes: 10, CreatedAt: now.AddDate(0, 0, -10), ExpiresAt: now.AddDate(0, 0, 80)}
+	deleted := model.Artifact{ID: 2, Repo: "acme/a", Name: "deleted", SizeBytes: 20, CreatedAt: now.AddDate(0, 0, -10), ExpiresAt: now.AddDate(0, 0, 80)}
+	other := model.Artifact{ID: 3, Repo: "acme/b", Name: "other", SizeBytes: 30, CreatedAt: now.AddDate(0, 0, -10), ExpiresAt: now.AddDate(0, 0, 80)}
+	if err := st.UpsertArtifacts([]model.Artifact{kept, deleted, other}); err != nil {
+		t.Fatal(err)
+	}
+	// The earlier scan saw all three a day ago.
+	seen := now.AddDate(0, 0, -1)
+	if _, err := st.db.Exec(`UPDATE artifacts SET fetched_at = ?`, asRFC3339(seen)); err != nil {
+		t.Fatal(err)
+	}
+
+	listedAt := time.Now()
+	if err := st.UpsertArtifacts([]model.Artifact{kept}); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.MarkRemovedArtifacts("acme/a", listedAt); err != nil {
+		t.Fatal(err)
+	}
+	got, err := st.ListArtifacts("acme/a", now.AddDate(0, 0, -5), now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || !got[0].RemovedAt.IsZero() || !got[1].RemovedAt.Equal(seen) {
+		t.Fatalf("expected the unlisted artifact removed when last seen, got %+v", got)
+	}
+	if got, err := st.ListArtifacts("acme/a", now, now.AddDate(0, 0, 1)); err != nil || len(got) != 1 || got[0].Name != "kept" {
+		t.Fatalf("expected the removed artifact out of later windows, got %+v %v", got, err)
+	}
+	if got, err := st.ListArtifacts("acme/b", now, now.AddDate(0, 0, 1)); err != nil || len(got) != 1 || !got[0].RemovedAt.IsZero() {
+		t.Fatalf("expected other repos untouched, got %+v %v", got, err)
+	}
+
+	// Listed again, it is stored again.
+	if err := st.UpsertArtifacts([]model.Artifact{deleted}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := st.ListArtifacts("acme/a", now, now.AddDate(0, 0, 1)); err != nil || len(got) != 2 {
+		t.Fatalf("expected a relisted artifact to be stored again, got %+v %v", got, err)
+	}
+}
@@ -2,7 +2,10 @@ package store
 
 import (
 	"database/sql"
+	"strings"
 	"time"
+
+	"github.com/peter941221/CICost/internal/model"
 )
 
 type SyncCursor struct {
@@ -43,3 +46,190 @@ ON CONFLICT(repo) DO UPDATE SET
 		c.Repo, c.LastRunID, asRFC3339(c.LastCreatedAt), asRFC3339(c.LastSyncAt), c.TotalRuns, c.TotalJobs)
 	return err
 }
+
+// Job fetch states recorded per run attempt in job_fetch_status.
+const (
+	JobFetchPending = "pending"
+	JobFetchDone    = "done"
+	JobFetchFailed  = "failed"
+)
+
+// QueueJobFetches marks runs as needing a jobs fetch. A run whose jobs were
+// already fetched stays done unless GitHub reports it changed since.
+func (s *Store) QueueJobFetches(runs []model.WorkflowRun) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer rollbackIfNeeded(tx, &err)
+
+	stmt, err := tx.Prepare(`
+INSERT INTO job_fetch_status (repo, run_id, run_attempt, run_updated_at, status, updated_at)
+VALUES (?, ?, ?, ?, 'pending', ?)
+ON CONFLICT(run_id, run_attempt) DO UPDATE SET
+	status=CASE
+		WHEN job_fetch_status.status = 'done' AND job_fetch_status.run_updated_at = excluded.run_updated_at THEN 'done'
+		ELSE 'pending'
+	END,
+	run_updated_at=excluded.run_updated_at,
+	updated_at=excluded.updated_at`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	now := asRFC3339(time.Now())
+	for _, r := range runs {
+		if _, err = stmt.Exec(r.Repo, r.ID, r.RunAttempt, asRFC3339(r.UpdatedAt), now); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// ListPendingJobFetches returns stored runs of repo whose jobs have not been
+// fetched yet, including ones left pending or failed by an earlier scan.
+func (s *Store) ListPendingJobFetches(repo string) ([]model.WorkflowRun, error) {
+	rows, err := s.db.Query(`
+SELECT r.id, r.repo, r.workflow_id, r.workflow_name, r.run_attempt, r.created_at
+FROM job_fetch_status f
+JOIN workflow_runs r ON r.id = f.run_id AND r.run_attempt = f.run_attempt
+WHERE f.repo = ? AND f.status <> 'done'
+ORDER BY r.created_at DESC`, repo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []model.WorkflowRun{}
+	for rows.Next() {
+		var r model.WorkflowRun
+		var createdAt sql.NullString
+		if err := rows.Scan(&r.ID, &r.Repo, &r.WorkflowID, &r.WorkflowName, &r.RunAttempt, &createdAt); err != nil {
+			return nil, err
+		}
+		r.CreatedAt = parseRFC3339(createdAt.String)
+		out = append(out, r)
+	}
+	return out, rows.Err()
+}
+
+// SetJobFetchStatus records the outcome of fetching jobs for one run attempt.
+func (s *Store) SetJobFetchStatus(runID int64, attempt int, status, lastError string) error {
+	_, err := s.db.Exec(`
+UPDATE job_fetch_status SET status = ?, last_error = ?, updated_at = ?
+WHERE run_id = ? AND run_attempt = ?`,
+		status, lastError, asRFC3339(time.Now()), runID, attempt)
+	return err
+}
+
+// Scan outcome states recorded per repository in scan_outcomes.
+const (
+	ScanOK          = "ok"
+	ScanFailed      = "failed"
+	ScanInterrupted = "interrupted"
+)
+
+// ScanOutcome is the result of the latest scan of one repository, kept so
+// org-wide commands can find the repositories a scan covered.
+type ScanOutcome struct {
+	Repo      string
+	Owner     string
+	Status    string
+	Runs      int
+	Jobs      int
+	Error     string
+	ScannedAt time.Time
+}
+
+func (s *Store) RecordScanOutcome(o ScanOutcome) error {
+	if o.Owner == "" {
+		if i := strings.Index(o.Repo, "/"); i > 0 {
+			o.Owner = o.Repo[:i]
+		}
+	}
+	if o.ScannedAt.IsZero() {
+		o.ScannedAt = time.Now().UTC()
+	}
+	_, err := s.db.Exec(`
+INSERT INTO scan_outcomes (repo, owner, status, runs, jobs, error, scanned_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(repo) DO UPDATE SET
+	owner=excluded.owner,
+	status=excluded.status,
+	runs=excluded.runs,
+	jobs=excluded.jobs,
+	error=excluded.error,
+	scanned_at=excluded.scanned_at`,
+		o.Repo, o.Owner, o.Status, o.Runs, o.Jobs, o.Error, asRFC3339(o.ScannedAt))
+	return err
+}
+
+// ListScanOutcomes returns the latest outcome per repository, limited to one
+// owner unless owner is empty, ordered by repository name.
+func (s *Store) ListScanOutcomes(owner string) ([]ScanOutcome, error) {
+	rows, err := s.db.Query(`
+SELECT repo, owner, status, runs, jobs, error, scanned_at
+FROM scan_outcomes
+WHERE ? = '' OR owner = ? COLLATE NOCASE
+ORDER BY repo`, owner, owner)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []ScanOutcome
+	for rows.Next() {
+		var o ScanOutcome
+		var scannedAt string
+		if err := rows.Scan(&o.Repo, &o.Owner, &o.Status, &o.Runs, &o.Jobs, &o.Error, &scannedAt); err != nil {
+			return nil, err
+		}
+		o.ScannedAt = parseRFC3339(scannedAt)
+		out = append(out, o)
+	}
+	return out, rows.Err()
+}
+
+// HTTPCacheEntry is a GitHub API response kept for conditional requests.
+type HTTPCacheEntry struct {
+	URL          string
+	ETag         string
+	LastModified string
+	Link         string
+	Body         []byte
+}
+
+func (s *Store) GetHTTPCache(url string) (HTTPCacheEntry, bool, error) {
+	e := HTTPCacheEntry{URL: url}
+	err := s.db.QueryRow(`SELECT etag, last_modified, link, body FROM http_cache WHERE url = ?`, url).
+		Scan(&e.ETag, &e.LastModified, &e.Link, &e.Body)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return HTTPCacheEntry{}, false, nil
+		}
+		return HTTPCacheEntry{}, false, err
+	}
+	return e, true, nil
+}
+
+func (s *Store) PutHTTPCache(e HTTPCacheEntry) error {
+	_, err := s.db.Exec(`
+INSERT INTO http_cache (url, etag, last_modified, link, body, updated_at)
+VALUES (?, ?, ?, ?, ?, ?)
+ON CONFLICT(url) DO UPDATE SET
+	etag=excluded.etag,
+	last_modified=excluded.last_modified,
+	link=excluded.link,
+	body=excluded.body,
+	updated_at=excluded.updated_at`,
+		e.URL, e.ETag, e.LastModified, e.Link, e.Body, asRFC3339(time.Now()))
+	return err
+}
+
+// PruneHTTPCache deletes cached responses last stored before cutoff and
+// returns how many it removed.
+func (s *Store) PruneHTTPCache(cutoff time.Time) (int64, error) {
+	res, err := s.db.Exec(`DELETE FROM http_cache WHERE updated_at < ?`, asRFC3339(cutoff))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
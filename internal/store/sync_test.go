@@ -4,6 +4,8 @@ import (
 	"path/filepath"
 	"testing"
 	"time"
+
+	"github.com/peter941221/CICost/internal/model"
 )
 
 func TestCursorMissing(t *testing.T) {
@@ -71,3 +73,131 @@ func TestCursorUpsertAndGet(t *testing.T) {
 		t.Fatalf("unexpected updated cursor: %+v", got)
 	}
 }
+
+func TestJobFetchStatusLifecycle(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "cicost.db")
+	st, err := Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	t0 := time.Date(2026, 2, 20, 10, 0, 0, 0, time.UTC)
+	runs := []model.WorkflowRun{
+		{ID: 1, Repo: "owner/repo", WorkflowName: "ci", RunAttempt: 1, CreatedAt: t0, UpdatedAt: t0},
+		{ID: 2, Repo: "owner/repo", WorkflowName: "ci", RunAttempt: 1, CreatedAt: t0.Add(time.Minute), UpdatedAt: t0},
+	}
+	if _, _, err := st.UpsertRuns(runs); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.QueueJobFetches(runs); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetJobFetchStatus(1, 1, JobFetchDone, ""); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.SetJobFetchStatus(2, 1, JobFetchDone, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	runs[1].UpdatedAt = t0.Add(time.Hour)
+	if err := st.QueueJobFetches(runs); err != nil {
+		t.Fatal(err)
+	}
+	pending, err := st.ListPendingJobFetches("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(pending) != 1 || pending[0].ID != 2 {
+		t.Fatalf("expected only the updated run to be requeued, got %+v", pending)
+	}
+}
+
+func TestHTTPCacheRoundtrip(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "cicost.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	url := "https://api.github.com/repos/o/r/actions/runs?per_page=100"
+	if _, ok, err := st.GetHTTPCache(url); err != nil || ok {
+		t.Fatalf("expected empty cache, ok=%v err=%v", ok, err)
+	}
+	for _, etag := range []string{`"v1"`, `"v2"`} {
+		if err := st.PutHTTPCache(HTTPCacheEntry{URL: url, ETag: etag, Link: `<next>; rel="next"`, Body: []byte(`{"v":` + etag + `}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	e, ok, err := st.GetHTTPCache(url)
+	if err != nil || !ok {
+		t.Fatalf("expected cached entry, ok=%v err=%v", ok, err)
+	}
+	if e.ETag != `"v2"` || e.Link != `<next>; rel="next"` || string(e.Body) != `{"v":"v2"}` {
+		t.Fatalf("unexpected cached entry: %+v", e)
+	}
+}
+
+func TestPruneHTTPCache(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "cicost.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	for _, url := range []string{"https://api.github.com/old", "https://api.github.com/new"} {
+		if err := st.PutHTTPCache(HTTPCacheEntry{URL: url, ETag: `"v1"`, Body: []byte(`{}`)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	old := time.Now().AddDate(0, 0, -40)
+	if _, err := st.db.Exec(`UPDATE http_cache SET updated_at = ? WHERE url = ?`, asRFC3339(old), "https://api.github.com/old"); err != nil {
+		t.Fatal(err)
+	}
+	n, err := st.PruneHTTPCache(time.Now().AddDate(0, 0, -30))
+	if err != nil || n != 1 {
+		t.Fatalf("expected one pruned entry, got %d %v", n, err)
+	}
+	if _, ok, _ := st.GetHTTPCache("https://api.github.com/old"); ok {
+		t.Fatal("expected the old entry to be pruned")
+	}
+	if _, ok, _ := st.GetHTTPCache("https://api.github.com/new"); !ok {
+		t.Fatal("expected the recent entry to be kept")
+	}
+}
+
+func TestScanOutcomesByOwner(t *testing.T) {
+	st, err := Open(filepath.Join(t.TempDir(), "cicost.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	for _, o := range []ScanOutcome{
+		{Repo: "acme/b", Status: ScanFailed, Error: "boom"},
+		{Repo: "acme/a", Status: ScanOK, Runs: 3, Jobs: 5},
+		{Repo: "other/c", Status: ScanOK},
+		{Repo: "acme/b", Status: ScanOK, Runs: 1},
+	} {
+		if err := st.RecordScanOutcome(o); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := st.ListScanOutcomes("acme")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Repo != "acme/a" || got[0].Jobs != 5 || got[0].Owner != "acme" {
+		t.Fatalf("unexpected outcomes: %+v", got)
+	}
+	if got[1].Status != ScanOK || got[1].Error != "" || got[1].ScannedAt.IsZero() {
+		t.Fatalf("expected latest outcome to replace the failed one, got %+v", got[1])
+	}
+	all, err := st.ListScanOutcomes("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 3 {
+		t.Fatalf("expected outcomes for all owners, got %d", len(all))
+	}
+}
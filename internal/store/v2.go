@@ -9,13 +9,15 @@ import (
 
 func (s *Store) UpsertBillingSnapshot(snapshot model.BillingSnapshot) error {
 	_, err := s.db.Exec(`
-INSERT INTO billing_snapshots (repo, period, actual_cost_usd, source, fetched_at)
-VALUES (?, ?, ?, ?, ?)
-ON CONFLICT(repo, period, source) DO UPDATE SET
+INSERT INTO billing_snapshots (repo, period, sku, workflow, actual_cost_usd, source, fetched_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)
+ON CONFLICT(repo, period, source, sku, workflow) DO UPDATE SET
 	actual_cost_usd=excluded.actual_cost_usd,
 	fetched_at=excluded.fetched_at`,
 		snapshot.Repo,
 		snapshot.Period,
+		snapshot.SKU,
+		snapshot.Workflow,
 		snapshot.ActualCostUSD,
 		snapshot.Source,
 		asRFC3339(snapshot.FetchedAt),
@@ -23,12 +25,42 @@ ON CONFLICT(repo, period, source) DO UPDATE SET
 	return err
 }
 
+// ReplaceBillingSnapshots swaps every row for repo, period and source with
+// snaps, so a re-import that drops or renames a SKU leaves no stale rows.
+func (s *Store) ReplaceBillingSnapshots(repo, period, source string, snaps []model.BillingSnapshot) (err error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer rollbackIfNeeded(tx, &err)
+
+	if _, err = tx.Exec(`DELETE FROM billing_snapshots WHERE repo = ? AND period = ? AND source = ?`, repo, period, source); err != nil {
+		return err
+	}
+	stmt, err := tx.Prepare(`
+INSERT INTO billing_snapshots (repo, period, sku, workflow, actual_cost_usd, source, fetched_at)
+VALUES (?, ?, ?, ?, ?, ?, ?)`)
+	if err != nil {
+		return err
+	}
+	defer stmt.Close()
+	for _, snap := range snaps {
+		if _, err = stmt.Exec(repo, period, snap.SKU, snap.Workflow, snap.ActualCostUSD, source, asRFC3339(snap.FetchedAt)); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
+
+// GetBillingSnapshot returns the repo total for period from the most recently
+// fetched source, summing its per-SKU rows.
 func (s *Store) GetBillingSnapshot(repo, period string) (model.BillingSnapshot, bool, error) {
 	row := s.db.QueryRow(`
-SELECT repo, period, actual_cost_usd, source, fetched_at
+SELECT repo, period, SUM(actual_cost_usd), source, MAX(fetched_at)
 FROM billing_snapshots
 WHERE repo = ? AND period = ?
-ORDER BY fetched_at DESC
+GROUP BY source
+ORDER BY MAX(fetched_at) DESC
 LIMIT 1`, repo, period)
 	var out model.BillingSnapshot
 	var fetchedAt sql.NullString
@@ -42,12 +74,38 @@ LIMIT 1`, repo, period)
 	return out, true, nil
 }
 
+// ListBillingSnapshots returns the per-SKU and per-workflow rows stored for
+// repo, period and source.
+func (s *Store) ListBillingSnapshots(repo, period, source string) ([]model.BillingSnapshot, error) {
+	rows, err := s.db.Query(`
+SELECT repo, period, sku, workflow, actual_cost_usd, source, fetched_at
+FROM billing_snapshots
+WHERE repo = ? AND period = ? AND source = ?
+ORDER BY sku, workflow`, repo, period, source)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []model.BillingSnapshot{}
+	for rows.Next() {
+		var snap model.BillingSnapshot
+		var fetchedAt sql.NullString
+		if err := rows.Scan(&snap.Repo, &snap.Period, &snap.SKU, &snap.Workflow, &snap.ActualCostUSD, &snap.Source, &fetchedAt); err != nil {
+			return nil, err
+		}
+		snap.FetchedAt = parseRFC3339(fetchedAt.String)
+		out = append(out, snap)
+	}
+	return out, rows.Err()
+}
+
 func (s *Store) InsertReconcileResult(res model.ReconcileResult) error {
 	_, err := s.db.Exec(`
-INSERT INTO reconcile_results (repo, period, estimated_cost_usd, actual_cost_usd, delta_ratio, calibration_factor, confidence)
-VALUES (?, ?, ?, ?, ?, ?, ?)`,
+INSERT INTO reconcile_results (repo, period, sku, estimated_cost_usd, actual_cost_usd, delta_ratio, calibration_factor, confidence)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?)`,
 		res.Repo,
 		res.Period,
+		res.SKU,
 		res.EstimatedCostUSD,
 		res.ActualCostUSD,
 		res.DeltaRatio,
@@ -57,37 +115,85 @@ VALUES (?, ?, ?, ?, ?, ?, ?)`,
 	return err
 }
 
+// GetLatestReconcile returns the latest repo-wide result; per-SKU rows are
+// read with ListLatestSKUReconcile.
 func (s *Store) GetLatestReconcile(repo string) (model.ReconcileResult, bool, error) {
 	row := s.db.QueryRow(`
-SELECT repo, period, estimated_cost_usd, actual_cost_usd, delta_ratio, calibration_factor, confidence, created_at
+SELECT repo, period, sku, estimated_cost_usd, actual_cost_usd, delta_ratio, calibration_factor, confidence, created_at
 FROM reconcile_results
-WHERE repo = ?
-ORDER BY created_at DESC
+WHERE repo = ? AND sku = ''
+ORDER BY created_at DESC, id DESC
 LIMIT 1`, repo)
-	var out model.ReconcileResult
-	var createdAt sql.NullString
-	if err := row.Scan(&out.Repo, &out.Period, &out.EstimatedCostUSD, &out.ActualCostUSD, &out.DeltaRatio, &out.CalibrationFactor, &out.Confidence, &createdAt); err != nil {
+	out, err := scanReconcile(row)
+	if err != nil {
 		if err == sql.ErrNoRows {
 			return model.ReconcileResult{}, false, nil
 		}
 		return model.ReconcileResult{}, false, err
 	}
-	out.CreatedAt = parseRFC3339(createdAt.String)
 	return out, true, nil
 }
 
+// ListLatestSKUReconcile returns the newest per-SKU result for each SKU in the
+// period of the latest repo-wide reconcile.
+func (s *Store) ListLatestSKUReconcile(repo string) ([]model.ReconcileResult, error) {
+	rows, err := s.db.Query(`
+SELECT repo, period, sku, estimated_cost_usd, actual_cost_usd, delta_ratio, calibration_factor, confidence, created_at
+FROM reconcile_results
+WHERE id IN (
+	SELECT MAX(id) FROM reconcile_results
+	WHERE repo = ? AND sku <> '' AND period = (
+		SELECT period FROM reconcile_results
+		WHERE repo = ? AND sku = ''
+		ORDER BY created_at DESC, id DESC
+		LIMIT 1
+	)
+	GROUP BY sku
+)
+ORDER BY sku`, repo, repo)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	out := []model.ReconcileResult{}
+	for rows.Next() {
+		res, err := scanReconcile(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, res)
+	}
+	return out, rows.Err()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanReconcile(row rowScanner) (model.ReconcileResult, error) {
+	var out model.ReconcileResult
+	var createdAt sql.NullString
+	if err := row.Scan(&out.Repo, &out.Period, &out.SKU, &out.EstimatedCostUSD, &out.ActualCostUSD, &out.DeltaRatio, &out.CalibrationFactor, &out.Confidence, &createdAt); err != nil {
+		return model.ReconcileResult{}, err
+	}
+	out.CreatedAt = parseRFC3339(createdAt.String)
+	return out, nil
+}
+
 func (s *Store) InsertPolicyRun(run model.PolicyRun) error {
 	matched := 0
 	if run.Matched {
 		matched = 1
 	}
 	_, err := s.db.Exec(`
-INSERT INTO policy_runs (repo, period_start, period_end, rule_id, severity, matched, evidence_key, evidence_value, expression)
-VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+INSERT INTO policy_runs (repo, period_start, period_end, rule_id, scope, entity, severity, matched, evidence_key, evidence_value, expression)
+VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
 		run.Repo,
 		asRFC3339(run.PeriodStart),
 		asRFC3339(run.PeriodEnd),
 		run.RuleID,
+		run.Scope,
+		run.Entity,
 		run.Severity,
 		matched,
 		run.EvidenceKey,
@@ -75,6 +75,8 @@ func TestReconcileAndPolicyAndSuggestionPersistence(t *testing.T) {
 		PeriodStart:   time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC),
 		PeriodEnd:     time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC),
 		RuleID:        "budget_cap",
+		Scope:         "workflow",
+		Entity:        "ci",
 		Severity:      "error",
 		Matched:       true,
 		EvidenceKey:   "monthly_cost_usd",
@@ -85,11 +87,12 @@ func TestReconcileAndPolicyAndSuggestionPersistence(t *testing.T) {
 		t.Fatal(err)
 	}
 	var matched int
-	if err := st.db.QueryRow(`SELECT matched FROM policy_runs WHERE repo=? AND rule_id=?`, "owner/repo", "budget_cap").Scan(&matched); err != nil {
+	var entity string
+	if err := st.db.QueryRow(`SELECT matched, entity FROM policy_runs WHERE repo=? AND rule_id=?`, "owner/repo", "budget_cap").Scan(&matched, &entity); err != nil {
 		t.Fatal(err)
 	}
-	if matched != 1 {
-		t.Fatalf("expected matched=1, got %d", matched)
+	if matched != 1 || entity != "ci" {
+		t.Fatalf("expected matched=1 for ci, got %d for %q", matched, entity)
 	}
 
 	sugg := model.SuggestionRecord{
@@ -112,3 +115,66 @@ func TestReconcileAndPolicyAndSuggestionPersistence(t *testing.T) {
 		t.Fatalf("expected sanitized evidence json, got %s", evidence)
 	}
 }
+
+func TestPerSKUBillingAndReconcile(t *testing.T) {
+	db := filepath.Join(t.TempDir(), "cicost.db")
+	st, err := Open(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+
+	fetched := time.Date(2026, 3, 2, 10, 0, 0, 0, time.UTC)
+	snaps := []model.BillingSnapshot{
+		{SKU: "linux", ActualCostUSD: 10, FetchedAt: fetched},
+		{SKU: "macos", ActualCostUSD: 26, FetchedAt: fetched},
+	}
+	if err := st.ReplaceBillingSnapshots("owner/repo", "2026-02", "csv", snaps); err != nil {
+		t.Fatal(err)
+	}
+	if err := st.ReplaceBillingSnapshots("owner/repo", "2026-02", "csv", snaps[1:]); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := st.ListBillingSnapshots("owner/repo", "2026-02", "csv")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0].SKU != "macos" {
+		t.Fatalf("expected replace to drop stale SKU rows, got %+v", rows)
+	}
+	if err := st.UpsertBillingSnapshot(model.BillingSnapshot{Repo: "owner/repo", Period: "2026-02", SKU: "linux", ActualCostUSD: 4, Source: "csv", FetchedAt: fetched}); err != nil {
+		t.Fatal(err)
+	}
+	total, ok, err := st.GetBillingSnapshot("owner/repo", "2026-02")
+	if err != nil || !ok {
+		t.Fatalf("expected snapshot, ok=%v err=%v", ok, err)
+	}
+	if total.ActualCostUSD != 30 {
+		t.Fatalf("expected per-SKU rows summed to 30, got %+v", total)
+	}
+
+	for _, rec := range []model.ReconcileResult{
+		{Repo: "owner/repo", Period: "2026-02", SKU: "macos", CalibrationFactor: 1.1, Confidence: "medium"},
+		{Repo: "owner/repo", Period: "2026-02", CalibrationFactor: 1.2, Confidence: "low"},
+		{Repo: "owner/repo", Period: "2026-02", SKU: "linux", CalibrationFactor: 1, Confidence: "high"},
+		{Repo: "owner/repo", Period: "2026-02", SKU: "macos", CalibrationFactor: 1.3, Confidence: "low"},
+	} {
+		if err := st.InsertReconcileResult(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+	latest, ok, err := st.GetLatestReconcile("owner/repo")
+	if err != nil || !ok {
+		t.Fatalf("expected repo-wide result, ok=%v err=%v", ok, err)
+	}
+	if latest.SKU != "" || latest.CalibrationFactor != 1.2 {
+		t.Fatalf("expected repo-wide row, got %+v", latest)
+	}
+	bySKU, err := st.ListLatestSKUReconcile("owner/repo")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bySKU) != 2 || bySKU[0].SKU != "linux" || bySKU[1].SKU != "macos" || bySKU[1].CalibrationFactor != 1.3 {
+		t.Fatalf("unexpected per-SKU results: %+v", bySKU)
+	}
+}
@@ -0,0 +1,206 @@
+package suggest
+
+import (
+	"strings"
+
+	"github.com/peter941221/CICost/internal/analytics"
+	"github.com/peter941221/CICost/internal/model"
+)
+
+// stepStat is the time one job step spent across the analysed period.
+type stepStat struct {
+	Workflow    string
+	Job         string
+	Step        string
+	Runs        int
+	TotalSec    int
+	JobSec      int
+	CostUSD     float64
+	SharePct    float64
+	AvgDuration float64
+}
+
+// dependencyStepMarkers are commands that only resolve dependencies; bare
+// tool names such as "gradle" or "mvn" would match build and test steps too.
+var dependencyStepMarkers = []string{
+	"npm ci", "npm install", "yarn install", "pnpm install", "pip install", "poetry install",
+	"bundle install", "go mod download", "gradle dependencies", "mvn dependency:go-offline",
+	"mvn dependency:resolve", "cargo fetch", "composer install", "dotnet restore", "nuget restore",
+	"install dependencies",
+}
+
+func isDependencyStep(name string) bool {
+	n := strings.ToLower(name)
+	for _, m := range dependencyStepMarkers {
+		if strings.Contains(n, m) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSetupStep matches toolchain setup steps such as "Set up Go" or
+// "Setup Node", but not the runner's own "Set up job".
+func isSetupStep(name string) bool {
+	n := strings.ToLower(strings.TrimSpace(name))
+	if n == "set up job" {
+		return false
+	}
+	return strings.HasPrefix(n, "set up ") || strings.HasPrefix(n, "setup ") || strings.HasPrefix(n, "setup-")
+}
+
+// isToolchainSetupStep matches setup steps that setupPatch has a patch for.
+func isToolchainSetupStep(name string) bool {
+	return isSetupStep(name) && setupToolchain(name) != ""
+}
+
+// setupPatch is the setup action with dependency caching for the toolchain
+// that step sets up. Steps of other tools, such as QEMU or Docker Buildx,
+// have no patch.
+func setupPatch(in Inputs, step string) (string, bool) {
+	switch setupToolchain(step) {
+	case "go":
+		return `- uses: actions/setup-go@v5
+  with:
+    go-version-file: go.mod
+    cache: true`, true
+	case "python":
+		return `- uses: actions/setup-python@v5
+  with:
+    python-version: "3.x"
+    cache: pip`, true
+	case "java":
+		cache := "maven"
+		if usesGradle(in.Jobs) {
+			cache = "gradle"
+		}
+		return `- uses: actions/setup-java@v4
+  with:
+    distribution: temurin
+    java-version: "21"
+    cache: ` + cache, true
+	case "node":
+		return `- uses: actions/setup-node@v4
+  with:
+    node-version-file: .nvmrc
+    cache: npm`, true
+	}
+	return "", false
+}
+
+// setupToolchain is the toolchain a setup step such as "Set up Go" or
+// "setup-node" installs: go, python, java or node, or "" for other tools.
+func setupToolchain(step string) string {
+	n := strings.ToLower(strings.TrimSpace(step))
+	for _, prefix := range []string{"set up ", "setup ", "setup-"} {
+		if strings.HasPrefix(n, prefix) {
+			n = strings.TrimSpace(n[len(prefix):])
+			break
+		}
+	}
+	tool, _, _ := strings.Cut(n, " ")
+	switch tool {
+	case "go", "golang":
+		return "go"
+	case "python", "python3":
+		return "python"
+	case "java", "jdk", "temurin":
+		return "java"
+	case "node", "node.js", "nodejs":
+		return "node"
+	}
+	return ""
+}
+
+func usesGradle(jobs []model.Job) bool {
+	for _, j := range jobs {
+		for _, st := range j.Steps {
+			if strings.Contains(strings.ToLower(st.Name), "gradle") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// slowestStep finds the matching step with the most total time, preferring
+// jobs of workflow when any match. Step cost is its share of job time applied
+// to the period's total cost.
+//
+// workflow is a hotspot name, the latest name of the workflow, so runs are
+// matched by workflow key to keep runs from before a rename.
+func slowestStep(in Inputs, workflow string, match func(string) bool) (stepStat, bool) {
+	names := analytics.LatestWorkflowNames(in.Runs)
+	keyByRun := map[int64]string{}
+	nameByRun := map[int64]string{}
+	for _, r := range in.Runs {
+		key := analytics.WorkflowKey(r)
+		keyByRun[r.ID] = key
+		nameByRun[r.ID] = r.WorkflowName
+		if name, ok := names[key]; ok {
+			nameByRun[r.ID] = name
+		}
+	}
+	totalJobSec := 0
+	for _, j := range in.Jobs {
+		totalJobSec += j.DurationSec
+	}
+
+	collect := func(onlyWorkflow string) map[string]*stepStat {
+		stats := map[string]*stepStat{}
+		for _, j := range in.Jobs {
+			wf := nameByRun[j.RunID]
+			if onlyWorkflow != "" && wf != onlyWorkflow {
+				continue
+			}
+			for _, st := range j.Steps {
+				if !match(st.Name) || st.DurationSec <= 0 {
+					continue
+				}
+				key := keyByRun[j.RunID] + "\x00" + j.Name + "\x00" + st.Name
+				s := stats[key]
+				if s == nil {
+					s = &stepStat{Workflow: wf, Job: j.Name, Step: st.Name}
+					stats[key] = s
+				}
+				s.Runs++
+				s.TotalSec += st.DurationSec
+				s.JobSec += j.DurationSec
+			}
+		}
+		return stats
+	}
+	stats := collect(workflow)
+	if len(stats) == 0 && workflow != "" {
+		stats = collect("")
+	}
+
+	var best *stepStat
+	for _, s := range stats {
+		if best == nil || s.TotalSec > best.TotalSec || (s.TotalSec == best.TotalSec && s.Step < best.Step) {
+			best = s
+		}
+	}
+	if best == nil {
+		return stepStat{}, false
+	}
+	out := *best
+	if out.JobSec > 0 {
+		out.SharePct = round2(float64(out.TotalSec) / float64(out.JobSec) * 100)
+	}
+	if totalJobSec > 0 {
+		out.CostUSD = round2(in.Cost.TotalCostUSD * float64(out.TotalSec) / float64(totalJobSec))
+	}
+	out.AvgDuration = round2(float64(out.TotalSec) / float64(out.Runs))
+	return out, true
+}
+
+// withStep adds step evidence to a suggestion's evidence map.
+func withStep(ev map[string]any, st stepStat) map[string]any {
+	ev["job"] = st.Job
+	ev["step"] = st.Step
+	ev["step_avg_duration_sec"] = st.AvgDuration
+	ev["step_share_of_job_pct"] = st.SharePct
+	ev["step_cost"] = st.CostUSD
+	return ev
+}
@@ -0,0 +1,134 @@
+package suggest
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/peter941221/CICost/internal/analytics"
+	"github.com/peter941221/CICost/internal/pricing"
+)
+
+const (
+	// artifactRetentionDays is the retention suggested for artifacts; ones
+	// kept longer than maxArtifactRetentionDays are flagged.
+	artifactRetentionDays    = 7
+	maxArtifactRetentionDays = 14
+	// A cache still unrestored a day after it was saved is counted as never
+	// restored.
+	cacheRestoreGrace = 24 * time.Hour
+)
+
+// artifactRetention flags the artifact name whose retention beyond
+// artifactRetentionDays costs the most storage in the report window.
+func artifactRetention(in Inputs) (Suggestion, bool) {
+	type group struct {
+		name, workflow string
+		count          int
+		maxDays        float64
+		excessGBMonths float64
+	}
+	workflowByRun := make(map[int64]string, len(in.Runs))
+	for _, r := range in.Runs {
+		workflowByRun[r.ID] = r.WorkflowName
+	}
+	groups := map[string]*group{}
+	for _, a := range in.Artifacts {
+		until := analytics.ArtifactStoredUntil(a)
+		days := until.Sub(a.CreatedAt).Hours() / 24
+		if days <= maxArtifactRetentionDays {
+			continue
+		}
+		g := groups[a.Name]
+		if g == nil {
+			g = &group{name: a.Name, workflow: workflowByRun[a.RunID]}
+			groups[a.Name] = g
+		}
+		g.count++
+		if days > g.maxDays {
+			g.maxDays = days
+		}
+		keep := a.CreatedAt.AddDate(0, 0, artifactRetentionDays)
+		g.excessGBMonths += pricing.GBMonths(a.SizeBytes, keep, until, in.Start, in.End)
+	}
+	if len(groups) == 0 {
+		return Suggestion{}, false
+	}
+	list := make([]*group, 0, len(groups))
+	for _, g := range groups {
+		list = append(list, g)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].excessGBMonths != list[j].excessGBMonths {
+			return list[i].excessGBMonths > list[j].excessGBMonths
+		}
+		return list[i].name < list[j].name
+	})
+	top := list[0]
+	saving := top.excessGBMonths * in.StorageRates.GBMonthUSD
+	ev := evidence(top.workflow, "", 0, saving)
+	ev["artifact"] = top.name
+	ev["retention_days"] = round2(top.maxDays)
+	ev["excess_gb_months"] = round2(top.excessGBMonths)
+	return Suggestion{
+		Type:               "artifact_retention",
+		Title:              fmt.Sprintf("Shorten retention of artifact %q", top.name),
+		Problem:            "Artifacts are kept far longer than they are used, and storage is billed per GB-month.",
+		CurrentData:        fmt.Sprintf("artifact=%s, uploads=%d, retention_days=%.0f, excess_gb_months=%.2f", top.name, top.count, top.maxDays, top.excessGBMonths),
+		EstimatedSavingUSD: round2(saving),
+		Patch: fmt.Sprintf(`- uses: actions/upload-artifact@v4
+  with:
+    name: %s
+    retention-days: %d`, top.name, artifactRetentionDays),
+		Evidence: ev,
+	}, true
+}
+
+// unusedCaches flags caches that were saved but never restored, priced at
+// the billable share of the repository's cache storage.
+func unusedCaches(in Inputs) (Suggestion, bool) {
+	st := in.Cost.Storage
+	if st.CacheGBMonths <= 0 {
+		return Suggestion{}, false
+	}
+	unused, gbMonths := 0, 0.0
+	refs := map[string]struct{}{}
+	for _, c := range in.Caches {
+		if c.LastSeenAt.Sub(c.CreatedAt) < cacheRestoreGrace {
+			continue
+		}
+		if c.LastAccessedAt.Sub(c.CreatedAt) > time.Minute {
+			continue
+		}
+		unused++
+		refs[c.Ref] = struct{}{}
+		gbMonths += pricing.GBMonths(c.SizeBytes, c.CreatedAt, c.LastSeenAt, in.Start, in.End)
+	}
+	if unused == 0 {
+		return Suggestion{}, false
+	}
+	billableShare := (st.CacheGBMonths - st.CacheIncludedGBMonths) / st.CacheGBMonths
+	saving := gbMonths * billableShare * in.StorageRates.GBMonthUSD
+	ev := evidence("", "", 0, saving)
+	ev["unused_caches"] = unused
+	ev["unused_gb_months"] = round2(gbMonths)
+	ev["refs"] = len(refs)
+	return Suggestion{
+		Type:               "cache_unused",
+		Title:              "Stop saving caches that are never restored",
+		Problem:            "Caches saved on short-lived refs are never read back but still count toward cache storage.",
+		CurrentData:        fmt.Sprintf("unused_caches=%d of %d, refs=%d, unused_gb_months=%.2f", unused, len(in.Caches), len(refs), gbMonths),
+		EstimatedSavingUSD: round2(saving),
+		Patch: `- uses: actions/cache/restore@v4
+  with:
+    path: ~/.npm
+    key: ${{ runner.os }}-${{ hashFiles('**/package-lock.json') }}
+# ... build ...
+- uses: actions/cache/save@v4
+  if: github.ref == 'refs/heads/main'
+  with:
+    path: ~/.npm
+    key: ${{ runner.os }}-${{ hashFiles('**/package-lock.json') }}`,
+		Evidence: ev,
+	}, true
+}
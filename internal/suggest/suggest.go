@@ -3,17 +3,26 @@ package suggest
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
 )
 
 type Inputs struct {
 	Repo     string
+	Start    time.Time
+	End      time.Time
 	Runs     []model.WorkflowRun
 	Jobs     []model.Job
 	Cost     model.CostResult
 	Waste    model.WasteMetrics
 	Hotspots []model.HotspotEntry
+	// Artifacts and Caches feed the storage suggestions, priced with
+	// StorageRates.
+	Artifacts    []model.Artifact
+	Caches       []model.ActionsCache
+	StorageRates pricing.StorageRates
 }
 
 type Suggestion struct {
@@ -27,7 +36,7 @@ type Suggestion struct {
 }
 
 func Generate(in Inputs) []Suggestion {
-	out := make([]Suggestion, 0, 4)
+	out := make([]Suggestion, 0, 5)
 
 	if in.Waste.CancelWasteUSD > 0 {
 		out = append(out, Suggestion{
@@ -45,7 +54,7 @@ func Generate(in Inputs) []Suggestion {
 
 	if len(in.Hotspots) > 0 && in.Hotspots[0].CostUSD > 0 {
 		top := in.Hotspots[0]
-		out = append(out, Suggestion{
+		s := Suggestion{
 			Type:               "cache",
 			Title:              "Add dependency cache to hottest workflow",
 			Problem:            "High-cost workflow repeats dependency resolution.",
@@ -58,7 +67,30 @@ func Generate(in Inputs) []Suggestion {
       ~/.cache/pip
     key: ${{ runner.os }}-${{ hashFiles('**/package-lock.json', '**/requirements.txt') }}`,
 			Evidence: evidence(top.Name, "", top.FailRate, top.CostUSD),
-		})
+		}
+		if st, ok := slowestStep(in, top.Name, isDependencyStep); ok {
+			s.Title = fmt.Sprintf("Cache dependencies for slow step %q", st.Step)
+			s.Problem = fmt.Sprintf("Step %q in job %q re-resolves dependencies on every run.", st.Step, st.Job)
+			s.CurrentData = fmt.Sprintf("workflow=%s, job=%s, step=%s, avg_step_sec=%.0f, step_share_of_job=%.1f%%, step_cost_usd=%.2f",
+				st.Workflow, st.Job, st.Step, st.AvgDuration, st.SharePct, st.CostUSD)
+			s.EstimatedSavingUSD = round2(st.CostUSD * 0.5)
+			s.Evidence = withStep(s.Evidence, st)
+		}
+		out = append(out, s)
+	}
+
+	if st, ok := slowestStep(in, firstWorkflow(in.Hotspots), isToolchainSetupStep); ok && st.SharePct >= 5 {
+		if patch, ok := setupPatch(in, st.Step); ok {
+			out = append(out, Suggestion{
+				Type:               "setup",
+				Title:              fmt.Sprintf("Speed up toolchain setup step %q", st.Step),
+				Problem:            fmt.Sprintf("Step %q in job %q spends a large share of the job installing tooling.", st.Step, st.Job),
+				CurrentData:        fmt.Sprintf("workflow=%s, job=%s, step=%s, avg_step_sec=%.0f, step_share_of_job=%.1f%%, step_cost_usd=%.2f", st.Workflow, st.Job, st.Step, st.AvgDuration, st.SharePct, st.CostUSD),
+				EstimatedSavingUSD: round2(st.CostUSD * 0.5),
+				Patch:              patch,
+				Evidence:           withStep(evidence(st.Workflow, st.Job, 0, st.CostUSD), st),
+			})
+		}
 	}
 
 	pushCount := 0
@@ -103,6 +135,13 @@ func Generate(in Inputs) []Suggestion {
 		}
 	}
 
+	if s, ok := artifactRetention(in); ok {
+		out = append(out, s)
+	}
+	if s, ok := unusedCaches(in); ok {
+		out = append(out, s)
+	}
+
 	filtered := make([]Suggestion, 0, len(out))
 	for _, s := range out {
 		if s.EstimatedSavingUSD <= 0 || len(s.Evidence) == 0 {
@@ -1,9 +1,12 @@
 package suggest
 
 import (
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/peter941221/CICost/internal/model"
+	"github.com/peter941221/CICost/internal/pricing"
 )
 
 func TestGenerateProducesExecutableSuggestions(t *testing.T) {
@@ -72,3 +75,167 @@ func TestGenerateProducesExecutableSuggestions(t *testing.T) {
 		}
 	}
 }
+
+func TestGenerateUsesStepEvidence(t *testing.T) {
+	input := Inputs{
+		Cost:     model.CostResult{TotalCostUSD: 100},
+		Hotspots: []model.HotspotEntry{{Name: "ci", CostUSD: 100}},
+		Runs:     []model.WorkflowRun{{ID: 1, WorkflowName: "ci"}},
+		Jobs: []model.Job{
+			{
+				ID: 10, RunID: 1, Name: "build", DurationSec: 1000,
+				Steps: []model.Step{
+					{Name: "Set up job", DurationSec: 10},
+					{Name: "Set up Node", DurationSec: 90},
+					{Name: "npm ci", DurationSec: 400},
+					{Name: "npm test", DurationSec: 500},
+				},
+			},
+		},
+	}
+	byType := map[string]Suggestion{}
+	for _, s := range Generate(input) {
+		byType[s.Type] = s
+	}
+	cache, ok := byType["cache"]
+	if !ok {
+		t.Fatal("expected cache suggestion")
+	}
+	if cache.Evidence["step"] != "npm ci" || cache.Evidence["step_share_of_job_pct"] != 40.0 || cache.EstimatedSavingUSD != 20 {
+		t.Fatalf("expected cache suggestion to cite npm ci, got %+v", cache)
+	}
+	setup, ok := byType["setup"]
+	if !ok {
+		t.Fatal("expected setup suggestion")
+	}
+	if setup.Evidence["step"] != "Set up Node" || setup.Evidence["job"] != "build" {
+		t.Fatalf("expected setup suggestion to cite Set up Node, got %+v", setup.Evidence)
+	}
+	if !strings.Contains(setup.Patch, "actions/setup-node@v4") || !strings.Contains(setup.Patch, "cache: npm") {
+		t.Fatalf("expected a setup-node patch, got %s", setup.Patch)
+	}
+}
+
+func TestGenerateSetupPatchFollowsToolchain(t *testing.T) {
+	setup := func(step string) (Suggestion, bool) {
+		t.Helper()
+		input := Inputs{
+			Cost:     model.CostResult{TotalCostUSD: 100},
+			Hotspots: []model.HotspotEntry{{Name: "ci", CostUSD: 100}},
+			Runs:     []model.WorkflowRun{{ID: 1, WorkflowName: "ci"}},
+			Jobs: []model.Job{{ID: 10, RunID: 1, Name: "build", DurationSec: 1000, Steps: []model.Step{
+				{Name: step, DurationSec: 200},
+				{Name: "go test ./...", DurationSec: 800},
+			}}},
+		}
+		for _, s := range Generate(input) {
+			if s.Type == "setup" {
+				return s, true
+			}
+		}
+		return Suggestion{}, false
+	}
+
+	goSetup, _ := setup("Set up Go")
+	if !strings.Contains(goSetup.Patch, "actions/setup-go@v5") || !strings.Contains(goSetup.Patch, "cache: true") ||
+		strings.Contains(goSetup.Patch, "setup-node") || goSetup.EstimatedSavingUSD != 10 {
+		t.Fatalf("expected a cached setup-go patch, got %+v", goSetup)
+	}
+	if py, _ := setup("Set up Python 3.12"); !strings.Contains(py.Patch, "actions/setup-python@v5") || !strings.Contains(py.Patch, "cache: pip") {
+		t.Fatalf("expected a setup-python patch, got %s", py.Patch)
+	}
+	// No setup action caches for QEMU, so there is nothing to suggest.
+	if qemu, ok := setup("Set up QEMU"); ok {
+		t.Fatalf("expected no setup suggestion for QEMU, got %+v", qemu)
+	}
+}
+
+func TestGenerateStepEvidenceAfterRenameAndBuildTools(t *testing.T) {
+	created := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	input := Inputs{
+		Cost: model.CostResult{TotalCostUSD: 100},
+		// Hotspots name a workflow by its latest name.
+		Hotspots: []model.HotspotEntry{{Name: "ci", CostUSD: 100}},
+		Runs: []model.WorkflowRun{
+			{ID: 1, WorkflowID: 7, WorkflowName: "build", CreatedAt: created},
+			{ID: 2, WorkflowID: 7, WorkflowName: "ci", CreatedAt: created.AddDate(0, 0, 1)},
+		},
+		Jobs: []model.Job{
+			{ID: 10, RunID: 1, Name: "test", DurationSec: 1000, Steps: []model.Step{
+				{Name: "Build with Gradle", DurationSec: 600},
+				{Name: "mvn test", DurationSec: 300},
+				{Name: "dotnet restore", DurationSec: 100},
+			}},
+			{ID: 20, RunID: 2, Name: "test", DurationSec: 1000, Steps: []model.Step{
+				{Name: "Build with Gradle", DurationSec: 600},
+				{Name: "mvn test", DurationSec: 300},
+				{Name: "dotnet restore", DurationSec: 100},
+			}},
+		},
+	}
+	var cache *Suggestion
+	for _, s := range Generate(input) {
+		if s.Type == "cache" {
+			cache = &s
+		}
+	}
+	if cache == nil {
+		t.Fatal("expected cache suggestion")
+	}
+	// Both runs count, the one from before the rename too.
+	if cache.Evidence["step"] != "dotnet restore" || cache.Evidence["step_avg_duration_sec"] != 100.0 || cache.Evidence["step_cost"] != 10.0 {
+		t.Fatalf("expected cache suggestion to cite dotnet restore over both runs, got %+v", cache.Evidence)
+	}
+	if !strings.Contains(cache.CurrentData, "workflow=ci,") {
+		t.Fatalf("expected the latest workflow name, got %s", cache.CurrentData)
+	}
+}
+
+func TestGenerateStorageSuggestions(t *testing.T) {
+	start := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	end := start.Add(730 * time.Hour)
+	gb := int64(1 << 30)
+	input := Inputs{
+		Start: start,
+		End:   end,
+		Runs:  []model.WorkflowRun{{ID: 7, WorkflowName: "nightly"}},
+		Cost: model.CostResult{Storage: model.StorageCost{
+			CacheGBMonths:         20,
+			CacheIncludedGBMonths: 10,
+		}},
+		Artifacts: []model.Artifact{
+			{ID: 1, RunID: 7, Name: "bundle", SizeBytes: 2 * gb, CreatedAt: start, ExpiresAt: start.AddDate(0, 0, 90)},
+			{ID: 2, RunID: 7, Name: "logs", SizeBytes: gb, CreatedAt: start, ExpiresAt: start.AddDate(0, 0, 5)},
+			// Deleted after two days, so it was never kept too long.
+			{ID: 3, RunID: 7, Name: "release", SizeBytes: 8 * gb, CreatedAt: start, ExpiresAt: start.AddDate(0, 0, 90), RemovedAt: start.AddDate(0, 0, 2)},
+		},
+		Caches: []model.ActionsCache{
+			{ID: 1, Ref: "refs/pull/1/merge", SizeBytes: 4 * gb, CreatedAt: start, LastAccessedAt: start, LastSeenAt: end},
+			{ID: 2, Ref: "refs/heads/main", SizeBytes: 4 * gb, CreatedAt: start, LastAccessedAt: start.Add(48 * time.Hour), LastSeenAt: end},
+		},
+		StorageRates: pricing.StorageRates{GBMonthUSD: 0.25, CacheIncludedGB: 10},
+	}
+	byType := map[string]Suggestion{}
+	for _, s := range Generate(input) {
+		byType[s.Type] = s
+	}
+	ret, ok := byType["artifact_retention"]
+	if !ok {
+		t.Fatalf("expected artifact retention suggestion, got %+v", byType)
+	}
+	if ret.Evidence["artifact"] != "bundle" || ret.Evidence["workflow"] != "nightly" || !strings.Contains(ret.Patch, "retention-days: 7") {
+		t.Fatalf("unexpected retention suggestion: %+v", ret)
+	}
+	// 2 GiB kept past its first 7 days for the rest of the 730h window.
+	if ret.EstimatedSavingUSD != 0.38 || ret.Evidence["excess_gb_months"] != 1.54 {
+		t.Fatalf("expected saving 0.38 over 1.54 GB-months, got %+v", ret)
+	}
+	unused, ok := byType["cache_unused"]
+	if !ok {
+		t.Fatalf("expected unused cache suggestion, got %+v", byType)
+	}
+	// 4 GB-months never restored, half of cache storage billable.
+	if unused.Evidence["unused_caches"] != 1 || unused.EstimatedSavingUSD != 0.5 {
+		t.Fatalf("unexpected unused cache suggestion: %+v", unused)
+	}
+}